@@ -9,6 +9,10 @@
 #   terraform show -json tfplan > tfplan.json
 #   conftest test tfplan.json -p policies/terraform/
 #
+# Every deny/warn rule carries a METADATA annotation with a unique custom.id.
+# The rule's passing and violating fixtures live in
+# tests/terraform/testdata/policy/<id>/ and are required for new rules.
+#
 # =============================================================================
 
 package terraform.azure
@@ -22,6 +26,10 @@ import future.keywords.in
 # Required tags for all Azure resources
 required_tags := ["environment", "project", "owner", "cost-center"]
 
+# METADATA
+# title: Required tags
+# custom:
+#   id: require-tags
 deny[msg] {
   resource := input.resource_changes[_]
   is_taggable_resource(resource.type)
@@ -53,6 +61,10 @@ is_taggable_resource(type) {
 # SECURITY - TLS VERSION
 # -----------------------------------------------------------------------------
 
+# METADATA
+# title: Storage account minimum TLS version
+# custom:
+#   id: storage-min-tls
 deny[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_storage_account"
@@ -62,19 +74,30 @@ deny[msg] {
   msg := sprintf("Storage account %s must use TLS 1.2 minimum (current: %s)", [resource.address, tls_version])
 }
 
+# Flexible server enforces SSL through the require_secure_transport server
+# parameter, which is on unless a server configuration turns it off.
+
+# METADATA
+# title: PostgreSQL SSL enforcement
+# custom:
+#   id: postgresql-ssl-enforcement
 deny[msg] {
   resource := input.resource_changes[_]
-  resource.type == "azurerm_postgresql_flexible_server"
+  resource.type == "azurerm_postgresql_flexible_server_configuration"
   resource.change.actions[_] in ["create", "update"]
-  ssl_mode := object.get(resource.change.after, "ssl_enforcement_enabled", false)
-  ssl_mode == false
-  msg := sprintf("PostgreSQL server %s must have SSL enforcement enabled", [resource.address])
+  object.get(resource.change.after, "name", "") == "require_secure_transport"
+  lower(object.get(resource.change.after, "value", "on")) != "on"
+  msg := sprintf("PostgreSQL server configuration %s must not disable SSL enforcement (require_secure_transport)", [resource.address])
 }
 
 # -----------------------------------------------------------------------------
 # SECURITY - ENCRYPTION
 # -----------------------------------------------------------------------------
 
+# METADATA
+# title: Storage account infrastructure encryption
+# custom:
+#   id: storage-infrastructure-encryption
 deny[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_storage_account"
@@ -84,6 +107,10 @@ deny[msg] {
   msg := sprintf("Storage account %s should have infrastructure encryption enabled", [resource.address])
 }
 
+# METADATA
+# title: Key Vault purge protection
+# custom:
+#   id: keyvault-purge-protection
 warn[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_key_vault"
@@ -97,6 +124,10 @@ warn[msg] {
 # SECURITY - PUBLIC ACCESS
 # -----------------------------------------------------------------------------
 
+# METADATA
+# title: Storage account public network access
+# custom:
+#   id: storage-public-access
 deny[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_storage_account"
@@ -106,6 +137,10 @@ deny[msg] {
   msg := sprintf("Storage account %s should not allow public network access", [resource.address])
 }
 
+# METADATA
+# title: Key Vault public network access
+# custom:
+#   id: keyvault-public-access
 deny[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_key_vault"
@@ -115,6 +150,10 @@ deny[msg] {
   msg := sprintf("Key Vault %s should not allow public network access", [resource.address])
 }
 
+# METADATA
+# title: AKS public API access
+# custom:
+#   id: aks-public-api
 warn[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_kubernetes_cluster"
@@ -128,6 +167,10 @@ warn[msg] {
 # SECURITY - HTTPS ONLY
 # -----------------------------------------------------------------------------
 
+# METADATA
+# title: Storage account HTTPS only
+# custom:
+#   id: storage-https-only
 deny[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_storage_account"
@@ -141,6 +184,10 @@ deny[msg] {
 # NETWORKING - PRIVATE ENDPOINTS
 # -----------------------------------------------------------------------------
 
+# METADATA
+# title: Storage account private endpoint
+# custom:
+#   id: storage-private-endpoint
 warn[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_storage_account"
@@ -159,6 +206,10 @@ has_private_endpoint(resource_address) {
 # AKS SPECIFIC POLICIES
 # -----------------------------------------------------------------------------
 
+# METADATA
+# title: AKS RBAC
+# custom:
+#   id: aks-rbac
 deny[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_kubernetes_cluster"
@@ -168,6 +219,10 @@ deny[msg] {
   msg := sprintf("AKS cluster %s must have RBAC enabled", [resource.address])
 }
 
+# METADATA
+# title: AKS managed identity
+# custom:
+#   id: aks-managed-identity
 deny[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_kubernetes_cluster"
@@ -177,6 +232,10 @@ deny[msg] {
   msg := sprintf("AKS cluster %s must use managed identity", [resource.address])
 }
 
+# METADATA
+# title: AKS Azure Policy add-on
+# custom:
+#   id: aks-azure-policy
 warn[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_kubernetes_cluster"
@@ -186,6 +245,10 @@ warn[msg] {
   msg := sprintf("AKS cluster %s should have Azure Policy enabled", [resource.address])
 }
 
+# METADATA
+# title: AKS Microsoft Defender
+# custom:
+#   id: aks-defender
 warn[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_kubernetes_cluster"
@@ -199,19 +262,38 @@ warn[msg] {
 # DATABASE POLICIES
 # -----------------------------------------------------------------------------
 
+# METADATA
+# title: PostgreSQL geo-redundant backup
+# custom:
+#   id: postgresql-geo-backup
 deny[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_postgresql_flexible_server"
   resource.change.actions[_] in ["create", "update"]
+  is_production(resource)
   geo_backup := object.get(resource.change.after, "geo_redundant_backup_enabled", false)
   geo_backup == false
   msg := sprintf("PostgreSQL server %s must have geo-redundant backup enabled for production", [resource.address])
 }
 
+# Production resources are tagged environment = prod, either by the caller
+# or by the modules' three-horizons/environment tag.
+production_environments := ["prod", "prd", "production"]
+
+is_production(resource) {
+  tags := object.get(resource.change.after, "tags", {})
+  some key in ["environment", "three-horizons/environment"]
+  lower(object.get(tags, key, "")) in production_environments
+}
+
 # -----------------------------------------------------------------------------
 # COST OPTIMIZATION
 # -----------------------------------------------------------------------------
 
+# METADATA
+# title: Node pool autoscaling
+# custom:
+#   id: node-pool-autoscaling
 warn[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_kubernetes_cluster_node_pool"
@@ -221,6 +303,10 @@ warn[msg] {
   msg := sprintf("Node pool %s should have autoscaling enabled for cost optimization", [resource.address])
 }
 
+# METADATA
+# title: Expensive VM sizes
+# custom:
+#   id: vm-expensive-size
 warn[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_virtual_machine"
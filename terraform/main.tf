@@ -161,6 +161,13 @@ locals {
   }
 
   config = local.deployment_configs[var.deployment_mode]
+
+  # Environment codes of modules/naming
+  naming_environments = {
+    dev     = "dev"
+    staging = "stg"
+    prod    = "prd"
+  }
 }
 
 # =============================================================================
@@ -195,8 +202,9 @@ module "networking" {
     app_gateway_cidr       = "10.0.6.0/24"
   }
 
-  enable_bastion     = var.deployment_mode == "enterprise"
-  enable_app_gateway = var.deployment_mode == "enterprise"
+  enable_bastion           = var.deployment_mode == "enterprise"
+  enable_app_gateway       = var.deployment_mode == "enterprise"
+  enable_purview_dns_zones = var.enable_purview
 
   dns_zone_name   = var.domain_name
   create_dns_zone = true
@@ -320,8 +328,6 @@ module "aks" {
 
   admin_group_ids = [var.admin_group_id]
 
-  private_dns_zone_id = module.networking.private_dns_zone_ids.aks
-
   tags = local.common_tags
 
   depends_on = [module.networking]
@@ -359,6 +365,9 @@ module "databases" {
     databases             = ["rhdh", "backstage"]
   }
 
+  # RHDH logs in with its own role, not the server admin
+  postgresql_users = var.enable_rhdh ? ["rhdh"] : []
+
   redis_config = {
     enabled             = true
     sku_name            = var.deployment_mode == "express" ? "Basic" : "Standard"
@@ -373,7 +382,8 @@ module "databases" {
 
   tags = local.common_tags
 
-  depends_on = [module.networking, module.security]
+  # The Job that creates postgresql_users runs on the cluster
+  depends_on = [module.networking, module.security, module.aks]
 }
 
 # =============================================================================
@@ -440,7 +450,7 @@ module "ai_foundry" {
   }
 
   key_vault_id               = module.security.key_vault_id
-  log_analytics_workspace_id = module.observability[0].log_analytics_workspace_id
+  log_analytics_workspace_id = var.enable_observability ? module.observability[0].log_analytics_workspace_id : ""
 
   tags = local.common_tags
 
@@ -522,15 +532,12 @@ module "container_registry" {
   location            = var.location
   resource_group_name = azurerm_resource_group.main.name
 
-  sku                       = var.deployment_mode == "express" ? "Standard" : "Premium"
-  zone_redundancy_enabled   = var.deployment_mode == "enterprise"
-  admin_enabled             = false
-  public_network_access_enabled = var.deployment_mode == "express"
+  sku = var.deployment_mode == "express" ? "Standard" : "Premium"
 
   subnet_id           = module.networking.subnet_ids.private_endpoints
   private_dns_zone_id = module.networking.private_dns_zone_ids.acr
 
-  aks_kubelet_identity_id = module.aks.kubelet_identity.object_id
+  aks_kubelet_identity_object_id = module.aks.kubelet_identity.object_id
 
   tags = local.common_tags
 
@@ -545,10 +552,17 @@ module "external_secrets" {
   source = "./modules/external-secrets"
   count  = var.enable_external_secrets ? 1 : 0
 
-  namespace        = "external-secrets"
-  chart_version    = "0.9.9"
-  key_vault_name   = module.security.keyvault_name
-  tenant_id        = var.azure_tenant_id
+  customer_name       = var.customer_name
+  environment         = var.environment
+  location            = var.location
+  resource_group_name = azurerm_resource_group.main.name
+
+  namespace         = "external-secrets"
+  eso_chart_version = "0.9.9"
+
+  aks_cluster_name = module.aks.cluster_name
+  key_vault_id     = module.security.key_vault_id
+  key_vault_uri    = module.security.key_vault_uri
 
   tags = local.common_tags
 
@@ -563,14 +577,16 @@ module "github_runners" {
   source = "./modules/github-runners"
   count  = var.enable_github_runners ? 1 : 0
 
-  namespace                   = "github-runners"
-  github_org                  = var.github_org
-  github_app_id               = var.github_app_id
-  github_app_installation_id  = var.github_app_installation_id
-  github_app_private_key      = var.github_app_private_key
-  runner_scale_set_name       = "arc-runners"
-  min_runners                 = 1
-  max_runners                 = var.deployment_mode == "enterprise" ? 10 : 5
+  customer_name = var.customer_name
+  environment   = var.environment
+  namespace     = "github-runners"
+
+  github_org                 = var.github_org
+  github_app_id              = var.github_app_id
+  github_app_installation_id = var.github_app_installation_id
+  github_app_private_key     = var.github_app_private_key
+
+  acr_login_server = var.enable_container_registry ? module.container_registry[0].login_server : ""
 
   tags = local.common_tags
 
@@ -587,17 +603,32 @@ module "rhdh" {
 
   customer_name       = var.customer_name
   environment         = var.environment
+  location            = var.location
+  resource_group_name = azurerm_resource_group.main.name
   namespace           = "rhdh"
 
-  domain_name         = var.domain_name
-  github_org          = var.github_org
+  base_url   = "https://developer.${var.domain_name}"
+  github_org = var.github_org
 
-  github_app_id            = var.github_app_id
-  github_app_client_id     = var.github_app_client_id
-  github_app_client_secret = var.github_app_client_secret
+  github_app_id             = var.github_app_id
+  github_app_client_id      = var.github_app_client_id
+  github_app_client_secret  = var.github_app_client_secret
+  github_app_private_key    = var.github_app_private_key
+  github_app_webhook_secret = var.github_app_webhook_secret
+
+  postgresql_host     = var.enable_databases ? module.databases[0].postgresql_server_fqdn : ""
+  postgresql_password = var.enable_databases ? module.databases[0].postgresql_user_passwords["rhdh"] : ""
 
-  postgresql_host     = var.enable_databases ? module.databases[0].postgresql_host : ""
-  key_vault_id        = module.security.key_vault_id
+  argocd_url        = var.enable_argocd ? module.argocd[0].url : ""
+  argocd_auth_token = var.argocd_auth_token
+
+  azure_tenant_id     = var.azure_tenant_id
+  azure_client_id     = var.rhdh_azure_client_id
+  azure_client_secret = var.rhdh_azure_client_secret
+
+  key_vault_name      = module.security.key_vault_name
+  aks_oidc_issuer_url = module.aks.oidc_issuer_url
+  subnet_id           = module.networking.subnet_ids.aks_nodes
 
   tags = local.common_tags
 
@@ -612,18 +643,18 @@ module "defender" {
   source = "./modules/defender"
   count  = var.enable_defender ? 1 : 0
 
-  customer_name       = var.customer_name
-  environment         = var.environment
-  location            = var.location
-  resource_group_name = azurerm_resource_group.main.name
+  subscription_id = var.azure_subscription_id
+  customer_name   = var.customer_name
+  environment     = var.environment
+
+  log_analytics_workspace_id = var.enable_observability ? module.observability[0].log_analytics_workspace_id : ""
+  security_contact_email     = var.security_contact_email
 
-  enable_container_plan     = true
-  enable_servers_plan       = var.deployment_mode == "enterprise"
-  enable_storage_plan       = var.deployment_mode == "enterprise"
-  enable_key_vault_plan     = true
-  enable_dns_plan           = false
+  aks_cluster_ids = [module.aks.cluster_id]
 
   tags = local.common_tags
+
+  depends_on = [module.observability]
 }
 
 # =============================================================================
@@ -639,8 +670,18 @@ module "purview" {
   location            = var.location
   resource_group_name = azurerm_resource_group.main.name
 
-  subnet_id           = module.networking.subnet_ids.private_endpoints
-  admin_group_id      = var.admin_group_id
+  subnet_id = module.networking.subnet_ids.private_endpoints
+
+  private_dns_zone_ids = {
+    purview        = module.networking.private_dns_zone_ids.purview
+    purview_studio = module.networking.private_dns_zone_ids.purview_studio
+    storage_blob   = module.networking.private_dns_zone_ids.blob
+    storage_queue  = module.networking.private_dns_zone_ids.queue
+    servicebus     = module.networking.private_dns_zone_ids.servicebus
+    eventhub       = module.networking.private_dns_zone_ids.eventhub
+  }
+
+  admin_group_id = var.admin_group_id
 
   tags = local.common_tags
 
@@ -655,11 +696,13 @@ module "cost_management" {
   source = "./modules/cost-management"
   count  = var.enable_cost_management ? 1 : 0
 
+  customer_name       = var.customer_name
+  environment         = var.environment
+  location            = var.location
   resource_group_name = azurerm_resource_group.main.name
-  subscription_id     = var.azure_subscription_id
-  budget_amount       = var.budget_amount
-  alert_thresholds    = [50, 75, 90, 100]
-  alert_emails        = var.alert_emails
+
+  monthly_budget        = var.budget_amount
+  alert_email_addresses = var.alert_emails
 
   tags = local.common_tags
 }
@@ -668,20 +711,40 @@ module "cost_management" {
 # MODULE: DISASTER RECOVERY (Cross-cutting)
 # =============================================================================
 
+# Region short codes come from modules/naming, the single table of them.
+# Only region_code is read, so project_name is just a value its validation
+# accepts (2-10 alphanumerics, starting with a letter).
+module "naming_primary" {
+  source = "./modules/naming"
+  count  = var.enable_disaster_recovery ? 1 : 0
+
+  project_name = substr(replace(var.customer_name, "-", ""), 0, 10)
+  environment  = local.naming_environments[var.environment]
+  location     = var.location
+}
+
+module "naming_dr" {
+  source = "./modules/naming"
+  count  = var.enable_disaster_recovery ? 1 : 0
+
+  project_name = substr(replace(var.customer_name, "-", ""), 0, 10)
+  environment  = local.naming_environments[var.environment]
+  location     = var.dr_location
+}
+
 module "disaster_recovery" {
   source = "./modules/disaster-recovery"
   count  = var.enable_disaster_recovery ? 1 : 0
 
-  customer_name       = var.customer_name
-  environment         = var.environment
-  resource_group_name = azurerm_resource_group.main.name
+  customer_name = var.customer_name
+  environment   = var.environment
 
-  primary_location   = var.location
-  secondary_location = var.dr_location
+  primary_location            = var.location
+  primary_region_short        = module.naming_primary[0].region_code
+  primary_resource_group_name = azurerm_resource_group.main.name
 
-  enable_aks_dr               = var.deployment_mode == "enterprise"
-  enable_database_replication = true
-  enable_storage_replication  = true
+  dr_location     = var.dr_location
+  dr_region_short = module.naming_dr[0].region_code
 
   tags = local.common_tags
 }
@@ -29,6 +29,17 @@ locals {
 
   # Production settings
   is_prod = var.environment == "prod"
+
+  # Run by psql once per postgresql_users entry, with the user and password
+  # variables set
+  postgresql_roles_sql = <<-EOT
+    SELECT format('CREATE ROLE %I', :'user')
+    WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = :'user')\gexec
+    ALTER ROLE :"user" WITH LOGIN CREATEDB PASSWORD :'password';
+    GRANT :"user" TO CURRENT_USER;
+    SELECT format('ALTER DATABASE %I OWNER TO %I', :'user', :'user')
+    WHERE EXISTS (SELECT FROM pg_database WHERE datname = :'user')\gexec
+  EOT
 }
 
 # =============================================================================
@@ -43,6 +54,14 @@ resource "random_password" "postgresql" {
   override_special = "!#$%&*()-_=+[]{}<>:?"
 }
 
+# Application roles, so workloads never get the admin password
+resource "random_password" "postgresql_users" {
+  for_each = var.postgresql_config.enabled ? toset(var.postgresql_users) : []
+
+  length  = 32
+  special = false
+}
+
 resource "random_password" "redis" {
   count = var.redis_config.enabled ? 1 : 0
 
@@ -148,6 +167,116 @@ resource "azurerm_postgresql_flexible_server_firewall_rule" "azure_services" {
   end_ip_address   = "0.0.0.0"
 }
 
+# =============================================================================
+# POSTGRESQL APPLICATION ROLES
+# =============================================================================
+#
+# The server is reachable only from the VNet, so the roles are created by a
+# Job on the cluster. The SQL is idempotent: a rerun resets the passwords.
+
+resource "kubernetes_secret_v1" "postgresql_users" {
+  count = var.postgresql_config.enabled && length(var.postgresql_users) > 0 ? 1 : 0
+
+  metadata {
+    name      = "postgresql-users-${local.name_prefix}"
+    namespace = var.postgresql_users_namespace
+  }
+
+  data = merge(
+    {
+      "admin-password" = random_password.postgresql[0].result
+      "roles.sql"      = local.postgresql_roles_sql
+    },
+    { for user, password in random_password.postgresql_users : "user-${user}" => password.result }
+  )
+}
+
+resource "kubernetes_job_v1" "postgresql_users" {
+  count = var.postgresql_config.enabled && length(var.postgresql_users) > 0 ? 1 : 0
+
+  metadata {
+    name      = "postgresql-users-${local.name_prefix}"
+    namespace = var.postgresql_users_namespace
+  }
+
+  spec {
+    backoff_limit = 6
+
+    template {
+      metadata {}
+
+      spec {
+        restart_policy = "OnFailure"
+
+        container {
+          name    = "psql"
+          image   = "postgres:${var.postgresql_config.version}-alpine"
+          command = ["/bin/sh", "-c"]
+          args = [<<-EOT
+            set -e
+            for user in ${join(" ", var.postgresql_users)}; do
+              psql -v ON_ERROR_STOP=1 -v user="$user" -v password="$(cat "/secrets/user-$user")" -f /secrets/roles.sql
+            done
+          EOT
+          ]
+
+          env {
+            name  = "PGHOST"
+            value = azurerm_postgresql_flexible_server.main[0].fqdn
+          }
+
+          env {
+            name  = "PGUSER"
+            value = var.postgresql_config.admin_username
+          }
+
+          env {
+            name = "PGPASSWORD"
+            value_from {
+              secret_key_ref {
+                name = kubernetes_secret_v1.postgresql_users[0].metadata[0].name
+                key  = "admin-password"
+              }
+            }
+          }
+
+          env {
+            name  = "PGDATABASE"
+            value = "postgres"
+          }
+
+          env {
+            name  = "PGSSLMODE"
+            value = "require"
+          }
+
+          volume_mount {
+            name       = "secrets"
+            mount_path = "/secrets"
+            read_only  = true
+          }
+        }
+
+        volume {
+          name = "secrets"
+          secret {
+            secret_name = kubernetes_secret_v1.postgresql_users[0].metadata[0].name
+          }
+        }
+      }
+    }
+  }
+
+  wait_for_completion = true
+
+  timeouts {
+    create = "10m"
+    update = "10m"
+  }
+
+  depends_on = [azurerm_postgresql_flexible_server_database.databases]
+}
+
 # =============================================================================
 # REDIS CACHE
 # =============================================================================
@@ -233,6 +362,17 @@ resource "azurerm_key_vault_secret" "postgresql_password" {
   tags = local.common_tags
 }
 
+# Store application role passwords
+resource "azurerm_key_vault_secret" "postgresql_users" {
+  for_each = random_password.postgresql_users
+
+  name         = "postgresql-${each.key}-password"
+  value        = each.value.result
+  key_vault_id = var.key_vault_id
+
+  tags = local.common_tags
+}
+
 # Store Redis connection string
 resource "azurerm_key_vault_secret" "redis_connection_string" {
   count = var.redis_config.enabled ? 1 : 0
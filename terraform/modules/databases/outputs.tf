@@ -13,6 +13,12 @@ output "postgresql_admin_username" {
   value       = var.postgresql_config.enabled ? var.postgresql_config.admin_username : null
 }
 
+output "postgresql_user_passwords" {
+  description = "Passwords of postgresql_users, by role name"
+  value       = { for user, password in random_password.postgresql_users : user => password.result }
+  sensitive   = true
+}
+
 output "postgresql_databases" {
   description = "Created PostgreSQL databases"
   value       = var.postgresql_config.enabled ? var.postgresql_config.databases : []
@@ -43,6 +49,7 @@ output "key_vault_secret_names" {
   value = {
     postgresql_connection_string = var.postgresql_config.enabled ? azurerm_key_vault_secret.postgresql_connection_string[0].name : null
     postgresql_password          = var.postgresql_config.enabled ? azurerm_key_vault_secret.postgresql_password[0].name : null
+    postgresql_user_passwords    = { for user, secret in azurerm_key_vault_secret.postgresql_users : user => secret.name }
     redis_connection_string      = var.redis_config.enabled ? azurerm_key_vault_secret.redis_connection_string[0].name : null
     redis_primary_key            = var.redis_config.enabled ? azurerm_key_vault_secret.redis_primary_key[0].name : null
   }
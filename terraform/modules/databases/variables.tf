@@ -60,6 +60,22 @@ variable "postgresql_config" {
     databases             = ["rhdh", "backstage"]
   }
 }
+variable "postgresql_users" {
+  description = "PostgreSQL login roles for applications. Each role owns the database of the same name, if there is one, and may create databases"
+  type        = list(string)
+  default     = []
+
+  validation {
+    condition     = alltrue([for user in var.postgresql_users : can(regex("^[a-z][a-z0-9_]{0,62}$", user))])
+    error_message = "PostgreSQL users must be lowercase identifiers: a letter followed by letters, digits or underscores."
+  }
+}
+
+variable "postgresql_users_namespace" {
+  description = "Kubernetes namespace of the Job that creates postgresql_users"
+  type        = string
+  default     = "kube-system"
+}
 
 variable "redis_config" {
   description = "Redis configuration"
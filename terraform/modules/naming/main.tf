@@ -71,6 +71,26 @@ locals {
 
   # Short prefix for length-limited resources
   short_prefix = "${var.project_name}${var.environment}${local.region_code}"
+
+  # Names that can exceed the Azure length limit. A longer name keeps its
+  # first characters and ends with 5 characters of a hash of the full name:
+  # plain truncation cuts the region code or instance, so deployments that
+  # differ only there would collide, and can leave a Key Vault name ending
+  # in "-". Names within the limit are unchanged.
+  limited_names = {
+    key_vault            = { name = "kv-${local.base_prefix}", max = 24 }
+    storage_account      = { name = "st${local.base_prefix_no_dash}${var.instance}", max = 24 }
+    storage_account_diag = { name = "stdiag${local.base_prefix_no_dash}", max = 24 }
+    grafana              = { name = "amg-${local.base_prefix}", max = 23 }
+  }
+
+  limited = {
+    for key, entry in local.limited_names : key => (
+      length(entry.name) <= entry.max
+      ? entry.name
+      : "${substr(entry.name, 0, entry.max - 5)}${substr(sha1(entry.name), 0, 5)}"
+    )
+  }
 }
 
 # -----------------------------------------------------------------------------
@@ -263,7 +283,7 @@ output "aks_node_pool" {
   description = "AKS Node Pool name prefix"
   value       = substr(replace("${var.project_name}${var.environment}", "-", ""), 0, 6)
   # Rules: 1-12 chars for Windows, 1-12 for Linux, lowercase alphanumeric
-  # Must start with letter
+  # Must start with letter (project_name does)
 }
 
 output "aks_node_pool_system" {
@@ -357,14 +377,14 @@ output "redis_cache" {
 
 output "storage_account" {
   description = "Storage Account name (st)"
-  value       = substr("st${local.base_prefix_no_dash}${var.instance}", 0, 24)
+  value       = local.limited["storage_account"]
   # Rules: 3-24 chars, lowercase and numbers ONLY
   # Must be globally unique
 }
 
 output "storage_account_diag" {
   description = "Diagnostics Storage Account name"
-  value       = substr("stdiag${local.base_prefix_no_dash}", 0, 24)
+  value       = local.limited["storage_account_diag"]
 }
 
 output "storage_container" {
@@ -402,7 +422,7 @@ output "data_lake_store" {
 
 output "key_vault" {
   description = "Key Vault name (kv-)"
-  value       = substr("kv-${local.base_prefix}", 0, 24)
+  value       = local.limited["key_vault"]
   # Rules: 3-24 chars, alphanumeric and hyphens
   # Must start with letter, cannot end with hyphen
   # Must be globally unique
@@ -471,7 +491,8 @@ output "monitor_workspace" {
 
 output "grafana" {
   description = "Azure Managed Grafana name (amg-)"
-  value       = "amg-${local.base_prefix}"
+  value       = local.limited["grafana"]
+  # Rules: 2-23 chars, alphanumeric and hyphens
 }
 
 # =============================================================================
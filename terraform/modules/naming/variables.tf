@@ -11,8 +11,8 @@ variable "project_name" {
   description = "Project or workload name (e.g., 'threehorizons')"
   type        = string
   validation {
-    condition     = can(regex("^[a-z0-9]{2,10}$", var.project_name))
-    error_message = "Project name must be 2-10 lowercase alphanumeric characters."
+    condition     = can(regex("^[a-z][a-z0-9]{1,9}$", var.project_name))
+    error_message = "Project name must be 2-10 lowercase alphanumeric characters, starting with a letter."
   }
 }
 
@@ -34,10 +34,20 @@ variable "instance" {
   description = "Instance number for multiple deployments (e.g., '001')"
   type        = string
   default     = "001"
+  # Fixed width: the storage account name ends with region code + instance,
+  # and region codes share prefixes (wus + 21 = wus2 + 1)
+  validation {
+    condition     = can(regex("^[0-9]{3}$", var.instance))
+    error_message = "Instance must be 3 digits (e.g., '001')."
+  }
 }
 
 variable "org_code" {
   description = "Organization code (2-4 chars, e.g., 'ms', 'cont')"
   type        = string
   default     = ""
+  validation {
+    condition     = var.org_code == "" || can(regex("^[a-z0-9]{2,4}$", var.org_code))
+    error_message = "Organization code must be empty or 2-4 lowercase alphanumeric characters."
+  }
 }
@@ -28,6 +28,30 @@ locals {
     "three-horizons/component"   = "networking"
   })
 
+  # Address checks of the deployed subnets. Two prefixes overlap when they
+  # have the same network address at the shorter prefix length.
+  subnet_cidrs = {
+    for key, cidr in var.subnet_config : key => cidr
+    if (key != "bastion_cidr" || var.enable_bastion) && (key != "app_gateway_cidr" || var.enable_app_gateway)
+  }
+  subnet_keys           = sort(keys(local.subnet_cidrs))
+  subnet_prefix_lengths = { for key, cidr in local.subnet_cidrs : key => tonumber(split("/", cidr)[1]) }
+  vnet_prefix_length    = tonumber(split("/", var.vnet_cidr)[1])
+
+  subnets_outside_vnet = [
+    for key, cidr in local.subnet_cidrs : "${key} (${cidr})"
+    if local.subnet_prefix_lengths[key] < local.vnet_prefix_length ||
+    cidrhost("${cidrhost(cidr, 0)}/${local.vnet_prefix_length}", 0) != cidrhost(var.vnet_cidr, 0)
+  ]
+
+  overlapping_subnets = flatten([
+    for i, a in local.subnet_keys : [
+      for b in slice(local.subnet_keys, i + 1, length(local.subnet_keys)) : "${a} and ${b}"
+      if cidrhost("${cidrhost(local.subnet_cidrs[a], 0)}/${min(local.subnet_prefix_lengths[a], local.subnet_prefix_lengths[b])}", 0) ==
+      cidrhost("${cidrhost(local.subnet_cidrs[b], 0)}/${min(local.subnet_prefix_lengths[a], local.subnet_prefix_lengths[b])}", 0)
+    ]
+  ])
+
   # Private DNS zones for Azure services
   private_dns_zones = {
     "postgres"          = "privatelink.postgres.database.azure.com"
@@ -39,6 +63,17 @@ locals {
     "cognitiveservices" = "privatelink.cognitiveservices.azure.com"
     "search"            = "privatelink.search.windows.net"
   }
+
+  # Zones of the Purview account and its managed storage and Event Hubs
+  purview_dns_zones = {
+    "queue"          = "privatelink.queue.core.windows.net"
+    "servicebus"     = "privatelink.servicebus.windows.net"
+    "eventhub"       = "privatelink.eventhub.windows.net"
+    "purview"        = "privatelink.purview.azure.com"
+    "purview_studio" = "privatelink.purviewstudio.azure.com"
+  }
+
+  dns_zones = merge(local.private_dns_zones, var.enable_purview_dns_zones ? local.purview_dns_zones : {})
 }
 
 # =============================================================================
@@ -52,6 +87,18 @@ resource "azurerm_virtual_network" "main" {
   address_space       = [var.vnet_cidr]
 
   tags = local.common_tags
+
+  lifecycle {
+    precondition {
+      condition     = length(local.subnets_outside_vnet) == 0
+      error_message = "Subnets outside vnet_cidr ${var.vnet_cidr}: ${join(", ", local.subnets_outside_vnet)}."
+    }
+
+    precondition {
+      condition     = length(local.overlapping_subnets) == 0
+      error_message = "Overlapping subnets: ${join(", ", local.overlapping_subnets)}. Application Gateway needs a dedicated subnet."
+    }
+  }
 }
 
 # =============================================================================
@@ -109,6 +156,13 @@ resource "azurerm_subnet" "bastion" {
   resource_group_name  = var.resource_group_name
   virtual_network_name = azurerm_virtual_network.main.name
   address_prefixes     = [var.subnet_config.bastion_cidr]
+
+  lifecycle {
+    precondition {
+      condition     = tonumber(split("/", var.subnet_config.bastion_cidr)[1]) <= 26
+      error_message = "AzureBastionSubnet must be /26 or larger."
+    }
+  }
 }
 
 # Application Gateway Subnet (if enabled)
@@ -236,7 +290,7 @@ resource "azurerm_subnet_network_security_group_association" "private_endpoints"
 # =============================================================================
 
 resource "azurerm_private_dns_zone" "zones" {
-  for_each = local.private_dns_zones
+  for_each = local.dns_zones
 
   name                = each.value
   resource_group_name = var.resource_group_name
@@ -246,7 +300,7 @@ resource "azurerm_private_dns_zone" "zones" {
 
 # Link private DNS zones to VNet
 resource "azurerm_private_dns_zone_virtual_network_link" "links" {
-  for_each = local.private_dns_zones
+  for_each = local.dns_zones
 
   name                  = "link-${each.key}-${local.name_prefix}"
   resource_group_name   = var.resource_group_name
@@ -26,6 +26,11 @@ variable "vnet_cidr" {
   description = "CIDR block for VNet"
   type        = string
   default     = "10.0.0.0/16"
+
+  validation {
+    condition     = can(cidrnetmask(var.vnet_cidr)) && try(cidrhost(var.vnet_cidr, 0) == split("/", var.vnet_cidr)[0], false)
+    error_message = "VNet CIDR must be an IPv4 network prefix without host bits, e.g. 10.0.0.0/16."
+  }
 }
 
 variable "subnet_config" {
@@ -44,6 +49,14 @@ variable "subnet_config" {
     bastion_cidr           = "10.0.5.0/26"
     app_gateway_cidr       = "10.0.6.0/24"
   }
+
+  validation {
+    condition = alltrue([
+      for cidr in values(var.subnet_config) :
+      can(cidrnetmask(cidr)) && try(cidrhost(cidr, 0) == split("/", cidr)[0] && tonumber(split("/", cidr)[1]) <= 29, false)
+    ])
+    error_message = "Subnet CIDRs must be IPv4 network prefixes without host bits, /29 or larger (Azure reserves 5 addresses in every subnet)."
+  }
 }
 
 variable "enable_bastion" {
@@ -58,6 +71,12 @@ variable "enable_app_gateway" {
   default     = false
 }
 
+variable "enable_purview_dns_zones" {
+  description = "Create the private DNS zones Microsoft Purview needs"
+  type        = bool
+  default     = false
+}
+
 variable "dns_zone_name" {
   description = "Public DNS zone name"
   type        = string
@@ -236,6 +236,47 @@ variable "github_app_private_key" {
   sensitive   = true
 }
 
+variable "github_app_webhook_secret" {
+  description = "GitHub App webhook secret for RHDH"
+  type        = string
+  default     = ""
+  sensitive   = true
+}
+
+# -----------------------------------------------------------------------------
+# RHDH — Required when enable_rhdh = true
+# -----------------------------------------------------------------------------
+
+variable "argocd_auth_token" {
+  description = "ArgoCD API token for the RHDH ArgoCD plugin"
+  type        = string
+  default     = ""
+  sensitive   = true
+}
+
+variable "rhdh_azure_client_id" {
+  description = "Azure AD application client ID for RHDH sign-in"
+  type        = string
+  default     = ""
+}
+
+variable "rhdh_azure_client_secret" {
+  description = "Azure AD application client secret for RHDH sign-in"
+  type        = string
+  default     = ""
+  sensitive   = true
+}
+
+# -----------------------------------------------------------------------------
+# Defender — Required when enable_defender = true
+# -----------------------------------------------------------------------------
+
+variable "security_contact_email" {
+  description = "Email address for Microsoft Defender security alerts"
+  type        = string
+  default     = ""
+}
+
 # -----------------------------------------------------------------------------
 # Cost Management — Required when enable_cost_management = true
 # -----------------------------------------------------------------------------
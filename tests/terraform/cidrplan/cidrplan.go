@@ -0,0 +1,277 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - SUBNET CIDR PLANNER
+// =============================================================================
+//
+// Address planning for the subnet_config of terraform/modules/networking:
+//
+//	vnet := netip.MustParsePrefix("10.0.0.0/16")
+//	layout, err := cidrplan.Allocate(vnet, cidrplan.Sizing{Nodes: 7, Pods: 770})
+//	problems := cidrplan.Validate(vnet, layout, cidrplan.Features{Bastion: true})
+//	options := helpers.NetworkingOptions(t, map[string]interface{}{
+//	    "vnet_cidr":     vnet.String(),
+//	    "subnet_config": layout.Vars(),
+//	})
+//
+// Validate applies the rules the module enforces at plan time, plus the
+// ones Azure enforces at apply time:
+//
+//   - every subnet is an IPv4 prefix without host bits, inside vnet_cidr
+//   - no two deployed subnets overlap, so Application Gateway gets a
+//     dedicated subnet
+//   - every subnet is /29 or larger: Azure reserves 5 addresses in each
+//   - AzureBastionSubnet is /26 or larger
+//
+// The Bastion and Application Gateway subnets are only placed when they
+// are deployed (enable_bastion, enable_app_gateway), but must be valid
+// prefixes either way, like the module's variable validation. The package
+// only depends on the standard library.
+//
+// =============================================================================
+
+package cidrplan
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+// Keys of the module's subnet_config.
+const (
+	AKSNodes         = "aks_nodes_cidr"
+	AKSPods          = "aks_pods_cidr"
+	PrivateEndpoints = "private_endpoints_cidr"
+	Bastion          = "bastion_cidr"
+	AppGateway       = "app_gateway_cidr"
+)
+
+// Subnets are the keys of subnet_config.
+var Subnets = []string{AKSNodes, AKSPods, PrivateEndpoints, Bastion, AppGateway}
+
+const (
+	// ReservedAddresses are the addresses Azure reserves in every subnet:
+	// the network address, the gateway, two for DNS and the broadcast.
+	ReservedAddresses = 5
+	// MinSubnetBits is the prefix length of the smallest subnet Azure
+	// allows.
+	MinSubnetBits = 29
+	// BastionBits is the prefix length of the smallest AzureBastionSubnet.
+	BastionBits = 26
+	// AppGatewayBits is the prefix length Allocate gives Application
+	// Gateway, enough for a v2 gateway to autoscale.
+	AppGatewayBits = 24
+	// DefaultPrivateEndpoints is the private endpoint capacity when
+	// Sizing.PrivateEndpoints is zero.
+	DefaultPrivateEndpoints = 64
+)
+
+// Layout is a subnet_config, keyed like Subnets.
+type Layout map[string]netip.Prefix
+
+// ParseLayout parses a subnet_config. Every key of Subnets must be set.
+func ParseLayout(config map[string]string) (Layout, error) {
+	layout := Layout{}
+	for _, key := range Subnets {
+		value, ok := config[key]
+		if !ok {
+			return nil, fmt.Errorf("%s is not set", key)
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		layout[key] = prefix
+	}
+	for key := range config {
+		if _, ok := layout[key]; !ok {
+			return nil, fmt.Errorf("%s is not a subnet of subnet_config", key)
+		}
+	}
+	return layout, nil
+}
+
+// Vars returns the layout as the subnet_config variable.
+func (l Layout) Vars() map[string]interface{} {
+	vars := make(map[string]interface{}, len(l))
+	for key, prefix := range l {
+		vars[key] = prefix.String()
+	}
+	return vars
+}
+
+// Features are the optional subnets that are deployed.
+type Features struct {
+	Bastion    bool
+	AppGateway bool
+}
+
+func (f Features) deployed(key string) bool {
+	switch key {
+	case Bastion:
+		return f.Bastion
+	case AppGateway:
+		return f.AppGateway
+	}
+	return true
+}
+
+// Usable returns the addresses of an IPv4 prefix left after Azure's
+// reserved addresses.
+func Usable(prefix netip.Prefix) int {
+	return 1<<(32-prefix.Bits()) - ReservedAddresses
+}
+
+// Validate checks layout against vnet. Every subnet must be a valid
+// prefix; only the deployed ones must fit in vnet and not overlap. Problems
+// are in Subnets order, overlaps last.
+func Validate(vnet netip.Prefix, layout Layout, features Features) []string {
+	var problems []string
+
+	if !vnet.Addr().Is4() {
+		return []string{fmt.Sprintf("vnet_cidr %s is not an IPv4 prefix", vnet)}
+	}
+	if vnet != vnet.Masked() {
+		problems = append(problems, fmt.Sprintf("vnet_cidr %s has host bits set, use %s", vnet, vnet.Masked()))
+	}
+
+	var deployed []string
+	for _, key := range Subnets {
+		prefix, ok := layout[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is not set", key))
+			continue
+		}
+		if !prefix.Addr().Is4() {
+			problems = append(problems, fmt.Sprintf("%s %s is not an IPv4 prefix", key, prefix))
+			continue
+		}
+		if prefix != prefix.Masked() {
+			problems = append(problems, fmt.Sprintf("%s %s has host bits set, use %s", key, prefix, prefix.Masked()))
+		}
+		if prefix.Bits() > MinSubnetBits {
+			problems = append(problems, fmt.Sprintf("%s %s is smaller than /%d: Azure reserves %d addresses in every subnet", key, prefix, MinSubnetBits, ReservedAddresses))
+		}
+		if !features.deployed(key) {
+			continue
+		}
+		deployed = append(deployed, key)
+
+		if key == Bastion && prefix.Bits() > BastionBits && prefix.Bits() <= MinSubnetBits {
+			problems = append(problems, fmt.Sprintf("%s %s is smaller than /%d, the minimum for AzureBastionSubnet", key, prefix, BastionBits))
+		}
+		if prefix.Bits() < vnet.Bits() || !vnet.Contains(prefix.Addr()) {
+			problems = append(problems, fmt.Sprintf("%s %s is outside vnet_cidr %s", key, prefix, vnet))
+		}
+	}
+
+	for i, a := range deployed {
+		for _, b := range deployed[i+1:] {
+			if !layout[a].Overlaps(layout[b]) {
+				continue
+			}
+			switch {
+			case a == AppGateway || b == AppGateway:
+				other := a
+				if a == AppGateway {
+					other = b
+				}
+				problems = append(problems, fmt.Sprintf("%s %s overlaps %s %s: Application Gateway needs a dedicated subnet", AppGateway, layout[AppGateway], other, layout[other]))
+			default:
+				problems = append(problems, fmt.Sprintf("%s %s overlaps %s %s", a, layout[a], b, layout[b]))
+			}
+		}
+	}
+
+	return problems
+}
+
+// Sizing is the address demand of a deployment.
+type Sizing struct {
+	// Nodes are the node addresses of the cluster at full scale, upgrade
+	// surge included.
+	Nodes int
+	// Pods are the pod addresses at full scale. The pod subnet gives every
+	// pod an address, so this is the node count times max_pods.
+	Pods int
+	// PrivateEndpoints is the private endpoint capacity, zero for
+	// DefaultPrivateEndpoints.
+	PrivateEndpoints int
+}
+
+// Demand returns the usable addresses each subnet needs.
+func (s Sizing) Demand() map[string]int {
+	privateEndpoints := s.PrivateEndpoints
+	if privateEndpoints == 0 {
+		privateEndpoints = DefaultPrivateEndpoints
+	}
+	return map[string]int{
+		AKSNodes:         s.Nodes,
+		AKSPods:          s.Pods,
+		PrivateEndpoints: privateEndpoints,
+		Bastion:          Usable(netip.PrefixFrom(netip.IPv4Unspecified(), BastionBits)),
+		AppGateway:       Usable(netip.PrefixFrom(netip.IPv4Unspecified(), AppGatewayBits)),
+	}
+}
+
+// CheckCapacity reports the subnets of layout with fewer usable addresses
+// than sizing demands.
+func CheckCapacity(layout Layout, sizing Sizing) []string {
+	var problems []string
+	demand := sizing.Demand()
+	for _, key := range []string{AKSNodes, AKSPods, PrivateEndpoints} {
+		prefix, ok := layout[key]
+		if !ok {
+			continue
+		}
+		if usable := Usable(prefix); usable < demand[key] {
+			problems = append(problems, fmt.Sprintf("%s %s has %d usable addresses, %d needed", key, prefix, usable, demand[key]))
+		}
+	}
+	return problems
+}
+
+// Bits returns the prefix length of the smallest subnet with usable
+// addresses.
+func Bits(usable int) int {
+	bits := MinSubnetBits
+	for bits > 0 && 1<<(32-bits)-ReservedAddresses < usable {
+		bits--
+	}
+	return bits
+}
+
+// Allocate lays the subnets out from the start of vnet, largest first so
+// every subnet is aligned without gaps. The layout passes Validate with
+// every feature deployed and CheckCapacity with sizing.
+func Allocate(vnet netip.Prefix, sizing Sizing) (Layout, error) {
+	if !vnet.Addr().Is4() || vnet != vnet.Masked() {
+		return nil, fmt.Errorf("vnet_cidr %s is not an IPv4 network prefix", vnet)
+	}
+
+	demand := sizing.Demand()
+	bits := map[string]int{}
+	for key, usable := range demand {
+		bits[key] = Bits(usable)
+	}
+
+	order := append([]string(nil), Subnets...)
+	sort.SliceStable(order, func(i, j int) bool { return bits[order[i]] < bits[order[j]] })
+
+	base := vnet.Addr().As4()
+	next := uint64(base[0])<<24 | uint64(base[1])<<16 | uint64(base[2])<<8 | uint64(base[3])
+	end := next + 1<<(32-vnet.Bits())
+
+	layout := Layout{}
+	for _, key := range order {
+		size := uint64(1) << (32 - bits[key])
+		next = (next + size - 1) / size * size
+		if next+size > end {
+			return nil, fmt.Errorf("%s needs a /%d for %d addresses, which does not fit in vnet_cidr %s", key, bits[key], demand[key], vnet)
+		}
+		addr := netip.AddrFrom4([4]byte{byte(next >> 24), byte(next >> 16), byte(next >> 8), byte(next)})
+		layout[key] = netip.PrefixFrom(addr, bits[key])
+		next += size
+	}
+
+	return layout, nil
+}
@@ -0,0 +1,196 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - SUBNET CIDR PLANNER TESTS
+// =============================================================================
+//
+// Static checks of the planner. The module enforcing the same rules is
+// tested in modules/networking_test.go.
+//
+// Run with: go test -v ./cidrplan/
+//
+// =============================================================================
+
+package cidrplan
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// defaultLayout is the default subnet_config of the module.
+func defaultLayout(t *testing.T) Layout {
+	t.Helper()
+
+	layout, err := ParseLayout(map[string]string{
+		AKSNodes:         "10.0.0.0/22",
+		AKSPods:          "10.0.16.0/20",
+		PrivateEndpoints: "10.0.4.0/24",
+		Bastion:          "10.0.5.0/26",
+		AppGateway:       "10.0.6.0/24",
+	})
+	require.NoError(t, err)
+	return layout
+}
+
+// TestValidate tests each rule, with and without the optional subnets
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	all := Features{Bastion: true, AppGateway: true}
+
+	testCases := []struct {
+		name     string
+		vnet     string
+		subnet   string
+		prefix   string
+		features Features
+		expected string
+	}{
+		{"valid", "10.0.0.0/16", "", "", all, ""},
+		{"valid_without_optional", "10.0.0.0/16", "", "", Features{}, ""},
+		{"vnet_host_bits", "10.0.0.1/16", "", "", all, "vnet_cidr 10.0.0.1/16 has host bits set, use 10.0.0.0/16"},
+		{"vnet_ipv6", "fd00::/48", "", "", all, "vnet_cidr fd00::/48 is not an IPv4 prefix"},
+		{"outside_vnet", "10.0.0.0/20", "", "", all, "aks_pods_cidr 10.0.16.0/20 is outside vnet_cidr 10.0.0.0/20"},
+		{"larger_than_vnet", "10.0.0.0/16", AKSPods, "10.0.0.0/15", all, "aks_pods_cidr 10.0.0.0/15 is outside vnet_cidr 10.0.0.0/16"},
+		{"subnet_host_bits", "10.0.0.0/16", PrivateEndpoints, "10.0.4.1/24", all, "private_endpoints_cidr 10.0.4.1/24 has host bits set, use 10.0.4.0/24"},
+		{"reserved_addresses", "10.0.0.0/16", PrivateEndpoints, "10.0.4.0/30", all, "private_endpoints_cidr 10.0.4.0/30 is smaller than /29: Azure reserves 5 addresses in every subnet"},
+		{"disabled_subnet_still_valid", "10.0.0.0/16", Bastion, "10.0.5.0/30", Features{}, "bastion_cidr 10.0.5.0/30 is smaller than /29: Azure reserves 5 addresses in every subnet"},
+		{"bastion_size", "10.0.0.0/16", Bastion, "10.0.5.0/27", all, "bastion_cidr 10.0.5.0/27 is smaller than /26, the minimum for AzureBastionSubnet"},
+		{"bastion_size_disabled", "10.0.0.0/16", Bastion, "10.0.5.0/27", Features{AppGateway: true}, ""},
+		{"overlap", "10.0.0.0/16", AKSPods, "10.0.0.0/20", all, "aks_nodes_cidr 10.0.0.0/22 overlaps aks_pods_cidr 10.0.0.0/20"},
+		{"app_gateway_dedicated", "10.0.0.0/16", AppGateway, "10.0.4.0/25", all, "app_gateway_cidr 10.0.4.0/25 overlaps private_endpoints_cidr 10.0.4.0/24: Application Gateway needs a dedicated subnet"},
+		{"app_gateway_disabled", "10.0.0.0/16", AppGateway, "10.0.4.0/25", Features{Bastion: true}, ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			layout := defaultLayout(t)
+			if tc.subnet != "" {
+				layout[tc.subnet] = netip.MustParsePrefix(tc.prefix)
+			}
+
+			problems := Validate(netip.MustParsePrefix(tc.vnet), layout, tc.features)
+			if tc.expected == "" {
+				assert.Empty(t, problems)
+				return
+			}
+			assert.Contains(t, problems, tc.expected)
+		})
+	}
+}
+
+// TestAllocate tests the layout of a known sizing and a VNet it cannot fit
+func TestAllocate(t *testing.T) {
+	t.Parallel()
+
+	vnet := netip.MustParsePrefix("10.0.0.0/16")
+	sizing := Sizing{Nodes: 7, Pods: 770}
+
+	layout, err := Allocate(vnet, sizing)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		AKSPods:          "10.0.0.0/22",
+		AppGateway:       "10.0.4.0/24",
+		PrivateEndpoints: "10.0.5.0/25",
+		Bastion:          "10.0.5.128/26",
+		AKSNodes:         "10.0.5.192/28",
+	}, layout.Vars())
+	assert.Empty(t, Validate(vnet, layout, Features{Bastion: true, AppGateway: true}))
+	assert.Empty(t, CheckCapacity(layout, sizing))
+
+	_, err = Allocate(netip.MustParsePrefix("10.0.0.0/22"), sizing)
+	assert.EqualError(t, err, "app_gateway_cidr needs a /24 for 251 addresses, which does not fit in vnet_cidr 10.0.0.0/22")
+
+	_, err = Allocate(netip.MustParsePrefix("10.0.0.1/16"), sizing)
+	assert.Error(t, err)
+}
+
+// TestBits tests subnet sizes around the reserved addresses
+func TestBits(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 29, Bits(0))
+	assert.Equal(t, 29, Bits(3))
+	assert.Equal(t, 28, Bits(4))
+	assert.Equal(t, 24, Bits(251))
+	assert.Equal(t, 23, Bits(252))
+	assert.Equal(t, 251, Usable(netip.MustParsePrefix("10.0.0.0/24")))
+}
+
+// TestCheckCapacity tests a subnet without room for the demand
+func TestCheckCapacity(t *testing.T) {
+	t.Parallel()
+
+	problems := CheckCapacity(defaultLayout(t), Sizing{Nodes: 10, Pods: 5000})
+	assert.Equal(t, []string{"aks_pods_cidr 10.0.16.0/20 has 4091 usable addresses, 5000 needed"}, problems)
+}
+
+// TestShippedLayoutsValid tests the subnet_config of the root module and of
+// the networking test baseline
+func TestShippedLayoutsValid(t *testing.T) {
+	t.Parallel()
+
+	all := Features{Bastion: true, AppGateway: true}
+
+	calls, err := helpers.ParseModuleCalls(helpers.RootModuleDir())
+	require.NoError(t, err)
+	for _, call := range calls {
+		if call.Name != "networking" {
+			continue
+		}
+		vnet, diags := call.Arguments["vnet_cidr"].Expr.Value(nil)
+		require.False(t, diags.HasErrors(), diags.Error())
+		config, diags := call.Arguments["subnet_config"].Expr.Value(nil)
+		require.False(t, diags.HasErrors(), diags.Error())
+
+		subnets := map[string]string{}
+		for key, value := range config.AsValueMap() {
+			subnets[key] = value.AsString()
+		}
+		layout, err := ParseLayout(subnets)
+		require.NoError(t, err)
+		assert.Empty(t, Validate(netip.MustParsePrefix(vnet.AsString()), layout, all), "root module")
+	}
+
+	baseline := helpers.Baseline(helpers.ModuleNetworking)
+	subnets := map[string]string{}
+	for key, value := range baseline["subnet_config"].(map[string]interface{}) {
+		subnets[key] = value.(string)
+	}
+	layout, err := ParseLayout(subnets)
+	require.NoError(t, err)
+	assert.Empty(t, Validate(netip.MustParsePrefix(baseline["vnet_cidr"].(string)), layout, all), "networking baseline")
+}
+
+// TestAllocateSizingProfiles tests that every sizing profile gets a valid
+// layout with room for its cluster at full scale
+func TestAllocateSizingProfiles(t *testing.T) {
+	t.Parallel()
+
+	profiles, err := helpers.LoadSizingProfiles()
+	require.NoError(t, err)
+
+	for _, name := range profiles.Names() {
+		profile := profiles.Get(name)
+
+		vnet := netip.MustParsePrefix("10.0.0.0/16")
+		if networking := profile.Infrastructure.Networking; networking != nil && networking.VNetCIDR != "" {
+			vnet = netip.MustParsePrefix(networking.VNetCIDR)
+		}
+		nodes, pods := profile.AddressDemand()
+		sizing := Sizing{Nodes: nodes, Pods: pods}
+
+		layout, err := Allocate(vnet, sizing)
+		require.NoError(t, err, name)
+		problems := append(Validate(vnet, layout, Features{Bastion: true, AppGateway: true}), CheckCapacity(layout, sizing)...)
+		assert.Empty(t, problems, "%s: %s", name, strings.Join(problems, "; "))
+	}
+}
@@ -0,0 +1,87 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - TEST TIER LISTING
+// =============================================================================
+//
+// Lists the module tests of each tier (unit, integration) from their build
+// tags, and exits non-zero when a test has no tier or a unit test calls
+// terraform Apply/Destroy, or plans with terraform Plan instead of
+// helpers.InitAndPlan (the unit tier runs with mock providers and no
+// credentials).
+//
+// Run with: go run ./cmd/list-tests [-tier unit|integration]
+//
+// =============================================================================
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+func main() {
+	dir := flag.String("dir", helpers.ModuleTestsDir(), "directory of the test files")
+	tier := flag.String("tier", "", "only list this tier (unit or integration)")
+	flag.Parse()
+
+	if *tier != "" && *tier != helpers.TierUnit && *tier != helpers.TierIntegration {
+		fatalf("unknown tier %q: use %s or %s", *tier, helpers.TierUnit, helpers.TierIntegration)
+	}
+
+	tests, err := helpers.ClassifyTests(*dir)
+	if err != nil {
+		fatalf("reading tests: %v", err)
+	}
+
+	var problems []string
+	byTier := map[string][]helpers.TestInfo{}
+	for _, test := range tests {
+		byTier[test.Tier] = append(byTier[test.Tier], test)
+
+		switch {
+		case test.Tier == helpers.TierUntagged:
+			problems = append(problems, fmt.Sprintf("%s (%s): no //go:build unit or integration tag", test.Name, test.File))
+		case test.Tier == helpers.TierUnit && test.Applies:
+			problems = append(problems, fmt.Sprintf("%s (%s): unit test calls terraform Apply/Destroy", test.Name, test.File))
+		case test.Tier == helpers.TierUnit && test.PlansDirectly:
+			problems = append(problems, fmt.Sprintf("%s (%s): unit test calls terraform Plan; use helpers.InitAndPlan", test.Name, test.File))
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, name := range append(helpers.Tiers(), helpers.TierUntagged) {
+		if *tier != "" && name != *tier {
+			continue
+		}
+		if name == helpers.TierUntagged && len(byTier[name]) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s (%d)\n", name, len(byTier[name]))
+		for _, test := range byTier[name] {
+			if test.Applies {
+				fmt.Fprintf(w, "  %s\t%s\tapplies\n", test.Name, test.File)
+			} else {
+				fmt.Fprintf(w, "  %s\t%s\n", test.Name, test.File)
+			}
+		}
+	}
+	w.Flush()
+
+	if len(problems) > 0 {
+		fmt.Fprintln(os.Stderr, "\nUnclassified or misclassified tests:")
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+		}
+		os.Exit(1)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "list-tests: "+format+"\n", args...)
+	os.Exit(1)
+}
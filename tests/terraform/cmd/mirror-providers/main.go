@@ -0,0 +1,138 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PROVIDER MIRROR
+// =============================================================================
+//
+// Populates a filesystem provider mirror with every provider required by
+// terraform/modules/*/versions.tf and terraform/main.tf, so the test suite
+// can run offline with TERRATEST_PROVIDER_MIRROR pointing at the mirror.
+//
+// Run with: go run ./cmd/mirror-providers -dir ~/.terraform.d/mirror
+//
+// Constraints declared by different modules for the same provider are
+// combined, so the mirror holds one version every module accepts.
+//
+// =============================================================================
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// platformFlags collects repeated -platform flags.
+type platformFlags []string
+
+func (p *platformFlags) String() string { return strings.Join(*p, ",") }
+
+func (p *platformFlags) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+func main() {
+	var platforms platformFlags
+
+	dir := flag.String("dir", os.Getenv(helpers.ProviderMirrorEnv), "mirror directory (default $"+helpers.ProviderMirrorEnv+")")
+	binary := flag.String("binary", defaultBinary(), "terraform or tofu executable")
+	dryRun := flag.Bool("dry-run", false, "print the combined required_providers and exit")
+	flag.Var(&platforms, "platform", "target platform, repeatable (default "+helpers.CurrentPlatform()+")")
+	flag.Parse()
+
+	requirements, err := helpers.ModuleProviders()
+	if err != nil {
+		fatalf("reading required_providers: %v", err)
+	}
+
+	config := requiredProvidersConfig(requirements)
+	if *dryRun {
+		fmt.Print(config)
+		return
+	}
+
+	if *dir == "" {
+		fatalf("no mirror directory: pass -dir or set %s", helpers.ProviderMirrorEnv)
+	}
+	if len(platforms) == 0 {
+		platforms = platformFlags{helpers.CurrentPlatform()}
+	}
+
+	mirrorDir, err := filepath.Abs(*dir)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	workDir, err := os.MkdirTemp("", "mirror-providers-")
+	if err != nil {
+		fatalf("%v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	if err := os.WriteFile(filepath.Join(workDir, "versions.tf"), []byte(config), 0o644); err != nil {
+		fatalf("%v", err)
+	}
+
+	args := []string{"-chdir=" + workDir, "providers", "mirror"}
+	for _, platform := range platforms {
+		args = append(args, "-platform="+platform)
+	}
+	args = append(args, mirrorDir)
+
+	fmt.Printf("Mirroring %d providers for %s into %s\n", len(requirements), platforms.String(), mirrorDir)
+
+	cmd := exec.Command(*binary, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Never let a CLI config pointing at the mirror itself get in the way
+	cmd.Env = append(os.Environ(), "TF_CLI_CONFIG_FILE=")
+	if err := cmd.Run(); err != nil {
+		fatalf("%s providers mirror failed: %v", *binary, err)
+	}
+
+	fmt.Printf("\nDone. Run the tests offline with:\n  %s=%s go test ./...\n", helpers.ProviderMirrorEnv, mirrorDir)
+}
+
+// requiredProvidersConfig renders a terraform block requiring every provider
+// with all of its constraints combined.
+func requiredProvidersConfig(requirements []helpers.ProviderRequirement) string {
+	var b strings.Builder
+
+	b.WriteString("terraform {\n  required_providers {\n")
+	used := map[string]bool{}
+	for _, req := range requirements {
+		parts := strings.Split(req.Source, "/")
+		name := parts[len(parts)-1]
+		if used[name] {
+			name = parts[len(parts)-2] + "-" + name
+		}
+		used[name] = true
+
+		fmt.Fprintf(&b, "    %s = {\n      source  = %q\n", name, req.Source)
+		if req.Constraint() != "" {
+			fmt.Fprintf(&b, "      version = %q\n", req.Constraint())
+		}
+		b.WriteString("    }\n")
+	}
+	b.WriteString("  }\n}\n")
+
+	return b.String()
+}
+
+// defaultBinary prefers terraform and falls back to tofu, like terratest.
+func defaultBinary() string {
+	if _, err := exec.LookPath("terraform"); err == nil {
+		return "terraform"
+	}
+	return "tofu"
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "mirror-providers: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,166 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - GOLDEN PATH SCAFFOLD TESTS
+// =============================================================================
+//
+// Renders each golden path with a sample parameter set from
+// testdata/backstage/samples, without RHDH (see helpers/scaffolder.go), and
+// checks the generated project:
+//
+//   - every fetch:template source is rendered, apart from the known gaps
+//   - no value is undefined and no placeholder is left in any file
+//   - every catalog entity has the expected owner, and the Component has
+//     the expected tags
+//   - the Kubernetes manifests (kustomize-built where there is a
+//     kustomization.yaml) are admitted by the Gatekeeper constraints
+//   - Terraform directories plan and pass policies/terraform, with
+//     TERRATEST_MOCK_PROVIDERS=true
+//
+// Run with: go test -v -run TestGoldenPathScaffold ./goldenpaths/
+//
+// =============================================================================
+
+package goldenpaths
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// TestGoldenPathScaffold tests the project each sample parameter set
+// generates
+func TestGoldenPathScaffold(t *testing.T) {
+	t.Parallel()
+
+	samples, err := filepath.Glob(filepath.Join(helpers.ScaffolderSamplesDir(), "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, samples)
+
+	templates := map[string]*helpers.SoftwareTemplate{}
+	for _, template := range loadTemplates(t) {
+		templates[template.Metadata.Name] = template
+	}
+
+	gaps, err := helpers.LoadKnownSkeletonGaps(helpers.KnownSkeletonGapsPath())
+	require.NoError(t, err)
+
+	constraintTemplates, err := helpers.LoadConstraintTemplates(helpers.ConstraintTemplatesDir())
+	require.NoError(t, err)
+	constraints, err := helpers.LoadConstraints(helpers.ConstraintsDir(), map[string]string{"ACR_NAME": "crthreehorizons"})
+	require.NoError(t, err)
+
+	for _, file := range samples {
+		sample, err := helpers.LoadScaffolderSample(file)
+		require.NoError(t, err)
+
+		t.Run(sample.Source, func(t *testing.T) {
+			t.Parallel()
+
+			template, ok := templates[sample.Template]
+			require.True(t, ok, "template %s does not exist", sample.Template)
+
+			result, err := template.Scaffold(sample.Parameters, t.TempDir())
+			require.NoError(t, err)
+			require.NotEmpty(t, result.Files, "nothing was rendered")
+
+			for _, problem := range result.Missing {
+				if !containsString(gaps[template.Metadata.Name], problem.URL) {
+					t.Errorf("%s", problem)
+				}
+			}
+			for _, undefined := range result.Undefined {
+				t.Errorf("undefined: %s", undefined)
+			}
+
+			unresolved, err := result.Unresolved()
+			require.NoError(t, err)
+			for _, placeholder := range unresolved {
+				t.Errorf("unresolved: %s", placeholder)
+			}
+
+			t.Run("catalog", func(t *testing.T) {
+				assertCatalogEntities(t, result, sample)
+			})
+
+			t.Run("kubernetes", func(t *testing.T) {
+				manifests, err := result.Manifests()
+				require.NoError(t, err)
+
+				results, err := helpers.Admit(constraintTemplates, constraints, manifests)
+				require.NoError(t, err)
+				for _, admission := range results {
+					if admission.Rejected() {
+						for _, violation := range admission.Violations {
+							t.Errorf("%s: %s: %s", admission.Constraint.Name, admission.Manifest, violation.Msg)
+						}
+					}
+				}
+			})
+
+			for _, dir := range terraformDirs(result) {
+				dir := dir
+				t.Run("terraform/"+dir, func(t *testing.T) {
+					if !helpers.MockProvidersEnabled() {
+						t.Skipf("Set %s=true to plan with mock providers", helpers.MockProvidersEnv)
+					}
+					helpers.InitAndPlan(t, &terraform.Options{
+						TerraformDir: filepath.Join(result.Dir, filepath.FromSlash(dir)),
+						NoColor:      true,
+					})
+				})
+			}
+		})
+	}
+}
+
+// assertCatalogEntities checks the owner and tags of the generated
+// catalog-info.yaml entities
+func assertCatalogEntities(t *testing.T, result *helpers.ScaffolderResult, sample *helpers.ScaffolderSample) {
+	t.Helper()
+
+	entities, err := result.Entities()
+	require.NoError(t, err)
+
+	components := 0
+	for _, entity := range entities {
+		if entity.Kind() == "Location" {
+			continue
+		}
+
+		spec, _ := entity.Object["spec"].(map[string]interface{})
+		assert.Equal(t, sample.Expect.Owner, spec["owner"], "%s spec.owner", entity)
+
+		if entity.Kind() != "Component" {
+			continue
+		}
+		components++
+
+		metadata, _ := entity.Object["metadata"].(map[string]interface{})
+		tags, _ := metadata["tags"].([]interface{})
+		for _, tag := range sample.Expect.Tags {
+			assert.Contains(t, tags, tag, "%s metadata.tags", entity)
+		}
+	}
+	assert.Equal(t, 1, components, "catalog-info.yaml declares one Component")
+}
+
+// terraformDirs returns the directories of the generated project that hold
+// .tf files, sorted
+func terraformDirs(result *helpers.ScaffolderResult) []string {
+	seen := map[string]bool{}
+	var dirs []string
+	for _, file := range result.Files {
+		if path.Ext(file) == ".tf" && !seen[path.Dir(file)] {
+			seen[path.Dir(file)] = true
+			dirs = append(dirs, path.Dir(file))
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
@@ -0,0 +1,104 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - BACKSTAGE SOFTWARE TEMPLATE TESTS
+// =============================================================================
+//
+// Validates golden-paths/*/*/template.yaml before RHDH imports them (see
+// helpers/backstage.go):
+//
+//   - apiVersion scaffolder.backstage.io/v1beta3, kind Template, a valid and
+//     unique metadata.name, spec.owner and spec.type
+//   - every parameters page is a valid draft-07 JSON Schema whose required
+//     properties are declared and whose defaults match their schema
+//   - every ${{ parameters.x }} in steps and output is declared, and every
+//     steps['id'] refers to an earlier step
+//   - every fetch:template source is a directory under skeleton/, apart from
+//     the known gaps in testdata/backstage/known-skeleton-gaps.yaml
+//
+// Run with: go test -v -run TestBackstage ./goldenpaths/
+//
+// =============================================================================
+
+package goldenpaths
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+func loadTemplates(t *testing.T) []*helpers.SoftwareTemplate {
+	t.Helper()
+
+	templates, err := helpers.LoadSoftwareTemplates(helpers.GoldenPathsDir())
+	require.NoError(t, err)
+	require.NotEmpty(t, templates)
+	return templates
+}
+
+// TestBackstageTemplates tests the entity, parameters and references of each
+// template
+func TestBackstageTemplates(t *testing.T) {
+	t.Parallel()
+
+	names := map[string]string{}
+	for _, template := range loadTemplates(t) {
+		template := template
+
+		if first, ok := names[template.Metadata.Name]; ok {
+			t.Errorf("%s: metadata.name %q is already used by %s", template.Source, template.Metadata.Name, first)
+		}
+		names[template.Metadata.Name] = template.Source
+
+		t.Run(template.Source, func(t *testing.T) {
+			t.Parallel()
+
+			for _, problem := range template.Check() {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+// TestBackstageTemplateSkeletons tests that fetch:template sources exist
+// under skeleton/ or are listed as known gaps
+func TestBackstageTemplateSkeletons(t *testing.T) {
+	t.Parallel()
+
+	gaps, err := helpers.LoadKnownSkeletonGaps(helpers.KnownSkeletonGapsPath())
+	require.NoError(t, err)
+
+	templates := map[string]bool{}
+	for _, template := range loadTemplates(t) {
+		templates[template.Metadata.Name] = true
+
+		missing := map[string]bool{}
+		for _, problem := range template.CheckSkeletons() {
+			missing[problem.URL] = true
+			if !containsString(gaps[template.Metadata.Name], problem.URL) {
+				t.Errorf("%s: %s", template.Source, problem)
+			}
+		}
+
+		for _, url := range gaps[template.Metadata.Name] {
+			if !missing[url] {
+				t.Errorf("known-skeleton-gaps.yaml: %s %s is no longer missing; remove it", template.Metadata.Name, url)
+			}
+		}
+	}
+
+	for name := range gaps {
+		if !templates[name] {
+			t.Errorf("known-skeleton-gaps.yaml: template %s does not exist; remove it", name)
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PROMETHEUS ALERT BEHAVIOR TESTS
+// =============================================================================
+//
+// Evaluates rule files over synthetic time series with the Prometheus rule
+// manager and PromQL engine, in-process, the way promtool test rules does:
+//
+//	fixture, err := helpers.LoadAlertTest(path)
+//	problems, err := fixture.Evaluate(t, helpers.AlertingRulesPath())
+//
+// A fixture lists input series in promtool's expanding notation and the
+// alerts expected to be firing at given times, with their rendered labels
+// and annotations:
+//
+//	interval: 1m
+//	input_series:
+//	  - series: 'kube_node_status_condition{condition="Ready",status="true",node="aks-system-0"}'
+//	    values: '1x4 0x9 1x5'
+//	alert_tests:
+//	  - eval_time: 8m
+//	    alertname: AKSNodeNotReady
+//	    exp_alerts: []
+//	  - eval_time: 10m
+//	    alertname: AKSNodeNotReady
+//	    exp_alerts:
+//	      - exp_labels: {...}
+//	        exp_annotations: {...}
+//
+// Time starts at 0 and every group is evaluated each interval, so `for:`
+// durations play out as they would in Prometheus. exp_labels omit
+// alertname; exp_annotations must list every annotation.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"gopkg.in/yaml.v3"
+)
+
+// AlertTestDir returns the directory of the alert behavior fixtures.
+func AlertTestDir() string {
+	return filepath.Join(TestDataDir(), "prometheus")
+}
+
+// AlertTest is one alert behavior fixture.
+type AlertTest struct {
+	Interval    model.Duration  `yaml:"interval"`
+	InputSeries []InputSeries   `yaml:"input_series"`
+	AlertTests  []AlertTestCase `yaml:"alert_tests"`
+}
+
+// InputSeries is one synthetic series, e.g. values '0+10x5 50x10'.
+type InputSeries struct {
+	Series string `yaml:"series"`
+	Values string `yaml:"values"`
+}
+
+// AlertTestCase lists the alerts expected to fire at eval_time.
+type AlertTestCase struct {
+	EvalTime  model.Duration  `yaml:"eval_time"`
+	Alertname string          `yaml:"alertname"`
+	ExpAlerts []ExpectedAlert `yaml:"exp_alerts"`
+}
+
+// ExpectedAlert is one firing alert.
+type ExpectedAlert struct {
+	ExpLabels      map[string]string `yaml:"exp_labels"`
+	ExpAnnotations map[string]string `yaml:"exp_annotations"`
+}
+
+// LoadAlertTest reads a fixture. Decoding is strict and the fixture needs
+// an interval, input series and at least one alert test.
+func LoadAlertTest(path string) (*AlertTest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture AlertTest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&fixture); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var problems []string
+	if fixture.Interval <= 0 {
+		problems = append(problems, "interval must be positive")
+	}
+	if len(fixture.InputSeries) == 0 {
+		problems = append(problems, "no input_series")
+	}
+	if len(fixture.AlertTests) == 0 {
+		problems = append(problems, "no alert_tests")
+	}
+	for i, test := range fixture.AlertTests {
+		if test.Alertname == "" {
+			problems = append(problems, fmt.Sprintf("alert test %d has no alertname", i+1))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s:\n  - %s", path, strings.Join(problems, "\n  - "))
+	}
+	return &fixture, nil
+}
+
+// Alertnames returns the alerts the fixture tests, sorted.
+func (f *AlertTest) Alertnames() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, test := range f.AlertTests {
+		if !seen[test.Alertname] {
+			seen[test.Alertname] = true
+			names = append(names, test.Alertname)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// seriesLoad returns the input series as a PromQL test load command.
+func (f *AlertTest) seriesLoad() string {
+	var load strings.Builder
+	fmt.Fprintf(&load, "load %s\n", f.Interval)
+	for _, series := range f.InputSeries {
+		fmt.Fprintf(&load, "  %s %s\n", series.Series, series.Values)
+	}
+	return load.String()
+}
+
+// maxEvalTime returns the latest eval_time of the fixture.
+func (f *AlertTest) maxEvalTime() time.Duration {
+	var max time.Duration
+	for _, test := range f.AlertTests {
+		if d := time.Duration(test.EvalTime); d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// Evaluate runs the rule files over the input series and returns one
+// problem per alert test whose firing alerts differ from the expected
+// ones. Rule evaluation errors and unknown alertnames are returned as an
+// error.
+func (f *AlertTest) Evaluate(t testing.TB, ruleFiles ...string) ([]string, error) {
+	suite, err := promql.NewLazyLoader(t, f.seriesLoad(), promql.LazyLoaderOpts{
+		EnableAtModifier:     true,
+		EnableNegativeOffset: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("input_series: %w", err)
+	}
+	defer suite.Close()
+	suite.SubqueryInterval = time.Duration(f.Interval)
+
+	manager := rules.NewManager(&rules.ManagerOptions{
+		QueryFunc:  rules.EngineQueryFunc(suite.QueryEngine(), suite.Storage()),
+		Appendable: suite.Storage(),
+		Context:    context.Background(),
+		NotifyFunc: func(context.Context, string, ...*rules.Alert) {},
+		Logger:     log.NewNopLogger(),
+	})
+	loaded, errs := manager.LoadGroups(time.Duration(f.Interval), labels.EmptyLabels(), "", nil, ruleFiles...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("loading rules: %w", errs[0])
+	}
+
+	groups, err := orderedRuleGroups(loaded, ruleFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	alerting := map[string][]*rules.AlertingRule{}
+	for _, group := range groups {
+		for _, rule := range group.Rules() {
+			if alert, ok := rule.(*rules.AlertingRule); ok {
+				// Write ALERTS series from the first evaluation, as a
+				// Prometheus that has finished restoring state does
+				alert.SetRestored(true)
+				alerting[alert.Name()] = append(alerting[alert.Name()], alert)
+			}
+		}
+	}
+	for _, name := range f.Alertnames() {
+		if len(alerting[name]) == 0 {
+			return nil, fmt.Errorf("alert %s is not defined in %s", name, strings.Join(ruleFiles, ", "))
+		}
+	}
+
+	tests := append([]AlertTestCase(nil), f.AlertTests...)
+	sort.SliceStable(tests, func(i, j int) bool { return tests[i].EvalTime < tests[j].EvalTime })
+
+	var problems []string
+	start := time.Unix(0, 0).UTC()
+	interval := time.Duration(f.Interval)
+	next := 0
+
+	for ts := start; !ts.After(start.Add(f.maxEvalTime())); ts = ts.Add(interval) {
+		var evalErr error
+		suite.WithSamplesTill(ts, func(err error) {
+			if err != nil {
+				evalErr = err
+				return
+			}
+			for _, group := range groups {
+				group.Eval(suite.Context(), ts)
+				for _, rule := range group.Rules() {
+					if err := rule.LastError(); err != nil && evalErr == nil {
+						evalErr = fmt.Errorf("rule %s at %s: %w", rule.Name(), ts.Sub(start), err)
+					}
+				}
+			}
+		})
+		if evalErr != nil {
+			return nil, evalErr
+		}
+
+		// An eval_time between two evaluations sees the earlier one
+		for next < len(tests) && time.Duration(tests[next].EvalTime) < ts.Sub(start)+interval {
+			test := tests[next]
+			next++
+
+			got := firingAlerts(alerting[test.Alertname])
+			expected := test.expectedAlerts()
+			if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+				problems = append(problems, fmt.Sprintf("%s at %s:\n  expected:\n%s\n  got:\n%s",
+					test.Alertname, test.EvalTime, indentAlerts(expected), indentAlerts(got)))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// orderedRuleGroups returns the loaded groups in file order, so recording
+// rules feed the groups after them as they do in Prometheus.
+func orderedRuleGroups(loaded map[string]*rules.Group, ruleFiles []string) ([]*rules.Group, error) {
+	var groups []*rules.Group
+	for _, file := range ruleFiles {
+		parsed, err := LoadRuleGroups(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range parsed.Groups {
+			groups = append(groups, loaded[rules.GroupKey(file, group.Name)])
+		}
+	}
+	return groups, nil
+}
+
+// firingAlerts renders the firing alerts of the rules, sorted.
+func firingAlerts(alertingRules []*rules.AlertingRule) []string {
+	var alerts []string
+	for _, rule := range alertingRules {
+		for _, alert := range rule.ActiveAlerts() {
+			if alert.State == rules.StateFiring {
+				alerts = append(alerts, renderAlert(alert.Labels, alert.Annotations))
+			}
+		}
+	}
+	sort.Strings(alerts)
+	return alerts
+}
+
+// expectedAlerts renders the expected alerts, sorted.
+func (c AlertTestCase) expectedAlerts() []string {
+	alerts := make([]string, 0, len(c.ExpAlerts))
+	for _, expected := range c.ExpAlerts {
+		expLabels := map[string]string{labels.AlertName: c.Alertname}
+		for key, value := range expected.ExpLabels {
+			expLabels[key] = value
+		}
+		alerts = append(alerts, renderAlert(labels.FromMap(expLabels), labels.FromMap(expected.ExpAnnotations)))
+	}
+	sort.Strings(alerts)
+	return alerts
+}
+
+func renderAlert(alertLabels, annotations labels.Labels) string {
+	return alertLabels.String() + " annotations=" + annotations.String()
+}
+
+func indentAlerts(alerts []string) string {
+	if len(alerts) == 0 {
+		return "    (none)"
+	}
+	return "    " + strings.Join(alerts, "\n    ")
+}
@@ -0,0 +1,162 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PROMETHEUS ALERT BEHAVIOR HARNESS TESTS
+// =============================================================================
+//
+// Run with: go test -v -run TestAlertTest ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const alertTestRules = `
+groups:
+  - name: targets
+    rules:
+      - record: job:up:sum
+        expr: sum by (job) (up)
+
+      - alert: JobDown
+        expr: job:up:sum == 0
+        for: 2m
+        labels:
+          severity: critical
+        annotations:
+          summary: "{{ $labels.job }} is down"
+`
+
+func writeAlertTest(t *testing.T, dir, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "fixture.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// TestAlertTestEvaluate tests pending, firing and resolved states over
+// simulated time
+func TestAlertTestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rules := filepath.Join(dir, "rules.yaml")
+	require.NoError(t, os.WriteFile(rules, []byte(alertTestRules), 0o644))
+
+	fixture, err := LoadAlertTest(writeAlertTest(t, dir, `
+interval: 1m
+input_series:
+  - series: 'up{job="api",instance="a"}'
+    values: '1 1 0 0 0 0 1'
+alert_tests:
+  - eval_time: 3m
+    alertname: JobDown
+    exp_alerts: []
+  # 4m30s sees the 4m evaluation
+  - eval_time: 4m30s
+    alertname: JobDown
+    exp_alerts:
+      - exp_labels:
+          severity: critical
+          job: api
+        exp_annotations:
+          summary: "api is down"
+  - eval_time: 6m
+    alertname: JobDown
+    exp_alerts:
+      - exp_labels:
+          severity: critical
+          job: api
+        exp_annotations:
+          summary: "api is down"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"JobDown"}, fixture.Alertnames())
+
+	problems, err := fixture.Evaluate(t, rules)
+	require.NoError(t, err)
+	require.Len(t, problems, 1, "the alert resolves at 6m")
+	assert.Equal(t, `JobDown at 6m:
+  expected:
+    {alertname="JobDown", job="api", severity="critical"} annotations={summary="api is down"}
+  got:
+    (none)`, problems[0])
+}
+
+// TestAlertTestUnknownAlert tests that a misspelled alertname is an error,
+// not a vacuous pass
+func TestAlertTestUnknownAlert(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rules := filepath.Join(dir, "rules.yaml")
+	require.NoError(t, os.WriteFile(rules, []byte(alertTestRules), 0o644))
+
+	fixture, err := LoadAlertTest(writeAlertTest(t, dir, `
+interval: 1m
+input_series:
+  - series: 'up{job="api"}'
+    values: '1x5'
+alert_tests:
+  - eval_time: 5m
+    alertname: JobDwn
+    exp_alerts: []
+`))
+	require.NoError(t, err)
+
+	_, err = fixture.Evaluate(t, rules)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "alert JobDwn is not defined")
+}
+
+// TestAlertTestInvalid tests fixture validation
+func TestAlertTestInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{
+			name:    "unknown_key",
+			content: "interval: 1m\nseries: []\n",
+			message: "field series not found",
+		},
+		{
+			name:    "empty",
+			content: "interval: 0s\n",
+			message: "interval must be positive\n  - no input_series\n  - no alert_tests",
+		},
+		{
+			name: "no_alertname",
+			content: `
+interval: 1m
+input_series:
+  - series: 'up'
+    values: '1'
+alert_tests:
+  - eval_time: 1m
+`,
+			message: "alert test 1 has no alertname",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := LoadAlertTest(writeAlertTest(t, t.TempDir(), tc.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.message)
+		})
+	}
+}
@@ -0,0 +1,598 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - BACKSTAGE SOFTWARE TEMPLATE CHECKS
+// =============================================================================
+//
+// Reads the golden-paths/<horizon>/<name>/template.yaml files RHDH imports
+// and checks what otherwise only breaks when a developer clicks "Create":
+//
+//	template, err := helpers.LoadSoftwareTemplate(path)
+//	problems := template.Check()
+//	missing := template.CheckSkeletons()
+//
+// Only the first YAML document of a template file is the Template entity;
+// the skeleton sketches some templates append after it are not read.
+//
+// Each parameters page is compiled as a draft-07 JSON Schema, the dialect
+// the scaffolder form uses, and property defaults are validated against
+// their schema. ${{ parameters.x }} and steps['id'] references in steps and
+// output must resolve to a declared parameter and an earlier step.
+//
+// fetch:template sources must be directories under the template's
+// skeleton/. A source such as ./skeleton/${{ parameters.language }} is
+// expanded over the enum of the parameter.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateAPIVersion is the only scaffolder API version RHDH 1.x imports.
+const TemplateAPIVersion = "scaffolder.backstage.io/v1beta3"
+
+// entityNamePattern is the Backstage catalog rule for metadata.name.
+var entityNamePattern = regexp.MustCompile(`^([A-Za-z0-9]+[-_.])*[A-Za-z0-9]+$`)
+
+// templateExpressionPattern matches a ${{ ... }} scaffolder expression.
+var templateExpressionPattern = regexp.MustCompile(`\$\{\{(.*?)\}\}`)
+
+// parameterReferencePattern matches parameters.x and parameters['x'].
+var parameterReferencePattern = regexp.MustCompile(`\bparameters(?:\.([A-Za-z_$][\w$]*)|\[\s*['"]([^'"]+)['"]\s*\])`)
+
+// documentSeparatorPattern matches a YAML document separator line.
+var documentSeparatorPattern = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// stepReferencePattern matches steps['id'] and steps.id.
+var stepReferencePattern = regexp.MustCompile(`\bsteps(?:\.([A-Za-z_$][\w$]*)|\[\s*['"]([^'"]+)['"]\s*\])`)
+
+// GoldenPathsDir returns the absolute path of golden-paths.
+func GoldenPathsDir() string {
+	return filepath.Join(RepoRoot(), "golden-paths")
+}
+
+// KnownSkeletonGapsPath returns the list of fetch:template sources that are
+// referenced by a template but not checked in yet.
+func KnownSkeletonGapsPath() string {
+	return filepath.Join(TestDataDir(), "backstage", "known-skeleton-gaps.yaml")
+}
+
+// SoftwareTemplate is a scaffolder Template entity.
+type SoftwareTemplate struct {
+	APIVersion string           `yaml:"apiVersion"`
+	Kind       string           `yaml:"kind"`
+	Metadata   TemplateMetadata `yaml:"metadata"`
+	Spec       TemplateSpec     `yaml:"spec"`
+
+	// Source is the file path relative to golden-paths.
+	Source string `yaml:"-"`
+	// Dir is the absolute directory holding template.yaml and skeleton/.
+	Dir string `yaml:"-"`
+}
+
+// TemplateMetadata is the entity metadata of a template.
+type TemplateMetadata struct {
+	Name        string            `yaml:"name"`
+	Title       string            `yaml:"title"`
+	Description string            `yaml:"description"`
+	Tags        []string          `yaml:"tags"`
+	Annotations map[string]string `yaml:"annotations"`
+	Links       []TemplateLink    `yaml:"links"`
+}
+
+// TemplateLink is a metadata link.
+type TemplateLink struct {
+	Title string `yaml:"title"`
+	URL   string `yaml:"url"`
+	Icon  string `yaml:"icon"`
+}
+
+// TemplateSpec is the spec of a template. Parameter pages and step inputs
+// are kept as generic values: they are JSON Schemas and action-specific
+// objects.
+type TemplateSpec struct {
+	Owner      string                   `yaml:"owner"`
+	Type       string                   `yaml:"type"`
+	Parameters []map[string]interface{} `yaml:"parameters"`
+	Steps      []TemplateStep           `yaml:"steps"`
+	Output     map[string]interface{}   `yaml:"output"`
+}
+
+// TemplateStep is a scaffolder step.
+type TemplateStep struct {
+	ID     string                 `yaml:"id"`
+	Name   string                 `yaml:"name"`
+	Action string                 `yaml:"action"`
+	If     interface{}            `yaml:"if"`
+	Input  map[string]interface{} `yaml:"input"`
+}
+
+// LoadSoftwareTemplate reads the Template entity of a template.yaml.
+func LoadSoftwareTemplate(path string) (*SoftwareTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var template SoftwareTemplate
+	decoder := yaml.NewDecoder(bytes.NewReader(firstDocument(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&template); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	template.Dir = filepath.Dir(path)
+	template.Source = path
+	if rel, err := filepath.Rel(GoldenPathsDir(), path); err == nil && !strings.HasPrefix(rel, "..") {
+		template.Source = rel
+	}
+	return &template, nil
+}
+
+// firstDocument returns the first YAML document of data. The documents
+// after it are cut off rather than decoded: some are not YAML at all.
+func firstDocument(data []byte) []byte {
+	for _, separator := range documentSeparatorPattern.FindAllIndex(data, -1) {
+		if len(bytes.TrimSpace(stripComments(data[:separator[0]]))) > 0 {
+			return data[:separator[0]]
+		}
+	}
+	return data
+}
+
+// stripComments drops the full-line comments of a YAML snippet.
+func stripComments(data []byte) []byte {
+	var kept [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			kept = append(kept, line)
+		}
+	}
+	return bytes.Join(kept, []byte("\n"))
+}
+
+// LoadSoftwareTemplates reads every <horizon>/<name>/template.yaml in dir.
+func LoadSoftwareTemplates(dir string) ([]*SoftwareTemplate, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*", "template.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]*SoftwareTemplate, 0, len(paths))
+	for _, path := range paths {
+		template, err := LoadSoftwareTemplate(path)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// Check returns the entity, parameter and reference problems of the
+// template.
+func (t *SoftwareTemplate) Check() []string {
+	var problems []string
+
+	if t.APIVersion != TemplateAPIVersion {
+		problems = append(problems, fmt.Sprintf("apiVersion is %q, expected %q", t.APIVersion, TemplateAPIVersion))
+	}
+	if t.Kind != "Template" {
+		problems = append(problems, fmt.Sprintf("kind is %q, expected Template", t.Kind))
+	}
+	if !entityNamePattern.MatchString(t.Metadata.Name) || len(t.Metadata.Name) > 63 {
+		problems = append(problems, fmt.Sprintf("metadata.name %q is not a valid entity name", t.Metadata.Name))
+	}
+	if t.Spec.Owner == "" {
+		problems = append(problems, "spec.owner is not set")
+	}
+	if t.Spec.Type == "" {
+		problems = append(problems, "spec.type is not set")
+	}
+	if len(t.Spec.Steps) == 0 {
+		problems = append(problems, "spec.steps is empty")
+	}
+
+	problems = append(problems, t.CheckParameters()...)
+	problems = append(problems, t.checkSteps()...)
+	return problems
+}
+
+// CheckParameters compiles each parameters page as a JSON Schema and
+// validates property defaults, enumNames and required properties.
+func (t *SoftwareTemplate) CheckParameters() []string {
+	var problems []string
+
+	for i, page := range t.Spec.Parameters {
+		location := fmt.Sprintf("parameters page %d", i+1)
+		if title, ok := page["title"].(string); ok {
+			location = fmt.Sprintf("%s %q", location, title)
+		}
+
+		properties, _ := page["properties"].(map[string]interface{})
+		if len(properties) == 0 {
+			problems = append(problems, location+": has no properties")
+		}
+
+		declared := map[string]bool{}
+		collectParameters(page, declared)
+		for _, name := range requiredNames(page) {
+			if !declared[name] {
+				problems = append(problems, fmt.Sprintf("%s: required property %q is not declared", location, name))
+			}
+		}
+
+		schema, err := compilePage(page)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", location, err))
+			continue
+		}
+
+		for _, name := range sortedSchemaKeys(properties) {
+			property, _ := properties[name].(map[string]interface{})
+			if enum, ok := property["enum"].([]interface{}); ok {
+				if names, ok := property["enumNames"].([]interface{}); ok && len(names) != len(enum) {
+					problems = append(problems, fmt.Sprintf("%s: %s has %d enumNames for %d enum values", location, name, len(names), len(enum)))
+				}
+			}
+
+			value, ok := property["default"]
+			if !ok {
+				continue
+			}
+			if err := schema.validateProperty(name, value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s default %v: %v", location, name, value, err))
+			}
+		}
+	}
+	return problems
+}
+
+// Parameters returns the property schemas declared by every page, by name.
+func (t *SoftwareTemplate) Parameters() map[string]map[string]interface{} {
+	declared := map[string]map[string]interface{}{}
+	for _, page := range t.Spec.Parameters {
+		collectParameterSchemas(page, declared)
+	}
+	return declared
+}
+
+// checkSteps checks step ids and the parameters and steps referenced by
+// expressions in steps and output.
+func (t *SoftwareTemplate) checkSteps() []string {
+	var problems []string
+	parameters := t.Parameters()
+	steps := map[string]bool{}
+
+	check := func(location string, value interface{}) {
+		for _, expression := range templateExpressions(value) {
+			for _, match := range parameterReferencePattern.FindAllStringSubmatch(expression, -1) {
+				name := match[1] + match[2]
+				if _, ok := parameters[name]; !ok {
+					problems = append(problems, fmt.Sprintf("%s: parameters.%s is not declared", location, name))
+				}
+			}
+			for _, match := range stepReferencePattern.FindAllStringSubmatch(expression, -1) {
+				id := match[1] + match[2]
+				if !steps[id] {
+					problems = append(problems, fmt.Sprintf("%s: steps['%s'] does not refer to an earlier step", location, id))
+				}
+			}
+		}
+	}
+
+	for i, step := range t.Spec.Steps {
+		location := fmt.Sprintf("step %d", i+1)
+		if step.ID == "" {
+			problems = append(problems, location+": has no id")
+		} else {
+			location = "step " + step.ID
+		}
+		if step.Action == "" {
+			problems = append(problems, location+": has no action")
+		}
+
+		check(location, step.If)
+		check(location, step.Input)
+
+		if step.Action == "fetch:template" {
+			if target, ok := step.Input["targetPath"].(string); ok {
+				if filepath.IsAbs(target) || strings.HasPrefix(filepath.Clean(target), "..") {
+					problems = append(problems, fmt.Sprintf("%s: targetPath %s is outside the workspace", location, target))
+				}
+			}
+		}
+
+		if steps[step.ID] && step.ID != "" {
+			problems = append(problems, location+": id is not unique")
+		}
+		steps[step.ID] = true
+	}
+
+	check("output", t.Spec.Output)
+	return problems
+}
+
+// SkeletonProblem is a fetch:template source that is not a directory under
+// the template's skeleton/.
+type SkeletonProblem struct {
+	Step string
+	// URL is the source after parameter expansion.
+	URL     string
+	Problem string
+}
+
+func (p SkeletonProblem) String() string {
+	return fmt.Sprintf("step %s: %s %s", p.Step, p.URL, p.Problem)
+}
+
+// CheckSkeletons returns the fetch:template sources that do not resolve to
+// a directory under skeleton/. Remote URLs are not checked.
+func (t *SoftwareTemplate) CheckSkeletons() []SkeletonProblem {
+	var problems []SkeletonProblem
+	parameters := t.Parameters()
+	skeleton := filepath.Join(t.Dir, "skeleton")
+
+	for _, step := range t.Spec.Steps {
+		if step.Action != "fetch:template" {
+			continue
+		}
+
+		url, _ := step.Input["url"].(string)
+		if url == "" {
+			problems = append(problems, SkeletonProblem{Step: step.ID, Problem: "has no url"})
+			continue
+		}
+		if strings.Contains(url, "://") {
+			continue
+		}
+
+		urls, err := expandParameters(url, parameters)
+		if err != nil {
+			problems = append(problems, SkeletonProblem{Step: step.ID, URL: url, Problem: err.Error()})
+			continue
+		}
+
+		for _, expanded := range urls {
+			path := filepath.Join(t.Dir, expanded)
+			rel, err := filepath.Rel(skeleton, path)
+			switch {
+			case err != nil || strings.HasPrefix(rel, ".."):
+				problems = append(problems, SkeletonProblem{Step: step.ID, URL: expanded, Problem: "is outside skeleton/"})
+			case !isDir(path):
+				problems = append(problems, SkeletonProblem{Step: step.ID, URL: expanded, Problem: "does not exist"})
+			}
+		}
+	}
+	return problems
+}
+
+// LoadKnownSkeletonGaps reads the fetch:template sources, by template name,
+// that are allowed to be missing.
+func LoadKnownSkeletonGaps(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var gaps struct {
+		Templates map[string][]string `yaml:"templates"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&gaps); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return gaps.Templates, nil
+}
+
+// expandParameters replaces a ${{ parameters.x }} expression in a source
+// URL by each value of the parameter's enum.
+func expandParameters(url string, parameters map[string]map[string]interface{}) ([]string, error) {
+	urls := []string{url}
+	for _, match := range templateExpressionPattern.FindAllStringSubmatch(url, -1) {
+		reference := parameterReferencePattern.FindStringSubmatch(match[1])
+		if reference == nil || strings.TrimSpace(match[1]) != reference[0] {
+			return nil, fmt.Errorf("expression %s is not a plain parameter reference", match[0])
+		}
+
+		name := reference[1] + reference[2]
+		enum, _ := parameters[name]["enum"].([]interface{})
+		if len(enum) == 0 {
+			return nil, fmt.Errorf("parameter %s has no enum to expand", name)
+		}
+
+		var expanded []string
+		for _, candidate := range urls {
+			for _, value := range enum {
+				expanded = append(expanded, strings.Replace(candidate, match[0], fmt.Sprint(value), 1))
+			}
+		}
+		urls = expanded
+	}
+	return urls, nil
+}
+
+// templateExpressions returns the contents of every ${{ ... }} expression in
+// the strings of value.
+func templateExpressions(value interface{}) []string {
+	var expressions []string
+	switch v := value.(type) {
+	case string:
+		for _, match := range templateExpressionPattern.FindAllStringSubmatch(v, -1) {
+			expressions = append(expressions, match[1])
+		}
+	case map[string]interface{}:
+		for _, key := range sortedSchemaKeys(v) {
+			expressions = append(expressions, templateExpressions(v[key])...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			expressions = append(expressions, templateExpressions(item)...)
+		}
+	}
+	return expressions
+}
+
+// collectParameters records the names declared by a page.
+func collectParameters(schema map[string]interface{}, declared map[string]bool) {
+	schemas := map[string]map[string]interface{}{}
+	collectParameterSchemas(schema, schemas)
+	for name := range schemas {
+		declared[name] = true
+	}
+}
+
+// collectParameterSchemas records the top-level properties of a page,
+// including those declared by dependencies and conditional subschemas.
+func collectParameterSchemas(schema map[string]interface{}, declared map[string]map[string]interface{}) {
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, property := range properties {
+			if _, ok := declared[name]; !ok {
+				propertySchema, _ := property.(map[string]interface{})
+				declared[name] = propertySchema
+			}
+		}
+	}
+
+	if dependencies, ok := schema["dependencies"].(map[string]interface{}); ok {
+		for _, dependency := range dependencies {
+			if subschema, ok := dependency.(map[string]interface{}); ok {
+				collectParameterSchemas(subschema, declared)
+			}
+		}
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		subschemas, _ := schema[keyword].([]interface{})
+		for _, subschema := range subschemas {
+			if subschema, ok := subschema.(map[string]interface{}); ok {
+				collectParameterSchemas(subschema, declared)
+			}
+		}
+	}
+	for _, keyword := range []string{"then", "else"} {
+		if subschema, ok := schema[keyword].(map[string]interface{}); ok {
+			collectParameterSchemas(subschema, declared)
+		}
+	}
+}
+
+// requiredNames returns the required property names of a page.
+func requiredNames(schema map[string]interface{}) []string {
+	required, _ := schema["required"].([]interface{})
+	names := make([]string, 0, len(required))
+	for _, name := range required {
+		names = append(names, fmt.Sprint(name))
+	}
+	return names
+}
+
+// pageSchema is a compiled parameters page.
+type pageSchema struct {
+	compiler *jsonschema.Compiler
+}
+
+const pageSchemaURL = "page.json"
+
+// compilePage compiles a parameters page with the draft-07 meta-schema.
+func compilePage(page map[string]interface{}) (*pageSchema, error) {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	if err := compiler.AddResource(pageSchemaURL, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if _, err := compiler.Compile(pageSchemaURL); err != nil {
+		return nil, schemaError(err)
+	}
+	return &pageSchema{compiler: compiler}, nil
+}
+
+// validateProperty validates value against the schema of a page property.
+func (s *pageSchema) validateProperty(name string, value interface{}) error {
+	return s.validate(pageSchemaURL+"#/properties/"+strings.ReplaceAll(name, "/", "~1"), value)
+}
+
+// validate validates value against the schema at url, the page or one of
+// its subschemas.
+func (s *pageSchema) validate(url string, value interface{}) error {
+	schema, err := s.compiler.Compile(url)
+	if err != nil {
+		return schemaError(err)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var instance interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&instance); err != nil {
+		return err
+	}
+	if err := schema.Validate(instance); err != nil {
+		return schemaError(err)
+	}
+	return nil
+}
+
+// schemaError flattens a jsonschema error to its leaf messages.
+func schemaError(err error) error {
+	var leaves []string
+	var walk func(*jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			location := e.InstanceLocation
+			if location == "" {
+				location = "/"
+			}
+			leaves = append(leaves, fmt.Sprintf("%s: %s", location, e.Message))
+		}
+		for _, cause := range e.Causes {
+			walk(cause)
+		}
+	}
+
+	switch e := err.(type) {
+	case *jsonschema.SchemaError:
+		if validation, ok := e.Err.(*jsonschema.ValidationError); ok {
+			walk(validation)
+			return fmt.Errorf("invalid schema: %s", strings.Join(leaves, "; "))
+		}
+		return fmt.Errorf("invalid schema: %v", e.Err)
+	case *jsonschema.ValidationError:
+		walk(e)
+		return fmt.Errorf("%s", strings.Join(leaves, "; "))
+	}
+	return err
+}
+
+func sortedSchemaKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
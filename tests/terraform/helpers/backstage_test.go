@@ -0,0 +1,210 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - BACKSTAGE SOFTWARE TEMPLATE CHECK TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestSoftwareTemplate|TestKnownSkeletonGaps' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSoftwareTemplate = `# Test template
+---
+apiVersion: scaffolder.backstage.io/v1beta3
+kind: Template
+metadata:
+  name: test-service
+  title: Test
+spec:
+  owner: platform-engineering
+  type: service
+  parameters:
+    - title: Service
+      required:
+        - name
+        - owner
+      properties:
+        name:
+          type: string
+          pattern: '^[a-z][a-z0-9-]*$'
+          default: Service
+        language:
+          type: string
+          default: go
+          enum: [go, python]
+          enumNames: [Go]
+        replicas:
+          type: integer
+          default: 2
+          minimum: 1
+      dependencies:
+        language:
+          oneOf:
+            - properties:
+                language:
+                  enum: [python]
+                pythonVersion:
+                  type: string
+  steps:
+    - id: fetch
+      name: Fetch
+      action: fetch:template
+      input:
+        url: ./skeleton/${{ parameters.language }}
+        targetPath: ../outside
+        values:
+          name: ${{ parameters.name }}
+          version: ${{ parameters.pythonVersion }}
+          team: ${{ parameters['team'] }}
+    - id: publish
+      name: Publish
+      if: ${{ parameters.name and steps['register'].output.entityRef }}
+      action: publish:github
+      input:
+        repoUrl: ${{ parameters.repoUrl }}
+    - id: register
+      name: Register
+      action: catalog:register
+      input:
+        repoContentsUrl: ${{ steps['publish'].output.repoContentsUrl }}
+    - id: register
+      name: Docs
+      action: fetch:template
+      input:
+        url: ../../common/docs
+  output:
+    links:
+      - url: ${{ steps['publish'].output.remoteUrl }}
+        title: ${{ parameters.title }}
+---
+# skeleton/src/main.go
+package main
+func main() { values: {} }
+`
+
+func writeSoftwareTemplate(t *testing.T, content string) *SoftwareTemplate {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "h1-foundation", "test-service")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "skeleton", "go"), 0o755))
+	path := filepath.Join(dir, "template.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	template, err := LoadSoftwareTemplate(path)
+	require.NoError(t, err)
+	return template
+}
+
+// TestSoftwareTemplateCheck tests entity, parameter and reference checks
+func TestSoftwareTemplateCheck(t *testing.T) {
+	t.Parallel()
+
+	template := writeSoftwareTemplate(t, testSoftwareTemplate)
+	assert.Equal(t, "test-service", template.Metadata.Name, "trailing documents are not decoded")
+
+	assert.Equal(t, []string{
+		`parameters page 1 "Service": required property "owner" is not declared`,
+		`parameters page 1 "Service": language has 1 enumNames for 2 enum values`,
+		`parameters page 1 "Service": name default Service: /: does not match pattern '^[a-z][a-z0-9-]*$'`,
+		`step fetch: parameters.team is not declared`,
+		`step fetch: targetPath ../outside is outside the workspace`,
+		`step publish: steps['register'] does not refer to an earlier step`,
+		`step publish: parameters.repoUrl is not declared`,
+		`step register: id is not unique`,
+		`output: parameters.title is not declared`,
+	}, template.Check())
+}
+
+// TestSoftwareTemplateInvalidSchema tests that a page that is not a valid
+// JSON Schema is reported
+func TestSoftwareTemplateInvalidSchema(t *testing.T) {
+	t.Parallel()
+
+	template := writeSoftwareTemplate(t, `
+apiVersion: backstage.io/v1beta2
+kind: Template
+metadata:
+  name: test service
+spec:
+  parameters:
+    - title: Service
+      properties:
+        name:
+          type: text
+  steps: []
+`)
+
+	problems := template.Check()
+	assert.Equal(t, []string{
+		`apiVersion is "backstage.io/v1beta2", expected "scaffolder.backstage.io/v1beta3"`,
+		`metadata.name "test service" is not a valid entity name`,
+		"spec.owner is not set",
+		"spec.type is not set",
+		"spec.steps is empty",
+	}, problems[:5])
+	require.Len(t, problems, 6)
+	assert.Contains(t, problems[5], `parameters page 1 "Service": invalid schema: /properties/name/type`)
+}
+
+// TestSoftwareTemplateSkeletons tests fetch:template source resolution
+func TestSoftwareTemplateSkeletons(t *testing.T) {
+	t.Parallel()
+
+	template := writeSoftwareTemplate(t, testSoftwareTemplate)
+
+	problems := make([]string, 0)
+	for _, problem := range template.CheckSkeletons() {
+		problems = append(problems, problem.String())
+	}
+	assert.Equal(t, []string{
+		"step fetch: ./skeleton/python does not exist",
+		"step register: ../../common/docs is outside skeleton/",
+	}, problems)
+}
+
+// TestSoftwareTemplateExpansion tests that only plain parameter references
+// with an enum are expanded
+func TestSoftwareTemplateExpansion(t *testing.T) {
+	t.Parallel()
+
+	parameters := map[string]map[string]interface{}{
+		"language": {"enum": []interface{}{"go", "python"}},
+		"name":     {"type": "string"},
+	}
+
+	urls, err := expandParameters("./skeleton/${{ parameters.language }}/${{parameters['language']}}", parameters)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"./skeleton/go/go", "./skeleton/go/python", "./skeleton/python/go", "./skeleton/python/python",
+	}, urls)
+
+	_, err = expandParameters("./skeleton/${{ parameters.name }}", parameters)
+	assert.EqualError(t, err, "parameter name has no enum to expand")
+
+	_, err = expandParameters("./skeleton/${{ parameters.language | lower }}", parameters)
+	assert.EqualError(t, err, "expression ${{ parameters.language | lower }} is not a plain parameter reference")
+}
+
+// TestKnownSkeletonGaps tests the known gap list loader
+func TestKnownSkeletonGaps(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "gaps.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("templates:\n  test-service:\n    - ./skeleton/python\n"), 0o644))
+	gaps, err := LoadKnownSkeletonGaps(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"test-service": {"./skeleton/python"}}, gaps)
+
+	require.NoError(t, os.WriteFile(path, []byte("test-service: []\n"), 0o644))
+	_, err = LoadKnownSkeletonGaps(path)
+	assert.ErrorContains(t, err, "field test-service not found")
+}
@@ -0,0 +1,635 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - ROOT MODULE COMPOSITION CHECKS
+// =============================================================================
+//
+// terraform/main.tf wires the modules together. The module tests plan each
+// module on its own, so a renamed output or variable only breaks the root:
+//
+//	problems, err := helpers.CheckComposition(helpers.RootModuleDir())
+//
+// For every module block with a local source CheckComposition reports:
+//
+//   - an argument that is not a variable of the module, or a required
+//     variable that is not set
+//   - an argument whose value cannot convert to the variable's type, or that
+//     sets an object attribute the type does not declare (Terraform drops
+//     it silently)
+//   - a module.<name>.<output> reference, anywhere in the root, to a module
+//     block or output that does not exist, or that indexes a module
+//     without count/for_each (or does not index one with it)
+//
+// Types are checked without Terraform: each argument is evaluated with
+// var.* as unknown values of their declared types, locals evaluated the
+// same way, and module outputs, resources and data sources as unknown
+// values of any type.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// moduleMetaArguments are the module block arguments that are not
+// variables of the called module.
+var moduleMetaArguments = []string{"source", "version", "count", "for_each", "providers", "depends_on"}
+
+// compositionFunctions are the Terraform functions whose result type
+// matters for the checks. Any other function returns an unknown value of
+// any type.
+var compositionFunctions = map[string]function.Function{
+	"can":        tryfunc.CanFunc,
+	"coalesce":   stdlib.CoalesceFunc,
+	"concat":     stdlib.ConcatFunc,
+	"contains":   stdlib.ContainsFunc,
+	"flatten":    stdlib.FlattenFunc,
+	"format":     stdlib.FormatFunc,
+	"join":       stdlib.JoinFunc,
+	"keys":       stdlib.KeysFunc,
+	"length":     stdlib.LengthFunc,
+	"lookup":     stdlib.LookupFunc,
+	"lower":      stdlib.LowerFunc,
+	"merge":      stdlib.MergeFunc,
+	"regex":      stdlib.RegexFunc,
+	"replace":    stdlib.ReplaceFunc,
+	"split":      stdlib.SplitFunc,
+	"substr":     stdlib.SubstrFunc,
+	"try":        tryfunc.TryFunc,
+	"upper":      stdlib.UpperFunc,
+	"values":     stdlib.ValuesFunc,
+	"jsonencode": stdlib.JSONEncodeFunc,
+}
+
+// anyFunction stands in for Terraform functions that are not in
+// compositionFunctions.
+var anyFunction = function.New(&function.Spec{
+	VarParam: &function.Parameter{
+		Name:             "args",
+		Type:             cty.DynamicPseudoType,
+		AllowUnknown:     true,
+		AllowNull:        true,
+		AllowDynamicType: true,
+	},
+	Type: function.StaticReturnType(cty.DynamicPseudoType),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.DynamicVal, nil
+	},
+})
+
+// RootModuleDir returns terraform/, the root module that composes the
+// modules.
+func RootModuleDir() string {
+	return filepath.Join(RepoRoot(), "terraform")
+}
+
+// RootExampleVarsPath returns terraform/terraform.tfvars.example.
+func RootExampleVarsPath() string {
+	return filepath.Join(RootModuleDir(), "terraform.tfvars.example")
+}
+
+// RootOptions builds terraform.Options for an isolated copy of the root
+// module from terraform.tfvars.example merged with overrides. The merged
+// Vars are checked against the root's variables like ModuleOptions does.
+func RootOptions(t testing.TB, overrides ...map[string]interface{}) *terraform.Options {
+	t.Helper()
+
+	example, err := ParseVarsFile(RootExampleVarsPath())
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", RootExampleVarsPath(), err)
+	}
+	vars := MergeVars(example, overrides...)
+
+	declared, err := DirVariables(RootModuleDir())
+	if err != nil {
+		t.Fatalf("Failed to parse the root module variables: %v", err)
+	}
+	if problems := CheckVars(declared, vars, unsetKeys(overrides)...); len(problems) > 0 {
+		t.Fatal(formatProblems("root", problems))
+	}
+
+	dir := IsolatedDir(t, RootModuleDir())
+	requireProvidersInMirror(t, dir)
+
+	return terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: dir,
+		Vars:         vars,
+		NoColor:      true,
+	})
+}
+
+// ModuleCall is a module block.
+type ModuleCall struct {
+	Name   string
+	Source string
+	// Dir is the called module's directory, "" for a registry source.
+	Dir string
+	// Repeated is set when the block has count or for_each, so references
+	// must index it.
+	Repeated  bool
+	Arguments map[string]*hclsyntax.Attribute
+	Range     hcl.Range
+}
+
+// rootModule is the parsed .tf files of a directory.
+type rootModule struct {
+	bodies    []*hclsyntax.Body
+	variables map[string]Variable
+	locals    map[string]*hclsyntax.Attribute
+	calls     map[string]*ModuleCall
+}
+
+// DirVariables returns the variables declared in every .tf file of dir.
+func DirVariables(dir string) (map[string]Variable, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]Variable{}
+	for _, file := range files {
+		fileVars, err := ParseVariablesFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for name, v := range fileVars {
+			vars[name] = v
+		}
+	}
+	return vars, nil
+}
+
+// DirOutputs returns the names of the outputs declared in every .tf file of
+// dir.
+func DirOutputs(dir string) (map[string]bool, error) {
+	bodies, err := parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]bool{}
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type == "output" && len(block.Labels) == 1 {
+				outputs[block.Labels[0]] = true
+			}
+		}
+	}
+	return outputs, nil
+}
+
+// ParseModuleCalls returns the module blocks of dir, sorted by name.
+func ParseModuleCalls(dir string) ([]*ModuleCall, error) {
+	root, err := parseRootModule(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := make([]*ModuleCall, 0, len(root.calls))
+	for _, call := range root.calls {
+		calls = append(calls, call)
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Name < calls[j].Name })
+	return calls, nil
+}
+
+// CheckComposition checks the module blocks of dir and every module output
+// reference in it. Problems are sorted.
+func CheckComposition(dir string) ([]string, error) {
+	root, err := parseRootModule(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := root.evalContext()
+	var problems []string
+
+	outputs := map[string]map[string]bool{}
+	for _, call := range root.calls {
+		if call.Dir == "" {
+			continue
+		}
+
+		declared, err := DirVariables(call.Dir)
+		if err != nil {
+			return nil, err
+		}
+		if outputs[call.Name], err = DirOutputs(call.Dir); err != nil {
+			return nil, err
+		}
+
+		for name, attr := range call.Arguments {
+			v, ok := declared[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("module.%s: %s is not a variable of %s", call.Name, name, call.Source))
+				continue
+			}
+			problems = append(problems, checkArgument(call.Name+"."+name, attr.Expr, v.Type, ctx)...)
+		}
+		for name, v := range declared {
+			if _, ok := call.Arguments[name]; v.Required && !ok {
+				problems = append(problems, fmt.Sprintf("module.%s: required variable %s is not set", call.Name, name))
+			}
+		}
+	}
+
+	for _, body := range root.bodies {
+		problems = append(problems, checkModuleReferences(body, root.calls, outputs)...)
+	}
+
+	sort.Strings(problems)
+	return dedupe(problems), nil
+}
+
+// checkArgument evaluates a module argument and checks it converts to the
+// variable's type.
+func checkArgument(path string, expr hclsyntax.Expression, ty cty.Type, ctx *hcl.EvalContext) []string {
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return []string{fmt.Sprintf("module.%s: %s", path, diagnosticsSummary(diags))}
+	}
+	if ty == cty.DynamicPseudoType || value.Type() == cty.DynamicPseudoType {
+		return nil
+	}
+
+	if _, err := convert.Convert(value, ty); err != nil {
+		return []string{fmt.Sprintf("module.%s: %s cannot be used as %s: %s",
+			path, value.Type().FriendlyName(), ty.FriendlyName(), convertErrorMessage(err))}
+	}
+
+	var problems []string
+	for _, extra := range undeclaredAttributes(path, value.Type(), ty) {
+		problems = append(problems, fmt.Sprintf("module.%s: attribute not declared in object type", extra))
+	}
+	return problems
+}
+
+// undeclaredAttributes returns the paths of object attributes in have that
+// the type constraint want does not declare.
+func undeclaredAttributes(path string, have, want cty.Type) []string {
+	var extra []string
+
+	switch {
+	case want.IsObjectType() && have.IsObjectType():
+		for name, attrType := range have.AttributeTypes() {
+			if !want.HasAttribute(name) {
+				extra = append(extra, path+"."+name)
+				continue
+			}
+			extra = append(extra, undeclaredAttributes(path+"."+name, attrType, want.AttributeType(name))...)
+		}
+
+	case want.IsMapType() && have.IsObjectType():
+		for name, attrType := range have.AttributeTypes() {
+			extra = append(extra, undeclaredAttributes(path+"."+name, attrType, want.ElementType())...)
+		}
+
+	case (want.IsListType() || want.IsSetType()) && have.IsTupleType():
+		for i, elemType := range have.TupleElementTypes() {
+			extra = append(extra, undeclaredAttributes(fmt.Sprintf("%s[%d]", path, i), elemType, want.ElementType())...)
+		}
+
+	case want.IsCollectionType() && have.IsCollectionType():
+		extra = append(extra, undeclaredAttributes(path+"[*]", have.ElementType(), want.ElementType())...)
+	}
+
+	sort.Strings(extra)
+	return extra
+}
+
+// checkModuleReferences checks every module.<name>... traversal in body.
+func checkModuleReferences(body *hclsyntax.Body, calls map[string]*ModuleCall, outputs map[string]map[string]bool) []string {
+	var problems []string
+
+	hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+		expr, ok := node.(hclsyntax.Expression)
+		if !ok {
+			return nil
+		}
+		for _, traversal := range expr.Variables() {
+			if traversal.RootName() != "module" || len(traversal) < 2 {
+				continue
+			}
+			name, ok := traversal[1].(hcl.TraverseAttr)
+			if !ok {
+				continue
+			}
+			location := fmt.Sprintf("%s:%d", filepath.Base(traversal.SourceRange().Filename), traversal.SourceRange().Start.Line)
+
+			call, ok := calls[name.Name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: module.%s is not declared", location, name.Name))
+				continue
+			}
+
+			rest := traversal[2:]
+			if len(rest) == 0 {
+				// module.x[...] with a dynamic index, or the whole module
+				continue
+			}
+			_, indexed := rest[0].(hcl.TraverseIndex)
+			switch {
+			case call.Repeated && !indexed:
+				problems = append(problems, fmt.Sprintf("%s: module.%s has count or for_each and must be indexed", location, call.Name))
+				continue
+			case !call.Repeated && indexed:
+				problems = append(problems, fmt.Sprintf("%s: module.%s has no count or for_each and cannot be indexed", location, call.Name))
+				continue
+			case indexed:
+				rest = rest[1:]
+			}
+
+			if len(rest) == 0 || outputs[call.Name] == nil {
+				continue
+			}
+			if output, ok := rest[0].(hcl.TraverseAttr); ok && !outputs[call.Name][output.Name] {
+				problems = append(problems, fmt.Sprintf("%s: module.%s has no output %s", location, call.Name, output.Name))
+			}
+		}
+		return nil
+	})
+
+	return problems
+}
+
+func parseDir(dir string) ([]*hclsyntax.Body, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var bodies []*hclsyntax.Body
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		bodies = append(bodies, file.Body.(*hclsyntax.Body))
+	}
+	return bodies, nil
+}
+
+func parseRootModule(dir string) (*rootModule, error) {
+	bodies, err := parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	variables, err := DirVariables(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &rootModule{
+		bodies:    bodies,
+		variables: variables,
+		locals:    map[string]*hclsyntax.Attribute{},
+		calls:     map[string]*ModuleCall{},
+	}
+
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			switch block.Type {
+			case "locals":
+				for name, attr := range block.Body.Attributes {
+					root.locals[name] = attr
+				}
+
+			case "module":
+				if len(block.Labels) != 1 {
+					continue
+				}
+				call := &ModuleCall{
+					Name:      block.Labels[0],
+					Arguments: map[string]*hclsyntax.Attribute{},
+					Range:     block.Range(),
+				}
+				for name, attr := range block.Body.Attributes {
+					switch name {
+					case "source":
+						value, diags := attr.Expr.Value(nil)
+						if diags.HasErrors() || value.Type() != cty.String {
+							return nil, fmt.Errorf("module.%s: source must be a string literal", call.Name)
+						}
+						call.Source = value.AsString()
+					case "count", "for_each":
+						call.Repeated = true
+					}
+					if !containsString(moduleMetaArguments, name) {
+						call.Arguments[name] = attr
+					}
+				}
+				if strings.HasPrefix(call.Source, "./") || strings.HasPrefix(call.Source, "../") {
+					call.Dir = filepath.Join(dir, filepath.FromSlash(call.Source))
+				}
+				root.calls[call.Name] = call
+			}
+		}
+	}
+
+	return root, nil
+}
+
+// evalContext returns the context module arguments are evaluated in.
+func (r *rootModule) evalContext() *hcl.EvalContext {
+	vars := map[string]cty.Value{}
+	for name, v := range r.variables {
+		vars[name] = cty.UnknownVal(v.Type.WithoutOptionalAttributesDeep())
+	}
+
+	modules := map[string]cty.Value{}
+	for name, call := range r.calls {
+		if call.Repeated || call.Dir == "" {
+			modules[name] = cty.DynamicVal
+			continue
+		}
+		attrs := map[string]cty.Type{}
+		if outputs, err := DirOutputs(call.Dir); err == nil {
+			for output := range outputs {
+				attrs[output] = cty.DynamicPseudoType
+			}
+		}
+		modules[name] = cty.UnknownVal(cty.Object(attrs))
+	}
+
+	functions := map[string]function.Function{}
+	variables := map[string]cty.Value{
+		"var":    cty.ObjectVal(vars),
+		"module": cty.ObjectVal(modules),
+	}
+	for _, body := range r.bodies {
+		hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			switch n := node.(type) {
+			case *hclsyntax.FunctionCallExpr:
+				if f, ok := compositionFunctions[n.Name]; ok {
+					functions[n.Name] = f
+				} else {
+					functions[n.Name] = anyFunction
+				}
+			case *hclsyntax.ScopeTraversalExpr:
+				// Resources, data sources, path, terraform, count, each
+				if name := n.Traversal.RootName(); name != "var" && name != "local" && name != "module" {
+					variables[name] = cty.DynamicVal
+				}
+			}
+			return nil
+		})
+	}
+
+	ctx := &hcl.EvalContext{Variables: variables, Functions: functions}
+	ctx.Variables["local"] = r.evalLocals(ctx)
+	return ctx
+}
+
+// evalLocals evaluates the locals in dependency order. A local that fails
+// to evaluate, or is part of a cycle, is unknown.
+func (r *rootModule) evalLocals(ctx *hcl.EvalContext) cty.Value {
+	values := map[string]cty.Value{}
+	pending := map[string]*hclsyntax.Attribute{}
+	for name, attr := range r.locals {
+		pending[name] = attr
+	}
+
+	for progress := true; progress && len(pending) > 0; {
+		progress = false
+		for _, name := range sortedAttributeNames(pending) {
+			attr := pending[name]
+			ready := true
+			for _, traversal := range attr.Expr.Variables() {
+				if traversal.RootName() != "local" || len(traversal) < 2 {
+					continue
+				}
+				if dep, ok := traversal[1].(hcl.TraverseAttr); ok {
+					if _, done := values[dep.Name]; !done {
+						ready = false
+					}
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			local := ctx.NewChild()
+			local.Variables = map[string]cty.Value{"local": cty.ObjectVal(values)}
+			value, diags := attr.Expr.Value(local)
+			if diags.HasErrors() {
+				value = cty.DynamicVal
+			}
+			values[name] = value
+			delete(pending, name)
+			progress = true
+		}
+	}
+
+	for name := range pending {
+		values[name] = cty.DynamicVal
+	}
+	return cty.ObjectVal(values)
+}
+
+// ParseVarsFile reads a .tfvars file into Vars.
+func ParseVarsFile(path string) (map[string]interface{}, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	vars := make(map[string]interface{}, len(attrs))
+	for name, attr := range attrs {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		data, err := ctyjson.Marshal(value, value.Type())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return nil, err
+		}
+		vars[name] = decoded
+	}
+	return vars, nil
+}
+
+func sortedAttributeNames(attrs map[string]*hclsyntax.Attribute) []string {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func diagnosticsSummary(diags hcl.Diagnostics) string {
+	var messages []string
+	for _, diag := range diags {
+		if diag.Severity == hcl.DiagError {
+			messages = append(messages, strings.TrimSuffix(diag.Summary+": "+diag.Detail, ": "))
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+func convertErrorMessage(err error) string {
+	if pathErr, ok := err.(cty.PathError); ok && len(pathErr.Path) > 0 {
+		return fmt.Sprintf("%s: %s", formatCtyPath(pathErr.Path), pathErr.Error())
+	}
+	return err.Error()
+}
+
+func formatCtyPath(path cty.Path) string {
+	var b strings.Builder
+	for _, step := range path {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			b.WriteString("." + s.Name)
+		case cty.IndexStep:
+			if s.Key.Type() == cty.String {
+				fmt.Fprintf(&b, "[%q]", s.Key.AsString())
+			} else if s.Key.IsKnown() {
+				fmt.Fprintf(&b, "[%s]", s.Key.AsBigFloat().Text('f', -1))
+			}
+		}
+	}
+	return strings.TrimPrefix(b.String(), ".")
+}
+
+func dedupe(sorted []string) []string {
+	var out []string
+	for i, value := range sorted {
+		if i == 0 || value != sorted[i-1] {
+			out = append(out, value)
+		}
+	}
+	return out
+}
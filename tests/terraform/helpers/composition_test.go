@@ -0,0 +1,175 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - ROOT MODULE COMPOSITION CHECK TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestCheckComposition|TestParseModuleCalls|TestParseVarsFile' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCompositionChild = `
+variable "name" {
+  type = string
+}
+
+variable "size" {
+  type    = number
+  default = 1
+}
+
+variable "config" {
+  type = object({
+    enabled = bool
+    zones   = optional(list(string), [])
+  })
+  default = { enabled = false }
+}
+
+output "id" {
+  value = "child"
+}
+`
+
+const testCompositionRoot = `
+variable "prefix" {
+  type = string
+}
+
+variable "enabled" {
+  type    = bool
+  default = true
+}
+
+locals {
+  name  = "${var.prefix}-app"
+  sizes = { small = 1, large = 3 }
+}
+
+module "base" {
+  source = "./modules/child"
+
+  name = local.name
+  size = local.sizes["large"]
+}
+
+module "extra" {
+  source = "./modules/child"
+  count  = var.enabled ? 1 : 0
+
+  name   = module.base.id
+  size   = "large"
+  config = {
+    enabled = true
+    zone    = "1"
+  }
+  color = "blue"
+}
+
+module "broken" {
+  source = "./modules/child"
+
+  size = length(module.missing.id)
+}
+
+module "registry" {
+  source  = "Azure/avm-res-example/azurerm"
+  version = "1.0.0"
+
+  anything = module.base[0].id
+}
+
+output "extra_id" {
+  value = var.enabled ? module.extra.id : module.base.name
+}
+`
+
+func writeCompositionRoot(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	child := filepath.Join(dir, "modules", "child")
+	require.NoError(t, os.MkdirAll(child, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(child, "main.tf"), []byte(testCompositionChild), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(testCompositionRoot), 0o644))
+	return dir
+}
+
+// TestCheckComposition tests every kind of wiring problem
+func TestCheckComposition(t *testing.T) {
+	t.Parallel()
+
+	problems, err := CheckComposition(writeCompositionRoot(t))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"main.tf:39: module.missing is not declared",
+		"main.tf:46: module.base has no count or for_each and cannot be indexed",
+		"main.tf:50: module.base has no output name",
+		"main.tf:50: module.extra has count or for_each and must be indexed",
+		`module.broken.size: Unsupported attribute: This object does not have an attribute named "missing".`,
+		"module.broken: required variable name is not set",
+		"module.extra.config.zone: attribute not declared in object type",
+		"module.extra.size: string cannot be used as number: a number is required",
+		"module.extra: color is not a variable of ./modules/child",
+	}, problems)
+}
+
+// TestParseModuleCalls tests sources, directories and repetition
+func TestParseModuleCalls(t *testing.T) {
+	t.Parallel()
+
+	dir := writeCompositionRoot(t)
+	calls, err := ParseModuleCalls(dir)
+	require.NoError(t, err)
+	require.Len(t, calls, 4)
+
+	assert.Equal(t, "base", calls[0].Name)
+	assert.Equal(t, filepath.Join(dir, "modules", "child"), calls[0].Dir)
+	assert.False(t, calls[0].Repeated)
+
+	assert.Equal(t, "extra", calls[2].Name)
+	assert.True(t, calls[2].Repeated)
+	assert.ElementsMatch(t, []string{"name", "size", "config", "color"}, sortedAttributeNames(calls[2].Arguments))
+
+	assert.Equal(t, "registry", calls[3].Name)
+	assert.Equal(t, "", calls[3].Dir)
+}
+
+// TestParseVarsFile tests conversion of tfvars values to Go values
+func TestParseVarsFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "terraform.tfvars")
+	require.NoError(t, os.WriteFile(path, []byte(`
+customer_name = "contoso" # inline comment
+node_count    = 3
+enabled       = true
+alert_emails  = []
+tags = {
+  Project = "three-horizons"
+}
+`), 0o644))
+
+	vars, err := ParseVarsFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"customer_name": "contoso",
+		"node_count":    float64(3),
+		"enabled":       true,
+		"alert_emails":  []interface{}{},
+		"tags":          map[string]interface{}{"Project": "three-horizons"},
+	}, vars)
+
+	require.NoError(t, os.WriteFile(path, []byte("name = var.prefix\n"), 0o644))
+	_, err = ParseVarsFile(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,201 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - ENVIRONMENTS
+// =============================================================================
+//
+// The canonical deployment environments and the value each module accepts
+// for them:
+//
+//	for _, env := range helpers.Environments() {
+//	    options := helpers.OptionsFor(t, helpers.ModuleNaming, env.Vars(helpers.ModuleNaming))
+//	    // environment = "stg" for staging
+//	}
+//
+// The root module, scripts/validate-config.sh and most modules spell the
+// environments dev, staging and prod. The naming module uses the short
+// codes dev, stg and prd (it also accepts sbx and tst, which have no
+// canonical environment). A matrix test that loops over one spelling feeds
+// the other modules a value they reject, or one they accept without ever
+// taking their prod branch.
+//
+// Each environment also carries the values the root module derives from it
+// (Log Analytics retention, PostgreSQL backup retention and geo-redundant
+// backup). Vars sends them to the modules that expose them. AKS sku_tier,
+// zones and high availability are not among them: the root module leaves
+// sku_tier at the module default and takes zones and high availability from
+// deployment_mode, so Vars leaves them at the module baseline.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Environment is a canonical deployment environment.
+type Environment struct {
+	// Name is the canonical name, the value of the root module's
+	// environment variable.
+	Name string
+	// Short is the naming module's code for the environment.
+	Short string
+	// RetentionDays is the Log Analytics retention the root module sets.
+	RetentionDays int
+	// BackupRetentionDays is the PostgreSQL backup retention the root
+	// module sets.
+	BackupRetentionDays int
+	// GeoRedundantBackup is the PostgreSQL geo_redundant_backup the root
+	// module sets.
+	GeoRedundantBackup bool
+}
+
+// The canonical environments.
+var (
+	EnvironmentDev = Environment{
+		Name:                "dev",
+		Short:               "dev",
+		RetentionDays:       30,
+		BackupRetentionDays: 7,
+	}
+	EnvironmentStaging = Environment{
+		Name:                "staging",
+		Short:               "stg",
+		RetentionDays:       30,
+		BackupRetentionDays: 7,
+	}
+	EnvironmentProd = Environment{
+		Name:                "prod",
+		Short:               "prd",
+		RetentionDays:       90,
+		BackupRetentionDays: 35,
+		GeoRedundantBackup:  true,
+	}
+)
+
+// shortEnvironmentModules are the modules that take Environment.Short.
+var shortEnvironmentModules = map[string]bool{
+	ModuleNaming: true,
+}
+
+// Environments returns the canonical environments, dev first.
+func Environments() []Environment {
+	return []Environment{EnvironmentDev, EnvironmentStaging, EnvironmentProd}
+}
+
+// LookupEnvironment returns the environment whose canonical name or short
+// code is value.
+func LookupEnvironment(value string) (Environment, bool) {
+	for _, env := range Environments() {
+		if env.Name == value || env.Short == value {
+			return env, true
+		}
+	}
+	return Environment{}, false
+}
+
+// String returns the canonical name, so environments name subtests.
+func (e Environment) String() string {
+	return e.Name
+}
+
+// HighAvailability reports whether the modules make their resources zone
+// redundant in the environment: their `environment == "prod"` switches.
+func (e Environment) HighAvailability() bool {
+	return e.Name == EnvironmentProd.Name
+}
+
+// Value returns the module's encoding of the environment.
+func (e Environment) Value(module string) string {
+	if shortEnvironmentModules[module] {
+		return e.Short
+	}
+	return e.Name
+}
+
+// Vars returns the environment variable of module set to Value, plus the
+// environment's defaults for the variables the module exposes. Use it as an
+// override of the module's baseline.
+func (e Environment) Vars(module string) map[string]interface{} {
+	vars := map[string]interface{}{
+		"environment": e.Value(module),
+	}
+
+	switch module {
+	case ModuleDatabases:
+		vars["postgresql_config"] = map[string]interface{}{
+			"backup_retention_days": e.BackupRetentionDays,
+			"geo_redundant_backup":  e.GeoRedundantBackup,
+		}
+	case ModuleObservability:
+		vars["retention_days"] = e.RetentionDays
+	}
+
+	return vars
+}
+
+// AcceptedEnvironments returns the values the validation of the environment
+// variable in dir allows, or nil when the variable has no validation. Only
+// `contains([...], var.environment)` conditions are understood.
+func AcceptedEnvironments(dir string) ([]string, error) {
+	bodies, err := parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "variable" || len(block.Labels) != 1 || block.Labels[0] != "environment" {
+				continue
+			}
+
+			var accepted []string
+			for _, validation := range block.Body.Blocks {
+				if validation.Type != "validation" {
+					continue
+				}
+				attr, ok := validation.Body.Attributes["condition"]
+				if !ok {
+					continue
+				}
+				values, err := containsEnvironment(attr.Expr)
+				if err != nil {
+					return nil, err
+				}
+				accepted = append(accepted, values...)
+			}
+			return accepted, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: no environment variable", dir)
+}
+
+// containsEnvironment returns the list of a `contains([...], var.environment)`
+// condition.
+func containsEnvironment(expr hclsyntax.Expression) ([]string, error) {
+	call, ok := expr.(*hclsyntax.FunctionCallExpr)
+	if !ok || call.Name != "contains" || len(call.Args) != 2 {
+		return nil, fmt.Errorf("%s: environment condition is not contains([...], var.environment)", expr.Range())
+	}
+
+	traversal, diags := hcl.AbsTraversalForExpr(call.Args[1])
+	if diags.HasErrors() || len(traversal) != 2 || traversal.RootName() != "var" {
+		return nil, fmt.Errorf("%s: environment condition is not contains([...], var.environment)", expr.Range())
+	}
+	if attr, ok := traversal[1].(hcl.TraverseAttr); !ok || attr.Name != "environment" {
+		return nil, fmt.Errorf("%s: environment condition is not contains([...], var.environment)", expr.Range())
+	}
+
+	list, diags := call.Args[0].Value(nil)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	var values []string
+	for _, value := range list.AsValueSlice() {
+		values = append(values, value.AsString())
+	}
+	return values, nil
+}
@@ -0,0 +1,147 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - ENVIRONMENTS TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'Environment' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// TestEnvironmentValue tests the encodings and the lookup by either spelling
+func TestEnvironmentValue(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "prd", EnvironmentProd.Value(ModuleNaming))
+	assert.Equal(t, "prod", EnvironmentProd.Value(ModuleAKSCluster))
+	assert.Equal(t, "staging", EnvironmentStaging.String())
+	assert.True(t, EnvironmentProd.HighAvailability())
+	assert.False(t, EnvironmentStaging.HighAvailability())
+
+	for _, value := range []string{"staging", "stg"} {
+		env, ok := LookupEnvironment(value)
+		assert.True(t, ok, value)
+		assert.Equal(t, EnvironmentStaging, env)
+	}
+	_, ok := LookupEnvironment("sbx")
+	assert.False(t, ok)
+}
+
+// TestEnvironmentsAcceptedByModules tests that every module and the root
+// module accept the value each environment sends them
+func TestEnvironmentsAcceptedByModules(t *testing.T) {
+	t.Parallel()
+
+	dirs := map[string]string{"root": RootModuleDir()}
+	for _, module := range Modules() {
+		dirs[module] = ModuleDir(module)
+	}
+
+	for module, dir := range dirs {
+		accepted, err := AcceptedEnvironments(dir)
+		require.NoError(t, err, module)
+		if accepted == nil {
+			continue
+		}
+		for _, env := range Environments() {
+			assert.Contains(t, accepted, env.Value(module), "%s rejects %s", module, env)
+		}
+	}
+
+	accepted, err := AcceptedEnvironments(ModuleDir(ModuleNaming))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "stg", "prd", "sbx", "tst"}, accepted)
+}
+
+// TestEnvironmentVarsMatchModules tests that Vars only sets declared
+// variables, with their types
+func TestEnvironmentVarsMatchModules(t *testing.T) {
+	t.Parallel()
+
+	for _, module := range Modules() {
+		for _, env := range Environments() {
+			problems, err := CheckModuleVars(module, MergeVars(Baseline(module), env.Vars(module)))
+			require.NoError(t, err)
+			assert.Empty(t, problems, "%s in %s", module, env)
+		}
+	}
+}
+
+// TestEnvironmentDefaultsMatchRootModule tests the retention and backup
+// defaults against the expressions the root module passes to the modules
+func TestEnvironmentDefaultsMatchRootModule(t *testing.T) {
+	t.Parallel()
+
+	calls, err := ParseModuleCalls(RootModuleDir())
+	require.NoError(t, err)
+	arguments := map[string]map[string]*hclsyntax.Attribute{}
+	for _, call := range calls {
+		arguments[call.Name] = call.Arguments
+	}
+
+	for _, env := range Environments() {
+		ctx := &hcl.EvalContext{Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(map[string]cty.Value{"environment": cty.StringVal(env.Name)}),
+		}}
+		evaluate := func(expr hclsyntax.Expression) cty.Value {
+			value, diags := expr.Value(ctx)
+			require.False(t, diags.HasErrors(), diags.Error())
+			return value
+		}
+
+		retention, _ := evaluate(arguments["observability"]["retention_days"].Expr).AsBigFloat().Int64()
+		assert.EqualValues(t, env.RetentionDays, retention, "%s retention_days", env)
+
+		postgresql := arguments["databases"]["postgresql_config"].Expr
+		backup, _ := evaluate(objectItem(t, postgresql, "backup_retention_days")).AsBigFloat().Int64()
+		assert.EqualValues(t, env.BackupRetentionDays, backup, "%s backup_retention_days", env)
+		assert.Equal(t, env.GeoRedundantBackup, evaluate(objectItem(t, postgresql, "geo_redundant_backup")).True(), "%s geo_redundant_backup", env)
+	}
+}
+
+// objectItem returns the value expression of key in an object constructor.
+func objectItem(t *testing.T, expr hclsyntax.Expression, key string) hclsyntax.Expression {
+	t.Helper()
+
+	object, ok := expr.(*hclsyntax.ObjectConsExpr)
+	require.True(t, ok, "%s is not an object", expr.Range())
+	for _, item := range object.Items {
+		if hcl.ExprAsKeyword(item.KeyExpr) == key {
+			return item.ValueExpr
+		}
+	}
+	t.Fatalf("%s has no %s", expr.Range(), key)
+	return nil
+}
+
+// TestEnvironmentsMatchValidateConfig tests that scripts/validate-config.sh
+// takes the canonical names
+func TestEnvironmentsMatchValidateConfig(t *testing.T) {
+	t.Parallel()
+
+	src, err := os.ReadFile(filepath.Join(RepoRoot(), "scripts", "validate-config.sh"))
+	require.NoError(t, err)
+
+	usage := regexp.MustCompile(`--environment <([a-z|]+)>`).FindStringSubmatch(string(src))
+	require.NotNil(t, usage, "no --environment usage in validate-config.sh")
+
+	var names []string
+	for _, env := range Environments() {
+		names = append(names, env.Name)
+	}
+	assert.Equal(t, names, strings.Split(usage[1], "|"))
+}
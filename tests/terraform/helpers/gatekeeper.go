@@ -0,0 +1,608 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - GATEKEEPER ADMISSION EMULATION
+// =============================================================================
+//
+// Evaluates the Rego embedded in policies/kubernetes/constraint-templates
+// with the parameters of each constraint in policies/kubernetes/constraints,
+// the way Gatekeeper does at admission time:
+//
+//	templates, err := helpers.LoadConstraintTemplates(helpers.ConstraintTemplatesDir())
+//	constraints, err := helpers.LoadConstraints(helpers.ConstraintsDir(), vars)
+//	manifests, err := helpers.LoadManifests(paths...)
+//	results, err := helpers.Admit(templates, constraints, manifests)
+//
+// Each template's violation rule gets input.review (the AdmissionReview
+// request for a CREATE of the object) and input.parameters (the
+// constraint's spec.parameters). A constraint applies to an object when its
+// spec.match kinds, namespaces, excludedNamespaces and labelSelector match.
+//
+// Placeholders such as ${ACR_NAME} in the constraints are substituted by the
+// deploy scripts (scripts/validate-substitutions.sh); LoadConstraints expands
+// them from vars.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/rego"
+	"gopkg.in/yaml.v3"
+)
+
+// GatekeeperTarget is the admission target of the template Rego.
+const GatekeeperTarget = "admission.k8s.gatekeeper.sh"
+
+// ConstraintTemplatesDir returns policies/kubernetes/constraint-templates.
+func ConstraintTemplatesDir() string {
+	return filepath.Join(RepoRoot(), "policies", "kubernetes", "constraint-templates")
+}
+
+// ConstraintsDir returns policies/kubernetes/constraints.
+func ConstraintsDir() string {
+	return filepath.Join(RepoRoot(), "policies", "kubernetes", "constraints")
+}
+
+// Manifest is one Kubernetes object read from a YAML file.
+type Manifest struct {
+	// Source is "<file>#<document index>".
+	Source string
+	Object map[string]interface{}
+}
+
+// Kind returns the object kind.
+func (m Manifest) Kind() string {
+	kind, _ := m.Object["kind"].(string)
+	return kind
+}
+
+// Group returns the API group of apiVersion, "" for the core group.
+func (m Manifest) Group() string {
+	group, _ := m.groupVersion()
+	return group
+}
+
+func (m Manifest) groupVersion() (group, version string) {
+	apiVersion, _ := m.Object["apiVersion"].(string)
+	if group, version, ok := strings.Cut(apiVersion, "/"); ok {
+		return group, version
+	}
+	return "", apiVersion
+}
+
+func (m Manifest) metadata() map[string]interface{} {
+	metadata, _ := m.Object["metadata"].(map[string]interface{})
+	return metadata
+}
+
+// Name returns metadata.name.
+func (m Manifest) Name() string {
+	name, _ := m.metadata()["name"].(string)
+	return name
+}
+
+// Namespace returns metadata.namespace.
+func (m Manifest) Namespace() string {
+	namespace, _ := m.metadata()["namespace"].(string)
+	return namespace
+}
+
+// Labels returns metadata.labels.
+func (m Manifest) Labels() map[string]string {
+	labels := map[string]string{}
+	raw, _ := m.metadata()["labels"].(map[string]interface{})
+	for key, value := range raw {
+		labels[key] = fmt.Sprint(value)
+	}
+	return labels
+}
+
+// Annotation returns a metadata.annotations value.
+func (m Manifest) Annotation(key string) string {
+	annotations, _ := m.metadata()["annotations"].(map[string]interface{})
+	value, _ := annotations[key].(string)
+	return value
+}
+
+// String identifies the object in test output.
+func (m Manifest) String() string {
+	if namespace := m.Namespace(); namespace != "" {
+		return fmt.Sprintf("%s %s/%s (%s)", m.Kind(), namespace, m.Name(), m.Source)
+	}
+	return fmt.Sprintf("%s %s (%s)", m.Kind(), m.Name(), m.Source)
+}
+
+// Review returns the input.review document Gatekeeper builds for a CREATE
+// of the object.
+func (m Manifest) Review() map[string]interface{} {
+	group, version := m.groupVersion()
+	return map[string]interface{}{
+		"kind": map[string]interface{}{
+			"group":   group,
+			"version": version,
+			"kind":    m.Kind(),
+		},
+		"name":      m.Name(),
+		"namespace": m.Namespace(),
+		"operation": "CREATE",
+		"object":    m.Object,
+	}
+}
+
+// LoadManifests reads every YAML document of the given files. Empty
+// documents (comment-only or a trailing ---) are skipped.
+func LoadManifests(paths ...string) ([]Manifest, error) {
+	var manifests []Manifest
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for index := 0; ; index++ {
+			var object map[string]interface{}
+			err := decoder.Decode(&object)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s#%d: %w", path, index, err)
+			}
+			if len(object) == 0 {
+				continue
+			}
+
+			manifests = append(manifests, Manifest{
+				Source: fmt.Sprintf("%s#%d", filepath.Base(path), index),
+				Object: object,
+			})
+		}
+	}
+
+	return manifests, nil
+}
+
+// ConstraintTemplate is a parsed ConstraintTemplate with its prepared Rego.
+type ConstraintTemplate struct {
+	Name string
+	// Kind is the constraint kind the template defines.
+	Kind string
+	// Schema is spec.crd.spec.validation.openAPIV3Schema, the schema of
+	// the constraints' parameters.
+	Schema map[string]interface{}
+	Rego   string
+
+	query rego.PreparedEvalQuery
+}
+
+type constraintTemplateDocument struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		CRD struct {
+			Spec struct {
+				Names struct {
+					Kind string `yaml:"kind"`
+				} `yaml:"names"`
+				Validation struct {
+					OpenAPIV3Schema map[string]interface{} `yaml:"openAPIV3Schema"`
+				} `yaml:"validation"`
+			} `yaml:"spec"`
+		} `yaml:"crd"`
+		Targets []struct {
+			Target string `yaml:"target"`
+			Rego   string `yaml:"rego"`
+		} `yaml:"targets"`
+	} `yaml:"spec"`
+}
+
+// LoadConstraintTemplates reads every ConstraintTemplate in dir and
+// compiles its admission Rego. Templates are keyed by constraint kind.
+func LoadConstraintTemplates(dir string) (map[string]*ConstraintTemplate, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	templates := map[string]*ConstraintTemplate{}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc constraintTemplateDocument
+			err := decoder.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if doc.Kind != "ConstraintTemplate" {
+				continue
+			}
+
+			template, err := newConstraintTemplate(doc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if _, ok := templates[template.Kind]; ok {
+				return nil, fmt.Errorf("%s: constraint kind %s is defined twice", path, template.Kind)
+			}
+			templates[template.Kind] = template
+		}
+	}
+
+	return templates, nil
+}
+
+func newConstraintTemplate(doc constraintTemplateDocument) (*ConstraintTemplate, error) {
+	template := &ConstraintTemplate{
+		Name:   doc.Metadata.Name,
+		Kind:   doc.Spec.CRD.Spec.Names.Kind,
+		Schema: doc.Spec.CRD.Spec.Validation.OpenAPIV3Schema,
+	}
+
+	// Gatekeeper rejects a template whose name is not its lowercased kind
+	if template.Name != strings.ToLower(template.Kind) {
+		return nil, fmt.Errorf("template %s: metadata.name must be %q", template.Name, strings.ToLower(template.Kind))
+	}
+
+	for _, target := range doc.Spec.Targets {
+		if target.Target == GatekeeperTarget {
+			template.Rego = target.Rego
+		}
+	}
+	if template.Rego == "" {
+		return nil, fmt.Errorf("template %s: no %s target", template.Name, GatekeeperTarget)
+	}
+
+	module, err := regoPackage(template.Rego)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", template.Name, err)
+	}
+
+	template.query, err = rego.New(
+		rego.Query("data."+module+".violation"),
+		rego.Module(template.Name+".rego", template.Rego),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", template.Name, err)
+	}
+
+	return template, nil
+}
+
+// regoPackage returns the package name of a Rego module.
+func regoPackage(src string) (string, error) {
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(line, "package "); ok {
+			return strings.TrimSpace(name), nil
+		}
+	}
+	return "", errors.New("rego has no package clause")
+}
+
+// Violation is one result of a template's violation rule.
+type Violation struct {
+	Msg     string
+	Details interface{}
+}
+
+// Review evaluates the template's violation rule for an object with the
+// constraint's parameters.
+func (t *ConstraintTemplate) Review(constraint *Constraint, manifest Manifest) ([]Violation, error) {
+	parameters := constraint.Parameters
+	if parameters == nil {
+		parameters = map[string]interface{}{}
+	}
+
+	results, err := t.query.Eval(context.Background(), rego.EvalInput(map[string]interface{}{
+		"review":     manifest.Review(),
+		"parameters": parameters,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []Violation
+	for _, result := range results {
+		for _, expression := range result.Expressions {
+			values, _ := expression.Value.([]interface{})
+			for _, value := range values {
+				object, _ := value.(map[string]interface{})
+				msg, _ := object["msg"].(string)
+				violations = append(violations, Violation{Msg: msg, Details: object["details"]})
+			}
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Msg < violations[j].Msg })
+	return violations, nil
+}
+
+// Constraint is a parsed constraint.
+type Constraint struct {
+	Kind              string
+	Name              string
+	EnforcementAction string
+	Match             ConstraintMatch
+	Parameters        map[string]interface{}
+}
+
+// ConstraintMatch is spec.match of a constraint.
+type ConstraintMatch struct {
+	Kinds              []ConstraintKinds `yaml:"kinds"`
+	Namespaces         []string          `yaml:"namespaces"`
+	ExcludedNamespaces []string          `yaml:"excludedNamespaces"`
+	LabelSelector      *LabelSelector    `yaml:"labelSelector"`
+}
+
+// ConstraintKinds is one spec.match.kinds entry.
+type ConstraintKinds struct {
+	APIGroups []string `yaml:"apiGroups"`
+	Kinds     []string `yaml:"kinds"`
+}
+
+// LabelSelector supports matchLabels only.
+type LabelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type constraintDocument struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		EnforcementAction string                 `yaml:"enforcementAction"`
+		Match             ConstraintMatch        `yaml:"match"`
+		Parameters        map[string]interface{} `yaml:"parameters"`
+	} `yaml:"spec"`
+}
+
+// Rejects reports whether a violation blocks admission (as opposed to
+// dryrun or warn).
+func (c *Constraint) Rejects() bool {
+	return c.EnforcementAction == "" || c.EnforcementAction == "deny"
+}
+
+// placeholderPattern matches ${VAR}; a bare $ (as in regexes) is left alone.
+var placeholderPattern = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+// LoadConstraints reads every constraint in dir, expanding ${VAR}
+// placeholders from vars. An unknown placeholder is an error.
+func LoadConstraints(dir string, vars map[string]string) ([]*Constraint, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var constraints []*Constraint
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var missing []string
+		expanded := placeholderPattern.ReplaceAllStringFunc(string(data), func(placeholder string) string {
+			name := placeholder[2 : len(placeholder)-1]
+			value, ok := vars[name]
+			if !ok {
+				missing = append(missing, name)
+			}
+			return value
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("%s: no value for placeholders %s", path, strings.Join(missing, ", "))
+		}
+
+		decoder := yaml.NewDecoder(strings.NewReader(expanded))
+		for {
+			var doc constraintDocument
+			err := decoder.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if !strings.HasPrefix(doc.APIVersion, "constraints.gatekeeper.sh/") {
+				continue
+			}
+
+			constraints = append(constraints, &Constraint{
+				Kind:              doc.Kind,
+				Name:              doc.Metadata.Name,
+				EnforcementAction: doc.Spec.EnforcementAction,
+				Match:             doc.Spec.Match,
+				Parameters:        doc.Spec.Parameters,
+			})
+		}
+	}
+
+	return constraints, nil
+}
+
+// Matches reports whether the constraint applies to the object.
+func (c *Constraint) Matches(manifest Manifest) bool {
+	match := c.Match
+
+	if len(match.Kinds) > 0 {
+		matched := false
+		for _, kinds := range match.Kinds {
+			if matchesAny(kinds.APIGroups, manifest.Group()) && matchesAny(kinds.Kinds, manifest.Kind()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	// Namespace rules only apply to namespaced objects
+	if namespace := manifest.Namespace(); namespace != "" {
+		if len(match.Namespaces) > 0 && !matchesNamespace(match.Namespaces, namespace) {
+			return false
+		}
+		if matchesNamespace(match.ExcludedNamespaces, namespace) {
+			return false
+		}
+	}
+
+	if match.LabelSelector != nil {
+		labels := manifest.Labels()
+		for key, value := range match.LabelSelector.MatchLabels {
+			if labels[key] != value {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func matchesAny(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == "*" || candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNamespace supports Gatekeeper's trailing-* prefix patterns.
+func matchesNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(namespace, prefix) {
+				return true
+			}
+		} else if pattern == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckParameters validates the constraint's parameters against the
+// template's openAPIV3Schema: known properties, types and required keys.
+func (t *ConstraintTemplate) CheckParameters(constraint *Constraint) []string {
+	var problems []string
+	checkSchema("parameters", t.Schema, constraint.Parameters, &problems)
+	sort.Strings(problems)
+	return problems
+}
+
+func checkSchema(path string, schema map[string]interface{}, value interface{}, problems *[]string) {
+	if schema == nil || value == nil {
+		return
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: expected an object, got %T", path, value))
+			return
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, child := range object {
+			property, ok := properties[key].(map[string]interface{})
+			if !ok {
+				*problems = append(*problems, fmt.Sprintf("%s.%s: not in the template schema", path, key))
+				continue
+			}
+			checkSchema(path+"."+key, property, child, problems)
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, key := range required {
+			if _, ok := object[fmt.Sprint(key)]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s.%s: required", path, key))
+			}
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: expected an array, got %T", path, value))
+			return
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range list {
+			checkSchema(fmt.Sprintf("%s[%d]", path, i), items, item, problems)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: expected a string, got %T", path, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: expected a boolean, got %T", path, value))
+		}
+	}
+}
+
+// AdmissionResult is the outcome of one constraint for one object it
+// matches.
+type AdmissionResult struct {
+	Constraint *Constraint
+	Manifest   Manifest
+	Violations []Violation
+}
+
+// Rejected reports whether the object would be refused at admission.
+func (r AdmissionResult) Rejected() bool {
+	return len(r.Violations) > 0 && r.Constraint.Rejects()
+}
+
+// Admit reviews every manifest against every constraint that matches it.
+func Admit(templates map[string]*ConstraintTemplate, constraints []*Constraint, manifests []Manifest) ([]AdmissionResult, error) {
+	var results []AdmissionResult
+
+	for _, manifest := range manifests {
+		for _, constraint := range constraints {
+			if !constraint.Matches(manifest) {
+				continue
+			}
+
+			template, ok := templates[constraint.Kind]
+			if !ok {
+				return nil, fmt.Errorf("constraint %s: no ConstraintTemplate defines kind %s", constraint.Name, constraint.Kind)
+			}
+
+			violations, err := template.Review(constraint, manifest)
+			if err != nil {
+				return nil, fmt.Errorf("constraint %s on %s: %w", constraint.Name, manifest, err)
+			}
+
+			results = append(results, AdmissionResult{
+				Constraint: constraint,
+				Manifest:   manifest,
+				Violations: violations,
+			})
+		}
+	}
+
+	return results, nil
+}
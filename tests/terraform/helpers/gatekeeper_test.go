@@ -0,0 +1,224 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - GATEKEEPER ADMISSION EMULATION TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestConstraint|TestManifests' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gatekeeperTestManifest(apiVersion, kind, namespace string, labels map[string]interface{}) Manifest {
+	metadata := map[string]interface{}{"name": "test"}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	if labels != nil {
+		metadata["labels"] = labels
+	}
+	return Manifest{
+		Source: "test.yaml#0",
+		Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   metadata,
+		},
+	}
+}
+
+// TestConstraintMatches tests kind, namespace and label selector matching
+func TestConstraintMatches(t *testing.T) {
+	t.Parallel()
+
+	constraint := &Constraint{
+		Match: ConstraintMatch{
+			Kinds:              []ConstraintKinds{{APIGroups: []string{"apps"}, Kinds: []string{"Deployment"}}},
+			ExcludedNamespaces: []string{"kube-*", "gatekeeper-system"},
+			LabelSelector:      &LabelSelector{MatchLabels: map[string]string{"tier": "web"}},
+		},
+	}
+	web := map[string]interface{}{"tier": "web"}
+
+	testCases := []struct {
+		name     string
+		manifest Manifest
+		expected bool
+	}{
+		{"matching", gatekeeperTestManifest("apps/v1", "Deployment", "orders", web), true},
+		{"other_group", gatekeeperTestManifest("v1", "Deployment", "orders", web), false},
+		{"other_kind", gatekeeperTestManifest("apps/v1", "StatefulSet", "orders", web), false},
+		{"excluded_prefix", gatekeeperTestManifest("apps/v1", "Deployment", "kube-system", web), false},
+		{"excluded_exact", gatekeeperTestManifest("apps/v1", "Deployment", "gatekeeper-system", web), false},
+		{"missing_label", gatekeeperTestManifest("apps/v1", "Deployment", "orders", nil), false},
+		{"other_label", gatekeeperTestManifest("apps/v1", "Deployment", "orders", map[string]interface{}{"tier": "db"}), false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, constraint.Matches(tc.manifest))
+		})
+	}
+}
+
+// TestConstraintTemplateReview tests a template's violations and parameter
+// checks
+func TestConstraintTemplateReview(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "template.yaml"), []byte(`
+apiVersion: templates.gatekeeper.sh/v1
+kind: ConstraintTemplate
+metadata:
+  name: k8sforbiddenlabel
+spec:
+  crd:
+    spec:
+      names:
+        kind: K8sForbiddenLabel
+      validation:
+        openAPIV3Schema:
+          type: object
+          required: [label]
+          properties:
+            label:
+              type: string
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package k8sforbiddenlabel
+
+        violation[{"msg": msg}] {
+          input.review.object.metadata.labels[input.parameters.label]
+          msg := sprintf("label %v is forbidden", [input.parameters.label])
+        }
+`), 0o644))
+
+	templates, err := LoadConstraintTemplates(dir)
+	require.NoError(t, err)
+	template, ok := templates["K8sForbiddenLabel"]
+	require.True(t, ok)
+
+	constraint := &Constraint{Kind: "K8sForbiddenLabel", Name: "no-debug", Parameters: map[string]interface{}{"label": "debug"}}
+	assert.Empty(t, template.CheckParameters(constraint))
+	assert.Equal(t, []string{"parameters.label: required", "parameters.lable: not in the template schema"},
+		template.CheckParameters(&Constraint{Parameters: map[string]interface{}{"lable": "debug"}}))
+	assert.Equal(t, []string{"parameters.label: expected a string, got bool"},
+		template.CheckParameters(&Constraint{Parameters: map[string]interface{}{"label": true}}))
+
+	results, err := Admit(templates, []*Constraint{constraint}, []Manifest{
+		gatekeeperTestManifest("v1", "Pod", "orders", map[string]interface{}{"debug": "true"}),
+		gatekeeperTestManifest("v1", "Pod", "orders", nil),
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.True(t, results[0].Rejected())
+	assert.Equal(t, []Violation{{Msg: "label debug is forbidden"}}, results[0].Violations)
+	assert.False(t, results[1].Rejected())
+
+	constraint.EnforcementAction = "warn"
+	assert.False(t, results[0].Rejected(), "warn constraints do not reject")
+}
+
+// TestConstraintTemplateNameMismatch tests that a template name must be its
+// lowercased kind
+func TestConstraintTemplateNameMismatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "template.yaml"), []byte(`
+kind: ConstraintTemplate
+metadata:
+  name: forbidden-label
+spec:
+  crd:
+    spec:
+      names:
+        kind: K8sForbiddenLabel
+`), 0o644))
+
+	_, err := LoadConstraintTemplates(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `metadata.name must be "k8sforbiddenlabel"`)
+}
+
+// TestConstraintPlaceholders tests ${VAR} expansion in constraints
+func TestConstraintPlaceholders(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "constraint.yaml"), []byte(`
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: K8sAllowedRepos
+metadata:
+  name: allowed-repos
+spec:
+  enforcementAction: dryrun
+  parameters:
+    repos:
+      - "${ACR_NAME}.azurecr.io/"
+    pattern: "^v[0-9]+$"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: not-a-constraint
+`), 0o644))
+
+	constraints, err := LoadConstraints(dir, map[string]string{"ACR_NAME": "crtest"})
+	require.NoError(t, err)
+	require.Len(t, constraints, 1)
+	assert.Equal(t, "dryrun", constraints[0].EnforcementAction)
+	assert.False(t, constraints[0].Rejects())
+	assert.Equal(t, []interface{}{"crtest.azurecr.io/"}, constraints[0].Parameters["repos"])
+	assert.Equal(t, "^v[0-9]+$", constraints[0].Parameters["pattern"], "a bare $ is not a placeholder")
+
+	_, err = LoadConstraints(dir, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no value for placeholders ACR_NAME")
+}
+
+// TestManifestsMultiDocument tests document indexes and skipped empty
+// documents
+func TestManifestsMultiDocument(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "manifests.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+# leading comment
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: orders
+  namespace: apps
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: apps
+---
+`), 0o644))
+
+	manifests, err := LoadManifests(path)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	assert.Equal(t, "Deployment apps/orders (manifests.yaml#0)", manifests[0].String())
+	assert.Equal(t, "apps", manifests[0].Group())
+	assert.Equal(t, "Namespace apps (manifests.yaml#1)", manifests[1].String())
+	assert.Equal(t, "", manifests[1].Group())
+	assert.Equal(t, map[string]interface{}{"group": "", "version": "v1", "kind": "Namespace"}, manifests[1].Review()["kind"])
+}
@@ -0,0 +1,194 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - GOLDEN PLAN SNAPSHOTS
+// =============================================================================
+//
+// Compares a normalized JSON rendering of a plan with a committed snapshot
+// in testdata/golden/<module>/<scenario>.json:
+//
+//	plan := helpers.InitAndPlan(t, terraformOptions)
+//	plan.AssertGolden(t, helpers.ModuleDatabases, "postgresql")
+//
+// Rewrite snapshots after an intended module change with:
+//
+//	TERRATEST_MOCK_PROVIDERS=true go test -tags=unit ./modules -run TestX -update
+//
+// Snapshots are recorded with mock providers (see mock.go). A real provider
+// fills in server-side defaults that a mock plan leaves empty, so without
+// mock mode AssertGolden skips the test.
+//
+// Normalization keeps the review diff stable:
+//   - only managed resources, sorted by address
+//   - the top-level id and null attributes are dropped
+//   - known-after-apply values become "(known after apply)"
+//   - sensitive values become "(sensitive)"
+//   - RFC 3339 timestamps become "(timestamp)"
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// UpdateGolden rewrites snapshots instead of comparing them. TestMain sets it
+// from the -update flag.
+var UpdateGolden bool
+
+const (
+	knownAfterApply = "(known after apply)"
+	sensitiveValue  = "(sensitive)"
+	timestampValue  = "(timestamp)"
+)
+
+var timestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$`)
+
+// GoldenPath returns the snapshot file of a module scenario.
+func GoldenPath(module, scenario string) string {
+	return filepath.Join(TestDataDir(), "golden", module, scenario+".json")
+}
+
+type snapshot struct {
+	Resources []snapshotResource `json:"resources"`
+}
+
+type snapshotResource struct {
+	Address string         `json:"address"`
+	Actions tfjson.Actions `json:"actions"`
+	Values  interface{}    `json:"values"`
+}
+
+// Snapshot renders the normalized plan as indented JSON.
+func (p *Plan) Snapshot() ([]byte, error) {
+	snap := snapshot{Resources: []snapshotResource{}}
+
+	for _, change := range p.RawPlan.ResourceChanges {
+		if change.Mode != tfjson.ManagedResourceMode || change.Change == nil {
+			continue
+		}
+
+		values := normalizeValue(change.Change.After, change.Change.AfterUnknown, change.Change.AfterSensitive)
+		if object, ok := values.(map[string]interface{}); ok {
+			delete(object, "id")
+		}
+
+		snap.Resources = append(snap.Resources, snapshotResource{
+			Address: change.Address,
+			Actions: change.Change.Actions,
+			Values:  values,
+		})
+	}
+
+	sort.Slice(snap.Resources, func(i, j int) bool { return snap.Resources[i].Address < snap.Resources[j].Address })
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snap); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeValue walks a planned value together with its after_unknown and
+// after_sensitive trees.
+func normalizeValue(value, unknown, sensitive interface{}) interface{} {
+	if unknown == true {
+		return knownAfterApply
+	}
+	if sensitive == true {
+		return sensitiveValue
+	}
+
+	switch node := value.(type) {
+	case map[string]interface{}:
+		unknownMap, _ := unknown.(map[string]interface{})
+		sensitiveMap, _ := sensitive.(map[string]interface{})
+
+		normalized := map[string]interface{}{}
+		for key, child := range node {
+			if child == nil && unknownMap[key] != true {
+				continue
+			}
+			normalized[key] = normalizeValue(child, unknownMap[key], sensitiveMap[key])
+		}
+		// Attributes that are entirely unknown are absent from after
+		for key, childUnknown := range unknownMap {
+			if _, ok := normalized[key]; !ok && childUnknown == true {
+				normalized[key] = knownAfterApply
+			}
+		}
+		return normalized
+	case []interface{}:
+		unknownList, _ := unknown.([]interface{})
+		sensitiveList, _ := sensitive.([]interface{})
+
+		normalized := make([]interface{}, len(node))
+		for i, child := range node {
+			normalized[i] = normalizeValue(child, indexOrNil(unknownList, i), indexOrNil(sensitiveList, i))
+		}
+		return normalized
+	case string:
+		if timestampPattern.MatchString(node) {
+			return timestampValue
+		}
+	}
+
+	return value
+}
+
+func indexOrNil(values []interface{}, i int) interface{} {
+	if i < len(values) {
+		return values[i]
+	}
+	return nil
+}
+
+// AssertGolden compares the plan snapshot with
+// testdata/golden/<module>/<scenario>.json, or rewrites it with -update.
+// Outside mock mode it skips the test; call it after the other assertions.
+func (p *Plan) AssertGolden(t testing.TB, module, scenario string) {
+	t.Helper()
+
+	if !MockProvidersEnabled() {
+		t.Skipf("Golden plan %s/%s not compared: snapshots are recorded with %s=true", module, scenario, MockProvidersEnv)
+	}
+
+	actual, err := p.Snapshot()
+	require.NoError(t, err)
+
+	AssertGoldenFile(t, GoldenPath(module, scenario), actual)
+}
+
+// AssertGoldenFile compares actual with the contents of path, or writes
+// actual to path when UpdateGolden is set.
+func AssertGoldenFile(t testing.TB, path string, actual []byte) {
+	t.Helper()
+
+	if UpdateGolden {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, actual, 0o644))
+		t.Logf("Updated golden file %s", path)
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("Golden file %s does not exist; record it with -update", path)
+	}
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), string(actual),
+		"%s is out of date; if the change is intended, rerun with -update and review the diff", path)
+}
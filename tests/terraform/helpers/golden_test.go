@@ -0,0 +1,81 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - GOLDEN PLAN SNAPSHOT TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestPlanSnapshot|TestNormalizeValue' ./helpers/ [-update]
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+// TestPlanSnapshot tests the normalized rendering of the plan fixture
+func TestPlanSnapshot(t *testing.T) {
+	UpdateGolden = *update
+
+	plan := loadPlanFixture(t)
+
+	actual, err := plan.Snapshot()
+	require.NoError(t, err)
+
+	AssertGoldenFile(t, "testdata/golden/plan.json", actual)
+}
+
+// TestNormalizeValue tests volatile and unknown value handling
+func TestNormalizeValue(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		value     interface{}
+		unknown   interface{}
+		sensitive interface{}
+		expected  interface{}
+	}{
+		{
+			name:     "known_value",
+			value:    map[string]interface{}{"sku": "Premium", "zones": []interface{}{"1", "2"}},
+			expected: map[string]interface{}{"sku": "Premium", "zones": []interface{}{"1", "2"}},
+		},
+		{
+			name:     "nulls_dropped",
+			value:    map[string]interface{}{"sku": "Premium", "tags": nil},
+			expected: map[string]interface{}{"sku": "Premium"},
+		},
+		{
+			name:     "unknown_attribute",
+			value:    map[string]interface{}{"name": "acr", "login_server": nil},
+			unknown:  map[string]interface{}{"login_server": true, "identity": true},
+			expected: map[string]interface{}{"name": "acr", "login_server": knownAfterApply, "identity": knownAfterApply},
+		},
+		{
+			name:      "sensitive_nested",
+			value:     map[string]interface{}{"admin": []interface{}{map[string]interface{}{"user": "x", "password": "secret"}}},
+			sensitive: map[string]interface{}{"admin": []interface{}{map[string]interface{}{"password": true}}},
+			expected:  map[string]interface{}{"admin": []interface{}{map[string]interface{}{"user": "x", "password": sensitiveValue}}},
+		},
+		{
+			name:     "timestamps",
+			value:    map[string]interface{}{"start": "2026-01-01T00:00:00Z", "end": "2027-06-30T12:00:00.5+03:00", "date": "2026-01-01"},
+			expected: map[string]interface{}{"start": timestampValue, "end": timestampValue, "date": "2026-01-01"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, normalizeValue(tc.value, tc.unknown, tc.sensitive))
+		})
+	}
+}
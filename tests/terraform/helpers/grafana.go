@@ -0,0 +1,552 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - GRAFANA DASHBOARD CHECKS
+// =============================================================================
+//
+// Reads the dashboards in grafana/dashboards and checks what breaks a panel
+// after a deploy:
+//
+//	dashboard, err := helpers.LoadDashboard(path)
+//	problems := dashboard.Check()
+//	problems = append(problems, dashboard.CheckDatasources(datasources)...)
+//	for _, query := range dashboard.Queries() { metrics, err := query.Metrics() }
+//
+// Datasources are the ones kube-prometheus-stack provisions from
+// deploy/helm/monitoring/values.yaml (LoadProvisionedDatasources). A
+// reference is either a provisioned uid or a datasource variable such as
+// ${datasource}, whose plugin type must be provisioned.
+//
+// PromQL targets, annotations and query variables are parsed after Grafana's
+// variables are replaced: $__rate_interval and friends by a duration, other
+// variables by their name.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// Dashboards must be at least MinDashboardSchemaVersion, where Grafana
+// stores every datasource reference as {type, uid}, and at most
+// MaxDashboardSchemaVersion, the newest the Grafana 10 release of
+// kube-prometheus-stack 55.x loads.
+const (
+	MinDashboardSchemaVersion = 36
+	MaxDashboardSchemaVersion = 38
+)
+
+// Built-in datasource uids that are never provisioned.
+var builtinDatasourceUIDs = []string{"-- Grafana --", "-- Mixed --", "-- Dashboard --"}
+
+// grafanaVariablePattern matches $var, ${var}, ${var:format} and [[var]].
+var grafanaVariablePattern = regexp.MustCompile(`\$\{(\w+)(?::[^}]*)?\}|\$(\w+)|\[\[(\w+)\]\]`)
+
+// labelValuesPattern matches the label_values(selector, label) variable query.
+var labelValuesPattern = regexp.MustCompile(`^\s*label_values\((.+),\s*\w+\s*\)\s*$`)
+
+// GrafanaDashboardsDir returns the absolute path of grafana/dashboards.
+func GrafanaDashboardsDir() string {
+	return filepath.Join(RepoRoot(), "grafana", "dashboards")
+}
+
+// MonitoringValuesPath returns the kube-prometheus-stack values file.
+func MonitoringValuesPath() string {
+	return filepath.Join(RepoRoot(), "deploy", "helm", "monitoring", "values.yaml")
+}
+
+// ExporterMetricsPath returns the allowlist of metrics dashboards may query
+// without a recording rule.
+func ExporterMetricsPath() string {
+	return filepath.Join(TestDataDir(), "grafana", "exporter-metrics.yaml")
+}
+
+// DatasourceRef is a panel, target, annotation or variable datasource.
+type DatasourceRef struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+	// Legacy is set when the reference is a plain datasource name, the
+	// format before schema version 33.
+	Legacy string `json:"-"`
+}
+
+// UnmarshalJSON accepts both {type, uid} and a legacy name.
+func (r *DatasourceRef) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &r.Legacy)
+	}
+	type plain DatasourceRef
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// Variable returns the variable name when the uid is a variable reference.
+func (r *DatasourceRef) Variable() (string, bool) {
+	match := grafanaVariablePattern.FindStringSubmatch(r.UID)
+	if match == nil || match[0] != r.UID {
+		return "", false
+	}
+	return match[1] + match[2] + match[3], true
+}
+
+// Dashboard is the part of a dashboard JSON model the checks read.
+type Dashboard struct {
+	UID           string   `json:"uid"`
+	Title         string   `json:"title"`
+	SchemaVersion int      `json:"schemaVersion"`
+	Panels        []*Panel `json:"panels"`
+	Templating    struct {
+		List []*TemplateVariable `json:"list"`
+	} `json:"templating"`
+	Annotations struct {
+		List []*DashboardAnnotation `json:"list"`
+	} `json:"annotations"`
+
+	// Source is the file name.
+	Source string `json:"-"`
+}
+
+// Panel is a dashboard panel; rows hold their collapsed panels.
+type Panel struct {
+	ID         int            `json:"id"`
+	Type       string         `json:"type"`
+	Title      string         `json:"title"`
+	Datasource *DatasourceRef `json:"datasource"`
+	Targets    []*PanelTarget `json:"targets"`
+	Panels     []*Panel       `json:"panels"`
+}
+
+// PanelTarget is one query of a panel.
+type PanelTarget struct {
+	RefID      string         `json:"refId"`
+	Datasource *DatasourceRef `json:"datasource"`
+	Expr       string         `json:"expr"`
+}
+
+// TemplateVariable is a dashboard variable.
+type TemplateVariable struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Datasource *DatasourceRef `json:"datasource"`
+	// Query is a plugin type for datasource variables, and a string or
+	// {"query": ...} for query variables.
+	Query interface{} `json:"query"`
+}
+
+// QueryString returns the variable query as a string.
+func (v *TemplateVariable) QueryString() string {
+	switch query := v.Query.(type) {
+	case string:
+		return query
+	case map[string]interface{}:
+		value, _ := query["query"].(string)
+		return value
+	}
+	return ""
+}
+
+// DashboardAnnotation is an annotation query.
+type DashboardAnnotation struct {
+	Name       string         `json:"name"`
+	BuiltIn    int            `json:"builtIn"`
+	Datasource *DatasourceRef `json:"datasource"`
+	Expr       string         `json:"expr"`
+}
+
+// LoadDashboard reads a dashboard JSON file.
+func LoadDashboard(path string) (*Dashboard, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dashboard Dashboard
+	if err := json.Unmarshal(data, &dashboard); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dashboard.Source = filepath.Base(path)
+	return &dashboard, nil
+}
+
+// LoadDashboards reads every dashboard in dir.
+func LoadDashboards(dir string) ([]*Dashboard, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	dashboards := make([]*Dashboard, 0, len(paths))
+	for _, path := range paths {
+		dashboard, err := LoadDashboard(path)
+		if err != nil {
+			return nil, err
+		}
+		dashboards = append(dashboards, dashboard)
+	}
+	return dashboards, nil
+}
+
+// AllPanels returns every panel, including those nested in rows.
+func (d *Dashboard) AllPanels() []*Panel {
+	var panels []*Panel
+	var walk func([]*Panel)
+	walk = func(list []*Panel) {
+		for _, panel := range list {
+			panels = append(panels, panel)
+			walk(panel.Panels)
+		}
+	}
+	walk(d.Panels)
+	return panels
+}
+
+// Variable returns the dashboard variable of that name, or nil.
+func (d *Dashboard) Variable(name string) *TemplateVariable {
+	for _, variable := range d.Templating.List {
+		if variable.Name == name {
+			return variable
+		}
+	}
+	return nil
+}
+
+// Check returns the structural problems of the dashboard: schema version,
+// uid and title, and panel ids.
+func (d *Dashboard) Check() []string {
+	var problems []string
+
+	if d.SchemaVersion < MinDashboardSchemaVersion || d.SchemaVersion > MaxDashboardSchemaVersion {
+		problems = append(problems, fmt.Sprintf("schemaVersion %d is outside %d-%d",
+			d.SchemaVersion, MinDashboardSchemaVersion, MaxDashboardSchemaVersion))
+	}
+	if d.UID == "" {
+		problems = append(problems, "no uid")
+	}
+	if d.Title == "" {
+		problems = append(problems, "no title")
+	}
+
+	ids := map[int]string{}
+	for _, panel := range d.AllPanels() {
+		if panel.ID == 0 {
+			problems = append(problems, fmt.Sprintf("panel %q has no id", panel.Title))
+			continue
+		}
+		if first, ok := ids[panel.ID]; ok {
+			problems = append(problems, fmt.Sprintf("panel %q reuses id %d of panel %q", panel.Title, panel.ID, first))
+			continue
+		}
+		ids[panel.ID] = panel.Title
+	}
+
+	return problems
+}
+
+// DashboardQuery is one query of a dashboard with its effective datasource.
+type DashboardQuery struct {
+	// Location names the panel target, annotation or variable.
+	Location   string
+	Datasource *DatasourceRef
+	Expr       string
+}
+
+// Queries returns the panel targets, annotations and query variables, each
+// with the datasource it runs against (a target inherits its panel's).
+func (d *Dashboard) Queries() []DashboardQuery {
+	var queries []DashboardQuery
+
+	for _, panel := range d.AllPanels() {
+		for _, target := range panel.Targets {
+			datasource := target.Datasource
+			if datasource == nil {
+				datasource = panel.Datasource
+			}
+			queries = append(queries, DashboardQuery{
+				Location:   fmt.Sprintf("panel %d %q target %s", panel.ID, panel.Title, target.RefID),
+				Datasource: datasource,
+				Expr:       target.Expr,
+			})
+		}
+	}
+
+	for _, annotation := range d.Annotations.List {
+		if annotation.BuiltIn != 0 {
+			continue
+		}
+		queries = append(queries, DashboardQuery{
+			Location:   fmt.Sprintf("annotation %q", annotation.Name),
+			Datasource: annotation.Datasource,
+			Expr:       annotation.Expr,
+		})
+	}
+
+	for _, variable := range d.Templating.List {
+		if variable.Type != "query" {
+			continue
+		}
+		queries = append(queries, DashboardQuery{
+			Location:   fmt.Sprintf("variable %q", variable.Name),
+			Datasource: variable.Datasource,
+			Expr:       variable.QueryString(),
+		})
+	}
+
+	return queries
+}
+
+// ProvisionedDatasource is a datasource Grafana is deployed with.
+type ProvisionedDatasource struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	UID  string `yaml:"uid"`
+}
+
+type monitoringValues struct {
+	Grafana struct {
+		Sidecar struct {
+			Datasources struct {
+				DefaultDatasourceEnabled *bool  `yaml:"defaultDatasourceEnabled"`
+				UID                      string `yaml:"uid"`
+				Alertmanager             struct {
+					Enabled *bool  `yaml:"enabled"`
+					UID     string `yaml:"uid"`
+				} `yaml:"alertmanager"`
+			} `yaml:"datasources"`
+		} `yaml:"sidecar"`
+		AdditionalDataSources []ProvisionedDatasource `yaml:"additionalDataSources"`
+	} `yaml:"grafana"`
+}
+
+// LoadProvisionedDatasources returns the datasources kube-prometheus-stack
+// provisions with the given values: the default Prometheus and Alertmanager
+// datasources (chart defaults apply when a key is not set) and
+// grafana.additionalDataSources.
+func LoadProvisionedDatasources(valuesPath string) ([]ProvisionedDatasource, error) {
+	data, err := os.ReadFile(valuesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var values monitoringValues
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", valuesPath, err)
+	}
+
+	sidecar := values.Grafana.Sidecar.Datasources
+	var datasources []ProvisionedDatasource
+
+	if sidecar.DefaultDatasourceEnabled == nil || *sidecar.DefaultDatasourceEnabled {
+		datasources = append(datasources, ProvisionedDatasource{
+			Name: "Prometheus",
+			Type: "prometheus",
+			UID:  valueOr(sidecar.UID, "prometheus"),
+		})
+	}
+	if sidecar.Alertmanager.Enabled == nil || *sidecar.Alertmanager.Enabled {
+		datasources = append(datasources, ProvisionedDatasource{
+			Name: "Alertmanager",
+			Type: "alertmanager",
+			UID:  valueOr(sidecar.Alertmanager.UID, "alertmanager"),
+		})
+	}
+	datasources = append(datasources, values.Grafana.AdditionalDataSources...)
+
+	return datasources, nil
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// CheckDatasources returns the datasource references of the dashboard that
+// do not resolve to a provisioned datasource.
+func (d *Dashboard) CheckDatasources(provisioned []ProvisionedDatasource) []string {
+	var problems []string
+
+	check := func(location string, ref *DatasourceRef) {
+		if ref == nil {
+			return
+		}
+		if ref.Legacy != "" {
+			problems = append(problems, fmt.Sprintf("%s: datasource %q is a legacy name, use {type, uid}", location, ref.Legacy))
+			return
+		}
+		if containsString(builtinDatasourceUIDs, ref.UID) {
+			return
+		}
+
+		if name, ok := ref.Variable(); ok {
+			variable := d.Variable(name)
+			if variable == nil || variable.Type != "datasource" {
+				problems = append(problems, fmt.Sprintf("%s: datasource variable $%s is not defined", location, name))
+				return
+			}
+			pluginType := variable.QueryString()
+			if ref.Type != "" && ref.Type != pluginType {
+				problems = append(problems, fmt.Sprintf("%s: datasource type %s, but $%s selects %s", location, ref.Type, name, pluginType))
+			}
+			for _, datasource := range provisioned {
+				if datasource.Type == pluginType {
+					return
+				}
+			}
+			problems = append(problems, fmt.Sprintf("%s: no %s datasource is provisioned for $%s", location, pluginType, name))
+			return
+		}
+
+		for _, datasource := range provisioned {
+			if datasource.UID == ref.UID {
+				if ref.Type != "" && ref.Type != datasource.Type {
+					problems = append(problems, fmt.Sprintf("%s: datasource %s is type %s, not %s", location, ref.UID, datasource.Type, ref.Type))
+				}
+				return
+			}
+		}
+		problems = append(problems, fmt.Sprintf("%s: datasource uid %q is not provisioned", location, ref.UID))
+	}
+
+	for _, panel := range d.AllPanels() {
+		location := fmt.Sprintf("panel %d %q", panel.ID, panel.Title)
+		check(location, panel.Datasource)
+		for _, target := range panel.Targets {
+			check(location+" target "+target.RefID, target.Datasource)
+		}
+	}
+	for _, annotation := range d.Annotations.List {
+		check(fmt.Sprintf("annotation %q", annotation.Name), annotation.Datasource)
+	}
+	for _, variable := range d.Templating.List {
+		check(fmt.Sprintf("variable %q", variable.Name), variable.Datasource)
+	}
+
+	return problems
+}
+
+// IsPrometheus reports whether the query runs against Prometheus, directly
+// or through a datasource variable.
+func (q DashboardQuery) IsPrometheus(dashboard *Dashboard) bool {
+	if q.Datasource == nil || q.Datasource.Legacy != "" {
+		return false
+	}
+	if q.Datasource.Type != "" {
+		return q.Datasource.Type == "prometheus"
+	}
+	if name, ok := q.Datasource.Variable(); ok {
+		if variable := dashboard.Variable(name); variable != nil {
+			return variable.QueryString() == "prometheus"
+		}
+	}
+	return false
+}
+
+// PromQL returns the query as parseable PromQL: the selector of a
+// label_values() variable query, with Grafana variables replaced.
+func (q DashboardQuery) PromQL() string {
+	expr := q.Expr
+	if match := labelValuesPattern.FindStringSubmatch(expr); match != nil {
+		expr = match[1]
+	}
+	return ExpandGrafanaVariables(expr)
+}
+
+// ExpandGrafanaVariables replaces Grafana's interval variables with a
+// duration and every other variable with its name.
+func ExpandGrafanaVariables(expr string) string {
+	return grafanaVariablePattern.ReplaceAllStringFunc(expr, func(reference string) string {
+		match := grafanaVariablePattern.FindStringSubmatch(reference)
+		name := match[1] + match[2] + match[3]
+		switch {
+		case strings.HasSuffix(name, "_ms"):
+			return "300000"
+		case strings.HasSuffix(name, "_s"):
+			return "300"
+		case strings.HasPrefix(name, "__"):
+			return "5m"
+		}
+		return name
+	})
+}
+
+// Metrics parses the query and returns the metric names it selects, sorted.
+func (q DashboardQuery) Metrics() ([]string, error) {
+	expr, err := parser.ParseExpr(q.PromQL())
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var unnamed []string
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		selector, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		name := selector.Name
+		for _, matcher := range selector.LabelMatchers {
+			if matcher.Name == labels.MetricName && matcher.Type == labels.MatchEqual {
+				name = matcher.Value
+			}
+		}
+		if name == "" {
+			unnamed = append(unnamed, selector.String())
+			return nil
+		}
+		seen[name] = true
+		return nil
+	})
+	if len(unnamed) > 0 {
+		return nil, fmt.Errorf("selectors without a metric name: %s", strings.Join(unnamed, ", "))
+	}
+
+	metrics := make([]string, 0, len(seen))
+	for name := range seen {
+		metrics = append(metrics, name)
+	}
+	sort.Strings(metrics)
+	return metrics, nil
+}
+
+// ExporterMetrics is the allowlist of metrics queried without a recording
+// rule, by exporter.
+type ExporterMetrics struct {
+	Exporters map[string][]string `yaml:"exporters"`
+}
+
+// LoadExporterMetrics reads the exporter metric allowlist.
+func LoadExporterMetrics(path string) (*ExporterMetrics, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowlist ExporterMetrics
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&allowlist); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &allowlist, nil
+}
+
+// Exporter returns the exporter that provides the metric, or "".
+func (e *ExporterMetrics) Exporter(metric string) string {
+	for exporter, metrics := range e.Exporters {
+		if containsString(metrics, metric) {
+			return exporter
+		}
+	}
+	return ""
+}
@@ -0,0 +1,200 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - GRAFANA DASHBOARD CHECK TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestDashboard|TestGrafana|TestProvisioned' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDashboard = `{
+  "uid": "test",
+  "title": "Test",
+  "schemaVersion": 38,
+  "annotations": {
+    "list": [
+      {"builtIn": 1, "datasource": {"type": "grafana", "uid": "-- Grafana --"}, "name": "Annotations & Alerts"},
+      {"datasource": {"type": "prometheus", "uid": "${datasource}"}, "name": "Alerts", "expr": "ALERTS{alertstate=\"firing\"}"}
+    ]
+  },
+  "templating": {
+    "list": [
+      {"name": "datasource", "type": "datasource", "query": "prometheus"},
+      {"name": "namespace", "type": "query", "datasource": {"type": "prometheus", "uid": "${datasource}"},
+       "query": {"query": "label_values(kube_namespace_labels, namespace)", "refId": "A"}}
+    ]
+  },
+  "panels": [
+    {"id": 100, "type": "row", "title": "Row", "panels": [
+      {"id": 1, "type": "stat", "title": "Requests", "datasource": {"type": "prometheus", "uid": "${datasource}"},
+       "targets": [{"refId": "A", "expr": "sum(rate(http_requests_total{namespace=\"$namespace\"}[$__rate_interval]))"}]}
+    ]},
+    {"id": 1, "type": "stat", "title": "Legacy", "datasource": "Prometheus",
+     "targets": [{"refId": "A", "expr": "up"}]},
+    {"id": 2, "type": "stat", "title": "Loki", "datasource": {"type": "loki", "uid": "loki"},
+     "targets": [{"refId": "A", "datasource": {"type": "prometheus", "uid": "${metrics}"}, "expr": "up"}]},
+    {"id": 3, "type": "stat", "title": "Recorded", "datasource": {"type": "prometheus", "uid": "prometheus"},
+     "targets": [{"refId": "A", "expr": "sum(namespace:cost:total_monthly_estimate)"}]}
+  ]
+}`
+
+func loadTestDashboard(t *testing.T) *Dashboard {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.json")
+	require.NoError(t, os.WriteFile(path, []byte(testDashboard), 0o644))
+	dashboard, err := LoadDashboard(path)
+	require.NoError(t, err)
+	return dashboard
+}
+
+// TestDashboardCheck tests schema version and panel id checks
+func TestDashboardCheck(t *testing.T) {
+	t.Parallel()
+
+	dashboard := loadTestDashboard(t)
+	assert.Len(t, dashboard.AllPanels(), 5, "row panels are included")
+	assert.Equal(t, []string{`panel "Legacy" reuses id 1 of panel "Requests"`}, dashboard.Check())
+
+	dashboard.SchemaVersion = 39
+	assert.Contains(t, dashboard.Check(), "schemaVersion 39 is outside 36-38")
+}
+
+// TestDashboardCheckDatasources tests datasource resolution
+func TestDashboardCheckDatasources(t *testing.T) {
+	t.Parallel()
+
+	dashboard := loadTestDashboard(t)
+	problems := dashboard.CheckDatasources([]ProvisionedDatasource{
+		{Name: "Prometheus", Type: "prometheus", UID: "prometheus"},
+	})
+
+	assert.Equal(t, []string{
+		`panel 1 "Legacy": datasource "Prometheus" is a legacy name, use {type, uid}`,
+		`panel 2 "Loki": datasource uid "loki" is not provisioned`,
+		`panel 2 "Loki" target A: datasource variable $metrics is not defined`,
+	}, problems)
+}
+
+// TestDashboardQueries tests query collection, variable expansion and
+// metric extraction
+func TestDashboardQueries(t *testing.T) {
+	t.Parallel()
+
+	dashboard := loadTestDashboard(t)
+
+	metrics := map[string][]string{}
+	prometheus := map[string]bool{}
+	for _, query := range dashboard.Queries() {
+		prometheus[query.Location] = query.IsPrometheus(dashboard)
+		if names, err := query.Metrics(); err == nil {
+			metrics[query.Location] = names
+		}
+	}
+
+	assert.Equal(t, map[string][]string{
+		`panel 1 "Requests" target A`: {"http_requests_total"},
+		`panel 1 "Legacy" target A`:   {"up"},
+		`panel 2 "Loki" target A`:     {"up"},
+		`panel 3 "Recorded" target A`: {"namespace:cost:total_monthly_estimate"},
+		`annotation "Alerts"`:         {"ALERTS"},
+		`variable "namespace"`:        {"kube_namespace_labels"},
+	}, metrics)
+
+	assert.True(t, prometheus[`panel 1 "Requests" target A`], "through $datasource")
+	assert.False(t, prometheus[`panel 1 "Legacy" target A`], "legacy references are not resolved")
+	assert.True(t, prometheus[`panel 3 "Recorded" target A`])
+}
+
+// TestGrafanaVariableExpansion tests the replacement of Grafana variables
+func TestGrafanaVariableExpansion(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		`rate(x{ns="namespace", pod=~"app.*", c="cluster"}[5m]) + y[5m] * 300`,
+		ExpandGrafanaVariables(`rate(x{ns="$namespace", pod=~"${app}.*", c="[[cluster]]"}[$__rate_interval]) + y[${__range}] * $__range_s`))
+
+	_, err := DashboardQuery{Expr: `{job="api"}`}.Metrics()
+	assert.ErrorContains(t, err, "selectors without a metric name")
+
+	names, err := DashboardQuery{Expr: `{__name__="up", job="api"}`}.Metrics()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up"}, names)
+}
+
+// TestDatasourceRefLegacy tests decoding both datasource reference forms
+func TestDatasourceRefLegacy(t *testing.T) {
+	t.Parallel()
+
+	var refs []*DatasourceRef
+	require.NoError(t, json.Unmarshal([]byte(`["Prometheus", {"type": "prometheus", "uid": "${datasource}"}]`), &refs))
+
+	assert.Equal(t, "Prometheus", refs[0].Legacy)
+	name, ok := refs[1].Variable()
+	assert.True(t, ok)
+	assert.Equal(t, "datasource", name)
+}
+
+// TestProvisionedDatasources tests chart defaults and additional datasources
+func TestProvisionedDatasources(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		values   string
+		expected []ProvisionedDatasource
+	}{
+		{
+			name:   "chart_defaults",
+			values: "grafana:\n  enabled: true\n",
+			expected: []ProvisionedDatasource{
+				{Name: "Prometheus", Type: "prometheus", UID: "prometheus"},
+				{Name: "Alertmanager", Type: "alertmanager", UID: "alertmanager"},
+			},
+		},
+		{
+			name: "overridden",
+			values: `
+grafana:
+  sidecar:
+    datasources:
+      uid: prom
+      alertmanager:
+        enabled: false
+  additionalDataSources:
+    - name: Loki
+      type: loki
+      uid: loki
+`,
+			expected: []ProvisionedDatasource{
+				{Name: "Prometheus", Type: "prometheus", UID: "prom"},
+				{Name: "Loki", Type: "loki", UID: "loki"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "values.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.values), 0o644))
+
+			datasources, err := LoadProvisionedDatasources(path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, datasources)
+		})
+	}
+}
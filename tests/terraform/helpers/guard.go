@@ -0,0 +1,125 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - APPLY/DESTROY GUARD
+// =============================================================================
+//
+// Keeps unit runs from creating or destroying real resources.
+//
+// EnforceTier is called once from TestMain. Unless the suite was built with
+// -tags=integration AND the ARM_* credentials are set, it puts wrapper
+// scripts named terraform and tofu first on PATH. The wrappers pass every
+// command through to the real binary except apply and destroy, which fail
+// with the reason. Terratest resolves the binary through PATH, so this
+// holds for terraform.Apply, InitAndApply and Destroy in any test.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// IntegrationCredentials are the variables the integration tier needs to
+// authenticate against Azure.
+var IntegrationCredentials = []string{"ARM_SUBSCRIPTION_ID", "ARM_TENANT_ID", "ARM_CLIENT_ID"}
+
+// guardedCommands are the Terraform subcommands refused outside the
+// integration tier.
+var guardedCommands = []string{"apply", "destroy"}
+
+// MissingCredentials returns the IntegrationCredentials that are not set.
+func MissingCredentials() []string {
+	var missing []string
+	for _, name := range IntegrationCredentials {
+		if os.Getenv(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// EnforceTier installs the apply/destroy guard unless this is an integration
+// build with credentials. It returns a description of the active mode for
+// the test log and a cleanup to run after m.Run.
+func EnforceTier(integration bool) (string, func(), error) {
+	missing := MissingCredentials()
+
+	if integration && len(missing) == 0 {
+		return "integration tier: apply and destroy allowed", func() {}, nil
+	}
+
+	reason := "built without -tags=integration"
+	if integration {
+		reason = strings.Join(missing, ", ") + " not set"
+	}
+
+	cleanup, err := GuardTerraform(reason)
+	if err != nil {
+		return "", nil, err
+	}
+	return "apply and destroy disabled: " + reason, cleanup, nil
+}
+
+// GuardTerraform shadows the terraform and tofu binaries on PATH with
+// wrappers that refuse apply and destroy. The returned cleanup restores PATH.
+func GuardTerraform(reason string) (func(), error) {
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("the apply/destroy guard needs a POSIX shell; run unit tests on Linux or macOS")
+	}
+
+	dir, err := os.MkdirTemp("", "terratest-guard-")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{"terraform", "tofu"} {
+		real, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(guardScript(name, real, reason)), 0o755); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	}
+
+	previousPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+previousPath)
+
+	return func() {
+		os.Setenv("PATH", previousPath)
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// guardScript renders the wrapper for one binary. Global flags such as
+// -chdir may precede the subcommand, so the first non-flag argument is the
+// one checked.
+func guardScript(name, real, reason string) string {
+	var b strings.Builder
+
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Generated by the test harness (helpers/guard.go). Do not edit.\n")
+	b.WriteString("for arg in \"$@\"; do\n")
+	b.WriteString("  case \"$arg\" in\n")
+	b.WriteString("    -*) ;;\n")
+	fmt.Fprintf(&b, "    %s)\n", strings.Join(guardedCommands, "|"))
+	fmt.Fprintf(&b, "      echo \"refusing to run %s $arg:\" %s >&2\n", name, shellQuote(reason+" (see tests/terraform/README.md, Test Types)"))
+	b.WriteString("      exit 1 ;;\n")
+	b.WriteString("    *) break ;;\n")
+	b.WriteString("  esac\n")
+	b.WriteString("done\n")
+	fmt.Fprintf(&b, "exec %s \"$@\"\n", shellQuote(real))
+
+	return b.String()
+}
+
+// shellQuote single-quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
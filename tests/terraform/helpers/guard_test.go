@@ -0,0 +1,107 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - APPLY/DESTROY GUARD TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestGuard|TestEnforceTier' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTerraformOnPath puts a terraform stub that echoes its arguments first on
+// PATH for the duration of the test.
+func fakeTerraformOnPath(t *testing.T) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the guard needs a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	stub := "#!/bin/sh\necho \"real terraform $*\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform"), []byte(stub), 0o755))
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// TestGuardTerraform tests that apply and destroy are refused and everything
+// else reaches the real binary
+func TestGuardTerraform(t *testing.T) {
+	fakeTerraformOnPath(t)
+	pathBefore := os.Getenv("PATH")
+
+	release, err := GuardTerraform("built without -tags=integration")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		args    []string
+		refused bool
+	}{
+		{"plan", []string{"plan", "-input=false"}, false},
+		{"init", []string{"init", "-upgrade=false"}, false},
+		{"output", []string{"output", "-json", "destroy"}, false},
+		{"apply", []string{"apply", "-input=false", "-auto-approve"}, true},
+		{"destroy", []string{"destroy", "-auto-approve"}, true},
+		{"chdir_apply", []string{"-chdir=/tmp", "apply"}, true},
+	}
+
+	for _, tc := range testCases {
+		output, err := exec.Command("terraform", tc.args...).CombinedOutput()
+
+		if tc.refused {
+			assert.Error(t, err, tc.name)
+			assert.Contains(t, string(output), "refusing to run terraform", tc.name)
+			assert.Contains(t, string(output), "built without -tags=integration", tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+			assert.Contains(t, string(output), "real terraform "+tc.args[0], tc.name)
+		}
+	}
+
+	release()
+	assert.Equal(t, pathBefore, os.Getenv("PATH"))
+}
+
+// TestEnforceTier tests when the guard is installed
+func TestEnforceTier(t *testing.T) {
+	fakeTerraformOnPath(t)
+	for _, name := range IntegrationCredentials {
+		t.Setenv(name, "")
+	}
+
+	mode, release, err := EnforceTier(true)
+	require.NoError(t, err)
+	assert.Contains(t, mode, "ARM_SUBSCRIPTION_ID, ARM_TENANT_ID, ARM_CLIENT_ID not set")
+	_, err = exec.Command("terraform", "apply").CombinedOutput()
+	assert.Error(t, err)
+	release()
+
+	for _, name := range IntegrationCredentials {
+		t.Setenv(name, "00000000-0000-0000-0000-000000000000")
+	}
+
+	mode, release, err = EnforceTier(false)
+	require.NoError(t, err)
+	assert.Contains(t, mode, "built without -tags=integration")
+	release()
+
+	mode, release, err = EnforceTier(true)
+	require.NoError(t, err)
+	assert.Contains(t, mode, "apply and destroy allowed")
+	output, err := exec.Command("terraform", "apply").CombinedOutput()
+	assert.NoError(t, err)
+	assert.Contains(t, string(output), "real terraform apply")
+	release()
+}
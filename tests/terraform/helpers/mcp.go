@@ -0,0 +1,387 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - MCP SERVER CONFIGURATION CHECKS
+// =============================================================================
+//
+// Validates mcp-servers/mcp-config.json, the MCP servers agents start:
+//
+//	config, err := helpers.LoadMCPConfig(helpers.MCPConfigPath(), helpers.MCPConfigSchemaPath())
+//	problems := config.CheckEnv(documented)
+//	problems = append(problems, config.CheckCapabilities(families)...)
+//
+// LoadMCPConfig validates the file against mcp-servers/mcp-config.schema.json
+// and rejects a server name declared twice, which encoding/json (and the
+// MCP clients) would silently collapse to the last one.
+//
+// Every ${VAR} in a server's env or args must be documented in
+// mcp-servers/USAGE.md or set by scripts/bootstrap.sh; an agent otherwise
+// starts the server with an empty value. Every capability must be a CLI
+// command family listed in testdata/mcp/command-families.yaml.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// envReferencePattern matches ${...}; the group is checked to be a
+	// variable name so a typo such as ${ GITHUB_TOKEN} is reported.
+	envReferencePattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+	envNamePattern      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// usageEnvPattern matches "VAR" or "export VAR=..." lines in the bash
+	// blocks of USAGE.md.
+	usageEnvPattern = regexp.MustCompile(`^\s*(?:export\s+)?([A-Z_][A-Z0-9_]*)\s*(?:=|$|#)`)
+
+	// scriptEnvPattern matches a top-level assignment or export in a
+	// shell script. Assignments inside functions are not visible to the
+	// MCP servers an agent starts afterwards.
+	scriptEnvPattern = regexp.MustCompile(`^(?:export\s+)?([A-Z_][A-Z0-9_]*)=`)
+)
+
+// MCPConfigPath returns the path of mcp-servers/mcp-config.json.
+func MCPConfigPath() string {
+	return filepath.Join(RepoRoot(), "mcp-servers", "mcp-config.json")
+}
+
+// MCPConfigSchemaPath returns the JSON Schema of mcp-config.json.
+func MCPConfigSchemaPath() string {
+	return filepath.Join(RepoRoot(), "mcp-servers", "mcp-config.schema.json")
+}
+
+// MCPUsagePath returns the path of mcp-servers/USAGE.md.
+func MCPUsagePath() string {
+	return filepath.Join(RepoRoot(), "mcp-servers", "USAGE.md")
+}
+
+// BootstrapScriptPath returns the path of scripts/bootstrap.sh.
+func BootstrapScriptPath() string {
+	return filepath.Join(RepoRoot(), "scripts", "bootstrap.sh")
+}
+
+// CommandFamiliesPath returns the catalog of CLI command families MCP
+// capabilities may grant.
+func CommandFamiliesPath() string {
+	return filepath.Join(TestDataDir(), "mcp", "command-families.yaml")
+}
+
+// MCPServer is one entry of mcpServers.
+type MCPServer struct {
+	Name         string            `json:"-"`
+	Command      string            `json:"command"`
+	Args         []string          `json:"args"`
+	Description  string            `json:"description"`
+	Env          map[string]string `json:"env"`
+	Capabilities []string          `json:"capabilities"`
+}
+
+// MCPConfig is a parsed mcp-config.json.
+type MCPConfig struct {
+	// Servers are sorted by name.
+	Servers []MCPServer
+}
+
+// LoadMCPConfig reads an MCP configuration and validates it against the
+// schema at schemaPath.
+func LoadMCPConfig(path, schemaPath string) (*MCPConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	problems, err := duplicateKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	schema, err := compiler.Compile(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", schemaPath, schemaError(err))
+	}
+
+	var instance interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&instance); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := schema.Validate(instance); err != nil {
+		problems = append(problems, schemaError(err).Error())
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s:\n  - %s", path, strings.Join(problems, "\n  - "))
+	}
+
+	var document struct {
+		MCPServers map[string]MCPServer `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	config := &MCPConfig{}
+	for name, server := range document.MCPServers {
+		server.Name = name
+		config.Servers = append(config.Servers, server)
+	}
+	sort.Slice(config.Servers, func(i, j int) bool { return config.Servers[i].Name < config.Servers[j].Name })
+	return config, nil
+}
+
+// duplicateKeys returns "<path>: <key> is declared more than once" for
+// every object key that appears twice in a JSON document.
+func duplicateKeys(data []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var problems []string
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'):
+			seen := map[string]bool{}
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				key := keyToken.(string)
+				if seen[key] {
+					problems = append(problems, fmt.Sprintf("%s: %s is declared more than once", path, key))
+				}
+				seen[key] = true
+				if err := walk(path + "/" + key); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+			return err
+
+		case json.Delim('['):
+			for index := 0; decoder.More(); index++ {
+				if err := walk(fmt.Sprintf("%s/%d", path, index)); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+			return err
+		}
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unexpected data after the top-level value")
+	}
+	return problems, nil
+}
+
+// CheckEnv returns a problem for every ${VAR} in env values and args that
+// is malformed or not in documented.
+func (c *MCPConfig) CheckEnv(documented map[string]bool) []string {
+	var problems []string
+	for _, server := range c.Servers {
+		var values []string
+		for _, key := range sortedStringKeys(server.Env) {
+			values = append(values, server.Env[key])
+		}
+		values = append(values, server.Args...)
+
+		for _, value := range values {
+			for _, match := range envReferencePattern.FindAllStringSubmatch(value, -1) {
+				switch {
+				case !envNamePattern.MatchString(match[1]):
+					problems = append(problems, fmt.Sprintf("%s: %s is not a valid ${VAR} reference", server.Name, match[0]))
+				case !documented[match[1]]:
+					problems = append(problems, fmt.Sprintf("%s: %s is not documented in mcp-servers/USAGE.md or set by scripts/bootstrap.sh", server.Name, match[0]))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// CheckCapabilities returns a problem for every capability that is not a
+// listed command family or server operation.
+func (c *MCPConfig) CheckCapabilities(families *CommandFamilies) []string {
+	var problems []string
+	for _, server := range c.Servers {
+		for _, capability := range server.Capabilities {
+			if !families.Grants(server.Name, capability) {
+				problems = append(problems, fmt.Sprintf("%s: capability %q is not a supported CLI command family", server.Name, capability))
+			}
+		}
+	}
+	return problems
+}
+
+// UsageEnvVars returns the variables listed in the bash blocks of an MCP
+// usage guide.
+func UsageEnvVars(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var names []string
+	inBash := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			inBash = !inBash && (trimmed == "```bash" || trimmed == "```sh")
+		case inBash:
+			if match := usageEnvPattern.FindStringSubmatch(line); match != nil && !containsString(names, match[1]) {
+				names = append(names, match[1])
+			}
+		}
+	}
+	return names, scanner.Err()
+}
+
+// ScriptEnvVars returns the variables a shell script assigns or exports at
+// top level.
+func ScriptEnvVars(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if match := scriptEnvPattern.FindStringSubmatch(line); match != nil && !containsString(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	return names, nil
+}
+
+// CommandFamilies is the catalog of CLI command families and server
+// operations MCP capabilities may grant.
+type CommandFamilies struct {
+	// Families maps a CLI to the command groups a capability may name.
+	Families map[string][]string `yaml:"families"`
+	// Operations maps a server to its non-CLI capabilities.
+	Operations map[string][]string `yaml:"operations"`
+}
+
+// LoadCommandFamilies reads the command family catalog.
+func LoadCommandFamilies(path string) (*CommandFamilies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var families CommandFamilies
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&families); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &families, nil
+}
+
+// Grants reports whether a capability of server is a listed CLI, a
+// listed command group of it, or one of the server's operations. Words
+// after the command group (az ad app) are not checked.
+func (f *CommandFamilies) Grants(server, capability string) bool {
+	if containsString(f.Operations[server], capability) {
+		return true
+	}
+
+	words := strings.Fields(capability)
+	if len(words) == 0 {
+		return false
+	}
+	groups, ok := f.Families[words[0]]
+	if !ok {
+		return false
+	}
+	return len(words) == 1 || containsString(groups, words[1])
+}
+
+// Unused returns the families, command groups and operations no
+// capability in config uses, as "az aks" or "filesystem: read".
+func (f *CommandFamilies) Unused(config *MCPConfig) []string {
+	used := map[string]bool{}
+	for _, server := range config.Servers {
+		for _, capability := range server.Capabilities {
+			if containsString(f.Operations[server.Name], capability) {
+				used[server.Name+": "+capability] = true
+				continue
+			}
+			words := strings.Fields(capability)
+			if len(words) > 0 {
+				used[words[0]] = true
+			}
+			if len(words) > 1 {
+				used[words[0]+" "+words[1]] = true
+			}
+		}
+	}
+
+	var unused []string
+	for _, family := range sortedStringSliceKeys(f.Families) {
+		if !used[family] {
+			unused = append(unused, family)
+			continue
+		}
+		for _, group := range f.Families[family] {
+			if !used[family+" "+group] {
+				unused = append(unused, family+" "+group)
+			}
+		}
+	}
+	for _, server := range sortedStringSliceKeys(f.Operations) {
+		for _, operation := range f.Operations[server] {
+			if !used[server+": "+operation] {
+				unused = append(unused, server+": "+operation)
+			}
+		}
+	}
+	return unused
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedStringSliceKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
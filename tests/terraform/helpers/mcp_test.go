@@ -0,0 +1,148 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - MCP SERVER CONFIGURATION CHECK TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestMCP|TestCommandFamilies|TestUsageEnvVars|TestScriptEnvVars' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeMCPConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "mcp-config.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// TestMCPConfigLoad tests parsing and the servers' sort order
+func TestMCPConfigLoad(t *testing.T) {
+	t.Parallel()
+
+	path := writeMCPConfig(t, `{
+  "mcpServers": {
+    "kubernetes": {"command": "npx", "args": ["-y", "mcp-kubernetes"], "description": "Kubernetes", "env": {"KUBECONFIG": "${KUBECONFIG}"}, "capabilities": ["kubectl get"]},
+    "azure": {"command": "npx", "args": [], "description": "Azure", "capabilities": ["az"]}
+  }
+}`)
+
+	config, err := LoadMCPConfig(path, MCPConfigSchemaPath())
+	require.NoError(t, err)
+	require.Len(t, config.Servers, 2)
+	assert.Equal(t, "azure", config.Servers[0].Name)
+	assert.Equal(t, MCPServer{
+		Name:         "kubernetes",
+		Command:      "npx",
+		Args:         []string{"-y", "mcp-kubernetes"},
+		Description:  "Kubernetes",
+		Env:          map[string]string{"KUBECONFIG": "${KUBECONFIG}"},
+		Capabilities: []string{"kubectl get"},
+	}, config.Servers[1])
+}
+
+// TestMCPConfigInvalid tests that schema violations and duplicate server
+// names are reported together
+func TestMCPConfigInvalid(t *testing.T) {
+	t.Parallel()
+
+	path := writeMCPConfig(t, `{
+  "mcpServers": {
+    "azure": {"command": "npx", "args": [], "description": "Azure", "capabilities": ["az"]},
+    "azure": {"command": "npx", "args": [], "description": "Azure", "capabilites": ["az"]},
+    "Git": {"command": "", "args": ["-y"], "description": "Git", "capabilities": ["git  push"]}
+  }
+}`)
+
+	_, err := LoadMCPConfig(path, MCPConfigSchemaPath())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/mcpServers: azure is declared more than once")
+	assert.Contains(t, err.Error(), "/mcpServers/azure: missing properties: 'capabilities'")
+	assert.Contains(t, err.Error(), "additionalProperties 'capabilites' not allowed")
+	assert.Contains(t, err.Error(), "/mcpServers/Git/command: length must be >= 1")
+	assert.Contains(t, err.Error(), "/mcpServers/Git/capabilities/0: does not match pattern")
+
+	_, err = LoadMCPConfig(writeMCPConfig(t, `{"mcpServers": {}} {}`), MCPConfigSchemaPath())
+	assert.ErrorContains(t, err, "unexpected data after the top-level value")
+}
+
+// TestMCPConfigCheckEnv tests ${VAR} references in env and args
+func TestMCPConfigCheckEnv(t *testing.T) {
+	t.Parallel()
+
+	config := &MCPConfig{Servers: []MCPServer{
+		{Name: "github", Env: map[string]string{"GH_TOKEN": "${GITHUB_TOKEN}", "HOST": "${GH_HOST}"}},
+		{Name: "terraform", Args: []string{"--env=${ENVIRONMENT}", "--workspace=${ TF_WORKSPACE}"}},
+	}}
+
+	assert.Equal(t, []string{
+		"github: ${GH_HOST} is not documented in mcp-servers/USAGE.md or set by scripts/bootstrap.sh",
+		"terraform: ${ TF_WORKSPACE} is not a valid ${VAR} reference",
+	}, config.CheckEnv(map[string]bool{"GITHUB_TOKEN": true, "ENVIRONMENT": true}))
+}
+
+// TestCommandFamilies tests capability matching and unused entries
+func TestCommandFamilies(t *testing.T) {
+	t.Parallel()
+
+	families := &CommandFamilies{
+		Families: map[string][]string{
+			"az":     {"aks", "ad"},
+			"helm":   {"install"},
+			"jq":     {},
+			"docker": {"build"},
+		},
+		Operations: map[string][]string{"filesystem": {"read", "write"}},
+	}
+
+	config := &MCPConfig{Servers: []MCPServer{
+		{Name: "azure", Capabilities: []string{"az", "az aks", "az ad app", "az akss", "kubect get"}},
+		{Name: "bash", Capabilities: []string{"jq", "read"}},
+		{Name: "filesystem", Capabilities: []string{"read"}},
+	}}
+
+	assert.Equal(t, []string{
+		`azure: capability "az akss" is not a supported CLI command family`,
+		`azure: capability "kubect get" is not a supported CLI command family`,
+		`bash: capability "read" is not a supported CLI command family`,
+	}, config.CheckCapabilities(families))
+
+	assert.Equal(t, []string{"docker", "helm", "filesystem: write"}, families.Unused(config))
+}
+
+// TestUsageEnvVars tests that only bash blocks of the usage guide are read
+func TestUsageEnvVars(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "USAGE.md")
+	require.NoError(t, os.WriteFile(path, []byte("Set KUBECONFIG first.\n\n"+
+		"```json\n{\"NOT_A_VAR\": 1}\n```\n\n"+
+		"```bash\nAZURE_SUBSCRIPTION_ID\nexport GITHUB_TOKEN=\"...\"  # GitHub\n# Comment\naz login\n```\n"), 0o644))
+
+	names, err := UsageEnvVars(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AZURE_SUBSCRIPTION_ID", "GITHUB_TOKEN"}, names)
+}
+
+// TestScriptEnvVars tests that only top-level assignments are read
+func TestScriptEnvVars(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "bootstrap.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/bash\n"+
+		"ENVIRONMENT=\"${ENVIRONMENT:-prod}\"\nexport DRY_RUN=false\n"+
+		"main() {\n    LOCAL_ONLY=1\n}\nENVIRONMENT=dev\n"), 0o644))
+
+	names, err := ScriptEnvVars(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ENVIRONMENT", "DRY_RUN"}, names)
+}
@@ -0,0 +1,221 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - MOCK PROVIDER PLANS
+// =============================================================================
+//
+// Produces a module plan with zero Azure or Kubernetes credentials.
+//
+// With TERRATEST_MOCK_PROVIDERS=true, helpers.InitAndPlan does not run
+// `terraform plan`. It writes a generated test file into the isolated module
+// copy that replaces every provider in MockedProviders with a Terraform
+// `mock_provider`, then runs `terraform test -json -verbose` and reads the
+// plan of its single `command = plan` run. The result is the same *Plan the
+// real mode returns, so tests do not change.
+//
+// Canned data-source results live per module in
+// testdata/mocks/<module>/<provider>.tfmock.hcl. Data sources without canned
+// results get generated values.
+//
+// Requires Terraform >= 1.7 (mock_provider support).
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+const (
+	// MockProvidersEnv switches InitAndPlan to mock provider mode.
+	MockProvidersEnv = "TERRATEST_MOCK_PROVIDERS"
+
+	mockTestFile = "terratest_mock.tftest.hcl"
+	mockVarsFile = "terratest_mock.tfvars.json"
+	mockDataDir  = "terratest-mocks"
+)
+
+// MockedProviders are the provider types replaced by mock_provider. They
+// all need credentials or a reachable cluster to configure; providers such
+// as random stay real. Only the root module requires github.
+var MockedProviders = []string{"azurerm", "azuread", "azapi", "helm", "kubernetes", "kubectl", "github"}
+
+// MockProvidersEnabled reports whether plans are produced with mock
+// providers.
+func MockProvidersEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(MockProvidersEnv))
+	return enabled
+}
+
+// TestDataDir returns the absolute path of tests/terraform/testdata.
+func TestDataDir() string {
+	return filepath.Join(RepoRoot(), "tests", "terraform", "testdata")
+}
+
+// MockDataDir returns the directory holding canned data-source results for
+// a module.
+func MockDataDir(module string) string {
+	return filepath.Join(TestDataDir(), "mocks", module)
+}
+
+// InitAndPlanWithMocks runs terraform init and a mock-provider plan in
+// options.TerraformDir. The directory is written to, so it must be an
+// isolated copy (every builder returns one).
+func InitAndPlanWithMocks(t testing.TB, options *terraform.Options) (*Plan, error) {
+	t.Helper()
+
+	dir := options.TerraformDir
+	module := filepath.Base(dir)
+
+	if err := writeMockLayer(dir, module, options.Vars); err != nil {
+		return nil, err
+	}
+
+	if _, err := terraform.InitE(t, options); err != nil {
+		return nil, err
+	}
+
+	// The verbose JSON output embeds provider schemas; keep it out of the logs
+	testOptions := *options
+	testOptions.Logger = logger.Discard
+
+	output, runErr := terraform.RunTerraformCommandAndGetStdoutE(t, &testOptions,
+		"test", "-json", "-verbose", "-filter="+mockTestFile, "-var-file="+mockVarsFile)
+
+	plan, err := parseMockTestOutput(output)
+	if err != nil && runErr != nil && !strings.Contains(output, "{") {
+		// Terraform failed before emitting any JSON, e.g. an unknown flag
+		return nil, runErr
+	}
+	return plan, err
+}
+
+// writeMockLayer writes the generated test file, the Vars and the canned
+// data for every mocked provider the module requires.
+func writeMockLayer(dir, module string, vars map[string]interface{}) error {
+	requirements, err := RequiredProviders(dir)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by the test harness (helpers/mock.go). Do not edit.\n")
+
+	for _, req := range requirements {
+		if !isMockedProvider(req.Source) {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\nmock_provider %q {\n", req.Name)
+
+		canned := filepath.Join(MockDataDir(module), req.Name+".tfmock.hcl")
+		if _, err := os.Stat(canned); err == nil {
+			target := filepath.Join(dir, mockDataDir, req.Name)
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			if err := copyFile(canned, filepath.Join(target, req.Name+".tfmock.hcl"), 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "  source = %q\n", "./"+mockDataDir+"/"+req.Name)
+		}
+
+		b.WriteString("}\n")
+	}
+
+	b.WriteString("\nrun \"plan\" {\n  command = plan\n}\n")
+
+	if err := os.WriteFile(filepath.Join(dir, mockTestFile), []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+
+	rawVars, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, mockVarsFile), rawVars, 0o644)
+}
+
+func isMockedProvider(source string) bool {
+	providerType := source[strings.LastIndex(source, "/")+1:]
+	for _, mocked := range MockedProviders {
+		if providerType == mocked {
+			return true
+		}
+	}
+	return false
+}
+
+// mockTestMessage is the subset of `terraform test -json` messages we read.
+type mockTestMessage struct {
+	Type       string          `json:"type"`
+	TestPlan   json.RawMessage `json:"test_plan"`
+	Diagnostic *struct {
+		Severity string `json:"severity"`
+		Summary  string `json:"summary"`
+		Detail   string `json:"detail"`
+	} `json:"diagnostic"`
+	TestRun *struct {
+		Run    string `json:"run"`
+		Status string `json:"status"`
+	} `json:"test_run"`
+}
+
+// parseMockTestOutput returns the plan of the "plan" run, or an error with
+// Terraform's diagnostics when the run did not pass. Lines that are not
+// JSON messages are ignored.
+func parseMockTestOutput(output string) (*Plan, error) {
+	var (
+		plan        *Plan
+		status      string
+		diagnostics []string
+	)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var msg mockTestMessage
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			continue
+		}
+
+		switch msg.Type {
+		case "test_plan":
+			// The same JSON as `terraform show -json`, so planned values and
+			// outputs are carried over with the resource changes
+			planStruct, err := terraform.ParsePlanJSON(string(msg.TestPlan))
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse mock plan: %w", err)
+			}
+			plan = &Plan{PlanStruct: planStruct}
+		case "test_run":
+			if msg.TestRun != nil && msg.TestRun.Run == "plan" && msg.TestRun.Status != "" {
+				status = msg.TestRun.Status
+			}
+		case "diagnostic":
+			if msg.Diagnostic != nil && msg.Diagnostic.Severity == "error" {
+				diagnostics = append(diagnostics, strings.TrimSpace(msg.Diagnostic.Summary+": "+msg.Diagnostic.Detail))
+			}
+		}
+	}
+
+	if len(diagnostics) > 0 || (status != "" && status != "pass") {
+		return nil, fmt.Errorf("mock plan failed (status %q):\n  - %s", status, strings.Join(diagnostics, "\n  - "))
+	}
+	if plan == nil {
+		return nil, fmt.Errorf("terraform test produced no plan; mock_provider needs Terraform >= 1.7")
+	}
+
+	return plan, nil
+}
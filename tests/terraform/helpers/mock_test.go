@@ -0,0 +1,133 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - MOCK PROVIDER TESTS
+// =============================================================================
+//
+// Run with: go test -v -run TestMock ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mockVersionsFixture = `
+terraform {
+  required_providers {
+    azurerm = {
+      source  = "hashicorp/azurerm"
+      version = "~> 3.85"
+    }
+    random = {
+      source  = "hashicorp/random"
+      version = "~> 3.6"
+    }
+    kubectl = {
+      source  = "gavinbunney/kubectl"
+      version = "~> 1.14"
+    }
+  }
+}
+`
+
+// TestMockLayer tests the generated test file, Vars file and canned data
+func TestMockLayer(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), ModuleExternalSecrets)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "versions.tf"), []byte(mockVersionsFixture), 0o644))
+
+	vars := map[string]interface{}{"customer_name": "mocktest", "tags": map[string]string{"a": "b"}}
+	require.NoError(t, writeMockLayer(dir, ModuleExternalSecrets, vars))
+
+	generated, err := os.ReadFile(filepath.Join(dir, mockTestFile))
+	require.NoError(t, err)
+
+	_, diags := hclsyntax.ParseConfig(generated, mockTestFile, hcl.InitialPos)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	content := string(generated)
+	assert.Contains(t, content, "mock_provider \"azurerm\" {\n  source = \"./terratest-mocks/azurerm\"\n}")
+	assert.Contains(t, content, "mock_provider \"kubectl\" {\n}", "providers without canned data are mocked with generated values")
+	assert.NotContains(t, content, "\"random\"", "random needs no credentials and stays real")
+	assert.Contains(t, content, "command = plan")
+
+	assert.FileExists(t, filepath.Join(dir, mockDataDir, "azurerm", "azurerm.tfmock.hcl"))
+
+	rawVars, err := os.ReadFile(filepath.Join(dir, mockVarsFile))
+	require.NoError(t, err)
+
+	var written map[string]interface{}
+	require.NoError(t, json.Unmarshal(rawVars, &written))
+	assert.Equal(t, "mocktest", written["customer_name"])
+	assert.Equal(t, map[string]interface{}{"a": "b"}, written["tags"])
+}
+
+// TestMockTestOutput tests reading the plan from `terraform test -json`
+func TestMockTestOutput(t *testing.T) {
+	t.Parallel()
+
+	raw, err := os.ReadFile("testdata/mock/test-output.jsonl")
+	require.NoError(t, err)
+
+	plan, err := parseMockTestOutput(string(raw))
+	require.NoError(t, err)
+
+	plan.AssertCreated(t, "azurerm_role_assignment.aks_acr_pull").
+		HasAttribute("role_definition_name", "AcrPull").
+		HasAttributeKnownAfterApply("scope")
+	plan.AssertAbsent(t, "azurerm_client_config")
+	assert.Contains(t, plan.ResourceChangesMap, "azurerm_role_assignment.aks_acr_pull")
+	assert.Contains(t, plan.ResourcePlannedValuesMap, "azurerm_role_assignment.aks_acr_pull")
+	assert.Equal(t, map[string]interface{}{"role_definition": "AcrPull"}, plan.Outputs())
+}
+
+// TestMockTestOutputErrors tests that failed runs surface Terraform diagnostics
+func TestMockTestOutputErrors(t *testing.T) {
+	t.Parallel()
+
+	raw, err := os.ReadFile("testdata/mock/test-output-error.jsonl")
+	require.NoError(t, err)
+
+	_, err = parseMockTestOutput(string(raw))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Environment must be dev, staging, or prod.")
+
+	_, err = parseMockTestOutput("Error: unknown command \"test\"\n")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Terraform >= 1.7")
+}
+
+// TestMockDataModules tests that canned data exists only for real modules and
+// every file parses
+func TestMockDataModules(t *testing.T) {
+	t.Parallel()
+
+	files, err := filepath.Glob(filepath.Join(TestDataDir(), "mocks", "*", "*.tfmock.hcl"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, path := range files {
+		module := filepath.Base(filepath.Dir(path))
+		assert.Contains(t, Modules(), module, "%s: no such module", path)
+
+		provider := strings.TrimSuffix(filepath.Base(path), ".tfmock.hcl")
+		assert.Contains(t, MockedProviders, provider, "%s: provider is never mocked", path)
+
+		src, err := os.ReadFile(path)
+		require.NoError(t, err)
+		_, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		assert.False(t, diags.HasErrors(), diags.Error())
+	}
+}
@@ -0,0 +1,323 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - AZURE NAMING RULES
+// =============================================================================
+//
+// Azure rejects a resource name at apply time, long after the plan passed.
+// AzureNamingRules holds the naming restrictions of every azurerm resource
+// type the modules create, keyed by Terraform resource type, and
+// InitAndPlan checks the name of every created resource against them:
+//
+//	problems := helpers.CheckResourceNames(plan.RawPlan.ResourceChanges)
+//
+// A name unknown until apply is not checked. Names of globally unique
+// types must also differ within the plan.
+//
+// The rules follow "Naming rules and restrictions for Azure resources"
+// (learn.microsoft.com/azure/azure-resource-manager/management/resource-name-rules).
+// A new azurerm resource type in terraform/ needs an entry here, or in
+// UnnamedResourceTypes when its name is not chosen by the module.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// NamingScope is where a resource name must be unique.
+type NamingScope string
+
+const (
+	ScopeGlobal        NamingScope = "global"
+	ScopeSubscription  NamingScope = "subscription"
+	ScopeResourceGroup NamingScope = "resource group"
+	ScopeParent        NamingScope = "parent resource"
+)
+
+// NamingRule is the naming restriction of one resource type. Chars, Start
+// and End are the contents of a regular expression character class.
+type NamingRule struct {
+	MinLength int
+	MaxLength int
+	// Chars are the characters allowed anywhere in the name.
+	Chars string
+	// Start and End restrict the first and last character; "" allows any
+	// character of Chars.
+	Start string
+	End   string
+	// NoConsecutiveHyphens rejects "--".
+	NoConsecutiveHyphens bool
+	Scope                NamingScope
+}
+
+// Character classes shared by many rules.
+const (
+	alphanumeric      = `a-zA-Z0-9`
+	lowerAlphanumeric = `a-z0-9`
+	letters           = `a-zA-Z`
+	networkChars      = `a-zA-Z0-9_.-`
+	networkEnd        = `a-zA-Z0-9_`
+	dnsChars          = `a-zA-Z0-9.-`
+)
+
+// AzureNamingRules maps an azurerm resource type to its naming rule.
+var AzureNamingRules = map[string]NamingRule{
+	// General
+	"azurerm_resource_group": {MinLength: 1, MaxLength: 90, Chars: `\p{L}\p{N}_().-`, End: `^.`, Scope: ScopeSubscription},
+
+	// Networking
+	"azurerm_virtual_network":                       {MinLength: 2, MaxLength: 64, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_subnet":                                {MinLength: 1, MaxLength: 80, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeParent},
+	"azurerm_network_security_group":                {MinLength: 1, MaxLength: 80, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_public_ip":                             {MinLength: 1, MaxLength: 80, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_bastion_host":                          {MinLength: 1, MaxLength: 80, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_private_endpoint":                      {MinLength: 2, MaxLength: 64, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_private_dns_zone":                      {MinLength: 1, MaxLength: 253, Chars: dnsChars, Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_private_dns_zone_virtual_network_link": {MinLength: 1, MaxLength: 80, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeParent},
+	"azurerm_dns_zone":                              {MinLength: 1, MaxLength: 253, Chars: dnsChars, Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+
+	// Security and identity
+	"azurerm_key_vault":                     {MinLength: 3, MaxLength: 24, Chars: alphanumeric + "-", Start: letters, End: alphanumeric, NoConsecutiveHyphens: true, Scope: ScopeGlobal},
+	"azurerm_key_vault_secret":              {MinLength: 1, MaxLength: 127, Chars: alphanumeric + "-", Scope: ScopeParent},
+	"azurerm_user_assigned_identity":        {MinLength: 3, MaxLength: 128, Chars: alphanumeric + "_-", Start: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_federated_identity_credential": {MinLength: 3, MaxLength: 120, Chars: alphanumeric + "_-", Start: alphanumeric, Scope: ScopeParent},
+	"azurerm_security_center_automation":    {MinLength: 1, MaxLength: 260, Chars: alphanumeric + "_.-", Scope: ScopeResourceGroup},
+	"azurerm_security_center_contact":       {MinLength: 1, MaxLength: 260, Chars: `^<>%&:\\?/`, Scope: ScopeSubscription},
+
+	// Containers
+	"azurerm_kubernetes_cluster":             {MinLength: 1, MaxLength: 63, Chars: alphanumeric + "_-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_kubernetes_cluster_node_pool":   {MinLength: 1, MaxLength: 12, Chars: lowerAlphanumeric, Start: `a-z`, Scope: ScopeParent},
+	"azurerm_container_registry":             {MinLength: 5, MaxLength: 50, Chars: alphanumeric, Scope: ScopeGlobal},
+	"azurerm_container_registry_replication": {MinLength: 1, MaxLength: 50, Chars: alphanumeric, Scope: ScopeParent},
+	"azurerm_container_registry_scope_map":   {MinLength: 5, MaxLength: 50, Chars: alphanumeric + "_-", Scope: ScopeParent},
+	"azurerm_container_registry_task":        {MinLength: 5, MaxLength: 50, Chars: alphanumeric + "_-", Scope: ScopeParent},
+	"azurerm_container_registry_webhook":     {MinLength: 5, MaxLength: 50, Chars: alphanumeric, Scope: ScopeParent},
+
+	// Databases
+	"azurerm_postgresql_flexible_server":               {MinLength: 3, MaxLength: 63, Chars: lowerAlphanumeric + "-", Start: lowerAlphanumeric, End: lowerAlphanumeric, Scope: ScopeGlobal},
+	"azurerm_postgresql_flexible_server_database":      {MinLength: 1, MaxLength: 63, Chars: alphanumeric + "_-", Start: letters + "_", Scope: ScopeParent},
+	"azurerm_postgresql_flexible_server_firewall_rule": {MinLength: 1, MaxLength: 128, Chars: alphanumeric + "_-", Scope: ScopeParent},
+	"azurerm_redis_cache":                              {MinLength: 1, MaxLength: 63, Chars: alphanumeric + "-", Start: alphanumeric, End: alphanumeric, NoConsecutiveHyphens: true, Scope: ScopeGlobal},
+	"azurerm_cosmosdb_account":                         {MinLength: 3, MaxLength: 44, Chars: lowerAlphanumeric + "-", Start: lowerAlphanumeric, End: lowerAlphanumeric, Scope: ScopeGlobal},
+
+	// Storage
+	"azurerm_storage_account":   {MinLength: 3, MaxLength: 24, Chars: lowerAlphanumeric, Scope: ScopeGlobal},
+	"azurerm_storage_container": {MinLength: 3, MaxLength: 63, Chars: lowerAlphanumeric + "-", Start: lowerAlphanumeric, End: lowerAlphanumeric, NoConsecutiveHyphens: true, Scope: ScopeParent},
+
+	// AI
+	"azurerm_cognitive_account":    {MinLength: 2, MaxLength: 64, Chars: alphanumeric + "-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_cognitive_deployment": {MinLength: 2, MaxLength: 64, Chars: alphanumeric + "_.-", Scope: ScopeParent},
+	"azurerm_search_service":       {MinLength: 2, MaxLength: 60, Chars: lowerAlphanumeric + "-", Start: lowerAlphanumeric, End: lowerAlphanumeric, NoConsecutiveHyphens: true, Scope: ScopeGlobal},
+	"azurerm_purview_account":      {MinLength: 3, MaxLength: 63, Chars: alphanumeric + "-", Start: alphanumeric, End: alphanumeric, Scope: ScopeGlobal},
+
+	// Monitoring
+	"azurerm_log_analytics_workspace":                  {MinLength: 4, MaxLength: 63, Chars: alphanumeric + "-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_monitor_workspace":                        {MinLength: 4, MaxLength: 44, Chars: alphanumeric + "-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_dashboard_grafana":                        {MinLength: 2, MaxLength: 23, Chars: alphanumeric + "-", Start: letters, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_monitor_action_group":                     {MinLength: 1, MaxLength: 260, Chars: `^:<>+/&%\\?\x00-\x1f`, Scope: ScopeResourceGroup},
+	"azurerm_monitor_alert_prometheus_rule_group":      {MinLength: 1, MaxLength: 260, Chars: `^<>*%{}&:\\?+/#|\x00-\x1f`, Scope: ScopeResourceGroup},
+	"azurerm_monitor_data_collection_endpoint":         {MinLength: 3, MaxLength: 44, Chars: alphanumeric + "-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_monitor_data_collection_rule":             {MinLength: 1, MaxLength: 64, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_monitor_data_collection_rule_association": {MinLength: 1, MaxLength: 64, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeParent},
+	"azurerm_monitor_diagnostic_setting":               {MinLength: 1, MaxLength: 260, Chars: `^*<>%:&?/\\\x00-\x1f`, Scope: ScopeParent},
+	"azurerm_monitor_metric_alert":                     {MinLength: 1, MaxLength: 260, Chars: `^*#&+:<>?@%{}\\/\x00-\x1f`, Scope: ScopeResourceGroup},
+	"azurerm_monitor_scheduled_query_rules_alert_v2":   {MinLength: 1, MaxLength: 260, Chars: `^*<>%{}&:\\?/#|\x00-\x1f`, Scope: ScopeResourceGroup},
+
+	// Backup and disaster recovery
+	"azurerm_recovery_services_vault":                    {MinLength: 2, MaxLength: 50, Chars: alphanumeric + "-", Start: letters, Scope: ScopeResourceGroup},
+	"azurerm_backup_policy_vm":                           {MinLength: 3, MaxLength: 150, Chars: alphanumeric + "_-", Start: letters, Scope: ScopeParent},
+	"azurerm_backup_policy_file_share":                   {MinLength: 3, MaxLength: 150, Chars: alphanumeric + "_-", Start: letters, Scope: ScopeParent},
+	"azurerm_site_recovery_fabric":                       {MinLength: 1, MaxLength: 260, Chars: alphanumeric + "_.-", Start: alphanumeric, Scope: ScopeParent},
+	"azurerm_site_recovery_protection_container":         {MinLength: 1, MaxLength: 260, Chars: alphanumeric + "_.-", Start: alphanumeric, Scope: ScopeParent},
+	"azurerm_site_recovery_protection_container_mapping": {MinLength: 1, MaxLength: 260, Chars: alphanumeric + "_.-", Start: alphanumeric, Scope: ScopeParent},
+	"azurerm_site_recovery_network_mapping":              {MinLength: 1, MaxLength: 260, Chars: alphanumeric + "_.-", Start: alphanumeric, Scope: ScopeParent},
+	"azurerm_site_recovery_replication_policy":           {MinLength: 1, MaxLength: 260, Chars: alphanumeric + "_.-", Start: alphanumeric, Scope: ScopeParent},
+
+	// Cost management
+	"azurerm_consumption_budget_resource_group":     {MinLength: 1, MaxLength: 63, Chars: alphanumeric + "_-", Scope: ScopeParent},
+	"azurerm_consumption_budget_subscription":       {MinLength: 1, MaxLength: 63, Chars: alphanumeric + "_-", Scope: ScopeSubscription},
+	"azurerm_cost_anomaly_alert":                    {MinLength: 1, MaxLength: 260, Chars: alphanumeric + "_-", Scope: ScopeSubscription},
+	"azurerm_resource_group_cost_management_export": {MinLength: 3, MaxLength: 64, Chars: alphanumeric + "_-", Scope: ScopeParent},
+}
+
+// UnnamedResourceTypes are the azurerm resource types whose name, if any,
+// is not chosen by the modules, with the reason.
+var UnnamedResourceTypes = map[string]string{
+	"azurerm_key_vault_access_policy":                   "no name",
+	"azurerm_log_analytics_solution":                    "solution_name is the marketplace solution",
+	"azurerm_postgresql_flexible_server_configuration":  "name is a PostgreSQL server parameter",
+	"azurerm_role_assignment":                           "name is a GUID Azure generates",
+	"azurerm_security_center_auto_provisioning":         "no name",
+	"azurerm_security_center_subscription_pricing":      "no name",
+	"azurerm_subnet_network_security_group_association": "no name",
+}
+
+// NamingOutputResourceTypes maps outputs of the naming module to the
+// resource type the name is for.
+var NamingOutputResourceTypes = map[string]string{
+	"resource_group":          "azurerm_resource_group",
+	"virtual_network":         "azurerm_virtual_network",
+	"subnet":                  "azurerm_subnet",
+	"subnet_aks":              "azurerm_subnet",
+	"subnet_db":               "azurerm_subnet",
+	"subnet_pe":               "azurerm_subnet",
+	"network_security_group":  "azurerm_network_security_group",
+	"public_ip":               "azurerm_public_ip",
+	"private_endpoint":        "azurerm_private_endpoint",
+	"private_dns_zone":        "azurerm_private_dns_zone",
+	"bastion":                 "azurerm_bastion_host",
+	"aks_cluster":             "azurerm_kubernetes_cluster",
+	"aks_node_pool":           "azurerm_kubernetes_cluster_node_pool",
+	"aks_node_pool_system":    "azurerm_kubernetes_cluster_node_pool",
+	"aks_node_pool_user":      "azurerm_kubernetes_cluster_node_pool",
+	"container_registry":      "azurerm_container_registry",
+	"postgresql_server":       "azurerm_postgresql_flexible_server",
+	"postgresql_database":     "azurerm_postgresql_flexible_server_database",
+	"cosmos_account":          "azurerm_cosmosdb_account",
+	"redis_cache":             "azurerm_redis_cache",
+	"storage_account":         "azurerm_storage_account",
+	"storage_account_diag":    "azurerm_storage_account",
+	"storage_container":       "azurerm_storage_container",
+	"key_vault":               "azurerm_key_vault",
+	"key_vault_secret":        "azurerm_key_vault_secret",
+	"managed_identity":        "azurerm_user_assigned_identity",
+	"managed_identity_aks":    "azurerm_user_assigned_identity",
+	"log_analytics_workspace": "azurerm_log_analytics_workspace",
+	"action_group":            "azurerm_monitor_action_group",
+	"monitor_workspace":       "azurerm_monitor_workspace",
+	"grafana":                 "azurerm_dashboard_grafana",
+	"cognitive_services":      "azurerm_cognitive_account",
+	"openai_service":          "azurerm_cognitive_account",
+	"search_service":          "azurerm_search_service",
+	"purview_account":         "azurerm_purview_account",
+}
+
+// Check returns a problem for every restriction name violates.
+func (r NamingRule) Check(name string) []string {
+	var problems []string
+
+	if length := utf8.RuneCountInString(name); length < r.MinLength || length > r.MaxLength {
+		problems = append(problems, fmt.Sprintf("is %d characters, allowed %d-%d", length, r.MinLength, r.MaxLength))
+	}
+	if name == "" {
+		return problems
+	}
+
+	if invalid := regexp.MustCompile(`[`+negateClass(r.Chars)+`]`).FindAllString(name, -1); len(invalid) > 0 {
+		problems = append(problems, fmt.Sprintf("contains %q, allowed [%s]", strings.Join(dedupeStrings(invalid), ""), r.Chars))
+	}
+
+	first, _ := utf8.DecodeRuneInString(name)
+	if r.Start != "" && !regexp.MustCompile(`^[`+r.Start+`]$`).MatchString(string(first)) {
+		problems = append(problems, fmt.Sprintf("must start with [%s]", r.Start))
+	}
+	last, _ := utf8.DecodeLastRuneInString(name)
+	if r.End != "" && !regexp.MustCompile(`^[`+r.End+`]$`).MatchString(string(last)) {
+		problems = append(problems, fmt.Sprintf("must end with [%s]", r.End))
+	}
+
+	if r.NoConsecutiveHyphens && strings.Contains(name, "--") {
+		problems = append(problems, "contains consecutive hyphens")
+	}
+	return problems
+}
+
+// negateClass returns the contents of the character class matching the
+// characters class does not, so [negateClass(c)] finds invalid ones.
+func negateClass(class string) string {
+	if strings.HasPrefix(class, "^") {
+		return class[1:]
+	}
+	return "^" + class
+}
+
+// CheckResourceNames checks the name of every resource the plan creates
+// against AzureNamingRules, and that globally unique names are not planned
+// twice. Problems are sorted.
+func CheckResourceNames(changes []*tfjson.ResourceChange) []string {
+	var problems []string
+	global := map[[2]string][]string{}
+
+	for _, change := range changes {
+		if change.Mode != tfjson.ManagedResourceMode || change.Change == nil || !change.Change.Actions.Create() {
+			continue
+		}
+		rule, ok := AzureNamingRules[change.Type]
+		if !ok {
+			continue
+		}
+		after, _ := change.Change.After.(map[string]interface{})
+		name, ok := after["name"].(string)
+		if !ok {
+			// Unknown until apply
+			continue
+		}
+
+		for _, problem := range rule.Check(name) {
+			problems = append(problems, fmt.Sprintf("%s: name %q %s", change.Address, name, problem))
+		}
+		if rule.Scope == ScopeGlobal {
+			key := [2]string{change.Type, strings.ToLower(name)}
+			global[key] = append(global[key], change.Address)
+		}
+	}
+
+	for key, addresses := range global {
+		if len(addresses) > 1 {
+			sort.Strings(addresses)
+			problems = append(problems, fmt.Sprintf("%s: name %q is planned %d times (%s) and must be globally unique",
+				key[0], key[1], len(addresses), strings.Join(addresses, ", ")))
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+// AssertNaming checks the names of the resources the plan creates.
+// InitAndPlan calls it for every plan.
+func (p *Plan) AssertNaming(t testing.TB) {
+	t.Helper()
+
+	for _, problem := range CheckResourceNames(p.RawPlan.ResourceChanges) {
+		t.Errorf("azure naming: %s", problem)
+	}
+}
+
+// ResourceTypes returns the types of the resource blocks in the .tf files
+// of dirs.
+func ResourceTypes(dirs ...string) (map[string]bool, error) {
+	types := map[string]bool{}
+	for _, dir := range dirs {
+		bodies, err := parseDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, body := range bodies {
+			for _, block := range body.Blocks {
+				if block.Type == "resource" && len(block.Labels) == 2 {
+					types[block.Labels[0]] = true
+				}
+			}
+		}
+	}
+	return types, nil
+}
+
+func dedupeStrings(values []string) []string {
+	var out []string
+	for _, value := range values {
+		if !containsString(out, value) {
+			out = append(out, value)
+		}
+	}
+	return out
+}
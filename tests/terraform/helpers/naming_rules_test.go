@@ -0,0 +1,133 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - AZURE NAMING RULE TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestNamingRule|TestCheckResourceNames|TestAzureNamingRules' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNamingRuleCheck tests each kind of restriction
+func TestNamingRuleCheck(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		resourceType string
+		name         string
+		expected     []string
+	}{
+		{"azurerm_storage_account", "stcontosodev001", nil},
+		{"azurerm_storage_account", "st-Contoso-dev", []string{`contains "-C", allowed [a-z0-9]`}},
+		{"azurerm_storage_account", "stcontosoenterpriseprod001", []string{"is 26 characters, allowed 3-24"}},
+		{"azurerm_key_vault", "kv-contoso-prod-brs-", []string{"must end with [a-zA-Z0-9]"}},
+		{"azurerm_key_vault", "1kv--contoso", []string{"must start with [a-zA-Z]", "contains consecutive hyphens"}},
+		{"azurerm_redis_cache", "redis-contoso-enterprise-platform-engineering-production-brazils", []string{"is 64 characters, allowed 1-63"}},
+		{"azurerm_dashboard_grafana", "grafana-contoso-staging", nil},
+		{"azurerm_dashboard_grafana", "grafana-fabrikam-staging", []string{"is 24 characters, allowed 2-23"}},
+		{"azurerm_resource_group", "rg-contoso-dev.", []string{"must end with [^.]"}},
+		{"azurerm_resource_group", "rg-contoso-désenvolvimento", nil},
+		{"azurerm_monitor_action_group", "ag: contoso", []string{`contains ":", allowed [^:<>+/&%\\?\x00-\x1f]`}},
+		{"azurerm_kubernetes_cluster_node_pool", "user001", nil},
+		{"azurerm_kubernetes_cluster_node_pool", "1user", []string{"must start with [a-z]"}},
+		{"azurerm_subnet", "AzureBastionSubnet", nil},
+		{"azurerm_container_registry", "", []string{"is 0 characters, allowed 5-50"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.resourceType+"/"+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rule, ok := AzureNamingRules[tc.resourceType]
+			require.True(t, ok)
+			assert.Equal(t, tc.expected, rule.Check(tc.name))
+		})
+	}
+}
+
+func createdResource(address, resourceType string, after map[string]interface{}, actions ...tfjson.Action) *tfjson.ResourceChange {
+	if len(actions) == 0 {
+		actions = tfjson.Actions{tfjson.ActionCreate}
+	}
+	return &tfjson.ResourceChange{
+		Address: address,
+		Mode:    tfjson.ManagedResourceMode,
+		Type:    resourceType,
+		Change:  &tfjson.Change{Actions: actions, After: after},
+	}
+}
+
+// TestCheckResourceNames tests created names, skipped changes and globally
+// unique names planned twice
+func TestCheckResourceNames(t *testing.T) {
+	t.Parallel()
+
+	changes := []*tfjson.ResourceChange{
+		createdResource("azurerm_storage_account.techdocs", "azurerm_storage_account", map[string]interface{}{"name": "sttechdocs"}),
+		createdResource("module.dr.azurerm_storage_account.cache", "azurerm_storage_account", map[string]interface{}{"name": "STtechdocs"}),
+		createdResource("azurerm_key_vault.main", "azurerm_key_vault", map[string]interface{}{"name": "kv-contoso-prod-brazil-"}),
+		createdResource("azurerm_key_vault.old", "azurerm_key_vault", map[string]interface{}{"name": "kv_old"}, tfjson.ActionDelete),
+		createdResource("azurerm_kubernetes_cluster.main", "azurerm_kubernetes_cluster", map[string]interface{}{}),
+		createdResource("azurerm_role_assignment.acr", "azurerm_role_assignment", map[string]interface{}{"name": "not a guid"}),
+		createdResource("azurerm_storage_container.a", "azurerm_storage_container", map[string]interface{}{"name": "docs"}),
+		createdResource("azurerm_storage_container.b", "azurerm_storage_container", map[string]interface{}{"name": "docs"}),
+	}
+
+	assert.Equal(t, []string{
+		`azurerm_key_vault.main: name "kv-contoso-prod-brazil-" must end with [a-zA-Z0-9]`,
+		`azurerm_storage_account: name "sttechdocs" is planned 2 times (azurerm_storage_account.techdocs, module.dr.azurerm_storage_account.cache) and must be globally unique`,
+		`module.dr.azurerm_storage_account.cache: name "STtechdocs" contains "ST", allowed [a-z0-9]`,
+	}, CheckResourceNames(changes))
+}
+
+// TestAzureNamingRulesCoverage tests that every azurerm resource type in
+// terraform/ has a naming rule or is listed as unnamed, and that every
+// entry is used
+func TestAzureNamingRulesCoverage(t *testing.T) {
+	t.Parallel()
+
+	dirs := []string{RootModuleDir()}
+	for _, module := range Modules() {
+		dirs = append(dirs, ModuleDir(module))
+	}
+	types, err := ResourceTypes(dirs...)
+	require.NoError(t, err)
+
+	for resourceType := range types {
+		if !strings.HasPrefix(resourceType, "azurerm_") {
+			continue
+		}
+		_, named := AzureNamingRules[resourceType]
+		_, unnamed := UnnamedResourceTypes[resourceType]
+		assert.True(t, named || unnamed, "%s has no naming rule; add it to AzureNamingRules or UnnamedResourceTypes", resourceType)
+		assert.False(t, named && unnamed, "%s is both named and unnamed", resourceType)
+	}
+
+	outputs, err := DirOutputs(ModuleDir("naming"))
+	require.NoError(t, err)
+
+	namingTypes := map[string]bool{}
+	for output, resourceType := range NamingOutputResourceTypes {
+		assert.True(t, outputs[output], "%s is not an output of the naming module", output)
+		_, ok := AzureNamingRules[resourceType]
+		assert.True(t, ok, "naming output %s: %s has no naming rule", output, resourceType)
+		namingTypes[resourceType] = true
+	}
+	for resourceType, rule := range AzureNamingRules {
+		assert.True(t, types[resourceType] || namingTypes[resourceType], "%s: no module creates it and no naming output names it; remove it", resourceType)
+		assert.NotPanics(t, func() { rule.Check("name") }, "%s: invalid character class", resourceType)
+	}
+	for resourceType := range UnnamedResourceTypes {
+		assert.True(t, types[resourceType], "%s: no module creates it; remove it", resourceType)
+	}
+}
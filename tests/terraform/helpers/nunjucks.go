@@ -0,0 +1,1422 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - NUNJUCKS TEMPLATE SUBSET
+// =============================================================================
+//
+// The part of Nunjucks the Backstage scaffolder uses in golden path
+// skeletons and step inputs, with the scaffolder's ${{ }} variable
+// delimiters:
+//
+//	${{ values.name | lower }}
+//	{% if values.enableRAG %}...{% elif ... %}...{% else %}...{% endif %}
+//	{% for env in values.environments %}...{% else %}...{% endfor %}
+//	{% set image = values.registry ~ "/" ~ values.name %}
+//	{% raw %}${{ github.actor }}{% endraw %}   {# comment #}
+//
+// {%- and -%} trim the whitespace before and after a tag. Expressions
+// support literals, member and index access, not/and/or, comparisons, in,
+// + - * / % ~, inline "a if c else b" and the filters in nunjucksFilters,
+// including the scaffolder's parseRepoUrl, pick and projectSlug.
+//
+// Values follow JavaScript: an array prints as a,b and undefined prints as
+// nothing. Unlike the scaffolder, every undefined reference is recorded so
+// tests can fail on a value the template forgot to pass.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// RenderNunjucks renders a template against context. It returns the
+// output and the undefined references it printed or tested, such as
+// "values.system".
+func RenderNunjucks(source string, context map[string]interface{}) (string, []string, error) {
+	nodes, err := parseNunjucks(source)
+	if err != nil {
+		return "", nil, err
+	}
+
+	r := &nunjucksRenderer{scopes: []map[string]interface{}{context}}
+	var out strings.Builder
+	if err := r.render(nodes, &out); err != nil {
+		return "", nil, err
+	}
+	return out.String(), r.undefinedReferences(), nil
+}
+
+// EvaluateNunjucks evaluates a single expression against context.
+func EvaluateNunjucks(expression string, context map[string]interface{}) (interface{}, []string, error) {
+	expr, err := parseNunjucksExpression(expression)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := &nunjucksRenderer{scopes: []map[string]interface{}{context}}
+	value, err := r.eval(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u, ok := value.(undefined); ok {
+		r.recordUndefined(u)
+		value = nil
+	}
+	return value, r.undefinedReferences(), nil
+}
+
+// undefined is a missing variable or member; path is how it was reached.
+type undefined struct {
+	path string
+}
+
+// -----------------------------------------------------------------------------
+// Template parsing
+// -----------------------------------------------------------------------------
+
+type nunjucksNode interface{}
+
+type textNode struct{ text string }
+
+type outputNode struct{ expr nunjucksExpr }
+
+type ifNode struct {
+	conditions []nunjucksExpr
+	bodies     [][]nunjucksNode
+	otherwise  []nunjucksNode
+}
+
+type forNode struct {
+	names     []string
+	iterable  nunjucksExpr
+	body      []nunjucksNode
+	otherwise []nunjucksNode
+}
+
+type setNode struct {
+	name string
+	expr nunjucksExpr
+}
+
+// nunjucksTag is a {% %} tag: its name and the rest of its content.
+type nunjucksTag struct {
+	name string
+	args string
+}
+
+var endRawPattern = regexp.MustCompile(`\{%-?\s*endraw\s*-?%\}`)
+
+// nunjucksToken is a lexed piece of template: text, an output expression
+// or a tag.
+type nunjucksToken struct {
+	text   string
+	output string
+	tag    *nunjucksTag
+}
+
+func lexNunjucks(source string) ([]nunjucksToken, error) {
+	var tokens []nunjucksToken
+	trimNext := false
+
+	emitText := func(text string) {
+		if trimNext {
+			text = strings.TrimLeftFunc(text, unicode.IsSpace)
+			trimNext = false
+		}
+		if text != "" {
+			tokens = append(tokens, nunjucksToken{text: text})
+		}
+	}
+	trimPrevious := func() {
+		if len(tokens) > 0 && tokens[len(tokens)-1].tag == nil && tokens[len(tokens)-1].output == "" {
+			tokens[len(tokens)-1].text = strings.TrimRightFunc(tokens[len(tokens)-1].text, unicode.IsSpace)
+		}
+	}
+
+	for {
+		start := indexOfAny(source, "${{", "{%", "{#")
+		if start < 0 {
+			emitText(source)
+			return tokens, nil
+		}
+		emitText(source[:start])
+		line := strings.Count(source[:start], "\n")
+
+		switch {
+		case strings.HasPrefix(source[start:], "{#"):
+			end := strings.Index(source[start:], "#}")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			source = source[start+end+2:]
+
+		case strings.HasPrefix(source[start:], "${{"):
+			end := closingDelimiter(source[start+3:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ${{ after %q", firstLine(source[start:]))
+			}
+			expression := strings.TrimSpace(source[start+3 : start+3+end])
+			if expression == "" {
+				return nil, fmt.Errorf("empty ${{ }} near line %d", line+1)
+			}
+			tokens = append(tokens, nunjucksToken{output: expression})
+			source = source[start+3+end+2:]
+
+		default:
+			end := closingDelimiter(source[start+2:], "%}")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated {%% after %q", firstLine(source[start:]))
+			}
+			content := source[start+2 : start+2+end]
+			source = source[start+2+end+2:]
+
+			if strings.HasPrefix(content, "-") {
+				content = content[1:]
+				trimPrevious()
+			}
+			if strings.HasSuffix(content, "-") {
+				content = content[:len(content)-1]
+				trimNext = true
+			}
+
+			content = strings.TrimSpace(content)
+			name, args, _ := strings.Cut(content, " ")
+			if name == "raw" {
+				loc := endRawPattern.FindStringIndex(source)
+				if loc == nil {
+					return nil, fmt.Errorf("{%% raw %%} without {%% endraw %%}")
+				}
+				emitText(source[:loc[0]])
+				trimNext = strings.HasSuffix(source[loc[0]:loc[1]], "-%}")
+				source = source[loc[1]:]
+				continue
+			}
+			tokens = append(tokens, nunjucksToken{tag: &nunjucksTag{name: name, args: strings.TrimSpace(args)}})
+		}
+	}
+}
+
+func indexOfAny(s string, substrings ...string) int {
+	index := -1
+	for _, substring := range substrings {
+		if i := strings.Index(s, substring); i >= 0 && (index < 0 || i < index) {
+			index = i
+		}
+	}
+	return index
+}
+
+// closingDelimiter returns the index of delimiter in s outside quoted
+// strings, or -1.
+func closingDelimiter(s, delimiter string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case strings.HasPrefix(s[i:], delimiter):
+			return i
+		}
+	}
+	return -1
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func parseNunjucks(source string) ([]nunjucksNode, error) {
+	tokens, err := lexNunjucks(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &nunjucksParser{tokens: tokens}
+	nodes, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		return nil, fmt.Errorf("unexpected {%% %s %%}", end.name)
+	}
+	return nodes, nil
+}
+
+type nunjucksParser struct {
+	tokens []nunjucksToken
+	pos    int
+}
+
+// parseBody parses nodes up to a closing or intermediate tag (endif, elif,
+// else, endfor), which it returns, or to the end of the template.
+func (p *nunjucksParser) parseBody() ([]nunjucksNode, *nunjucksTag, error) {
+	var nodes []nunjucksNode
+
+	for p.pos < len(p.tokens) {
+		token := p.tokens[p.pos]
+		p.pos++
+
+		switch {
+		case token.tag == nil && token.output == "":
+			nodes = append(nodes, textNode{text: token.text})
+
+		case token.output != "":
+			expr, err := parseNunjucksExpression(token.output)
+			if err != nil {
+				return nil, nil, fmt.Errorf("${{ %s }}: %w", token.output, err)
+			}
+			nodes = append(nodes, outputNode{expr: expr})
+
+		default:
+			switch token.tag.name {
+			case "if":
+				node, err := p.parseIf(token.tag)
+				if err != nil {
+					return nil, nil, err
+				}
+				nodes = append(nodes, node)
+			case "for":
+				node, err := p.parseFor(token.tag)
+				if err != nil {
+					return nil, nil, err
+				}
+				nodes = append(nodes, node)
+			case "set":
+				name, value, ok := strings.Cut(token.tag.args, "=")
+				if !ok || !isIdentifier(strings.TrimSpace(name)) {
+					return nil, nil, fmt.Errorf("{%% set %s %%}: expected name = expression", token.tag.args)
+				}
+				expr, err := parseNunjucksExpression(value)
+				if err != nil {
+					return nil, nil, fmt.Errorf("{%% set %s %%}: %w", token.tag.args, err)
+				}
+				nodes = append(nodes, setNode{name: strings.TrimSpace(name), expr: expr})
+			case "endif", "elif", "elseif", "else", "endfor":
+				return nodes, token.tag, nil
+			default:
+				return nil, nil, fmt.Errorf("unsupported tag {%% %s %%}", token.tag.name)
+			}
+		}
+	}
+	return nodes, nil, nil
+}
+
+func (p *nunjucksParser) parseIf(tag *nunjucksTag) (nunjucksNode, error) {
+	node := ifNode{}
+	for {
+		condition, err := parseNunjucksExpression(tag.args)
+		if err != nil {
+			return nil, fmt.Errorf("{%% %s %s %%}: %w", tag.name, tag.args, err)
+		}
+		body, end, err := p.parseBody()
+		if err != nil {
+			return nil, err
+		}
+		node.conditions = append(node.conditions, condition)
+		node.bodies = append(node.bodies, body)
+
+		if end == nil {
+			return nil, fmt.Errorf("{%% if %s %%} without {%% endif %%}", node.conditions[0])
+		}
+		switch end.name {
+		case "elif", "elseif":
+			tag = end
+			continue
+		case "else":
+			node.otherwise, end, err = p.parseBody()
+			if err != nil {
+				return nil, err
+			}
+			if end == nil || end.name != "endif" {
+				return nil, fmt.Errorf("{%% else %%} without {%% endif %%}")
+			}
+			return node, nil
+		case "endif":
+			return node, nil
+		default:
+			return nil, fmt.Errorf("unexpected {%% %s %%} in {%% if %%}", end.name)
+		}
+	}
+}
+
+func (p *nunjucksParser) parseFor(tag *nunjucksTag) (nunjucksNode, error) {
+	names, iterable, ok := strings.Cut(tag.args, " in ")
+	if !ok {
+		return nil, fmt.Errorf("{%% for %s %%}: expected names in expression", tag.args)
+	}
+
+	node := forNode{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("{%% for %s %%}: invalid loop variable %q", tag.args, name)
+		}
+		node.names = append(node.names, name)
+	}
+
+	var err error
+	if node.iterable, err = parseNunjucksExpression(iterable); err != nil {
+		return nil, fmt.Errorf("{%% for %s %%}: %w", tag.args, err)
+	}
+
+	body, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	node.body = body
+	if end != nil && end.name == "else" {
+		node.otherwise, end, err = p.parseBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if end == nil || end.name != "endfor" {
+		return nil, fmt.Errorf("{%% for %s %%} without {%% endfor %%}", tag.args)
+	}
+	return node, nil
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
+
+// -----------------------------------------------------------------------------
+// Expression parsing
+// -----------------------------------------------------------------------------
+
+type nunjucksExpr interface{}
+
+type literalExpr struct{ value interface{} }
+
+type nameExpr struct{ name string }
+
+type memberExpr struct {
+	object nunjucksExpr
+	key    nunjucksExpr
+}
+
+type unaryExpr struct {
+	op      string
+	operand nunjucksExpr
+}
+
+type binaryExpr struct {
+	op          string
+	left, right nunjucksExpr
+}
+
+type conditionalExpr struct {
+	condition, then, otherwise nunjucksExpr
+}
+
+type filterExpr struct {
+	name  string
+	input nunjucksExpr
+	args  []nunjucksExpr
+}
+
+type listExpr struct{ items []nunjucksExpr }
+
+type dictExpr struct {
+	keys   []string
+	values []nunjucksExpr
+}
+
+var nunjucksTokenPattern = regexp.MustCompile(`^(?:` +
+	`(?P<number>\d+(?:\.\d+)?)` +
+	`|(?P<string>'(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*")` +
+	`|(?P<name>[A-Za-z_$][\w$]*)` +
+	`|(?P<op>==|!=|<=|>=|\*\*|//|[-+*/%~<>()\[\]{}.,|:?=])` +
+	`)`)
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+func parseNunjucksExpression(source string) (nunjucksExpr, error) {
+	var tokens []string
+	rest := strings.TrimSpace(source)
+	for rest != "" {
+		match := nunjucksTokenPattern.FindString(rest)
+		if match == "" {
+			return nil, fmt.Errorf("unexpected %q", firstLine(rest))
+		}
+		tokens = append(tokens, match)
+		rest = strings.TrimLeftFunc(rest[len(match):], unicode.IsSpace)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	p := &exprParser{tokens: tokens}
+	expr, err := p.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) accept(token string) bool {
+	if p.peek() == token {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(token string) error {
+	if !p.accept(token) {
+		if p.peek() == "" {
+			return fmt.Errorf("expected %q at end of expression", token)
+		}
+		return fmt.Errorf("expected %q, got %q", token, p.peek())
+	}
+	return nil
+}
+
+func (p *exprParser) parseConditional() (nunjucksExpr, error) {
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("if") {
+		return expr, nil
+	}
+
+	condition, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	var otherwise nunjucksExpr = literalExpr{value: undefined{}}
+	if p.accept("else") {
+		if otherwise, err = p.parseConditional(); err != nil {
+			return nil, err
+		}
+	}
+	return conditionalExpr{condition: condition, then: expr, otherwise: otherwise}, nil
+}
+
+func (p *exprParser) parseOr() (nunjucksExpr, error) {
+	return p.parseBinary([]string{"or"}, p.parseAnd)
+}
+
+func (p *exprParser) parseAnd() (nunjucksExpr, error) {
+	return p.parseBinary([]string{"and"}, p.parseNot)
+}
+
+func (p *exprParser) parseNot() (nunjucksExpr, error) {
+	if p.accept("not") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op: "not", operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (nunjucksExpr, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		switch op {
+		case "==", "!=", "<", ">", "<=", ">=", "in":
+			p.pos++
+		case "not":
+			if p.pos+1 >= len(p.tokens) || p.tokens[p.pos+1] != "in" {
+				return left, nil
+			}
+			p.pos += 2
+			op = "not in"
+		default:
+			return left, nil
+		}
+		right, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseConcat() (nunjucksExpr, error) {
+	return p.parseBinary([]string{"~"}, p.parseAdd)
+}
+
+func (p *exprParser) parseAdd() (nunjucksExpr, error) {
+	return p.parseBinary([]string{"+", "-"}, p.parseMul)
+}
+
+func (p *exprParser) parseMul() (nunjucksExpr, error) {
+	return p.parseBinary([]string{"*", "/", "//", "%", "**"}, p.parseUnary)
+}
+
+func (p *exprParser) parseBinary(ops []string, next func() (nunjucksExpr, error)) (nunjucksExpr, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for containsString(ops, p.peek()) {
+		op := p.tokens[p.pos]
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (nunjucksExpr, error) {
+	if p.accept("-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op: "-", operand: operand}, nil
+	}
+	return p.parseFilters()
+}
+
+func (p *exprParser) parseFilters() (nunjucksExpr, error) {
+	expr, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("|") {
+		name := p.peek()
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("expected a filter name after |, got %q", name)
+		}
+		p.pos++
+
+		filter := filterExpr{name: name, input: expr}
+		if p.accept("(") {
+			if filter.args, err = p.parseList(")"); err != nil {
+				return nil, err
+			}
+		}
+		expr = filter
+	}
+	return expr, nil
+}
+
+func (p *exprParser) parsePostfix() (nunjucksExpr, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			name := p.peek()
+			if !isIdentifier(name) {
+				return nil, fmt.Errorf("expected a member name after ., got %q", name)
+			}
+			p.pos++
+			expr = memberExpr{object: expr, key: literalExpr{value: name}}
+		case p.accept("["):
+			key, err := p.parseConditional()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			expr = memberExpr{object: expr, key: key}
+		case p.peek() == "(":
+			return nil, fmt.Errorf("function calls are not supported")
+		default:
+			return expr, nil
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary() (nunjucksExpr, error) {
+	token := p.peek()
+	if token == "" {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	switch {
+	case token == "(":
+		expr, err := p.parseConditional()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	case token == "[":
+		items, err := p.parseList("]")
+		if err != nil {
+			return nil, err
+		}
+		return listExpr{items: items}, nil
+	case token == "{":
+		return p.parseDict()
+	case token[0] == '\'' || token[0] == '"':
+		return literalExpr{value: unquote(token)}, nil
+	case token[0] >= '0' && token[0] <= '9':
+		if strings.Contains(token, ".") {
+			value, _ := strconv.ParseFloat(token, 64)
+			return literalExpr{value: value}, nil
+		}
+		value, _ := strconv.Atoi(token)
+		return literalExpr{value: value}, nil
+	}
+
+	switch token {
+	case "true", "True":
+		return literalExpr{value: true}, nil
+	case "false", "False":
+		return literalExpr{value: false}, nil
+	case "none", "None", "null":
+		return literalExpr{value: nil}, nil
+	}
+	if isIdentifier(token) {
+		return nameExpr{name: token}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", token)
+}
+
+func (p *exprParser) parseList(closing string) ([]nunjucksExpr, error) {
+	var items []nunjucksExpr
+	for !p.accept(closing) {
+		if len(items) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			if p.accept(closing) {
+				break
+			}
+		}
+		item, err := p.parseConditional()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (p *exprParser) parseDict() (nunjucksExpr, error) {
+	dict := dictExpr{}
+	for !p.accept("}") {
+		if len(dict.keys) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		key := p.peek()
+		p.pos++
+		if key != "" && (key[0] == '\'' || key[0] == '"') {
+			key = unquote(key)
+		} else if !isIdentifier(key) {
+			return nil, fmt.Errorf("expected a dict key, got %q", key)
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseConditional()
+		if err != nil {
+			return nil, err
+		}
+		dict.keys = append(dict.keys, key)
+		dict.values = append(dict.values, value)
+	}
+	return dict, nil
+}
+
+func unquote(token string) string {
+	body := token[1 : len(token)-1]
+	replacer := strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\\`, `\`, `\'`, `'`, `\"`, `"`)
+	return replacer.Replace(body)
+}
+
+// -----------------------------------------------------------------------------
+// Evaluation
+// -----------------------------------------------------------------------------
+
+type nunjucksRenderer struct {
+	scopes    []map[string]interface{}
+	undefined map[string]bool
+}
+
+func (r *nunjucksRenderer) recordUndefined(u undefined) {
+	if u.path == "" {
+		return
+	}
+	if r.undefined == nil {
+		r.undefined = map[string]bool{}
+	}
+	r.undefined[u.path] = true
+}
+
+func (r *nunjucksRenderer) undefinedReferences() []string {
+	var references []string
+	for reference := range r.undefined {
+		references = append(references, reference)
+	}
+	sort.Strings(references)
+	return references
+}
+
+func (r *nunjucksRenderer) render(nodes []nunjucksNode, out *strings.Builder) error {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case textNode:
+			out.WriteString(n.text)
+
+		case outputNode:
+			value, err := r.eval(n.expr)
+			if err != nil {
+				return err
+			}
+			if u, ok := value.(undefined); ok {
+				r.recordUndefined(u)
+			}
+			out.WriteString(nunjucksString(value))
+
+		case setNode:
+			value, err := r.eval(n.expr)
+			if err != nil {
+				return err
+			}
+			r.scopes[len(r.scopes)-1][n.name] = value
+
+		case ifNode:
+			body := n.otherwise
+			for i, condition := range n.conditions {
+				value, err := r.eval(condition)
+				if err != nil {
+					return err
+				}
+				if u, ok := value.(undefined); ok {
+					r.recordUndefined(u)
+				}
+				if truthy(value) {
+					body = n.bodies[i]
+					break
+				}
+			}
+			if err := r.render(body, out); err != nil {
+				return err
+			}
+
+		case forNode:
+			if err := r.renderFor(n, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *nunjucksRenderer) renderFor(n forNode, out *strings.Builder) error {
+	iterable, err := r.eval(n.iterable)
+	if err != nil {
+		return err
+	}
+	if u, ok := iterable.(undefined); ok {
+		r.recordUndefined(u)
+	}
+
+	var iterations []map[string]interface{}
+	switch v := iterable.(type) {
+	case []interface{}:
+		for i, item := range v {
+			scope := map[string]interface{}{}
+			if len(n.names) == 1 {
+				scope[n.names[0]] = item
+			} else if tuple, ok := item.([]interface{}); ok {
+				for j, name := range n.names {
+					if j < len(tuple) {
+						scope[name] = tuple[j]
+					}
+				}
+			}
+			scope["loop"] = loopVariable(i, len(v))
+			iterations = append(iterations, scope)
+		}
+	case map[string]interface{}:
+		keys := sortedSchemaKeys(v)
+		for i, key := range keys {
+			scope := map[string]interface{}{n.names[0]: key}
+			if len(n.names) > 1 {
+				scope[n.names[1]] = v[key]
+			}
+			scope["loop"] = loopVariable(i, len(keys))
+			iterations = append(iterations, scope)
+		}
+	case undefined, nil:
+	default:
+		return fmt.Errorf("{%% for %%}: cannot iterate over %T", iterable)
+	}
+
+	if len(iterations) == 0 {
+		return r.render(n.otherwise, out)
+	}
+	for _, scope := range iterations {
+		r.scopes = append(r.scopes, scope)
+		err := r.render(n.body, out)
+		r.scopes = r.scopes[:len(r.scopes)-1]
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loopVariable(index, length int) map[string]interface{} {
+	return map[string]interface{}{
+		"index":  index + 1,
+		"index0": index,
+		"first":  index == 0,
+		"last":   index == length-1,
+		"length": length,
+	}
+}
+
+func (r *nunjucksRenderer) eval(expr nunjucksExpr) (interface{}, error) {
+	switch e := expr.(type) {
+	case literalExpr:
+		return e.value, nil
+
+	case nameExpr:
+		for i := len(r.scopes) - 1; i >= 0; i-- {
+			if value, ok := r.scopes[i][e.name]; ok {
+				return value, nil
+			}
+		}
+		return undefined{path: e.name}, nil
+
+	case memberExpr:
+		object, err := r.eval(e.object)
+		if err != nil {
+			return nil, err
+		}
+		key, err := r.eval(e.key)
+		if err != nil {
+			return nil, err
+		}
+		return member(object, key, referencePath(e.object)), nil
+
+	case unaryExpr:
+		operand, err := r.eval(e.operand)
+		if err != nil {
+			return nil, err
+		}
+		if e.op == "not" {
+			if u, ok := operand.(undefined); ok {
+				r.recordUndefined(u)
+			}
+			return !truthy(operand), nil
+		}
+		number, ok := toNumber(operand)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %s", nunjucksString(operand))
+		}
+		return normalizeNumber(-number), nil
+
+	case binaryExpr:
+		return r.evalBinary(e)
+
+	case conditionalExpr:
+		condition, err := r.eval(e.condition)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(condition) {
+			return r.eval(e.then)
+		}
+		return r.eval(e.otherwise)
+
+	case filterExpr:
+		input, err := r.eval(e.input)
+		if err != nil {
+			return nil, err
+		}
+		args := make([]interface{}, len(e.args))
+		for i, arg := range e.args {
+			if args[i], err = r.eval(arg); err != nil {
+				return nil, err
+			}
+		}
+
+		filter, ok := nunjucksFilters[e.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter %q", e.name)
+		}
+		if u, ok := input.(undefined); ok && e.name != "default" && e.name != "d" {
+			r.recordUndefined(u)
+		}
+		return filter(input, args)
+
+	case listExpr:
+		items := make([]interface{}, len(e.items))
+		for i, item := range e.items {
+			value, err := r.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+
+	case dictExpr:
+		dict := map[string]interface{}{}
+		for i, key := range e.keys {
+			value, err := r.eval(e.values[i])
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = value
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("unknown expression %T", expr)
+}
+
+func (r *nunjucksRenderer) evalBinary(e binaryExpr) (interface{}, error) {
+	left, err := r.eval(e.left)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "and":
+		if !truthy(left) {
+			return left, nil
+		}
+		return r.eval(e.right)
+	case "or":
+		if truthy(left) {
+			return left, nil
+		}
+		return r.eval(e.right)
+	}
+
+	right, err := r.eval(e.right)
+	if err != nil {
+		return nil, err
+	}
+	for _, operand := range []interface{}{left, right} {
+		if u, ok := operand.(undefined); ok {
+			r.recordUndefined(u)
+		}
+	}
+
+	switch e.op {
+	case "==":
+		return looseEqual(left, right), nil
+	case "!=":
+		return !looseEqual(left, right), nil
+	case "in", "not in":
+		found := contains(right, left)
+		return found == (e.op == "in"), nil
+	case "~":
+		return nunjucksString(left) + nunjucksString(right), nil
+	}
+
+	leftNumber, leftOK := toNumber(left)
+	rightNumber, rightOK := toNumber(right)
+	if e.op == "+" && (!leftOK || !rightOK) {
+		return nunjucksString(left) + nunjucksString(right), nil
+	}
+	if !leftOK || !rightOK {
+		if ls, ok := left.(string); ok {
+			if rs, ok := right.(string); ok {
+				switch e.op {
+				case "<":
+					return ls < rs, nil
+				case ">":
+					return ls > rs, nil
+				case "<=":
+					return ls <= rs, nil
+				case ">=":
+					return ls >= rs, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("%s %s %s: operands are not numbers", nunjucksString(left), e.op, nunjucksString(right))
+	}
+
+	switch e.op {
+	case "<":
+		return leftNumber < rightNumber, nil
+	case ">":
+		return leftNumber > rightNumber, nil
+	case "<=":
+		return leftNumber <= rightNumber, nil
+	case ">=":
+		return leftNumber >= rightNumber, nil
+	case "+":
+		return normalizeNumber(leftNumber + rightNumber), nil
+	case "-":
+		return normalizeNumber(leftNumber - rightNumber), nil
+	case "*":
+		return normalizeNumber(leftNumber * rightNumber), nil
+	case "/":
+		return normalizeNumber(leftNumber / rightNumber), nil
+	case "//":
+		return normalizeNumber(math.Floor(leftNumber / rightNumber)), nil
+	case "%":
+		return normalizeNumber(math.Mod(leftNumber, rightNumber)), nil
+	case "**":
+		return normalizeNumber(math.Pow(leftNumber, rightNumber)), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", e.op)
+}
+
+// referencePath returns the dotted path of a name or member expression,
+// such as values.name, for undefined references.
+func referencePath(expr nunjucksExpr) string {
+	switch e := expr.(type) {
+	case nameExpr:
+		return e.name
+	case memberExpr:
+		if key, ok := e.key.(literalExpr); ok {
+			if name, ok := key.value.(string); ok && isIdentifier(name) {
+				return referencePath(e.object) + "." + name
+			}
+			return fmt.Sprintf("%s[%s]", referencePath(e.object), nunjucksString(key.value))
+		}
+		return referencePath(e.object) + "[?]"
+	}
+	return "?"
+}
+
+// member returns object[key]. where is the path of object, used when
+// object is defined but has no such key.
+func member(object, key interface{}, where string) interface{} {
+	path := func() string {
+		prefix := where
+		if u, ok := object.(undefined); ok {
+			prefix = u.path
+		}
+		if name, ok := key.(string); ok && isIdentifier(name) {
+			return prefix + "." + name
+		}
+		return fmt.Sprintf("%s[%s]", prefix, nunjucksString(key))
+	}
+
+	switch o := object.(type) {
+	case map[string]interface{}:
+		if value, ok := o[nunjucksString(key)]; ok {
+			return value
+		}
+	case []interface{}:
+		if index, ok := toNumber(key); ok && index >= 0 && int(index) < len(o) {
+			return o[int(index)]
+		}
+		if key == "length" {
+			return len(o)
+		}
+	case string:
+		if key == "length" {
+			return len(o)
+		}
+	case undefined:
+		if o.path == "" {
+			return o
+		}
+	}
+	return undefined{path: path()}
+}
+
+// nunjucksString formats a value the way JavaScript prints it.
+func nunjucksString(value interface{}) string {
+	switch v := value.(type) {
+	case nil, undefined:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = nunjucksString(item)
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		return "[object Object]"
+	}
+	return fmt.Sprint(value)
+}
+
+// truthy is JavaScript truthiness.
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil, undefined:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case int, int64, uint64, float64:
+		number, _ := toNumber(v)
+		return number != 0 && !math.IsNaN(number)
+	}
+	return true
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// normalizeNumber returns whole numbers as int so they print without a
+// decimal point.
+func normalizeNumber(number float64) interface{} {
+	if number == math.Trunc(number) && math.Abs(number) < 1<<53 {
+		return int(number)
+	}
+	return number
+}
+
+func looseEqual(left, right interface{}) bool {
+	if leftNumber, ok := toNumber(left); ok {
+		rightNumber, ok := toNumber(right)
+		return ok && leftNumber == rightNumber
+	}
+	_, leftUndefined := left.(undefined)
+	_, rightUndefined := right.(undefined)
+	if leftUndefined || rightUndefined || left == nil || right == nil {
+		return (leftUndefined || left == nil) && (rightUndefined || right == nil)
+	}
+	switch left.(type) {
+	case string, bool:
+		return left == right
+	}
+	return false
+}
+
+func contains(container, item interface{}) bool {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, element := range c {
+			if looseEqual(element, item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		_, ok := c[nunjucksString(item)]
+		return ok
+	case string:
+		return strings.Contains(c, nunjucksString(item))
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+// Filters
+// -----------------------------------------------------------------------------
+
+type nunjucksFilter func(input interface{}, args []interface{}) (interface{}, error)
+
+// nunjucksFilters are the Nunjucks built-ins the templates use and the
+// filters the scaffolder adds.
+var nunjucksFilters = map[string]nunjucksFilter{
+	"default": defaultFilter,
+	"d":       defaultFilter,
+	"dump": func(input interface{}, args []interface{}) (interface{}, error) {
+		if _, ok := input.(undefined); ok {
+			return undefined{}, nil
+		}
+		data, err := json.Marshal(input)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	},
+	"lower": stringFilter(strings.ToLower),
+	"upper": stringFilter(strings.ToUpper),
+	"trim":  stringFilter(strings.TrimSpace),
+	"capitalize": stringFilter(func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+	}),
+	"title": stringFilter(func(s string) string {
+		words := strings.Fields(s)
+		for i, word := range words {
+			words[i] = strings.ToUpper(word[:1]) + strings.ToLower(word[1:])
+		}
+		return strings.Join(words, " ")
+	}),
+	"replace": func(input interface{}, args []interface{}) (interface{}, error) {
+		if len(args) < 2 {
+			return nil, fmt.Errorf("replace needs a search string and a replacement")
+		}
+		return strings.ReplaceAll(nunjucksString(input), nunjucksString(args[0]), nunjucksString(args[1])), nil
+	},
+	"join": func(input interface{}, args []interface{}) (interface{}, error) {
+		items, _ := input.([]interface{})
+		separator := ""
+		if len(args) > 0 {
+			separator = nunjucksString(args[0])
+		}
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = nunjucksString(item)
+		}
+		return strings.Join(parts, separator), nil
+	},
+	"length": func(input interface{}, args []interface{}) (interface{}, error) {
+		switch v := input.(type) {
+		case []interface{}:
+			return len(v), nil
+		case map[string]interface{}:
+			return len(v), nil
+		case string:
+			return len(v), nil
+		}
+		return 0, nil
+	},
+	"first": func(input interface{}, args []interface{}) (interface{}, error) {
+		if items, ok := input.([]interface{}); ok && len(items) > 0 {
+			return items[0], nil
+		}
+		return undefined{}, nil
+	},
+	"last": func(input interface{}, args []interface{}) (interface{}, error) {
+		if items, ok := input.([]interface{}); ok && len(items) > 0 {
+			return items[len(items)-1], nil
+		}
+		return undefined{}, nil
+	},
+	"string": func(input interface{}, args []interface{}) (interface{}, error) {
+		return nunjucksString(input), nil
+	},
+	"int": func(input interface{}, args []interface{}) (interface{}, error) {
+		if number, ok := toNumber(input); ok {
+			return int(number), nil
+		}
+		number, err := strconv.ParseFloat(nunjucksString(input), 64)
+		if err != nil {
+			return 0, nil
+		}
+		return int(number), nil
+	},
+	"parseRepoUrl": func(input interface{}, args []interface{}) (interface{}, error) {
+		return parseRepoURL(input)
+	},
+	"pick": func(input interface{}, args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("pick needs a key")
+		}
+		return member(input, args[0], "pick"), nil
+	},
+	"projectSlug": func(input interface{}, args []interface{}) (interface{}, error) {
+		repo, err := parseRepoURL(input)
+		if err != nil {
+			return nil, err
+		}
+		owner := repo["owner"]
+		if owner == nil {
+			owner = repo["organization"]
+		}
+		return fmt.Sprintf("%s/%s", owner, repo["repo"]), nil
+	},
+}
+
+// parseRepoURL splits a RepoUrlPicker value such as
+// github.com?owner=org&repo=name into host, owner and repo.
+func parseRepoURL(input interface{}) (map[string]interface{}, error) {
+	raw := nunjucksString(input)
+	parsed, err := url.Parse("https://" + raw)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("parseRepoUrl: invalid repo URL %q", raw)
+	}
+
+	query := parsed.Query()
+	if query.Get("repo") == "" || (query.Get("owner") == "" && query.Get("organization") == "") {
+		return nil, fmt.Errorf("parseRepoUrl: %q has no owner and repo", raw)
+	}
+
+	repo := map[string]interface{}{"host": parsed.Host, "repo": query.Get("repo")}
+	for _, key := range []string{"owner", "organization", "workspace", "project"} {
+		if value := query.Get(key); value != "" {
+			repo[key] = value
+		}
+	}
+	return repo, nil
+}
+
+func defaultFilter(input interface{}, args []interface{}) (interface{}, error) {
+	_, isUndefined := input.(undefined)
+	if len(args) == 0 {
+		return input, nil
+	}
+	// default(value, true) also replaces falsy values.
+	if isUndefined || (len(args) > 1 && truthy(args[1]) && !truthy(input)) {
+		return args[0], nil
+	}
+	return input, nil
+}
+
+func stringFilter(transform func(string) string) nunjucksFilter {
+	return func(input interface{}, args []interface{}) (interface{}, error) {
+		if u, ok := input.(undefined); ok {
+			return u, nil
+		}
+		return transform(nunjucksString(input)), nil
+	}
+}
@@ -0,0 +1,111 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - NUNJUCKS TEMPLATE SUBSET TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestRenderNunjucks|TestEvaluateNunjucks' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nunjucksTestContext() map[string]interface{} {
+	return map[string]interface{}{
+		"values": map[string]interface{}{
+			"name":         "orders-api",
+			"description":  `Orders "v2"`,
+			"enableRAG":    false,
+			"replicas":     3,
+			"environments": []interface{}{"dev", "staging", "prod"},
+			"repoUrl":      "github.com?owner=three-horizons&repo=orders-api",
+			"labels":       map[string]interface{}{"tier": "backend"},
+		},
+	}
+}
+
+// TestRenderNunjucks tests placeholders, tags and whitespace control
+func TestRenderNunjucks(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		source    string
+		expected  string
+		undefined []string
+	}{
+		{"placeholder", "name: ${{ values.name }}", "name: orders-api", nil},
+		{"no spaces", "${{values.name}}-svc", "orders-api-svc", nil},
+		{"dump", "description: ${{ values.description | dump }}", `description: "Orders \"v2\""`, nil},
+		{"array prints joined", "${{ values.environments }}", "dev,staging,prod", nil},
+		{"index and member", "${{ values.environments[1] }} ${{ values['labels'].tier }}", "staging backend", nil},
+		{"filters", "${{ values.name | upper | replace('-', '_') }}", "ORDERS_API", nil},
+		{"join and length", "${{ values.environments | join(' ') }} (${{ values.environments | length }})", "dev staging prod (3)", nil},
+		{"default", "${{ values.system | default('platform') }}", "platform", nil},
+		{"parseRepoUrl", "${{ values.repoUrl | parseRepoUrl | pick('owner') }}/${{ values.repoUrl | projectSlug }}", "three-horizons/three-horizons/orders-api", nil},
+		{"arithmetic", "${{ values.replicas * 2 + 1 }} ${{ 7 // 2 }} ${{ 'v' ~ values.replicas }}", "7 3 v3", nil},
+		{"inline if", "${{ 'ai' if values.enableRAG else 'plain' }}", "plain", nil},
+		{"if elif else", "{% if values.enableRAG %}rag{% elif values.replicas > 2 %}ha{% else %}single{% endif %}", "ha", nil},
+		{"in", "{% if 'prod' in values.environments and not ('qa' in values.environments) %}yes{% endif %}", "yes", nil},
+		{"for with loop", "{% for env in values.environments %}${{ loop.index }}:${{ env }}{% if not loop.last %},{% endif %}{% endfor %}", "1:dev,2:staging,3:prod", nil},
+		{"for else", "{% for env in values.missing %}${{ env }}{% else %}none{% endfor %}", "none", []string{"values.missing"}},
+		{"set", "{% set image = 'acr/' ~ values.name %}${{ image }}", "acr/orders-api", nil},
+		{"trim", "a\n  {%- if true -%}\n  b\n{%- endif %}", "ab", nil},
+		{"comment", "a{# ${{ values.nope }} #}b", "ab", nil},
+		{"raw", "{% raw %}${{ github.sha }}{% endraw %}", "${{ github.sha }}", nil},
+		{"github expression is undefined", "${{ github.sha }}", "", []string{"github.sha"}},
+		{"undefined prints nothing", "[${{ values.system }}]", "[]", []string{"values.system"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			output, undefined, err := RenderNunjucks(tc.source, nunjucksTestContext())
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, output)
+			assert.Equal(t, tc.undefined, undefined)
+		})
+	}
+}
+
+// TestRenderNunjucksErrors tests that malformed templates are reported
+func TestRenderNunjucksErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, source := range []string{
+		"${{ values.name ",
+		"{% if values.name %}open",
+		"{% endif %}",
+		"{% for values.name %}{% endfor %}",
+		"${{ values.name | nosuchfilter }}",
+		"${{ values.name + }}",
+	} {
+		_, _, err := RenderNunjucks(source, nunjucksTestContext())
+		assert.Error(t, err, source)
+	}
+}
+
+// TestEvaluateNunjucks tests that an expression keeps its type
+func TestEvaluateNunjucks(t *testing.T) {
+	t.Parallel()
+
+	value, undefined, err := EvaluateNunjucks("values.environments", nunjucksTestContext())
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"dev", "staging", "prod"}, value)
+	assert.Empty(t, undefined)
+
+	value, _, err = EvaluateNunjucks("values.enableRAG or values.replicas > 2", nunjucksTestContext())
+	require.NoError(t, err)
+	assert.Equal(t, true, value)
+
+	_, undefined, err = EvaluateNunjucks("values.name and steps['publish'].output.remoteUrl", nunjucksTestContext())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"steps.publish.output.remoteUrl"}, undefined)
+}
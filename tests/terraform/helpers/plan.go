@@ -0,0 +1,347 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PLAN ASSERTIONS
+// =============================================================================
+//
+// Assertions on the structured plan (`terraform show -json`) instead of
+// substring matches on plan text:
+//
+//	plan := helpers.InitAndPlan(t, terraformOptions)
+//	plan.AssertCreated(t, "azurerm_kubernetes_cluster.main").
+//	    HasAttribute("sku_tier", "Standard").
+//	    HasAttribute("oidc_issuer_enabled", true)
+//	plan.AssertResourceCount(t, "azurerm_container_registry_replication", 2)
+//	plan.AssertAbsent(t, "azurerm_bastion_host.main")
+//	plan.AssertCreated(t, "helm_release.argocd").
+//	    HasHelmValue("controller.replicas", 3)
+//
+// Addresses may omit the instance key: "azurerm_bastion_host.main" matches
+// "azurerm_bastion_host.main[0]" as long as there is a single instance.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// Plan is a parsed Terraform plan with assertion helpers.
+type Plan struct {
+	*terraform.PlanStruct
+}
+
+// InitAndPlan runs terraform init, plan and show -json and returns the parsed
+// plan. The plan file goes to a per-test temp dir; options is not modified.
+// With TERRATEST_MOCK_PROVIDERS=true the plan is produced with mock
+// providers instead (see mock.go). The plan is checked against
+// policies/terraform (see policy.go) and the Azure naming rules (see
+// naming_rules.go).
+func InitAndPlan(t testing.TB, options *terraform.Options) *Plan {
+	t.Helper()
+
+	plan, err := InitAndPlanE(t, options)
+	require.NoError(t, err)
+
+	plan.AssertPolicy(t)
+	plan.AssertNaming(t)
+	return plan
+}
+
+// InitAndPlanE is like InitAndPlan but returns the error instead of failing,
+// for tests that expect the plan to be rejected.
+func InitAndPlanE(t testing.TB, options *terraform.Options) (*Plan, error) {
+	t.Helper()
+
+	if MockProvidersEnabled() {
+		return InitAndPlanWithMocks(t, options)
+	}
+
+	planOptions := *options
+	planOptions.PlanFilePath = filepath.Join(t.TempDir(), "plan.out")
+
+	planStruct, err := terraform.InitAndPlanAndShowWithStructE(t, &planOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{PlanStruct: planStruct}, nil
+}
+
+// PlanE runs terraform plan and show -json in a TerraformDir that is
+// already initialized, for tests that plan the same module many times with
+// different Vars, such as fuzz targets. Mock providers are not used and the
+// plan is not checked against policy or naming rules.
+func PlanE(t testing.TB, options *terraform.Options) (*Plan, error) {
+	t.Helper()
+
+	planOptions := *options
+	planOptions.PlanFilePath = filepath.Join(t.TempDir(), "plan.out")
+
+	if _, err := terraform.PlanE(t, &planOptions); err != nil {
+		return nil, err
+	}
+	planStruct, err := terraform.ShowWithStructE(t, &planOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{PlanStruct: planStruct}, nil
+}
+
+// AssertPlanError asserts a plan was rejected with message. Terraform wraps
+// diagnostics and prefixes their lines with "│", so both are collapsed to
+// single spaces before matching.
+func AssertPlanError(t testing.TB, err error, message string) {
+	t.Helper()
+
+	if !assert.Error(t, err, "Expected the plan to fail with %q", message) {
+		return
+	}
+	assert.Contains(t, collapseDiagnostic(err.Error()), collapseDiagnostic(message))
+}
+
+func collapseDiagnostic(text string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(text, "│", " ")), " ")
+}
+
+// Outputs returns the planned values of the root module outputs. An output
+// known only after apply has a nil value.
+func (p *Plan) Outputs() map[string]interface{} {
+	outputs := map[string]interface{}{}
+	if p.RawPlan.PlannedValues == nil {
+		return outputs
+	}
+	for name, output := range p.RawPlan.PlannedValues.Outputs {
+		outputs[name] = output.Value
+	}
+	return outputs
+}
+
+// Instances returns the managed resource changes matching address, sorted by
+// address. Pure deletes are skipped; they are not part of the planned state.
+//
+// An address with a dot ("azurerm_subnet.aks") matches that resource and all
+// of its count/for_each instances. An address without a dot
+// ("azurerm_subnet") matches every resource of that type.
+func (p *Plan) Instances(address string) []*tfjson.ResourceChange {
+	var matches []*tfjson.ResourceChange
+
+	for _, change := range p.RawPlan.ResourceChanges {
+		if change.Mode != tfjson.ManagedResourceMode || change.Change == nil {
+			continue
+		}
+		if change.Change.Actions.Delete() {
+			continue
+		}
+		if matchesAddress(change, address) {
+			matches = append(matches, change)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Address < matches[j].Address })
+	return matches
+}
+
+func matchesAddress(change *tfjson.ResourceChange, address string) bool {
+	if !strings.Contains(address, ".") {
+		return change.Type == address
+	}
+
+	if change.Address == address {
+		return true
+	}
+
+	// azurerm_subnet.aks matches azurerm_subnet.aks[0] and azurerm_subnet.aks["x"]
+	return strings.HasPrefix(change.Address, address+"[")
+}
+
+// AssertResourceCount asserts how many instances of a resource type or
+// address the plan will leave in place after apply.
+func (p *Plan) AssertResourceCount(t testing.TB, address string, expected int) {
+	t.Helper()
+
+	instances := p.Instances(address)
+	assert.Len(t, instances, expected, "Unexpected number of %s instances: %s", address, addresses(instances))
+}
+
+// AssertAbsent asserts the plan has no instance of a resource type or address.
+func (p *Plan) AssertAbsent(t testing.TB, address string) {
+	t.Helper()
+
+	instances := p.Instances(address)
+	assert.Empty(t, instances, "Expected no %s in plan, found: %s", address, addresses(instances))
+}
+
+// AssertCreated asserts that exactly one instance matching address will be
+// created and returns it for attribute assertions.
+func (p *Plan) AssertCreated(t testing.TB, address string) *ResourceAssertion {
+	t.Helper()
+
+	return p.AssertAction(t, address, tfjson.ActionCreate)
+}
+
+// AssertAction asserts that exactly one instance matching address is planned
+// with the given action and returns it for attribute assertions.
+func (p *Plan) AssertAction(t testing.TB, address string, action tfjson.Action) *ResourceAssertion {
+	t.Helper()
+
+	instances := p.Instances(address)
+	require.Len(t, instances, 1, "Expected a single %s in plan, found: %s", address, addresses(instances))
+
+	change := instances[0]
+	assert.Contains(t, change.Change.Actions, action, "Unexpected actions for %s", change.Address)
+
+	after, _ := change.Change.After.(map[string]interface{})
+	unknown, _ := change.Change.AfterUnknown.(map[string]interface{})
+
+	return &ResourceAssertion{t: t, address: change.Address, after: after, unknown: unknown}
+}
+
+func addresses(changes []*tfjson.ResourceChange) string {
+	if len(changes) == 0 {
+		return "none"
+	}
+
+	names := make([]string, len(changes))
+	for i, change := range changes {
+		names[i] = change.Address
+	}
+	return strings.Join(names, ", ")
+}
+
+// ResourceAssertion asserts on the planned attribute values of a single
+// resource instance.
+type ResourceAssertion struct {
+	t       testing.TB
+	address string
+	after   map[string]interface{}
+	unknown map[string]interface{}
+}
+
+// Attribute returns the planned value at path. Paths use dots for object
+// keys and list indexes, e.g. "default_node_pool.0.vm_size". The second
+// result is false when the attribute is missing or only known after apply.
+func (r *ResourceAssertion) Attribute(path string) (interface{}, bool) {
+	if unknown, _ := lookupPath(r.unknown, path); unknown == true {
+		return nil, false
+	}
+	return lookupPath(r.after, path)
+}
+
+// HasAttribute asserts the planned value at path equals expected. Expected
+// values are compared after a JSON round trip, so 3 matches 3.0 and
+// []string matches []interface{}.
+func (r *ResourceAssertion) HasAttribute(path string, expected interface{}) *ResourceAssertion {
+	r.t.Helper()
+
+	actual, ok := r.Attribute(path)
+	if !assert.True(r.t, ok, "%s: attribute %q is missing or known only after apply", r.address, path) {
+		return r
+	}
+
+	assert.Equal(r.t, normalizeJSON(r.t, expected), actual, "%s: unexpected value for %q", r.address, path)
+	return r
+}
+
+// HasAttributeKnownAfterApply asserts the value at path is computed on apply.
+func (r *ResourceAssertion) HasAttributeKnownAfterApply(path string) *ResourceAssertion {
+	r.t.Helper()
+
+	value, _ := lookupPath(r.unknown, path)
+	assert.Equal(r.t, true, value, "%s: attribute %q is not known-after-apply", r.address, path)
+	return r
+}
+
+// HasHelmValue asserts the value at path of a helm_release's rendered
+// values, e.g. "controller.replicas". The values entries are decoded as
+// YAML and merged in order, the way helm merges repeated -f files.
+func (r *ResourceAssertion) HasHelmValue(path string, expected interface{}) *ResourceAssertion {
+	r.t.Helper()
+
+	values, ok := r.Attribute("values")
+	if !assert.True(r.t, ok, "%s: values are missing or known only after apply", r.address) {
+		return r
+	}
+
+	entries, _ := values.([]interface{})
+	merged := map[string]interface{}{}
+	for i, entry := range entries {
+		text, ok := entry.(string)
+		if !assert.True(r.t, ok, "%s: values[%d] is not a string", r.address, i) {
+			return r
+		}
+
+		var document map[string]interface{}
+		if !assert.NoError(r.t, yaml.Unmarshal([]byte(text), &document), "%s: values[%d]", r.address, i) {
+			return r
+		}
+		mergeHelmValues(merged, document)
+	}
+
+	actual, ok := lookupPath(normalizeJSON(r.t, merged), path)
+	if !assert.True(r.t, ok, "%s: helm value %q is not set", r.address, path) {
+		return r
+	}
+
+	assert.Equal(r.t, normalizeJSON(r.t, expected), actual, "%s: unexpected helm value for %q", r.address, path)
+	return r
+}
+
+// mergeHelmValues merges src into dst. Nested maps merge key by key; any
+// other value replaces the one in dst.
+func mergeHelmValues(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeHelmValues(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+func lookupPath(root interface{}, path string) (interface{}, bool) {
+	current := root
+
+	for _, part := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func normalizeJSON(t testing.TB, value interface{}) interface{} {
+	t.Helper()
+
+	raw, err := json.Marshal(value)
+	require.NoError(t, err)
+
+	var normalized interface{}
+	require.NoError(t, json.Unmarshal(raw, &normalized))
+	return normalized
+}
@@ -0,0 +1,108 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PLAN ASSERTION TESTS
+// =============================================================================
+//
+// Run with: go test -v -run TestPlan ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadPlanFixture(t *testing.T) *Plan {
+	t.Helper()
+
+	raw, err := os.ReadFile("testdata/plan.json")
+	require.NoError(t, err)
+
+	planStruct, err := terraform.ParsePlanJSON(string(raw))
+	require.NoError(t, err)
+	return &Plan{PlanStruct: planStruct}
+}
+
+// TestPlanAssertCreated tests attribute assertions on a created resource
+func TestPlanAssertCreated(t *testing.T) {
+	t.Parallel()
+
+	plan := loadPlanFixture(t)
+
+	aks := plan.AssertCreated(t, "azurerm_kubernetes_cluster.main").
+		HasAttribute("sku_tier", "Standard").
+		HasAttribute("oidc_issuer_enabled", true).
+		HasAttribute("default_node_pool.0.node_count", 3).
+		HasAttributeKnownAfterApply("oidc_issuer_url")
+
+	vmSize, ok := aks.Attribute("default_node_pool.0.vm_size")
+	assert.True(t, ok)
+	assert.Equal(t, "Standard_D2s_v5", vmSize)
+
+	_, ok = aks.Attribute("oidc_issuer_url")
+	assert.False(t, ok, "known-after-apply values must not be reported as known")
+
+	_, ok = aks.Attribute("default_node_pool.5.vm_size")
+	assert.False(t, ok)
+}
+
+// TestPlanInstances tests address and type matching
+func TestPlanInstances(t *testing.T) {
+	t.Parallel()
+
+	plan := loadPlanFixture(t)
+
+	plan.AssertResourceCount(t, "azurerm_container_registry_replication", 2)
+	plan.AssertResourceCount(t, "azurerm_container_registry_replication.replicas", 2)
+	plan.AssertResourceCount(t, `azurerm_container_registry_replication.replicas["eastus"]`, 1)
+	plan.AssertCreated(t, `azurerm_container_registry_replication.replicas["westeurope"]`).
+		HasAttribute("location", "westeurope")
+
+	// Pure deletes and data sources are not part of the planned state
+	plan.AssertAbsent(t, "azurerm_bastion_host.main")
+	plan.AssertAbsent(t, "azurerm_client_config")
+
+	// A prefix of another resource name must not match
+	plan.AssertAbsent(t, "azurerm_kubernetes_cluster.mai")
+}
+
+// TestPlanHelmValues tests assertions on merged helm_release values
+func TestPlanHelmValues(t *testing.T) {
+	t.Parallel()
+
+	plan := loadPlanFixture(t)
+
+	plan.AssertCreated(t, "helm_release.argocd").
+		HasHelmValue("controller.replicas", 1).
+		HasHelmValue("controller.metrics.enabled", true).
+		HasHelmValue("global.domain", "argocd.example.com").
+		HasHelmValue("redis", map[string]interface{}{"enabled": false})
+}
+
+// TestAssertPlanError tests matching of wrapped Terraform diagnostics
+func TestAssertPlanError(t *testing.T) {
+	t.Parallel()
+
+	err := errors.New("╷\n│ Error: Invalid value for variable\n│\n│ Overlapping subnets: aks\n│ (10.0.0.0/20) and\n│ data (10.0.8.0/22).\n╵")
+	AssertPlanError(t, err, "Overlapping subnets: aks (10.0.0.0/20) and data")
+	assert.Equal(t, "╷ Error: Invalid value ╵", collapseDiagnostic("╷\n│ Error: Invalid\n│   value\n╵"))
+}
+
+// TestPlanOutputs tests known and known-after-apply output values
+func TestPlanOutputs(t *testing.T) {
+	t.Parallel()
+
+	plan := loadPlanFixture(t)
+
+	assert.Equal(t, map[string]interface{}{
+		"cluster_name":    "aks-test-dev-eus",
+		"node_pools":      []interface{}{"system", "user001"},
+		"oidc_issuer_url": nil,
+	}, plan.Outputs())
+}
@@ -0,0 +1,269 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - TERRAFORM POLICY CHECKS
+// =============================================================================
+//
+// Evaluates policies/terraform/*.rego (package terraform.azure) in-process
+// with the OPA Go library against the JSON plan of every InitAndPlan call:
+//
+//   - each deny message fails the test unless the scenario's allowlist
+//     records it with a justification
+//   - each warn message is written to the test log
+//
+// The allowlist of a scenario is keyed by its test name:
+//
+//	testdata/policy-allowlist/TestSizingProfileMatrix/small/databases.yaml
+//
+//	exceptions:
+//	  - deny: 'PostgreSQL server azurerm_postgresql_flexible_server\.main\[0\] must have geo-redundant backup enabled for production'
+//	    justification: The small profile has no geo-redundant backup; the matrix plans it as prod only so the profile's PostgreSQL settings reach the server
+//
+// deny is a regular expression matched against the whole message. An entry
+// that matches nothing fails the test, so exceptions do not outlive the
+// violation they excuse.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// TerraformPolicyPackage is the Rego package the plan is evaluated against.
+const TerraformPolicyPackage = "terraform.azure"
+
+// TerraformPolicyDir returns the absolute path of policies/terraform.
+func TerraformPolicyDir() string {
+	return filepath.Join(RepoRoot(), "policies", "terraform")
+}
+
+// PolicyAllowlistDir returns the directory of the per-scenario allowlists.
+func PolicyAllowlistDir() string {
+	return filepath.Join(TestDataDir(), "policy-allowlist")
+}
+
+// PolicyResult holds the sorted deny and warn messages of an evaluation.
+type PolicyResult struct {
+	Deny []string
+	Warn []string
+}
+
+var (
+	terraformPolicyOnce  sync.Once
+	terraformPolicyQuery rego.PreparedEvalQuery
+	terraformPolicyErr   error
+)
+
+// PreparePolicy compiles the .rego files of dir and prepares a query for the
+// given package. Compilation errors are returned here, not at evaluation.
+func PreparePolicy(dir, pkg string) (rego.PreparedEvalQuery, error) {
+	return rego.New(
+		rego.Query("data."+pkg),
+		rego.Load([]string{dir}, func(abspath string, info os.FileInfo, depth int) bool {
+			// Load only policies; test files and fixtures are not part of it
+			return !info.IsDir() && (filepath.Ext(abspath) != ".rego" || strings.HasSuffix(abspath, "_test.rego"))
+		}),
+	).PrepareForEval(context.Background())
+}
+
+// EvaluatePolicy runs a prepared query against input and collects the
+// deny and warn sets of the package.
+func EvaluatePolicy(query rego.PreparedEvalQuery, input interface{}) (PolicyResult, error) {
+	results, err := query.Eval(context.Background(), rego.EvalInput(input))
+	if err != nil {
+		return PolicyResult{}, err
+	}
+
+	var result PolicyResult
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return result, nil
+	}
+
+	document, ok := results[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return result, fmt.Errorf("policy package evaluated to %T, expected an object", results[0].Expressions[0].Value)
+	}
+
+	if result.Deny, err = policyMessages(document, "deny"); err != nil {
+		return result, err
+	}
+	if result.Warn, err = policyMessages(document, "warn"); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func policyMessages(document map[string]interface{}, rule string) ([]string, error) {
+	values, _ := document[rule].([]interface{})
+
+	messages := make([]string, 0, len(values))
+	for _, value := range values {
+		message, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s produced %T, expected a string message", rule, value)
+		}
+		messages = append(messages, message)
+	}
+	sort.Strings(messages)
+	return messages, nil
+}
+
+// EvaluateTerraformPolicy evaluates policies/terraform against a plan in
+// `terraform show -json` form. The policy is compiled once per test binary.
+func EvaluateTerraformPolicy(input interface{}) (PolicyResult, error) {
+	terraformPolicyOnce.Do(func() {
+		terraformPolicyQuery, terraformPolicyErr = PreparePolicy(TerraformPolicyDir(), TerraformPolicyPackage)
+	})
+	if terraformPolicyErr != nil {
+		return PolicyResult{}, fmt.Errorf("compiling %s: %w", TerraformPolicyDir(), terraformPolicyErr)
+	}
+	return EvaluatePolicy(terraformPolicyQuery, input)
+}
+
+// PolicyInput converts the plan to the generic JSON document Rego reads as
+// input.
+func (p *Plan) PolicyInput() (interface{}, error) {
+	data, err := json.Marshal(p.RawPlan)
+	if err != nil {
+		return nil, err
+	}
+
+	var input interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// PolicyException is one allowlist entry.
+type PolicyException struct {
+	Deny          string `yaml:"deny"`
+	Justification string `yaml:"justification"`
+
+	pattern *regexp.Regexp
+}
+
+// PolicyAllowlist is the allowlist of one scenario.
+type PolicyAllowlist struct {
+	Exceptions []*PolicyException `yaml:"exceptions"`
+}
+
+// PolicyAllowlistPath returns the allowlist file of a test, by test name.
+func PolicyAllowlistPath(testName string) string {
+	return filepath.Join(PolicyAllowlistDir(), filepath.FromSlash(testName)+".yaml")
+}
+
+// LoadPolicyAllowlist reads an allowlist file. A missing file is an empty
+// allowlist. Every entry needs a valid deny pattern and a justification.
+func LoadPolicyAllowlist(path string) (*PolicyAllowlist, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &PolicyAllowlist{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var allowlist PolicyAllowlist
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&allowlist); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i, exception := range allowlist.Exceptions {
+		if exception.Deny == "" {
+			return nil, fmt.Errorf("%s: exception %d has no deny pattern", path, i+1)
+		}
+		if strings.TrimSpace(exception.Justification) == "" {
+			return nil, fmt.Errorf("%s: exception %q has no justification", path, exception.Deny)
+		}
+		exception.pattern, err = regexp.Compile("^(?:" + exception.Deny + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return &allowlist, nil
+}
+
+// Filter splits deny messages into the ones the allowlist excuses and the
+// ones it does not, and returns the exceptions that matched nothing.
+func (a *PolicyAllowlist) Filter(deny []string) (allowed map[string]*PolicyException, denied []string, unused []*PolicyException) {
+	allowed = map[string]*PolicyException{}
+	used := map[*PolicyException]bool{}
+
+	for _, message := range deny {
+		var match *PolicyException
+		for _, exception := range a.Exceptions {
+			if exception.pattern.MatchString(message) {
+				match = exception
+				break
+			}
+		}
+		if match == nil {
+			denied = append(denied, message)
+			continue
+		}
+		allowed[message] = match
+		used[match] = true
+	}
+
+	for _, exception := range a.Exceptions {
+		if !used[exception] {
+			unused = append(unused, exception)
+		}
+	}
+	return allowed, denied, unused
+}
+
+// AssertPolicy evaluates policies/terraform against the plan and applies
+// the allowlist of the running test. InitAndPlan calls it for every plan.
+func (p *Plan) AssertPolicy(t testing.TB) {
+	t.Helper()
+
+	input, err := p.PolicyInput()
+	require.NoError(t, err)
+
+	result, err := EvaluateTerraformPolicy(input)
+	require.NoError(t, err)
+
+	path := PolicyAllowlistPath(t.Name())
+	allowlist, err := LoadPolicyAllowlist(path)
+	require.NoError(t, err)
+
+	allowed, denied, unused := allowlist.Filter(result.Deny)
+
+	for _, message := range result.Warn {
+		t.Logf("policy warn: %s", message)
+	}
+	for _, message := range result.Deny {
+		if exception, ok := allowed[message]; ok {
+			t.Logf("policy deny allowed: %s (%s)", message, exception.Justification)
+		}
+	}
+	for _, message := range denied {
+		t.Errorf("policy deny: %s", message)
+	}
+	if len(denied) > 0 {
+		t.Logf("Fix the module, or record an exception with a justification in %s", path)
+	}
+	for _, exception := range unused {
+		t.Errorf("%s: exception %q matches no deny message; remove it", path, exception.Deny)
+	}
+}
@@ -0,0 +1,142 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - TERRAFORM POLICY CHECK TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestTerraformPolicy|TestPolicyAllowlist' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func policyTestPlan(changes ...*tfjson.ResourceChange) *Plan {
+	planStruct := &terraform.PlanStruct{
+		RawPlan:                  tfjson.Plan{ResourceChanges: changes},
+		ResourcePlannedValuesMap: map[string]*tfjson.StateResource{},
+		ResourceChangesMap:       map[string]*tfjson.ResourceChange{},
+	}
+	for _, change := range changes {
+		planStruct.ResourceChangesMap[change.Address] = change
+	}
+
+	return &Plan{PlanStruct: planStruct}
+}
+
+func policyTestChange(resourceType, name string, after map[string]interface{}) *tfjson.ResourceChange {
+	return &tfjson.ResourceChange{
+		Address: resourceType + "." + name,
+		Mode:    tfjson.ManagedResourceMode,
+		Type:    resourceType,
+		Name:    name,
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{tfjson.ActionCreate},
+			After:   after,
+		},
+	}
+}
+
+// TestTerraformPolicyEvaluation tests deny and warn collection from a plan
+func TestTerraformPolicyEvaluation(t *testing.T) {
+	t.Parallel()
+
+	tags := map[string]interface{}{}
+	for key, value := range testTags() {
+		tags[key] = value
+	}
+
+	plan := policyTestPlan(
+		policyTestChange("azurerm_kubernetes_cluster", "main", map[string]interface{}{
+			"tags":                              tags,
+			"role_based_access_control_enabled": true,
+			"identity":                          []interface{}{map[string]interface{}{"type": "SystemAssigned"}},
+			"public_network_access_enabled":     false,
+			"azure_policy_enabled":              true,
+			"microsoft_defender":                []interface{}{map[string]interface{}{}},
+		}),
+		policyTestChange("azurerm_kubernetes_cluster_node_pool", "user", map[string]interface{}{
+			"enable_auto_scaling": false,
+		}),
+		policyTestChange("azurerm_key_vault", "main", map[string]interface{}{
+			"tags":                          map[string]interface{}{"environment": "dev"},
+			"public_network_access_enabled": false,
+			"purge_protection_enabled":      true,
+		}),
+	)
+
+	input, err := plan.PolicyInput()
+	require.NoError(t, err)
+
+	result, err := EvaluateTerraformPolicy(input)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`Resource azurerm_key_vault.main is missing required tags: {"cost-center", "owner", "project"}`,
+	}, result.Deny)
+	assert.Equal(t, []string{
+		"Node pool azurerm_kubernetes_cluster_node_pool.user should have autoscaling enabled for cost optimization",
+	}, result.Warn)
+}
+
+// TestPolicyAllowlist tests allowlist loading and matching
+func TestPolicyAllowlist(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("testdata", "policy-allowlist")
+
+	missing, err := LoadPolicyAllowlist(filepath.Join(dir, "missing.yaml"))
+	require.NoError(t, err)
+	assert.Empty(t, missing.Exceptions)
+
+	_, err = LoadPolicyAllowlist(filepath.Join(dir, "unjustified.yaml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has no justification")
+
+	allowlist, err := LoadPolicyAllowlist(filepath.Join(dir, "valid.yaml"))
+	require.NoError(t, err)
+
+	allowed, denied, unused := allowlist.Filter([]string{
+		"Node pool azurerm_kubernetes_cluster_node_pool.user must not be tiny",
+		"AKS cluster azurerm_kubernetes_cluster.main must have RBAC enabled",
+	})
+
+	require.Contains(t, allowed, "Node pool azurerm_kubernetes_cluster_node_pool.user must not be tiny")
+	assert.Equal(t, []string{"AKS cluster azurerm_kubernetes_cluster.main must have RBAC enabled"}, denied)
+	require.Len(t, unused, 1)
+	assert.Contains(t, unused[0].Deny, "azurerm_storage_account")
+}
+
+// TestPolicyAllowlistPath tests that subtests get their own allowlist file
+func TestPolicyAllowlistPath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		filepath.Join(PolicyAllowlistDir(), "TestSizingProfileMatrix", "small", "databases.yaml"),
+		PolicyAllowlistPath("TestSizingProfileMatrix/small/databases"))
+}
+
+// TestPolicyAllowlistsValid tests that every committed allowlist loads
+func TestPolicyAllowlistsValid(t *testing.T) {
+	t.Parallel()
+
+	err := filepath.WalkDir(PolicyAllowlistDir(), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		allowlist, err := LoadPolicyAllowlist(path)
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, allowlist.Exceptions, path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
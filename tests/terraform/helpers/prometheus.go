@@ -0,0 +1,131 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PROMETHEUS RULE CHECKS
+// =============================================================================
+//
+// Loads prometheus/alerting-rules.yaml and prometheus/recording-rules.yaml
+// with the Prometheus rule-group parser (the one Prometheus itself uses), so
+// every expr is parsed as PromQL and every annotation as a template:
+//
+//	groups, err := helpers.LoadRuleGroups(helpers.AlertingRulesPath())
+//	problems := helpers.CheckRuleConventions(groups)
+//
+// Conventions on top of what Prometheus accepts:
+//
+//   - alerts carry the severity, horizon and component labels used for
+//     routing, with a known severity and horizon
+//   - alerts carry summary and runbook_url annotations; runbook_url starts
+//     with ${RUNBOOK_BASE_URL}/, which the deploy scripts substitute
+//   - recording rules are named level:metric:operation
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+// RequiredAlertLabels are the labels Alertmanager routes on.
+var RequiredAlertLabels = []string{"severity", "horizon", "component"}
+
+// RequiredAlertAnnotations are the annotations every notification shows.
+var RequiredAlertAnnotations = []string{"summary", "runbook_url"}
+
+// AlertSeverities are the accepted severity label values.
+var AlertSeverities = []string{"critical", "warning", "info"}
+
+// Horizons are the accepted horizon label values.
+var Horizons = []string{"h1", "h2", "h3"}
+
+// RunbookURLPrefix is the placeholder every runbook_url starts with.
+const RunbookURLPrefix = "${RUNBOOK_BASE_URL}/"
+
+// recordingRuleNamePattern is level:metric:operation, e.g.
+// namespace:cpu:usage_cores.
+var recordingRuleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*:[a-z][a-z0-9_]*:[a-z0-9][a-z0-9_]*$`)
+
+// PrometheusDir returns the absolute path of prometheus/.
+func PrometheusDir() string {
+	return filepath.Join(RepoRoot(), "prometheus")
+}
+
+// AlertingRulesPath returns the absolute path of the alerting rules.
+func AlertingRulesPath() string {
+	return filepath.Join(PrometheusDir(), "alerting-rules.yaml")
+}
+
+// RecordingRulesPath returns the absolute path of the recording rules.
+func RecordingRulesPath() string {
+	return filepath.Join(PrometheusDir(), "recording-rules.yaml")
+}
+
+// LoadRuleGroups parses a rule file. Unknown keys, invalid PromQL and
+// invalid annotation templates are all errors.
+func LoadRuleGroups(path string) (*rulefmt.RuleGroups, error) {
+	groups, errs := rulefmt.ParseFile(path)
+	if len(errs) > 0 {
+		problems := make([]string, 0, len(errs))
+		for _, err := range errs {
+			problems = append(problems, err.Error())
+		}
+		return nil, fmt.Errorf("rule groups:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return groups, nil
+}
+
+// CheckRuleConventions returns the convention violations of the rule
+// groups, one "<line>: <rule>: <problem>" line each.
+func CheckRuleConventions(groups *rulefmt.RuleGroups) []string {
+	var problems []string
+
+	for _, group := range groups.Groups {
+		for _, rule := range group.Rules {
+			if rule.Alert.Value != "" {
+				for _, problem := range checkAlertConventions(rule) {
+					problems = append(problems, fmt.Sprintf("%d: %s: %s", rule.Alert.Line, rule.Alert.Value, problem))
+				}
+				continue
+			}
+			if !recordingRuleNamePattern.MatchString(rule.Record.Value) {
+				problems = append(problems, fmt.Sprintf("%d: %s: recording rule name is not level:metric:operation",
+					rule.Record.Line, rule.Record.Value))
+			}
+		}
+	}
+
+	return problems
+}
+
+func checkAlertConventions(rule rulefmt.RuleNode) []string {
+	var problems []string
+
+	for _, label := range RequiredAlertLabels {
+		if rule.Labels[label] == "" {
+			problems = append(problems, fmt.Sprintf("no %s label", label))
+		}
+	}
+	if severity := rule.Labels["severity"]; severity != "" && !containsString(AlertSeverities, severity) {
+		problems = append(problems, fmt.Sprintf("severity %q is not one of %s", severity, strings.Join(AlertSeverities, ", ")))
+	}
+	if horizon := rule.Labels["horizon"]; horizon != "" && !containsString(Horizons, horizon) {
+		problems = append(problems, fmt.Sprintf("horizon %q is not one of %s", horizon, strings.Join(Horizons, ", ")))
+	}
+
+	for _, annotation := range RequiredAlertAnnotations {
+		if strings.TrimSpace(rule.Annotations[annotation]) == "" {
+			problems = append(problems, fmt.Sprintf("no %s annotation", annotation))
+		}
+	}
+	if url := rule.Annotations["runbook_url"]; url != "" && !strings.HasPrefix(url, RunbookURLPrefix) {
+		problems = append(problems, fmt.Sprintf("runbook_url %q does not start with %s", url, RunbookURLPrefix))
+	}
+
+	sort.Strings(problems)
+	return problems
+}
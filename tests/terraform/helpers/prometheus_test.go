@@ -0,0 +1,141 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PROMETHEUS RULE CHECK TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestRuleGroups|TestRuleConventions' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRuleFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// TestRuleGroupsInvalid tests that parser errors are reported per rule
+func TestRuleGroupsInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{
+			name: "invalid_promql",
+			content: `
+groups:
+  - name: broken
+    rules:
+      - record: job:up:sum
+        expr: sum(up by (job)
+`,
+			message: "job:up:sum",
+		},
+		{
+			name: "invalid_template",
+			content: `
+groups:
+  - name: broken
+    rules:
+      - alert: Down
+        expr: up == 0
+        annotations:
+          summary: "{{ $labels.job "
+`,
+			message: "Down",
+		},
+		{
+			name: "unknown_key",
+			content: `
+groups:
+  - name: broken
+    rules:
+      - alert: Down
+        expr: up == 0
+        severity: critical
+`,
+			message: "field severity not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := LoadRuleGroups(writeRuleFile(t, tc.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.message)
+		})
+	}
+}
+
+// TestRuleConventions tests the label, annotation and naming conventions
+func TestRuleConventions(t *testing.T) {
+	t.Parallel()
+
+	groups, err := LoadRuleGroups(writeRuleFile(t, `
+groups:
+  - name: conventions
+    rules:
+      - alert: Compliant
+        expr: up == 0
+        labels:
+          severity: critical
+          horizon: h1
+          component: aks
+        annotations:
+          summary: "Target down"
+          runbook_url: "${RUNBOOK_BASE_URL}/compliant"
+
+      - alert: Unlabelled
+        expr: up == 0
+        annotations:
+          summary: "Target down"
+          runbook_url: "https://wiki.example.com/unlabelled"
+
+      - alert: UnknownValues
+        expr: up == 0
+        labels:
+          severity: page
+          horizon: h4
+          component: aks
+
+      - record: job:up:sum
+        expr: sum by (job) (up)
+
+      - record: job:up:sum:by_instance
+        expr: sum by (job, instance) (up)
+
+      - record: up_sum
+        expr: sum(up)
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"15: Unlabelled: no component label",
+		"15: Unlabelled: no horizon label",
+		"15: Unlabelled: no severity label",
+		`15: Unlabelled: runbook_url "https://wiki.example.com/unlabelled" does not start with ${RUNBOOK_BASE_URL}/`,
+		`21: UnknownValues: horizon "h4" is not one of h1, h2, h3`,
+		"21: UnknownValues: no runbook_url annotation",
+		"21: UnknownValues: no summary annotation",
+		`21: UnknownValues: severity "page" is not one of critical, warning, info`,
+		"31: job:up:sum:by_instance: recording rule name is not level:metric:operation",
+		"34: up_sum: recording rule name is not level:metric:operation",
+	}, CheckRuleConventions(groups))
+}
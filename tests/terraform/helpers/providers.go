@@ -0,0 +1,446 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PROVIDER MIRROR AND PLUGIN CACHE
+// =============================================================================
+//
+// Lets the suite run `terraform init` without registry access.
+//
+// ConfigureProviders is called once from TestMain. It shares one plugin cache
+// between all tests. When TERRATEST_PROVIDER_MIRROR is set it also writes a
+// Terraform CLI config that installs providers only from that filesystem
+// mirror, keeping the rest of the user's CLI config, and points
+// TF_CLI_CONFIG_FILE at it for every Terraform process the tests start.
+//
+// Populate the mirror with:
+//
+//	go run ./cmd/mirror-providers -dir /path/to/mirror
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	// ProviderMirrorEnv points at a filesystem provider mirror. When set,
+	// providers are installed from the mirror only and never downloaded.
+	ProviderMirrorEnv = "TERRATEST_PROVIDER_MIRROR"
+
+	// PluginCacheEnv is Terraform's own plugin cache variable. When unset a
+	// cache under the user cache directory is used.
+	PluginCacheEnv = "TF_PLUGIN_CACHE_DIR"
+
+	// pluginCacheBreakLockEnv lets isolated workspaces, which start without
+	// a lock file, use the plugin cache anyway.
+	pluginCacheBreakLockEnv = "TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE"
+
+	defaultRegistryHost = "registry.terraform.io"
+)
+
+var providerInstallationPattern = regexp.MustCompile(`(?m)^[\s{]*"?provider_installation"?\s*[:={]`)
+
+// ProviderRequirement is one provider from required_providers together with
+// every version constraint declared for it.
+type ProviderRequirement struct {
+	// Name is the local name used in required_providers, e.g. azurerm.
+	Name string
+	// Source is the fully qualified source, e.g. registry.terraform.io/hashicorp/azurerm.
+	Source      string
+	Constraints []string
+}
+
+// Constraint returns all constraints joined into a single constraint string.
+func (p ProviderRequirement) Constraint() string {
+	return strings.Join(p.Constraints, ", ")
+}
+
+var terraformBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "terraform"},
+	},
+}
+
+var requiredProvidersSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "required_providers"},
+	},
+}
+
+// RequiredProviders returns the union of required_providers declared in the
+// .tf files of the given directories, sorted by source. Constraints for the
+// same provider are collected without duplicates.
+func RequiredProviders(dirs ...string) ([]ProviderRequirement, error) {
+	bySource := map[string]*ProviderRequirement{}
+
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range files {
+			if err := collectRequiredProviders(path, bySource); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	requirements := make([]ProviderRequirement, 0, len(bySource))
+	for _, req := range bySource {
+		sort.Strings(req.Constraints)
+		requirements = append(requirements, *req)
+	}
+	sort.Slice(requirements, func(i, j int) bool { return requirements[i].Source < requirements[j].Source })
+
+	return requirements, nil
+}
+
+// ModuleProviders returns the union of required_providers across every
+// module in terraform/modules and the root module that composes them.
+func ModuleProviders() ([]ProviderRequirement, error) {
+	dirs := make([]string, 0, len(Modules())+1)
+	for _, module := range Modules() {
+		dirs = append(dirs, ModuleDir(module))
+	}
+	dirs = append(dirs, RootModuleDir())
+	return RequiredProviders(dirs...)
+}
+
+func collectRequiredProviders(path string, bySource map[string]*ProviderRequirement) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	content, _, _ := file.Body.PartialContent(terraformBlockSchema)
+	for _, tfBlock := range content.Blocks {
+		inner, _, _ := tfBlock.Body.PartialContent(requiredProvidersSchema)
+		for _, rpBlock := range inner.Blocks {
+			attrs, diags := rpBlock.Body.JustAttributes()
+			if diags.HasErrors() {
+				return diags
+			}
+
+			for name, attr := range attrs {
+				value, diags := attr.Expr.Value(nil)
+				if diags.HasErrors() {
+					return fmt.Errorf("%s: required_providers.%s must be a literal: %s", path, name, diags.Error())
+				}
+
+				source, constraint := providerSourceAndVersion(name, value)
+				req, ok := bySource[source]
+				if !ok {
+					req = &ProviderRequirement{Name: name, Source: source}
+					bySource[source] = req
+				}
+				if constraint != "" && !containsString(req.Constraints, constraint) {
+					req.Constraints = append(req.Constraints, constraint)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// providerSourceAndVersion reads either `name = { source, version }` or the
+// legacy `name = "version"` form.
+func providerSourceAndVersion(name string, value cty.Value) (string, string) {
+	source := "hashicorp/" + name
+	constraint := ""
+
+	switch {
+	case value.Type() == cty.String:
+		constraint = value.AsString()
+	case value.Type().IsObjectType():
+		if value.Type().HasAttribute("source") {
+			source = value.GetAttr("source").AsString()
+		}
+		if value.Type().HasAttribute("version") {
+			constraint = value.GetAttr("version").AsString()
+		}
+	}
+
+	return qualifyProviderSource(source), constraint
+}
+
+// qualifyProviderSource adds the default registry host to short sources.
+func qualifyProviderSource(source string) string {
+	source = strings.ToLower(source)
+	if strings.Count(source, "/") == 1 {
+		return defaultRegistryHost + "/" + source
+	}
+	return source
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentPlatform returns the Terraform platform string of this machine,
+// e.g. linux_amd64.
+func CurrentPlatform() string {
+	return runtime.GOOS + "_" + runtime.GOARCH
+}
+
+// MirrorVersions returns the versions of a provider available in a filesystem
+// mirror for the given platform. Both the packed (zip) and unpacked layouts
+// written by `terraform providers mirror` are recognised.
+func MirrorVersions(mirrorDir, source, platform string) ([]*version.Version, error) {
+	providerDir := filepath.Join(mirrorDir, filepath.FromSlash(source))
+	providerType := source[strings.LastIndex(source, "/")+1:]
+
+	entries, err := os.ReadDir(providerDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	packedPrefix := "terraform-provider-" + providerType + "_"
+	packedSuffix := "_" + platform + ".zip"
+
+	var versions []*version.Version
+	for _, entry := range entries {
+		name := entry.Name()
+
+		var raw string
+		switch {
+		case !entry.IsDir() && strings.HasPrefix(name, packedPrefix) && strings.HasSuffix(name, packedSuffix):
+			raw = strings.TrimSuffix(strings.TrimPrefix(name, packedPrefix), packedSuffix)
+		case entry.IsDir():
+			if info, err := os.Stat(filepath.Join(providerDir, name, platform)); err != nil || !info.IsDir() {
+				continue
+			}
+			raw = name
+		default:
+			continue
+		}
+
+		if v, err := version.NewVersion(raw); err == nil {
+			versions = append(versions, v)
+		}
+	}
+
+	sort.Sort(version.Collection(versions))
+	return versions, nil
+}
+
+// CheckProviderMirror returns one problem per provider required by the .tf
+// files in dir that has no version in the mirror satisfying its constraints.
+func CheckProviderMirror(mirrorDir, dir string) ([]string, error) {
+	requirements, err := RequiredProviders(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, req := range requirements {
+		constraint := req.Constraint()
+		if constraint == "" {
+			constraint = ">= 0"
+		}
+
+		constraints, err := version.NewConstraint(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid version constraint %q: %w", req.Source, constraint, err)
+		}
+
+		available, err := MirrorVersions(mirrorDir, req.Source, CurrentPlatform())
+		if err != nil {
+			return nil, err
+		}
+
+		if !anyVersionMatches(available, constraints) {
+			problems = append(problems, fmt.Sprintf("%s %q (%s): mirror has %s",
+				req.Source, req.Constraint(), CurrentPlatform(), describeVersions(available)))
+		}
+	}
+
+	return problems, nil
+}
+
+func anyVersionMatches(versions []*version.Version, constraints version.Constraints) bool {
+	for _, v := range versions {
+		if constraints.Check(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeVersions(versions []*version.Version) string {
+	if len(versions) == 0 {
+		return "no versions"
+	}
+
+	names := make([]string, len(versions))
+	for i, v := range versions {
+		names[i] = v.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// ProviderMirrorDir returns the configured provider mirror, or "" when the
+// suite may download providers from the registry.
+func ProviderMirrorDir() string {
+	return os.Getenv(ProviderMirrorEnv)
+}
+
+// ConfigureProviders exports TF_PLUGIN_CACHE_DIR for every Terraform process
+// started by the tests and, with a mirror, a TF_CLI_CONFIG_FILE that adds it
+// to the user's CLI config. Call it once from TestMain and run the returned
+// cleanup after m.Run.
+func ConfigureProviders() (func(), error) {
+	cacheDir := os.Getenv(PluginCacheEnv)
+	if cacheDir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to locate a plugin cache directory, set %s: %w", PluginCacheEnv, err)
+		}
+		cacheDir = filepath.Join(userCache, "three-horizons", "terraform-plugin-cache")
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create plugin cache %s: %w", cacheDir, err)
+	}
+
+	restore := setenv(map[string]string{
+		PluginCacheEnv:          cacheDir,
+		pluginCacheBreakLockEnv: "true",
+	})
+
+	mirrorDir := ProviderMirrorDir()
+	if mirrorDir == "" {
+		// The user's CLI config, credentials and registries apply unchanged
+		return restore, nil
+	}
+
+	mirrorDir, err := filepath.Abs(mirrorDir)
+	if err != nil {
+		restore()
+		return nil, err
+	}
+	if info, err := os.Stat(mirrorDir); err != nil || !info.IsDir() {
+		restore()
+		return nil, fmt.Errorf("%s=%s is not a directory; populate it with `go run ./cmd/mirror-providers -dir %s`", ProviderMirrorEnv, ProviderMirrorDir(), ProviderMirrorDir())
+	}
+
+	userConfigFile := userCLIConfigFile()
+	userConfig, err := os.ReadFile(userConfigFile)
+	if err != nil && !os.IsNotExist(err) {
+		restore()
+		return nil, fmt.Errorf("unable to read the Terraform CLI config %s: %w", userConfigFile, err)
+	}
+	if providerInstallationPattern.Match(userConfig) {
+		restore()
+		return nil, fmt.Errorf("%s has a provider_installation block; add %s to it instead of setting %s", userConfigFile, mirrorDir, ProviderMirrorEnv)
+	}
+
+	configFile, err := os.CreateTemp("", "terratest-cli-*.tfrc")
+	if err != nil {
+		restore()
+		return nil, err
+	}
+	if _, err := configFile.WriteString(CLIConfig(string(userConfig), mirrorDir)); err != nil {
+		configFile.Close()
+		restore()
+		return nil, err
+	}
+	if err := configFile.Close(); err != nil {
+		restore()
+		return nil, err
+	}
+
+	restoreConfig := setenv(map[string]string{"TF_CLI_CONFIG_FILE": configFile.Name()})
+
+	return func() {
+		restoreConfig()
+		restore()
+		os.Remove(configFile.Name())
+	}, nil
+}
+
+// userCLIConfigFile returns the CLI config Terraform would read without the
+// suite: TF_CLI_CONFIG_FILE, or the per-user default.
+func userCLIConfigFile() string {
+	if file := os.Getenv("TF_CLI_CONFIG_FILE"); file != "" {
+		return file
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "terraform.rc")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".terraformrc")
+}
+
+// setenv sets variables and returns a function that restores their previous
+// values.
+func setenv(values map[string]string) func() {
+	previous := map[string]*string{}
+	for key, value := range values {
+		if old, ok := os.LookupEnv(key); ok {
+			previous[key] = &old
+		} else {
+			previous[key] = nil
+		}
+		os.Setenv(key, value)
+	}
+
+	return func() {
+		for key, old := range previous {
+			if old == nil {
+				os.Unsetenv(key)
+			} else {
+				os.Setenv(key, *old)
+			}
+		}
+	}
+}
+
+// CLIConfig renders a Terraform CLI configuration: userConfig followed by a
+// provider_installation block that installs from mirrorDir only, so provider
+// installation never reaches the registry.
+func CLIConfig(userConfig, mirrorDir string) string {
+	var b strings.Builder
+
+	if userConfig != "" {
+		b.WriteString(strings.TrimRight(userConfig, "\n"))
+		b.WriteString("\n\n")
+	}
+
+	fmt.Fprintf(&b, `provider_installation {
+  filesystem_mirror {
+    path    = %q
+    include = ["*/*/*"]
+  }
+  direct {
+    exclude = ["*/*/*"]
+  }
+}
+`, filepath.ToSlash(mirrorDir))
+
+	return b.String()
+}
@@ -0,0 +1,139 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PROVIDER MIRROR TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestRequiredProviders|TestModuleProviders|TestMirror|TestCheckProviderMirror|TestCLIConfig' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequiredProviders tests the union of required_providers across directories
+func TestRequiredProviders(t *testing.T) {
+	t.Parallel()
+
+	requirements, err := RequiredProviders(
+		filepath.Join("testdata", "providers", "a"),
+		filepath.Join("testdata", "providers", "b"),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ProviderRequirement{
+		{Name: "kubectl", Source: "registry.terraform.io/gavinbunney/kubectl", Constraints: []string{">= 1.14"}},
+		{Name: "azurerm", Source: "registry.terraform.io/hashicorp/azurerm", Constraints: []string{">= 3.80.0", "~> 3.85"}},
+		{Name: "random", Source: "registry.terraform.io/hashicorp/random", Constraints: []string{"~> 3.6"}},
+	}, requirements)
+	assert.Equal(t, ">= 3.80.0, ~> 3.85", requirements[1].Constraint())
+}
+
+// TestModuleProviders tests that every module's providers are found
+func TestModuleProviders(t *testing.T) {
+	t.Parallel()
+
+	requirements, err := ModuleProviders()
+	require.NoError(t, err)
+
+	sources := make([]string, len(requirements))
+	for i, req := range requirements {
+		sources[i] = req.Source
+	}
+	assert.Contains(t, sources, "registry.terraform.io/hashicorp/azurerm")
+	assert.Contains(t, sources, "registry.terraform.io/hashicorp/helm")
+	assert.Contains(t, sources, "registry.terraform.io/hashicorp/kubernetes")
+}
+
+// writeMirror creates a fake mirror with azurerm in the packed layout and
+// random in the unpacked layout
+func writeMirror(t *testing.T, platform string) string {
+	t.Helper()
+
+	mirror := t.TempDir()
+	azurerm := filepath.Join(mirror, "registry.terraform.io", "hashicorp", "azurerm")
+	require.NoError(t, os.MkdirAll(azurerm, 0o755))
+	for _, name := range []string{
+		"terraform-provider-azurerm_3.116.0_" + platform + ".zip",
+		"terraform-provider-azurerm_4.10.0_" + platform + ".zip",
+		"terraform-provider-azurerm_3.90.0_windows_386.zip",
+		"3.116.0.json",
+		"index.json",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(azurerm, name), nil, 0o644))
+	}
+
+	random := filepath.Join(mirror, "registry.terraform.io", "hashicorp", "random", "3.6.3", platform)
+	require.NoError(t, os.MkdirAll(random, 0o755))
+
+	return mirror
+}
+
+// TestMirrorVersions tests both mirror layouts and platform filtering
+func TestMirrorVersions(t *testing.T) {
+	t.Parallel()
+
+	mirror := writeMirror(t, "linux_amd64")
+
+	azurerm, err := MirrorVersions(mirror, "registry.terraform.io/hashicorp/azurerm", "linux_amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "3.116.0, 4.10.0", describeVersions(azurerm))
+
+	random, err := MirrorVersions(mirror, "registry.terraform.io/hashicorp/random", "linux_amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "3.6.3", describeVersions(random))
+
+	missing, err := MirrorVersions(mirror, "registry.terraform.io/hashicorp/helm", "linux_amd64")
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+// TestCheckProviderMirror tests the failure message for missing versions
+func TestCheckProviderMirror(t *testing.T) {
+	t.Parallel()
+
+	mirror := writeMirror(t, CurrentPlatform())
+
+	problems, err := CheckProviderMirror(mirror, filepath.Join("testdata", "providers", "b"))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	problems, err = CheckProviderMirror(mirror, filepath.Join("testdata", "providers", "a"))
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "registry.terraform.io/gavinbunney/kubectl")
+	assert.Contains(t, problems[0], "no versions")
+}
+
+// TestCLIConfig tests the generated Terraform CLI configuration
+func TestCLIConfig(t *testing.T) {
+	t.Parallel()
+
+	offline := CLIConfig("", "/mirror")
+	assert.Contains(t, offline, "filesystem_mirror")
+	assert.Contains(t, offline, `path    = "/mirror"`)
+	assert.Contains(t, offline, `exclude = ["*/*/*"]`)
+	assert.NotContains(t, offline, "plugin_cache_dir", "the cache is set through TF_PLUGIN_CACHE_DIR")
+
+	userConfig := "credentials \"app.terraform.io\" {\n  token = \"x\"\n}\n"
+	merged := CLIConfig(userConfig, "/mirror")
+	assert.True(t, strings.HasPrefix(merged, userConfig), "the user's CLI config is kept")
+	assert.Contains(t, merged, "filesystem_mirror")
+}
+
+// TestProviderInstallationPattern tests detecting a user-defined
+// provider_installation block
+func TestProviderInstallationPattern(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, providerInstallationPattern.MatchString("provider_installation {\n}\n"))
+	assert.True(t, providerInstallationPattern.MatchString(`{"provider_installation": {}}`), "JSON CLI config")
+	assert.False(t, providerInstallationPattern.MatchString("plugin_cache_dir = \"/cache\"\n"))
+}
@@ -0,0 +1,477 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - REGION AVAILABILITY
+// =============================================================================
+//
+// Typed loader for config/region-availability.yaml and the region matrix
+// derived from it:
+//
+//	matrix, err := helpers.LoadRegionAvailability()
+//	for _, placement := range matrix.Placements() {
+//	    // plan placement.Module in placement.Location
+//	}
+//
+// The services map of each region is free text ("Full support",
+// "Limited models (GPT-4, GPT-3.5)", "Not available (use East US 2)").
+// ParseSupport reduces it to a Support level, and a "(use <Display Name>)"
+// note on an unavailable service is the documented spillover region.
+//
+// Decoding is strict, like sizing.go: an unknown key is an error.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"gopkg.in/yaml.v3"
+)
+
+// Service keys of the services map in config/region-availability.yaml.
+const (
+	ServiceAKS          = "aks"
+	ServiceAIFoundry    = "ai_foundry"
+	ServiceOpenAIGPT4o  = "openai_gpt4o"
+	ServiceDefender     = "defender_cloud"
+	ServicePurview      = "purview"
+	ServiceACRPremium   = "acr_premium"
+	ServicePostgreSQL   = "postgresql_flexible"
+	ServiceRedis        = "redis_cache"
+	ServiceLogAnalytics = "log_analytics"
+)
+
+// ModuleServices lists the services each regional module deploys. The
+// ai-foundry module deploys gpt-4o by default, so it needs openai_gpt4o as
+// well as ai_foundry. defender is subscription-scoped and has no location.
+var ModuleServices = map[string][]string{
+	ModuleAKSCluster:        {ServiceAKS},
+	ModuleAIFoundry:         {ServiceAIFoundry, ServiceOpenAIGPT4o},
+	ModuleContainerRegistry: {ServiceACRPremium},
+	ModuleDatabases:         {ServicePostgreSQL, ServiceRedis},
+	ModuleObservability:     {ServiceLogAnalytics},
+	ModulePurview:           {ServicePurview},
+}
+
+// MatrixTiers are the region tiers the region matrix covers.
+var MatrixTiers = []int{1, 2}
+
+// Support is how well a region supports a service.
+type Support int
+
+// Support levels, from worst to best.
+const (
+	// SupportUnknown means the region does not list the service.
+	SupportUnknown Support = iota
+	SupportUnavailable
+	SupportLimited
+	SupportFull
+)
+
+func (s Support) String() string {
+	switch s {
+	case SupportUnavailable:
+		return "unavailable"
+	case SupportLimited:
+		return "limited"
+	case SupportFull:
+		return "full"
+	}
+	return "unknown"
+}
+
+// Supported reports whether the service can be deployed in the region.
+func (s Support) Supported() bool {
+	return s >= SupportLimited
+}
+
+// ParseSupport classifies a services entry of the availability file.
+func ParseSupport(text string) Support {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	switch {
+	case lower == "":
+		return SupportUnknown
+	case strings.HasPrefix(lower, "not available"):
+		return SupportUnavailable
+	case strings.Contains(lower, "limited"), strings.Contains(lower, "preview"):
+		return SupportLimited
+	}
+	return SupportFull
+}
+
+var spilloverPattern = regexp.MustCompile(`\(use ([^)]+)\)`)
+
+// RegionAvailability is config/region-availability.yaml.
+type RegionAvailability struct {
+	Regions            map[string]*Region            `yaml:"regions"`
+	DeploymentPatterns map[string]*DeploymentPattern `yaml:"deployment_patterns"`
+	Metadata           RegionMetadata                `yaml:"metadata"`
+
+	names []string
+}
+
+// Region is one entry of the regions map.
+type Region struct {
+	DisplayName        string                        `yaml:"display_name"`
+	DataResidency      string                        `yaml:"data_residency"`
+	Tier               int                           `yaml:"tier"`
+	Latency            map[string]string             `yaml:"latency"`
+	Services           map[string]string             `yaml:"services"`
+	RecommendedFor     []string                      `yaml:"recommended_for"`
+	Limitations        []string                      `yaml:"limitations"`
+	SizingAvailability map[string]SizingAvailability `yaml:"sizing_availability"`
+
+	name      string
+	spillover map[string]string // service -> region
+}
+
+// SizingAvailability is a sizing_availability value: true, false or a note
+// such as "With AI spillover", which means available.
+type SizingAvailability struct {
+	Available bool
+	Note      string
+}
+
+// UnmarshalYAML accepts a boolean or a note.
+func (s *SizingAvailability) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: sizing_availability must be a boolean or a note", node.Line)
+	}
+	if node.Tag == "!!bool" {
+		return node.Decode(&s.Available)
+	}
+	s.Available = true
+	s.Note = node.Value
+	return nil
+}
+
+// DeploymentPattern is one entry of the deployment_patterns map.
+type DeploymentPattern struct {
+	Name             string            `yaml:"name"`
+	Description      string            `yaml:"description"`
+	PrimaryRegion    string            `yaml:"primary_region"`
+	AIRegion         string            `yaml:"ai_region"`
+	DRRegion         string            `yaml:"dr_region"`
+	SecondaryRegions []string          `yaml:"secondary_regions"`
+	Architecture     []string          `yaml:"architecture"`
+	Compliance       map[string]string `yaml:"compliance"`
+}
+
+// RegionMetadata is the metadata section, including the issue template
+// dropdowns generated from the matrix.
+type RegionMetadata struct {
+	LastUpdated         string               `yaml:"last_updated"`
+	IssueTemplateConfig []IssueTemplateField `yaml:"issue_template_config"`
+}
+
+// IssueTemplateField is a GitHub issue form field.
+type IssueTemplateField struct {
+	Type       string `yaml:"type"`
+	ID         string `yaml:"id"`
+	Attributes struct {
+		Label       string   `yaml:"label"`
+		Description string   `yaml:"description"`
+		Options     []string `yaml:"options"`
+	} `yaml:"attributes"`
+	Validations struct {
+		Required bool `yaml:"required"`
+	} `yaml:"validations"`
+}
+
+// OptionValues returns the value before " - " of each dropdown option.
+func (f IssueTemplateField) OptionValues() []string {
+	values := make([]string, 0, len(f.Attributes.Options))
+	for _, option := range f.Attributes.Options {
+		value, _, _ := strings.Cut(option, " - ")
+		values = append(values, strings.TrimSpace(value))
+	}
+	return values
+}
+
+// RegionAvailabilityPath returns the absolute path of
+// config/region-availability.yaml.
+func RegionAvailabilityPath() string {
+	return filepath.Join(RepoRoot(), "config", "region-availability.yaml")
+}
+
+// LoadRegionAvailability reads and validates config/region-availability.yaml.
+func LoadRegionAvailability() (*RegionAvailability, error) {
+	data, err := os.ReadFile(RegionAvailabilityPath())
+	if err != nil {
+		return nil, err
+	}
+	return ParseRegionAvailability(data)
+}
+
+// ParseRegionAvailability decodes a region availability document, rejecting
+// unknown keys, resolves spillover regions and validates it.
+func ParseRegionAvailability(data []byte) (*RegionAvailability, error) {
+	var matrix RegionAvailability
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&matrix); err != nil {
+		return nil, fmt.Errorf("region availability: %w", err)
+	}
+
+	// Keep the file order so tier 1 regions run first
+	var order struct {
+		Regions yaml.Node `yaml:"regions"`
+	}
+	if err := yaml.Unmarshal(data, &order); err != nil {
+		return nil, fmt.Errorf("region availability: %w", err)
+	}
+	for i := 0; i+1 < len(order.Regions.Content); i += 2 {
+		name := order.Regions.Content[i].Value
+		matrix.names = append(matrix.names, name)
+		if region := matrix.Regions[name]; region != nil {
+			region.name = name
+		}
+	}
+
+	if err := matrix.validate(); err != nil {
+		return nil, err
+	}
+	return &matrix, nil
+}
+
+func (m *RegionAvailability) validate() error {
+	var problems []string
+
+	for _, name := range m.names {
+		region := m.Regions[name]
+		if region == nil {
+			problems = append(problems, fmt.Sprintf("%s: empty region", name))
+			continue
+		}
+		if region.Tier < 1 {
+			problems = append(problems, fmt.Sprintf("%s: tier must be 1 or higher, got %d", name, region.Tier))
+		}
+
+		region.spillover = map[string]string{}
+		for service, text := range region.Services {
+			match := spilloverPattern.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+
+			target := m.regionByDisplayName(match[1])
+			switch {
+			case target == nil:
+				problems = append(problems, fmt.Sprintf("%s: %s spillover %q is not a region in the file", name, service, match[1]))
+			case !target.Support(service).Supported():
+				problems = append(problems, fmt.Sprintf("%s: %s spillover %s does not support it either", name, service, target.name))
+			default:
+				region.spillover[service] = target.name
+			}
+		}
+	}
+
+	for key, pattern := range m.DeploymentPatterns {
+		regions := append([]string{pattern.PrimaryRegion, pattern.AIRegion, pattern.DRRegion}, pattern.SecondaryRegions...)
+		for _, region := range regions {
+			if region != "" && m.Regions[region] == nil {
+				problems = append(problems, fmt.Sprintf("deployment pattern %s: unknown region %q", key, region))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("region availability:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// regionByDisplayName matches "East US 2" against display names such as
+// "East US 2 (Virginia)".
+func (m *RegionAvailability) regionByDisplayName(display string) *Region {
+	display = strings.TrimSpace(display)
+	for _, name := range m.names {
+		region := m.Regions[name]
+		if region == nil {
+			continue
+		}
+		short, _, _ := strings.Cut(region.DisplayName, " (")
+		if strings.EqualFold(short, display) || strings.EqualFold(region.DisplayName, display) {
+			return region
+		}
+	}
+	return nil
+}
+
+// Names returns the region names in file order.
+func (m *RegionAvailability) Names() []string {
+	return append([]string(nil), m.names...)
+}
+
+// Region returns a region by name, or nil.
+func (m *RegionAvailability) Region(name string) *Region {
+	return m.Regions[name]
+}
+
+// RegionsInTiers returns the regions of the given tiers, in file order.
+func (m *RegionAvailability) RegionsInTiers(tiers ...int) []string {
+	var names []string
+	for _, name := range m.names {
+		for _, tier := range tiers {
+			if m.Regions[name].Tier == tier {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// Name returns the region name (its key in the file).
+func (r *Region) Name() string {
+	return r.name
+}
+
+// Support returns the support level of a service in the region.
+func (r *Region) Support(service string) Support {
+	return ParseSupport(r.Services[service])
+}
+
+// Spillover returns the documented region to use for a service the region
+// does not offer.
+func (r *Region) Spillover(service string) (string, bool) {
+	region, ok := r.spillover[service]
+	return region, ok
+}
+
+// ModulePlacement is where a module is deployed for a chosen region.
+type ModulePlacement struct {
+	Module string
+	Region string
+	// Location is Region, or the spillover region when Region lacks one of
+	// the module's services. Empty when there is no documented spillover.
+	Location string
+	// Unsupported lists the module's services Region does not offer.
+	Unsupported []string
+}
+
+// Spillover reports whether the module is routed away from its region.
+func (p ModulePlacement) Spillover() bool {
+	return p.Location != "" && p.Location != p.Region
+}
+
+// Placement decides where module goes when region is chosen. ok is false
+// when the region does not list every service of the module, so the file
+// makes no claim about it.
+func (m *RegionAvailability) Placement(module, region string) (placement ModulePlacement, ok bool) {
+	r := m.Regions[region]
+	if r == nil {
+		return ModulePlacement{}, false
+	}
+
+	placement = ModulePlacement{Module: module, Region: region, Location: region}
+	spillovers := map[string]bool{}
+
+	for _, service := range ModuleServices[module] {
+		support := r.Support(service)
+		if support == SupportUnknown {
+			return ModulePlacement{}, false
+		}
+		if support.Supported() {
+			continue
+		}
+
+		placement.Unsupported = append(placement.Unsupported, service)
+		target, found := r.Spillover(service)
+		if !found {
+			placement.Location = ""
+			continue
+		}
+		spillovers[target] = true
+	}
+
+	if placement.Location != "" && len(placement.Unsupported) > 0 {
+		// A module is deployed in one place; two different spillovers
+		// cannot both be honoured
+		if len(spillovers) != 1 {
+			placement.Location = ""
+		} else {
+			for target := range spillovers {
+				placement.Location = target
+			}
+		}
+	}
+
+	return placement, true
+}
+
+// Placements returns the placement of every module in ModuleServices for
+// every region of MatrixTiers, skipping modules a region makes no claim
+// about. Regions keep file order and modules are sorted.
+func (m *RegionAvailability) Placements() []ModulePlacement {
+	modules := make([]string, 0, len(ModuleServices))
+	for module := range ModuleServices {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	var placements []ModulePlacement
+	for _, region := range m.RegionsInTiers(MatrixTiers...) {
+		for _, module := range modules {
+			if placement, ok := m.Placement(module, region); ok {
+				placements = append(placements, placement)
+			}
+		}
+	}
+	return placements
+}
+
+// NamingRegionCodes returns the region_codes local of the naming module.
+// Regions missing from it fall back to the first four letters of the name.
+func NamingRegionCodes() (map[string]string, error) {
+	path := filepath.Join(ModuleDir(ModuleNaming), "main.tf")
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "locals"}},
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	for _, block := range content.Blocks {
+		attrs, diags := block.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		attr, ok := attrs["region_codes"]
+		if !ok {
+			continue
+		}
+
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		codes := map[string]string{}
+		for key, code := range value.AsValueMap() {
+			codes[key] = code.AsString()
+		}
+		return codes, nil
+	}
+
+	return nil, fmt.Errorf("%s: no region_codes local", path)
+}
@@ -0,0 +1,258 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - REGION AVAILABILITY TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestRegion|TestParseSupport|TestNamingRegionCodes' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadRegionAvailability(t *testing.T) *RegionAvailability {
+	t.Helper()
+
+	matrix, err := LoadRegionAvailability()
+	require.NoError(t, err)
+	return matrix
+}
+
+// TestRegionAvailabilityLoad tests that every region loads, in file order
+func TestRegionAvailabilityLoad(t *testing.T) {
+	t.Parallel()
+
+	matrix := loadRegionAvailability(t)
+
+	assert.Equal(t, []string{"brazilsouth", "eastus2", "southcentralus", "westus2"}, matrix.Names())
+	assert.Equal(t, []string{"brazilsouth", "eastus2", "southcentralus"}, matrix.RegionsInTiers(1))
+	assert.Equal(t, "brazilsouth", matrix.Region("brazilsouth").Name())
+
+	xlarge := matrix.Region("brazilsouth").SizingAvailability["xlarge"]
+	assert.True(t, xlarge.Available)
+	assert.Equal(t, "With AI spillover", xlarge.Note)
+	assert.True(t, matrix.Region("eastus2").SizingAvailability["xlarge"].Available)
+}
+
+// TestParseSupport tests the classification of free-text service entries
+func TestParseSupport(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		text     string
+		expected Support
+	}{
+		{"", SupportUnknown},
+		{"Full support", SupportFull},
+		{"Available", SupportFull},
+		{"All tiers", SupportFull},
+		{"Limited availability", SupportLimited},
+		{"Limited models (GPT-4, GPT-3.5)", SupportLimited},
+		{"Preview available", SupportLimited},
+		{"Not available (use East US 2)", SupportUnavailable},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, ParseSupport(tc.text), tc.text)
+	}
+}
+
+// TestRegionPlacement tests spillover routing and regions that make no claim
+func TestRegionPlacement(t *testing.T) {
+	t.Parallel()
+
+	matrix := loadRegionAvailability(t)
+
+	spillover, ok := matrix.Region("brazilsouth").Spillover(ServiceOpenAIGPT4o)
+	require.True(t, ok)
+	assert.Equal(t, "eastus2", spillover)
+
+	placement, ok := matrix.Placement(ModuleAIFoundry, "brazilsouth")
+	require.True(t, ok)
+	assert.Equal(t, "eastus2", placement.Location)
+	assert.Equal(t, []string{ServiceOpenAIGPT4o}, placement.Unsupported)
+	assert.True(t, placement.Spillover())
+
+	placement, ok = matrix.Placement(ModuleAKSCluster, "brazilsouth")
+	require.True(t, ok)
+	assert.Equal(t, "brazilsouth", placement.Location)
+	assert.False(t, placement.Spillover())
+
+	// westus2 has limited gpt-4o, which is still supported
+	placement, ok = matrix.Placement(ModuleAIFoundry, "westus2")
+	require.True(t, ok)
+	assert.Equal(t, "westus2", placement.Location)
+
+	// westus2 does not list PostgreSQL or Redis
+	_, ok = matrix.Placement(ModuleDatabases, "westus2")
+	assert.False(t, ok)
+}
+
+// TestRegionPlacementWithoutSpillover tests that an unavailable service with
+// no documented spillover leaves the module without a location
+func TestRegionPlacementWithoutSpillover(t *testing.T) {
+	t.Parallel()
+
+	matrix, err := ParseRegionAvailability([]byte(`
+regions:
+  chilecentral:
+    display_name: "Chile Central"
+    tier: 2
+    services:
+      ai_foundry: "Limited models"
+      openai_gpt4o: "Not available"
+`))
+	require.NoError(t, err)
+
+	placement, ok := matrix.Placement(ModuleAIFoundry, "chilecentral")
+	require.True(t, ok)
+	assert.Empty(t, placement.Location)
+	assert.Equal(t, []string{ServiceOpenAIGPT4o}, placement.Unsupported)
+}
+
+// TestRegionAvailabilityStrict tests that unknown keys and broken references are rejected
+func TestRegionAvailabilityStrict(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		yaml     string
+		expected string
+	}{
+		{
+			name: "unknown_key",
+			yaml: `
+regions:
+  eastus2:
+    display_name: "East US 2"
+    tier: 1
+    pricing: "standard"
+`,
+			expected: "field pricing not found",
+		},
+		{
+			name: "unknown_spillover",
+			yaml: `
+regions:
+  brazilsouth:
+    display_name: "Brazil South"
+    tier: 1
+    services:
+      openai_gpt4o: "Not available (use Sweden Central)"
+`,
+			expected: `brazilsouth: openai_gpt4o spillover "Sweden Central" is not a region in the file`,
+		},
+		{
+			name: "spillover_without_service",
+			yaml: `
+regions:
+  brazilsouth:
+    display_name: "Brazil South"
+    tier: 1
+    services:
+      openai_gpt4o: "Not available (use East US 2)"
+  eastus2:
+    display_name: "East US 2 (Virginia)"
+    tier: 1
+    services:
+      openai_gpt4o: "Not available"
+`,
+			expected: "brazilsouth: openai_gpt4o spillover eastus2 does not support it either",
+		},
+		{
+			name: "unknown_pattern_region",
+			yaml: `
+regions:
+  eastus2:
+    display_name: "East US 2"
+    tier: 1
+deployment_patterns:
+  us_based:
+    primary_region: "eastus2"
+    dr_region: "centralus"
+`,
+			expected: `deployment pattern us_based: unknown region "centralus"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseRegionAvailability([]byte(tc.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+// TestRegionDeploymentPatterns tests that the published patterns route AI
+// the way the matrix does
+func TestRegionDeploymentPatterns(t *testing.T) {
+	t.Parallel()
+
+	matrix := loadRegionAvailability(t)
+
+	for name, pattern := range matrix.DeploymentPatterns {
+		if pattern.AIRegion == "" {
+			continue
+		}
+
+		placement, ok := matrix.Placement(ModuleAIFoundry, pattern.PrimaryRegion)
+		require.True(t, ok, name)
+		if placement.Spillover() {
+			assert.Equal(t, placement.Location, pattern.AIRegion,
+				"%s: ai_region differs from the spillover of %s", name, pattern.PrimaryRegion)
+		}
+		assert.Equal(t, SupportFull, matrix.Region(pattern.AIRegion).Support(ServiceOpenAIGPT4o), name)
+	}
+
+	placement, ok := matrix.Placement(ModuleAIFoundry, TestLocation)
+	require.True(t, ok)
+	assert.Equal(t, TestAILocation, placement.Location)
+}
+
+// TestRegionIssueTemplateOptions tests that the issue form dropdowns match the matrix
+func TestRegionIssueTemplateOptions(t *testing.T) {
+	t.Parallel()
+
+	matrix := loadRegionAvailability(t)
+
+	fields := map[string]IssueTemplateField{}
+	for _, field := range matrix.Metadata.IssueTemplateConfig {
+		fields[field.ID] = field
+	}
+
+	require.Contains(t, fields, "azure_region")
+	assert.Equal(t, matrix.Names(), fields["azure_region"].OptionValues())
+
+	require.Contains(t, fields, "ai_region")
+	for _, value := range fields["ai_region"].OptionValues() {
+		if value == "same" {
+			continue
+		}
+		region := matrix.Region(value)
+		if assert.NotNil(t, region, "ai_region option %q", value) {
+			assert.True(t, region.Support(ServiceOpenAIGPT4o).Supported(), "ai_region option %q", value)
+		}
+	}
+}
+
+// TestNamingRegionCodes tests that the naming module has a code for every matrix region
+func TestNamingRegionCodes(t *testing.T) {
+	t.Parallel()
+
+	codes, err := NamingRegionCodes()
+	require.NoError(t, err)
+	assert.Equal(t, "brs", codes["brazilsouth"])
+
+	for _, region := range loadRegionAvailability(t).Names() {
+		assert.Contains(t, codes, region, "naming module falls back to a truncated code for %s", region)
+	}
+}
@@ -0,0 +1,449 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - OFFLINE SCAFFOLDER
+// =============================================================================
+//
+// Runs the fetch:template steps of a golden path template against a sample
+// parameter set, the way the RHDH scaffolder would, without RHDH:
+//
+//	sample, err := helpers.LoadScaffolderSample(path)
+//	result, err := template.Scaffold(sample.Parameters, t.TempDir())
+//	unresolved, err := result.Unresolved()
+//
+// Parameters get their schema defaults and are validated against every
+// parameters page. Step if conditions and inputs are evaluated with
+// ${{ parameters.x }}; a string that is a single expression keeps the
+// expression's type. Each fetch:template source is copied to its
+// targetPath with file names and contents rendered against
+// ${{ values.x }} (see nunjucks.go); copyWithoutTemplating globs and
+// binary files are copied as they are.
+//
+// Other actions (publish:github, catalog:register, ...) are not run, and
+// steps['id'].output references evaluate to undefined. A source that is
+// not a directory under skeleton/ is reported in Missing and skipped.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// unresolvedPattern matches scaffolder placeholders and Nunjucks tags left
+// in a rendered file. ${{ github.x }} and other GitHub Actions expressions
+// are expected in workflows and are not matched.
+var unresolvedPattern = regexp.MustCompile(`\$\{\{\s*(?:values|parameters)\b[^}]*\}\}|\{%.*?%\}|\{#.*?#\}`)
+
+// ScaffolderSamplesDir returns the directory of the sample parameter sets.
+func ScaffolderSamplesDir() string {
+	return filepath.Join(TestDataDir(), "backstage", "samples")
+}
+
+// ScaffolderSample is a sample parameter set for one template and what
+// the generated catalog-info.yaml must declare.
+type ScaffolderSample struct {
+	// Template is the metadata.name of the template.
+	Template   string                 `yaml:"template"`
+	Parameters map[string]interface{} `yaml:"parameters"`
+	Expect     struct {
+		// Owner is the spec.owner of every catalog entity.
+		Owner string `yaml:"owner"`
+		// Tags must all be in the Component's metadata.tags.
+		Tags []string `yaml:"tags"`
+	} `yaml:"expect"`
+
+	Source string `yaml:"-"`
+}
+
+// LoadScaffolderSample reads a sample parameter set.
+func LoadScaffolderSample(path string) (*ScaffolderSample, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sample ScaffolderSample
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&sample); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var problems []string
+	if sample.Template == "" {
+		problems = append(problems, "no template")
+	}
+	if sample.Expect.Owner == "" {
+		problems = append(problems, "no expect.owner")
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s:\n  - %s", path, strings.Join(problems, "\n  - "))
+	}
+
+	sample.Source = filepath.Base(path)
+	return &sample, nil
+}
+
+// ScaffolderResult is the outcome of Scaffold.
+type ScaffolderResult struct {
+	// Dir is the workspace the files were written to.
+	Dir string
+	// Files are the written files, relative to Dir, sorted.
+	Files []string
+	// Undefined lists "<where>: <reference>" for every undefined value a
+	// step input or file printed or tested.
+	Undefined []string
+	// Skipped are the ids of steps whose if condition was false.
+	Skipped []string
+	// Missing are the fetch:template sources that were not rendered.
+	Missing []SkeletonProblem
+}
+
+// DefaultParameters returns parameters with the schema default of every
+// property that is not set.
+func (t *SoftwareTemplate) DefaultParameters(parameters map[string]interface{}) map[string]interface{} {
+	resolved := map[string]interface{}{}
+	for name, schema := range t.Parameters() {
+		if value, ok := schema["default"]; ok {
+			resolved[name] = value
+		}
+	}
+	for name, value := range parameters {
+		resolved[name] = value
+	}
+	return resolved
+}
+
+// ValidateParameters validates parameters against every parameters page.
+func (t *SoftwareTemplate) ValidateParameters(parameters map[string]interface{}) []string {
+	var problems []string
+	for i, page := range t.Spec.Parameters {
+		location := fmt.Sprintf("parameters page %d", i+1)
+		if title, ok := page["title"].(string); ok {
+			location = fmt.Sprintf("%s %q", location, title)
+		}
+
+		schema, err := compilePage(page)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", location, err))
+			continue
+		}
+		if err := schema.validate(pageSchemaURL, parameters); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", location, err))
+		}
+	}
+	return problems
+}
+
+// Scaffold renders the template's fetch:template steps into dir.
+func (t *SoftwareTemplate) Scaffold(parameters map[string]interface{}, dir string) (*ScaffolderResult, error) {
+	parameters = t.DefaultParameters(parameters)
+	if problems := t.ValidateParameters(parameters); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid parameters:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	result := &ScaffolderResult{Dir: dir}
+	context := map[string]interface{}{
+		"parameters": parameters,
+		"steps":      map[string]interface{}{},
+	}
+	skeleton := filepath.Join(t.Dir, "skeleton")
+
+	for _, step := range t.Spec.Steps {
+		location := "step " + step.ID
+
+		if step.If != nil {
+			condition, err := renderInput(step.If, context, location, result)
+			if err != nil {
+				return nil, fmt.Errorf("%s: if: %w", location, err)
+			}
+			if !truthy(condition) {
+				result.Skipped = append(result.Skipped, step.ID)
+				continue
+			}
+		}
+		if step.Action != "fetch:template" {
+			continue
+		}
+
+		value, err := renderInput(step.Input, context, location, result)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", location, err)
+		}
+		input, _ := value.(map[string]interface{})
+
+		source := nunjucksString(input["url"])
+		path := filepath.Join(t.Dir, source)
+		if rel, err := filepath.Rel(skeleton, path); err != nil || strings.HasPrefix(rel, "..") {
+			result.Missing = append(result.Missing, SkeletonProblem{Step: step.ID, URL: source, Problem: "is outside skeleton/"})
+			continue
+		}
+		if !isDir(path) {
+			result.Missing = append(result.Missing, SkeletonProblem{Step: step.ID, URL: source, Problem: "does not exist"})
+			continue
+		}
+
+		target := filepath.Join(dir, nunjucksString(input["targetPath"]))
+		if rel, err := filepath.Rel(dir, target); err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("%s: targetPath %v is outside the workspace", location, input["targetPath"])
+		}
+
+		values, _ := input["values"].(map[string]interface{})
+		var verbatim []string
+		for _, key := range []string{"copyWithoutTemplating", "copyWithoutRender"} {
+			globs, _ := input[key].([]interface{})
+			for _, glob := range globs {
+				verbatim = append(verbatim, nunjucksString(glob))
+			}
+		}
+
+		if err := result.fetchTemplate(path, target, values, verbatim); err != nil {
+			return nil, fmt.Errorf("%s: %w", location, err)
+		}
+	}
+
+	sort.Strings(result.Files)
+	return result, nil
+}
+
+// fetchTemplate copies the source directory to target, rendering paths and
+// contents against values.
+func (r *ScaffolderResult) fetchTemplate(source, target string, values map[string]interface{}, verbatim []string) error {
+	context := map[string]interface{}{"values": values}
+
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		renderedRel, undefined, err := RenderNunjucks(filepath.ToSlash(rel), context)
+		if err != nil {
+			return fmt.Errorf("%s: file name: %w", rel, err)
+		}
+		for _, reference := range undefined {
+			r.Undefined = append(r.Undefined, fmt.Sprintf("%s (name): %s", rel, reference))
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if utf8.Valid(data) && !matchesAnyGlob(verbatim, filepath.ToSlash(rel)) {
+			rendered, undefined, err := RenderNunjucks(string(data), context)
+			if err != nil {
+				return fmt.Errorf("%s: %w", rel, err)
+			}
+			for _, reference := range undefined {
+				r.Undefined = append(r.Undefined, fmt.Sprintf("%s: %s", rel, reference))
+			}
+			data = []byte(rendered)
+		}
+
+		destination := filepath.Join(target, filepath.FromSlash(renderedRel))
+		if err := os.MkdirAll(filepath.Dir(destination), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(destination, data, info.Mode().Perm()); err != nil {
+			return err
+		}
+
+		written, err := filepath.Rel(r.Dir, destination)
+		if err != nil {
+			return err
+		}
+		if !containsString(r.Files, filepath.ToSlash(written)) {
+			r.Files = append(r.Files, filepath.ToSlash(written))
+		}
+		return nil
+	})
+}
+
+// Unresolved returns "<file>:<line>: <placeholder>" for every scaffolder
+// placeholder or Nunjucks tag left in the written files.
+func (r *ScaffolderResult) Unresolved() ([]string, error) {
+	var unresolved []string
+	for _, file := range r.Files {
+		data, err := os.ReadFile(filepath.Join(r.Dir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(data) {
+			continue
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			for _, match := range unresolvedPattern.FindAllString(line, -1) {
+				unresolved = append(unresolved, fmt.Sprintf("%s:%d: %s", file, i+1, match))
+			}
+		}
+	}
+	return unresolved, nil
+}
+
+// Manifests returns the Kubernetes objects the generated project deploys.
+// A directory with a kustomization.yaml is built with kustomize, and the
+// files it builds are not read again; every other .yaml or .yml file is
+// read as it is. Backstage entities, Kustomizations and documents without
+// apiVersion and kind are skipped.
+func (r *ScaffolderResult) Manifests() ([]Manifest, error) {
+	var manifests []Manifest
+	built := map[string]bool{}
+
+	for _, file := range r.Files {
+		if path.Base(file) != "kustomization.yaml" {
+			continue
+		}
+		dir := path.Dir(file)
+		for _, other := range r.Files {
+			if strings.HasPrefix(other, dir+"/") || dir == "." {
+				built[other] = true
+			}
+		}
+
+		resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).
+			Run(filesys.MakeFsOnDisk(), filepath.Join(r.Dir, filepath.FromSlash(dir)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: kustomize build: %w", file, err)
+		}
+		for index, resource := range resources.Resources() {
+			object, err := resource.Map()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			manifests = append(manifests, Manifest{
+				Source: fmt.Sprintf("%s#%d", file, index),
+				Object: object,
+			})
+		}
+	}
+
+	for _, file := range r.Files {
+		if built[file] || (path.Ext(file) != ".yaml" && path.Ext(file) != ".yml") {
+			continue
+		}
+		loaded, err := LoadManifests(filepath.Join(r.Dir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		for _, manifest := range loaded {
+			if !isKubernetesObject(manifest) {
+				continue
+			}
+			manifest.Source = path.Join(path.Dir(file), manifest.Source)
+			manifests = append(manifests, manifest)
+		}
+	}
+
+	return manifests, nil
+}
+
+// Entities returns the Backstage catalog entities in catalog-info.yaml
+// files of the generated project.
+func (r *ScaffolderResult) Entities() ([]Manifest, error) {
+	var entities []Manifest
+	for _, file := range r.Files {
+		if path.Base(file) != "catalog-info.yaml" {
+			continue
+		}
+		loaded, err := LoadManifests(filepath.Join(r.Dir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		for _, manifest := range loaded {
+			if manifest.Group() == "backstage.io" {
+				manifest.Source = path.Join(path.Dir(file), manifest.Source)
+				entities = append(entities, manifest)
+			}
+		}
+	}
+	return entities, nil
+}
+
+// isKubernetesObject reports whether a document is an object to deploy.
+func isKubernetesObject(manifest Manifest) bool {
+	apiVersion, _ := manifest.Object["apiVersion"].(string)
+	if apiVersion == "" || manifest.Kind() == "" {
+		return false
+	}
+	group := manifest.Group()
+	return group != "backstage.io" && group != "scaffolder.backstage.io" &&
+		group != "kustomize.config.k8s.io"
+}
+
+// renderInput evaluates the expressions in a step input. A string that is
+// a single ${{ }} expression evaluates to the expression's value.
+func renderInput(value interface{}, context map[string]interface{}, location string, result *ScaffolderResult) (interface{}, error) {
+	record := func(undefined []string) {
+		for _, reference := range undefined {
+			result.Undefined = append(result.Undefined, fmt.Sprintf("%s: %s", location, reference))
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if match := templateExpressionPattern.FindStringSubmatch(trimmed); match != nil && match[0] == trimmed {
+			evaluated, undefined, err := EvaluateNunjucks(match[1], context)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", v, err)
+			}
+			record(undefined)
+			return evaluated, nil
+		}
+		rendered, undefined, err := RenderNunjucks(v, context)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v, err)
+		}
+		record(undefined)
+		return rendered, nil
+
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for _, key := range sortedSchemaKeys(v) {
+			item, err := renderInput(v[key], context, location, result)
+			if err != nil {
+				return nil, err
+			}
+			rendered[key] = item
+		}
+		return rendered, nil
+
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if rendered[i], err = renderInput(item, context, location, result); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	}
+	return value, nil
+}
+
+// matchesAnyGlob reports whether path matches one of the globs, or is
+// inside a directory a glob names.
+func matchesAnyGlob(globs []string, path string) bool {
+	for _, glob := range globs {
+		glob = strings.TrimPrefix(strings.TrimSuffix(glob, "/**"), "./")
+		if ok, _ := filepath.Match(glob, path); ok || strings.HasPrefix(path, glob+"/") {
+			return true
+		}
+	}
+	return false
+}
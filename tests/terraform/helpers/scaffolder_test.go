@@ -0,0 +1,191 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - OFFLINE SCAFFOLDER TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestScaffold' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testScaffolderTemplate = `
+apiVersion: scaffolder.backstage.io/v1beta3
+kind: Template
+metadata:
+  name: test-service
+spec:
+  owner: platform-engineering
+  type: service
+  parameters:
+    - title: Service
+      required: [name, owner]
+      properties:
+        name:
+          type: string
+          pattern: '^[a-z][a-z0-9-]*$'
+        owner:
+          type: string
+        replicas:
+          type: integer
+          default: 2
+        withDocs:
+          type: boolean
+          default: false
+  steps:
+    - id: fetch
+      name: Fetch
+      action: fetch:template
+      input:
+        url: ./skeleton
+        copyWithoutTemplating:
+          - .github/workflows/*
+        values:
+          name: ${{ parameters.name }}
+          owner: ${{ parameters.owner }}
+          replicas: ${{ parameters.replicas }}
+    - id: docs
+      name: Docs
+      if: ${{ parameters.withDocs }}
+      action: fetch:template
+      input:
+        url: ./docs
+    - id: infra
+      name: Infra
+      action: fetch:template
+      input:
+        url: ./skeleton-infra
+        targetPath: ./infra
+    - id: publish
+      name: Publish
+      action: publish:github
+      input:
+        repoUrl: ${{ steps['fetch'].output.repoUrl }}
+`
+
+func writeScaffolderTemplate(t *testing.T, files map[string]string) *SoftwareTemplate {
+	t.Helper()
+
+	template := writeSoftwareTemplate(t, testScaffolderTemplate)
+	for name, content := range files {
+		path := filepath.Join(template.Dir, "skeleton", filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return template
+}
+
+// TestScaffold tests rendering, conditions and missing sources
+func TestScaffold(t *testing.T) {
+	t.Parallel()
+
+	template := writeScaffolderTemplate(t, map[string]string{
+		"catalog-info.yaml":         "metadata:\n  name: ${{ values.name }}\nspec:\n  owner: ${{ values.owner }}\n  system: ${{ values.system }}\n",
+		"${{ values.name }}.txt":    "{% if values.replicas > 1 %}ha{% endif %}\n",
+		".github/workflows/ci.yaml": "run: echo ${{ values.name }}\n",
+		"README.md":                 "# {% raw %}${{ values.name }}{% endraw %}\n",
+	})
+
+	dir := t.TempDir()
+	result, err := template.Scaffold(map[string]interface{}{"name": "orders", "owner": "team-orders"}, dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{".github/workflows/ci.yaml", "README.md", "catalog-info.yaml", "orders.txt"}, result.Files)
+	assert.Equal(t, []string{"catalog-info.yaml: values.system"}, result.Undefined)
+	assert.Equal(t, []string{"docs"}, result.Skipped)
+	assert.Equal(t, []SkeletonProblem{{Step: "infra", URL: "./skeleton-infra", Problem: "is outside skeleton/"}}, result.Missing)
+
+	data, err := os.ReadFile(filepath.Join(dir, "catalog-info.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "metadata:\n  name: orders\nspec:\n  owner: team-orders\n  system: \n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, "orders.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "ha\n", string(data))
+
+	unresolved, err := result.Unresolved()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		".github/workflows/ci.yaml:1: ${{ values.name }}",
+		"README.md:1: ${{ values.name }}",
+	}, unresolved)
+}
+
+// TestScaffoldInvalidParameters tests that parameters are validated
+// against the pages before rendering
+func TestScaffoldInvalidParameters(t *testing.T) {
+	t.Parallel()
+
+	template := writeScaffolderTemplate(t, nil)
+
+	_, err := template.Scaffold(map[string]interface{}{"name": "Orders", "replicas": "two"}, t.TempDir())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `parameters page 1 "Service"`)
+	assert.Contains(t, err.Error(), "missing properties: 'owner'")
+}
+
+// TestScaffoldManifests tests that kustomizations are built and entities
+// are kept apart from Kubernetes objects
+func TestScaffoldManifests(t *testing.T) {
+	t.Parallel()
+
+	template := writeScaffolderTemplate(t, map[string]string{
+		"catalog-info.yaml": "apiVersion: backstage.io/v1alpha1\nkind: Component\nmetadata:\n  name: ${{ values.name }}\nspec:\n  owner: ${{ values.owner }}\n",
+		"deploy/kustomization.yaml": "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nnamespace: ${{ values.name }}\n" +
+			"resources:\n  - service.yaml\ncommonLabels:\n  app.kubernetes.io/name: ${{ values.name }}\n",
+		"deploy/service.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: ${{ values.name }}\nspec:\n  ports:\n    - port: 80\n",
+		"config/map.yaml":     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: ${{ values.name }}\n---\nnot: an object\n",
+	})
+
+	result, err := template.Scaffold(map[string]interface{}{"name": "orders", "owner": "team-orders"}, t.TempDir())
+	require.NoError(t, err)
+
+	manifests, err := result.Manifests()
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	assert.Equal(t, "deploy/kustomization.yaml#0", manifests[0].Source)
+	assert.Equal(t, "Service", manifests[0].Kind())
+	assert.Equal(t, "orders", manifests[0].Namespace())
+	assert.Equal(t, map[string]string{"app.kubernetes.io/name": "orders"}, manifests[0].Labels())
+
+	assert.Equal(t, "config/map.yaml#0", manifests[1].Source)
+	assert.Equal(t, "ConfigMap", manifests[1].Kind())
+
+	entities, err := result.Entities()
+	require.NoError(t, err)
+	require.Len(t, entities, 1)
+	assert.Equal(t, "catalog-info.yaml#0", entities[0].Source)
+	assert.Equal(t, "orders", entities[0].Name())
+}
+
+// TestScaffolderSample tests the sample loader
+func TestScaffolderSample(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "test-service.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("template: test-service\nparameters:\n  name: orders\nexpect:\n  owner: team-orders\n  tags: [go]\n"), 0o644))
+	sample, err := LoadScaffolderSample(path)
+	require.NoError(t, err)
+	assert.Equal(t, "test-service", sample.Template)
+	assert.Equal(t, map[string]interface{}{"name": "orders"}, sample.Parameters)
+	assert.Equal(t, []string{"go"}, sample.Expect.Tags)
+	assert.Equal(t, "test-service.yaml", sample.Source)
+
+	require.NoError(t, os.WriteFile(path, []byte("parameters: {}\n"), 0o644))
+	_, err = LoadScaffolderSample(path)
+	assert.ErrorContains(t, err, "no template")
+	assert.ErrorContains(t, err, "no expect.owner")
+
+	require.NoError(t, os.WriteFile(path, []byte("template: x\nowner: y\n"), 0o644))
+	_, err = LoadScaffolderSample(path)
+	assert.ErrorContains(t, err, "field owner not found")
+}
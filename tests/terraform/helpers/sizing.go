@@ -0,0 +1,732 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - SIZING PROFILES
+// =============================================================================
+//
+// Typed loader for config/sizing-profiles.yaml and the translation of each
+// T-shirt profile into module inputs:
+//
+//	profiles, err := helpers.LoadSizingProfiles()
+//	profile := profiles.Get("large")
+//	options := helpers.OptionsFor(t, helpers.ModuleAKSCluster, profile.VarsFor(helpers.ModuleAKSCluster))
+//
+// Decoding is strict: an unknown key in the YAML is an error, so a profile
+// cannot grow a setting that no test (and no module) reads.
+//
+// The profiles are not all shaped alike. small and medium have a single
+// node_pool, large has node_pools, and xlarge nests AKS and PostgreSQL under
+// primary/secondary. The accessors below hide those differences.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SizingModules are the modules whose inputs are derived from a profile.
+var SizingModules = []string{
+	ModuleAKSCluster,
+	ModuleDatabases,
+	ModuleContainerRegistry,
+	ModuleObservability,
+	ModuleAIFoundry,
+}
+
+// DefaultMaxPods is max_pods of a node pool that does not set it.
+const DefaultMaxPods = 110
+
+// OpenAIModel is the deployment used for a model key of a profile.
+type OpenAIModel struct {
+	Name    string
+	Version string
+}
+
+// OpenAIModels maps the model keys of sizing-profiles.yaml to deployments.
+var OpenAIModels = map[string]OpenAIModel{
+	"gpt4o":      {Name: "gpt-4o", Version: "2024-05-13"},
+	"gpt4o_mini": {Name: "gpt-4o-mini", Version: "2024-07-18"},
+	"embedding":  {Name: "text-embedding-3-large", Version: "1"},
+}
+
+// SizingProfiles is config/sizing-profiles.yaml.
+type SizingProfiles struct {
+	Profiles map[string]*SizingProfile `yaml:"profiles"`
+	Metadata struct {
+		Version               string        `yaml:"version"`
+		UsageInIssueTemplates []interface{} `yaml:"usage_in_issue_templates"`
+	} `yaml:"metadata"`
+
+	names []string
+}
+
+// SizingProfile is one T-shirt size.
+type SizingProfile struct {
+	Profile          string                   `yaml:"profile"`
+	Description      string                   `yaml:"description"`
+	MultiRegion      bool                     `yaml:"multi_region"`
+	Regions          *ProfileRegions          `yaml:"regions"`
+	Infrastructure   ProfileInfrastructure    `yaml:"infrastructure"`
+	Databases        ProfileDatabases         `yaml:"databases"`
+	AIFoundry        *AIFoundryProfile        `yaml:"ai_foundry"`
+	Observability    *ObservabilityProfile    `yaml:"observability"`
+	DisasterRecovery *DisasterRecoveryProfile `yaml:"disaster_recovery"`
+}
+
+// ProfileRegions are the regions of a multi-region profile.
+type ProfileRegions struct {
+	Primary   string `yaml:"primary"`
+	Secondary string `yaml:"secondary"`
+}
+
+// ProfileInfrastructure holds the platform settings. Multi-region profiles
+// set Primary/Secondary instead of AKS.
+type ProfileInfrastructure struct {
+	AKS        *AKSProfile        `yaml:"aks"`
+	Primary    *RegionProfile     `yaml:"primary"`
+	Secondary  *RegionProfile     `yaml:"secondary"`
+	ACR        ACRProfile         `yaml:"acr"`
+	KeyVault   *KeyVaultProfile   `yaml:"keyvault"`
+	Networking *NetworkingProfile `yaml:"networking"`
+	FrontDoor  *FrontDoorProfile  `yaml:"front_door"`
+}
+
+// RegionProfile is the per-region infrastructure of a multi-region profile.
+type RegionProfile struct {
+	AKS AKSProfile `yaml:"aks"`
+}
+
+// AKSProfile is the cluster of a profile.
+type AKSProfile struct {
+	Name              string            `yaml:"name"`
+	KubernetesVersion string            `yaml:"kubernetes_version"`
+	NodePool          *NodePoolProfile  `yaml:"node_pool"`
+	NodePools         []NodePoolProfile `yaml:"node_pools"`
+	AutoScaling       *AutoScaling      `yaml:"auto_scaling"`
+	Features          map[string]bool   `yaml:"features"`
+}
+
+// NodePoolProfile is one node pool. Zero values mean "not set".
+type NodePoolProfile struct {
+	Name         string       `yaml:"name"`
+	NodeCount    int          `yaml:"node_count"`
+	VMSize       string       `yaml:"vm_size"`
+	OSDiskSizeGB int          `yaml:"os_disk_size_gb"`
+	MaxPods      int          `yaml:"max_pods"`
+	Mode         string       `yaml:"mode"`
+	Zones        []string     `yaml:"zones"`
+	AutoScaling  *AutoScaling `yaml:"auto_scaling"`
+	Taints       []string     `yaml:"taints"`
+}
+
+// AutoScaling is the autoscaler setting of a node pool.
+type AutoScaling struct {
+	Enabled  bool `yaml:"enabled"`
+	MinNodes int  `yaml:"min_nodes"`
+	MaxNodes int  `yaml:"max_nodes"`
+}
+
+// ACRProfile is the container registry of a profile.
+type ACRProfile struct {
+	Name           string           `yaml:"name"`
+	SKU            string           `yaml:"sku"`
+	GeoReplication bool             `yaml:"geo_replication"`
+	Replications   []ACRReplication `yaml:"replications"`
+	RetentionDays  int              `yaml:"retention_days"`
+	ZoneRedundancy bool             `yaml:"zone_redundancy"`
+}
+
+// ACRReplication is one geo-replica.
+type ACRReplication struct {
+	Location       string `yaml:"location"`
+	ZoneRedundancy bool   `yaml:"zone_redundancy"`
+}
+
+// KeyVaultProfile is the Key Vault of a profile.
+type KeyVaultProfile struct {
+	Name            string `yaml:"name"`
+	SKU             string `yaml:"sku"`
+	SoftDeleteDays  int    `yaml:"soft_delete_days"`
+	PurgeProtection bool   `yaml:"purge_protection"`
+}
+
+// NetworkingProfile is the network layout of a profile.
+type NetworkingProfile struct {
+	VNetCIDR               string                     `yaml:"vnet_cidr"`
+	AKSSubnet              string                     `yaml:"aks_subnet"`
+	ServicesSubnet         string                     `yaml:"services_subnet"`
+	PrivateEndpointsSubnet string                     `yaml:"private_endpoints_subnet"`
+	AppGatewaySubnet       string                     `yaml:"appgw_subnet"`
+	PrivateCluster         bool                       `yaml:"private_cluster"`
+	ApplicationGateway     *ApplicationGatewayProfile `yaml:"application_gateway"`
+}
+
+// ApplicationGatewayProfile is the ingress gateway of a profile.
+type ApplicationGatewayProfile struct {
+	Enabled  bool   `yaml:"enabled"`
+	SKU      string `yaml:"sku"`
+	Capacity int    `yaml:"capacity"`
+}
+
+// FrontDoorProfile is the global entry point of a multi-region profile.
+type FrontDoorProfile struct {
+	Enabled   bool   `yaml:"enabled"`
+	SKU       string `yaml:"sku"`
+	WAFPolicy bool   `yaml:"waf_policy"`
+}
+
+// ProfileDatabases holds the data services of a profile.
+type ProfileDatabases struct {
+	PostgreSQL PostgreSQLProfile `yaml:"postgresql"`
+	Redis      RedisProfile      `yaml:"redis"`
+	CosmosDB   *CosmosDBProfile  `yaml:"cosmos_db"`
+}
+
+// PostgreSQLProfile is the PostgreSQL flexible server of a profile.
+// Multi-region profiles set Primary and ReadReplicas instead.
+type PostgreSQLProfile struct {
+	Enabled             *bool               `yaml:"enabled"`
+	Name                string              `yaml:"name"`
+	Version             string              `yaml:"version"`
+	SKU                 string              `yaml:"sku"`
+	StorageGB           int                 `yaml:"storage_gb"`
+	HAEnabled           bool                `yaml:"ha_enabled"`
+	HAMode              string              `yaml:"ha_mode"`
+	BackupRetentionDays int                 `yaml:"backup_retention_days"`
+	GeoRedundantBackup  bool                `yaml:"geo_redundant_backup"`
+	Primary             *PostgreSQLProfile  `yaml:"primary"`
+	ReadReplicas        []PostgreSQLReplica `yaml:"read_replicas"`
+}
+
+// PostgreSQLReplica is a cross-region read replica.
+type PostgreSQLReplica struct {
+	Region string `yaml:"region"`
+	SKU    string `yaml:"sku"`
+}
+
+// RedisProfile is the Redis cache of a profile.
+type RedisProfile struct {
+	Enabled        *bool    `yaml:"enabled"`
+	Name           string   `yaml:"name"`
+	SKU            string   `yaml:"sku"`
+	Family         string   `yaml:"family"`
+	Capacity       int      `yaml:"capacity"`
+	Zones          []string `yaml:"zones"`
+	GeoReplication bool     `yaml:"geo_replication"`
+}
+
+// CosmosDBProfile is the Cosmos DB account of a profile.
+type CosmosDBProfile struct {
+	Enabled           bool     `yaml:"enabled"`
+	API               string   `yaml:"api"`
+	MultiRegionWrites bool     `yaml:"multi_region_writes"`
+	Regions           []string `yaml:"regions"`
+}
+
+// AIFoundryProfile is the AI platform of a profile. Multi-region profiles
+// set Regions instead of Models.
+type AIFoundryProfile struct {
+	Enabled       *bool                      `yaml:"enabled"`
+	MultiRegion   bool                       `yaml:"multi_region"`
+	Models        map[string]ModelProfile    `yaml:"models"`
+	Regions       map[string]AIFoundryRegion `yaml:"regions"`
+	AISearch      *AISearchProfile           `yaml:"ai_search"`
+	ContentSafety *ContentSafetyProfile      `yaml:"content_safety"`
+}
+
+// AIFoundryRegion is the per-region model capacity of a multi-region profile.
+type AIFoundryRegion struct {
+	Models map[string]ModelProfile `yaml:"models"`
+}
+
+// ModelProfile is the capacity of one model deployment.
+type ModelProfile struct {
+	CapacityTPM int `yaml:"capacity_tpm"`
+}
+
+// AISearchProfile is the AI Search service of a profile.
+type AISearchProfile struct {
+	SKU        string `yaml:"sku"`
+	Replicas   int    `yaml:"replicas"`
+	Partitions int    `yaml:"partitions"`
+}
+
+// ContentSafetyProfile is the Content Safety service of a profile.
+type ContentSafetyProfile struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// ObservabilityProfile is the monitoring stack of a profile.
+type ObservabilityProfile struct {
+	LogAnalytics *struct {
+		RetentionDays int `yaml:"retention_days"`
+	} `yaml:"log_analytics"`
+	Prometheus *struct {
+		RetentionDays int  `yaml:"retention_days"`
+		StorageGB     int  `yaml:"storage_gb"`
+		HAEnabled     bool `yaml:"ha_enabled"`
+	} `yaml:"prometheus"`
+	Grafana *struct {
+		SKU string `yaml:"sku"`
+	} `yaml:"grafana"`
+	Alertmanager *struct {
+		PagerDuty bool `yaml:"pagerduty"`
+	} `yaml:"alertmanager"`
+}
+
+// DisasterRecoveryProfile is the recovery objective of a profile.
+type DisasterRecoveryProfile struct {
+	RPOMinutes int `yaml:"rpo_minutes"`
+	RTOMinutes int `yaml:"rto_minutes"`
+	Backup     struct {
+		CrossRegion   bool `yaml:"cross_region"`
+		RetentionDays int  `yaml:"retention_days"`
+	} `yaml:"backup"`
+}
+
+// SizingProfilesPath returns the absolute path of config/sizing-profiles.yaml.
+func SizingProfilesPath() string {
+	return filepath.Join(RepoRoot(), "config", "sizing-profiles.yaml")
+}
+
+// LoadSizingProfiles reads and validates config/sizing-profiles.yaml.
+func LoadSizingProfiles() (*SizingProfiles, error) {
+	data, err := os.ReadFile(SizingProfilesPath())
+	if err != nil {
+		return nil, err
+	}
+	return ParseSizingProfiles(data)
+}
+
+// ParseSizingProfiles decodes a sizing profiles document, rejecting unknown
+// keys, and validates it.
+func ParseSizingProfiles(data []byte) (*SizingProfiles, error) {
+	var profiles SizingProfiles
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&profiles); err != nil {
+		return nil, fmt.Errorf("sizing profiles: %w", err)
+	}
+
+	// Maps lose the file order; read it separately so matrices run
+	// small -> xlarge
+	var order struct {
+		Profiles yaml.Node `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &order); err != nil {
+		return nil, fmt.Errorf("sizing profiles: %w", err)
+	}
+	for i := 0; i+1 < len(order.Profiles.Content); i += 2 {
+		profiles.names = append(profiles.names, order.Profiles.Content[i].Value)
+	}
+
+	if err := profiles.validate(); err != nil {
+		return nil, err
+	}
+	return &profiles, nil
+}
+
+func (s *SizingProfiles) validate() error {
+	var problems []string
+
+	for _, name := range s.names {
+		profile := s.Profiles[name]
+		if profile == nil {
+			problems = append(problems, fmt.Sprintf("%s: empty profile", name))
+			continue
+		}
+		if profile.Profile != name {
+			problems = append(problems, fmt.Sprintf("%s: profile field is %q", name, profile.Profile))
+		}
+		if profile.AKS() == nil {
+			problems = append(problems, fmt.Sprintf("%s: no aks settings", name))
+		} else if len(profile.AKS().Pools()) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: aks has no node pools", name))
+		}
+		for model := range profile.OpenAIModelCapacity() {
+			if _, ok := OpenAIModels[model]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: ai_foundry model %q has no entry in helpers.OpenAIModels", name, model))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("sizing profiles:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// Names returns the profile names in file order.
+func (s *SizingProfiles) Names() []string {
+	return append([]string(nil), s.names...)
+}
+
+// Get returns a profile by name, or nil.
+func (s *SizingProfiles) Get(name string) *SizingProfile {
+	return s.Profiles[name]
+}
+
+// AKS returns the cluster of the profile, the primary one for multi-region
+// profiles.
+func (p *SizingProfile) AKS() *AKSProfile {
+	if p.Infrastructure.AKS != nil {
+		return p.Infrastructure.AKS
+	}
+	if p.Infrastructure.Primary != nil {
+		return &p.Infrastructure.Primary.AKS
+	}
+	return nil
+}
+
+// Pools returns the node pools whichever form the profile uses. A single
+// node_pool inherits the cluster-level auto_scaling.
+func (a *AKSProfile) Pools() []NodePoolProfile {
+	if a.NodePool != nil {
+		pool := *a.NodePool
+		if pool.AutoScaling == nil {
+			pool.AutoScaling = a.AutoScaling
+		}
+		return []NodePoolProfile{pool}
+	}
+	return a.NodePools
+}
+
+// SystemPool returns the pool that becomes the module's default_node_pool:
+// the first System-mode pool, the pool named "system", or the first pool.
+func (a *AKSProfile) SystemPool() NodePoolProfile {
+	pools := a.Pools()
+	for _, pool := range pools {
+		if pool.Mode == "System" {
+			return pool
+		}
+	}
+	for _, pool := range pools {
+		if pool.Name == "system" {
+			return pool
+		}
+	}
+	return pools[0]
+}
+
+// UserPools returns every pool except the system pool.
+func (a *AKSProfile) UserPools() []NodePoolProfile {
+	system := a.SystemPool().Name
+
+	var pools []NodePoolProfile
+	for _, pool := range a.Pools() {
+		if pool.Name != system {
+			pools = append(pools, pool)
+		}
+	}
+	return pools
+}
+
+// MaxNodes returns the node count of the pool at full scale.
+func (pool NodePoolProfile) MaxNodes() int {
+	if pool.AutoScaling != nil && pool.AutoScaling.Enabled {
+		return pool.AutoScaling.MaxNodes
+	}
+	return pool.NodeCount
+}
+
+// AddressDemand returns the node and pod addresses the cluster of the
+// profile needs at full scale, with one surge node per pool for upgrades.
+// Every pod gets an address from the pod subnet.
+func (p *SizingProfile) AddressDemand() (nodes, pods int) {
+	aks := p.AKS()
+	if aks == nil {
+		return 0, 0
+	}
+	for _, pool := range aks.Pools() {
+		maxPods := pool.MaxPods
+		if maxPods == 0 {
+			maxPods = DefaultMaxPods
+		}
+		count := pool.MaxNodes() + 1
+		nodes += count
+		pods += count * maxPods
+	}
+	return nodes, pods
+}
+
+// PostgreSQL returns the PostgreSQL server of the profile, the primary one
+// for multi-region profiles.
+func (p *SizingProfile) PostgreSQL() PostgreSQLProfile {
+	if p.Databases.PostgreSQL.Primary != nil {
+		return *p.Databases.PostgreSQL.Primary
+	}
+	return p.Databases.PostgreSQL
+}
+
+// OpenAIModelCapacity returns the tokens-per-minute of each model key, from
+// the primary region for multi-region profiles.
+func (p *SizingProfile) OpenAIModelCapacity() map[string]int {
+	capacity := map[string]int{}
+	if p.AIFoundry == nil {
+		return capacity
+	}
+
+	models := p.AIFoundry.Models
+	if primary, ok := p.AIFoundry.Regions["primary"]; ok {
+		models = primary.Models
+	}
+	for name, model := range models {
+		capacity[name] = model.CapacityTPM
+	}
+	return capacity
+}
+
+// enabled reads an optional enabled flag; a section without one is enabled.
+func enabled(flag *bool) bool {
+	return flag == nil || *flag
+}
+
+// FlexibleServerSKU turns a VM size from the profile ("Standard_D4ds_v5")
+// into a PostgreSQL flexible server SKU name ("GP_Standard_D4ds_v5").
+func FlexibleServerSKU(vmSize string) string {
+	series := strings.TrimPrefix(vmSize, "Standard_")
+	if series == "" {
+		return vmSize
+	}
+
+	switch series[0] {
+	case 'B':
+		return "B_" + vmSize
+	case 'E':
+		return "MO_" + vmSize
+	default:
+		return "GP_" + vmSize
+	}
+}
+
+// OpenAICapacityUnits turns tokens per minute into deployment capacity units
+// (1 unit = 1,000 TPM on Standard deployments).
+func OpenAICapacityUnits(tpm int) int {
+	return tpm / 1000
+}
+
+// VarsFor returns the module inputs derived from the profile, or nil when
+// the profile has no settings for the module.
+func (p *SizingProfile) VarsFor(module string) map[string]interface{} {
+	switch module {
+	case ModuleAKSCluster:
+		return p.AKSVars()
+	case ModuleDatabases:
+		return p.DatabasesVars()
+	case ModuleContainerRegistry:
+		return p.ContainerRegistryVars()
+	case ModuleObservability:
+		return p.ObservabilityVars()
+	case ModuleAIFoundry:
+		return p.AIFoundryVars()
+	}
+	return nil
+}
+
+// AKSVars maps the cluster to aks-cluster inputs. Settings the profile does
+// not define are left to the baseline.
+func (p *SizingProfile) AKSVars() map[string]interface{} {
+	aks := p.AKS()
+	if aks == nil {
+		return nil
+	}
+
+	vars := map[string]interface{}{
+		"default_node_pool": nodePoolVars(aks.SystemPool(), false),
+	}
+	if aks.KubernetesVersion != "" {
+		vars["kubernetes_version"] = aks.KubernetesVersion
+	}
+
+	userPools := map[string]interface{}{}
+	for _, pool := range aks.UserPools() {
+		userPools[pool.Name] = nodePoolVars(pool, true)
+	}
+	vars["additional_node_pools"] = userPools
+
+	features := map[string]string{
+		"azure_policy":      "enable_azure_policy",
+		"workload_identity": "enable_workload_identity",
+		"defender":          "enable_defender",
+	}
+	for feature, variable := range features {
+		if value, ok := aks.Features[feature]; ok {
+			vars[variable] = value
+		}
+	}
+
+	return vars
+}
+
+// nodePoolVars builds a default_node_pool object, or a complete
+// additional_node_pools entry when full is set.
+func nodePoolVars(pool NodePoolProfile, full bool) map[string]interface{} {
+	autoScaling := pool.AutoScaling != nil && pool.AutoScaling.Enabled
+
+	vars := map[string]interface{}{
+		"name":                pool.Name,
+		"node_count":          pool.NodeCount,
+		"vm_size":             pool.VMSize,
+		"enable_auto_scaling": autoScaling,
+		"min_count":           pool.NodeCount,
+		"max_count":           pool.NodeCount,
+	}
+	if autoScaling {
+		vars["min_count"] = pool.AutoScaling.MinNodes
+		vars["max_count"] = pool.AutoScaling.MaxNodes
+	}
+	if pool.OSDiskSizeGB > 0 && !full {
+		vars["os_disk_size_gb"] = pool.OSDiskSizeGB
+	}
+	if pool.MaxPods > 0 {
+		vars["max_pods"] = pool.MaxPods
+	}
+	if len(pool.Zones) > 0 {
+		vars["zones"] = pool.Zones
+	}
+
+	if full {
+		// additional_node_pools has no defaults for its attributes
+		if _, ok := vars["max_pods"]; !ok {
+			vars["max_pods"] = DefaultMaxPods
+		}
+		if _, ok := vars["zones"]; !ok {
+			vars["zones"] = []string{}
+		}
+		taints := pool.Taints
+		if taints == nil {
+			taints = []string{}
+		}
+		vars["node_taints"] = taints
+		vars["node_labels"] = map[string]string{}
+	}
+
+	return vars
+}
+
+// DatabasesVars maps PostgreSQL and Redis to databases inputs.
+func (p *SizingProfile) DatabasesVars() map[string]interface{} {
+	postgres := p.PostgreSQL()
+	redis := p.Databases.Redis
+
+	postgresVars := map[string]interface{}{
+		"enabled":              enabled(postgres.Enabled),
+		"high_availability":    postgres.HAEnabled,
+		"geo_redundant_backup": postgres.GeoRedundantBackup,
+	}
+	if postgres.SKU != "" {
+		postgresVars["sku_name"] = FlexibleServerSKU(postgres.SKU)
+	}
+	if postgres.Version != "" {
+		postgresVars["version"] = postgres.Version
+	}
+	if postgres.StorageGB > 0 {
+		postgresVars["storage_mb"] = postgres.StorageGB * 1024
+	}
+	if postgres.BackupRetentionDays > 0 {
+		postgresVars["backup_retention_days"] = postgres.BackupRetentionDays
+	}
+
+	redisVars := map[string]interface{}{
+		"enabled": enabled(redis.Enabled) && redis.SKU != "",
+	}
+	if redis.SKU != "" {
+		redisVars["sku_name"] = redis.SKU
+		redisVars["family"] = redis.Family
+		redisVars["capacity"] = redis.Capacity
+	}
+
+	return map[string]interface{}{
+		"postgresql_config": postgresVars,
+		"redis_config":      redisVars,
+	}
+}
+
+// ContainerRegistryVars maps the registry to container-registry inputs.
+func (p *SizingProfile) ContainerRegistryVars() map[string]interface{} {
+	acr := p.Infrastructure.ACR
+
+	locations := []string{}
+	if acr.GeoReplication {
+		for _, replica := range acr.Replications {
+			locations = append(locations, replica.Location)
+		}
+	}
+
+	vars := map[string]interface{}{
+		"sku":                       acr.SKU,
+		"geo_replication_locations": locations,
+	}
+	if acr.RetentionDays > 0 {
+		vars["retention_policy_days"] = acr.RetentionDays
+	}
+	return vars
+}
+
+// ObservabilityVars maps Log Analytics retention to observability inputs,
+// or returns nil when the profile defines none.
+func (p *SizingProfile) ObservabilityVars() map[string]interface{} {
+	if p.Observability == nil || p.Observability.LogAnalytics == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"retention_days": p.Observability.LogAnalytics.RetentionDays,
+	}
+}
+
+// AIFoundryVars maps models, AI Search and Content Safety to ai-foundry
+// inputs. Model deployments are listed in OpenAIModels key order.
+func (p *SizingProfile) AIFoundryVars() map[string]interface{} {
+	if p.AIFoundry == nil {
+		return nil
+	}
+	ai := p.AIFoundry
+	capacity := p.OpenAIModelCapacity()
+
+	models := []map[string]interface{}{}
+	for _, key := range sortedKeys(capacity) {
+		model := OpenAIModels[key]
+		models = append(models, map[string]interface{}{
+			"name":          model.Name,
+			"model_name":    model.Name,
+			"model_version": model.Version,
+			"capacity":      OpenAICapacityUnits(capacity[key]),
+			"rai_policy":    "Microsoft.Default",
+		})
+	}
+
+	searchVars := map[string]interface{}{"enabled": ai.AISearch != nil}
+	if ai.AISearch != nil {
+		searchVars["sku_name"] = ai.AISearch.SKU
+		searchVars["replica_count"] = ai.AISearch.Replicas
+		searchVars["partition_count"] = ai.AISearch.Partitions
+	}
+
+	return map[string]interface{}{
+		"openai_config": map[string]interface{}{
+			"enabled": enabled(ai.Enabled),
+			"models":  models,
+		},
+		"ai_search_config": searchVars,
+		"content_safety_config": map[string]interface{}{
+			"enabled": ai.ContentSafety != nil && ai.ContentSafety.Enabled,
+		},
+	}
+}
+
+func sortedKeys(values map[string]int) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
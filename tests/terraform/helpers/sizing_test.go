@@ -0,0 +1,238 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - SIZING PROFILE TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestSizing|TestFlexibleServerSKU' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadSizingProfiles(t *testing.T) *SizingProfiles {
+	t.Helper()
+
+	profiles, err := LoadSizingProfiles()
+	require.NoError(t, err)
+	return profiles
+}
+
+// TestSizingProfilesLoad tests that every profile loads, in file order
+func TestSizingProfilesLoad(t *testing.T) {
+	t.Parallel()
+
+	profiles := loadSizingProfiles(t)
+
+	assert.Equal(t, []string{"small", "medium", "large", "xlarge"}, profiles.Names())
+	assert.Nil(t, profiles.Get("huge"))
+}
+
+// TestSizingProfilesShapes tests the accessors across the different layouts
+func TestSizingProfilesShapes(t *testing.T) {
+	t.Parallel()
+
+	profiles := loadSizingProfiles(t)
+
+	// small: single node_pool, no autoscaling
+	small := profiles.Get("small").AKS()
+	assert.Equal(t, "Standard_D2s_v5", small.SystemPool().VMSize)
+	assert.False(t, small.SystemPool().AutoScaling.Enabled)
+	assert.Empty(t, small.UserPools())
+
+	// medium: single node_pool inherits the cluster autoscaler
+	medium := profiles.Get("medium").AKS().SystemPool()
+	assert.Equal(t, 3, medium.AutoScaling.MinNodes)
+	assert.Equal(t, 10, medium.AutoScaling.MaxNodes)
+
+	// large: node_pools with an explicit System pool
+	large := profiles.Get("large").AKS()
+	assert.Equal(t, "system", large.SystemPool().Name)
+	require.Len(t, large.UserPools(), 2)
+	assert.Equal(t, []string{"workload=ai:NoSchedule"}, large.UserPools()[1].Taints)
+
+	// xlarge: primary region cluster and primary PostgreSQL server
+	xlarge := profiles.Get("xlarge")
+	assert.Equal(t, "Standard_D8s_v5", xlarge.AKS().SystemPool().VMSize)
+	assert.Equal(t, 5, xlarge.AKS().SystemPool().NodeCount)
+	assert.Equal(t, "Standard_D8ds_v5", xlarge.PostgreSQL().SKU)
+	assert.Equal(t, 300000, xlarge.OpenAIModelCapacity()["gpt4o"])
+	assert.Nil(t, xlarge.ObservabilityVars())
+}
+
+// TestSizingProfilesStrict tests that unknown keys and unmapped models are rejected
+func TestSizingProfilesStrict(t *testing.T) {
+	t.Parallel()
+
+	valid := `
+profiles:
+  tiny:
+    profile: tiny
+    infrastructure:
+      aks:
+        node_pool: {name: system, node_count: 1, vm_size: Standard_B2s}
+`
+	profiles, err := ParseSizingProfiles([]byte(valid))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tiny"}, profiles.Names())
+
+	testCases := []struct {
+		name     string
+		yaml     string
+		expected string
+	}{
+		{
+			name: "unknown_key",
+			yaml: `
+profiles:
+  tiny:
+    profile: tiny
+    infrastructure:
+      aks:
+        node_pool: {name: system, node_count: 1, vm_size: Standard_B2s, spot: true}
+`,
+			expected: "field spot not found",
+		},
+		{
+			name: "unknown_model",
+			yaml: `
+profiles:
+  tiny:
+    profile: tiny
+    infrastructure:
+      aks:
+        node_pool: {name: system, node_count: 1, vm_size: Standard_B2s}
+    ai_foundry:
+      models:
+        o1:
+          capacity_tpm: 1000
+`,
+			expected: `ai_foundry model "o1" has no entry in helpers.OpenAIModels`,
+		},
+		{
+			name: "no_node_pools",
+			yaml: `
+profiles:
+  tiny:
+    profile: tiny
+    infrastructure:
+      aks:
+        kubernetes_version: "1.30"
+`,
+			expected: "tiny: aks has no node pools",
+		},
+		{
+			name: "name_mismatch",
+			yaml: `
+profiles:
+  tiny:
+    profile: small
+    infrastructure:
+      aks:
+        node_pool: {name: system, node_count: 1, vm_size: Standard_B2s}
+`,
+			expected: `tiny: profile field is "small"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseSizingProfiles([]byte(tc.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+// TestFlexibleServerSKU tests the VM size to PostgreSQL SKU mapping
+func TestFlexibleServerSKU(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "B_Standard_B1ms", FlexibleServerSKU("Standard_B1ms"))
+	assert.Equal(t, "GP_Standard_D4ds_v5", FlexibleServerSKU("Standard_D4ds_v5"))
+	assert.Equal(t, "MO_Standard_E4ds_v5", FlexibleServerSKU("Standard_E4ds_v5"))
+}
+
+// TestSizingProfileVars tests the module inputs derived from a profile
+func TestSizingProfileVars(t *testing.T) {
+	t.Parallel()
+
+	large := loadSizingProfiles(t).Get("large")
+
+	aks := large.AKSVars()
+	assert.Equal(t, "1.30", aks["kubernetes_version"])
+	assert.Equal(t, "Standard_D4s_v5", aks["default_node_pool"].(map[string]interface{})["vm_size"])
+	workloads := aks["additional_node_pools"].(map[string]interface{})["workloads"].(map[string]interface{})
+	assert.Equal(t, true, workloads["enable_auto_scaling"])
+	assert.Equal(t, 15, workloads["max_count"])
+
+	postgres := large.DatabasesVars()["postgresql_config"].(map[string]interface{})
+	assert.Equal(t, "GP_Standard_D4ds_v5", postgres["sku_name"])
+	assert.Equal(t, 256*1024, postgres["storage_mb"])
+	assert.Equal(t, true, postgres["high_availability"])
+
+	acr := large.ContainerRegistryVars()
+	assert.Equal(t, "Premium", acr["sku"])
+	assert.Equal(t, []string{"eastus"}, acr["geo_replication_locations"])
+
+	ai := large.AIFoundryVars()
+	models := ai["openai_config"].(map[string]interface{})["models"].([]map[string]interface{})
+	require.Len(t, models, 3)
+	assert.Equal(t, "text-embedding-3-large", models[0]["name"])
+	assert.Equal(t, 300, models[0]["capacity"])
+	assert.Equal(t, true, ai["content_safety_config"].(map[string]interface{})["enabled"])
+}
+
+// TestSizingProfileAddressDemand tests node and pod addresses at full scale
+func TestSizingProfileAddressDemand(t *testing.T) {
+	t.Parallel()
+
+	profiles := loadSizingProfiles(t)
+
+	// small: 3 fixed nodes with max_pods 30, plus a surge node
+	nodes, pods := profiles.Get("small").AddressDemand()
+	assert.Equal(t, 4, nodes)
+	assert.Equal(t, 120, pods)
+
+	for _, name := range profiles.Names() {
+		nodes, pods := profiles.Get(name).AddressDemand()
+		assert.Positive(t, nodes, name)
+		assert.GreaterOrEqual(t, pods, nodes, name)
+	}
+}
+
+// TestSizingProfilesMatchVariables tests that every profile produces inputs
+// every sizing module declares
+func TestSizingProfilesMatchVariables(t *testing.T) {
+	t.Parallel()
+
+	profiles := loadSizingProfiles(t)
+
+	for _, name := range profiles.Names() {
+		profile := profiles.Get(name)
+		for _, module := range SizingModules {
+			name, module := name, module
+			vars := profile.VarsFor(module)
+			if vars == nil {
+				continue
+			}
+
+			t.Run(name+"/"+module, func(t *testing.T) {
+				t.Parallel()
+
+				merged := MergeVars(Baseline(module), vars)
+				problems, err := CheckModuleVars(module, merged)
+				require.NoError(t, err)
+				assert.Empty(t, problems)
+			})
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - TERRAFORM TEST HELPERS
+// =============================================================================
+//
+// Shared builders for terraform.Options used by the module tests.
+//
+// Every module test starts from the same baseline Vars for its module and
+// only overrides what the scenario is about:
+//
+//	terraformOptions := helpers.AKSOptions(t, map[string]interface{}{
+//	    "sku_tier": "Premium",
+//	})
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// unsetMarker is the type of Unset.
+type unsetMarker struct{}
+
+// Unset removes a key from the merged Vars when used as an override value.
+// Use it to drop a baseline variable, e.g. for missing-input validation tests.
+var Unset = unsetMarker{}
+
+// RepoRoot returns the absolute path of the repository root.
+func RepoRoot() string {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		panic("helpers: unable to determine source location")
+	}
+
+	// This file lives in tests/terraform/helpers
+	return filepath.Clean(filepath.Join(filepath.Dir(file), "..", "..", ".."))
+}
+
+// ModulesDir returns the absolute path of terraform/modules.
+func ModulesDir() string {
+	return filepath.Join(RepoRoot(), "terraform", "modules")
+}
+
+// ModuleDir returns the absolute path of a single Terraform module.
+func ModuleDir(module string) string {
+	return filepath.Join(ModulesDir(), module)
+}
+
+// ModuleOptions builds terraform.Options for a module from a baseline set of
+// Vars merged with the given overrides.
+//
+// Retryable errors and NoColor are always applied so every test behaves the
+// same way regardless of who wrote it. TerraformDir is a per-test copy of the
+// module (see IsolatedModuleDir) so parallel tests never share .terraform/ or
+// state. The merged Vars are checked against the module's variables.tf and
+// the test fails on any drift; required variables dropped on purpose with
+// Unset are not reported.
+func ModuleOptions(t testing.TB, module string, baseline map[string]interface{}, overrides ...map[string]interface{}) *terraform.Options {
+	t.Helper()
+
+	vars := MergeVars(baseline, overrides...)
+
+	problems, err := CheckModuleVars(module, vars, unsetKeys(overrides)...)
+	if err != nil {
+		t.Fatalf("Failed to parse variables.tf for module %q: %v", module, err)
+	}
+	if len(problems) > 0 {
+		t.Fatal(formatProblems(module, problems))
+	}
+
+	dir := IsolatedModuleDir(t, module)
+	requireProvidersInMirror(t, dir)
+
+	return terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: dir,
+		Vars:         vars,
+		NoColor:      true,
+	})
+}
+
+// requireProvidersInMirror fails the test up front when an offline run is
+// configured and the mirror lacks a provider version the module needs, rather
+// than letting terraform init fail with a registry error.
+func requireProvidersInMirror(t testing.TB, dir string) {
+	t.Helper()
+
+	mirrorDir := ProviderMirrorDir()
+	if mirrorDir == "" {
+		return
+	}
+
+	problems, err := CheckProviderMirror(mirrorDir, dir)
+	if err != nil {
+		t.Fatalf("Failed to check provider mirror %s: %v", mirrorDir, err)
+	}
+	if len(problems) > 0 {
+		t.Fatalf("Provider mirror %s is missing required providers:\n  - %s\nPopulate it with: go run ./cmd/mirror-providers -dir %s",
+			mirrorDir, strings.Join(problems, "\n  - "), mirrorDir)
+	}
+}
+
+// MergeVars deep-merges overrides onto base and returns a new map.
+//
+// Nested maps (any map keyed by string) are merged key by key, every other
+// value - including lists - replaces the base value. An override value of
+// Unset deletes the key; an Unset with no base key to delete, e.g. inside a
+// map that replaces a non-map value, is dropped so it never reaches
+// Terraform. Neither base nor overrides are modified.
+func MergeVars(base map[string]interface{}, overrides ...map[string]interface{}) map[string]interface{} {
+	merged := copyMap(base)
+
+	for _, override := range overrides {
+		merged = mergeMaps(merged, override)
+	}
+
+	return merged
+}
+
+func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	for key, value := range src {
+		if _, ok := value.(unsetMarker); ok {
+			delete(dst, key)
+			continue
+		}
+
+		srcMap, srcIsMap := asStringMap(value)
+		dstMap, dstIsMap := asStringMap(dst[key])
+
+		if srcIsMap && dstIsMap {
+			dst[key] = mergeMaps(copyMap(dstMap), srcMap)
+			continue
+		}
+
+		dst[key] = copyValue(value)
+	}
+
+	return dst
+}
+
+func copyMap(src map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{}, len(src))
+	for key, value := range src {
+		if _, ok := value.(unsetMarker); ok {
+			continue
+		}
+		dst[key] = copyValue(value)
+	}
+	return dst
+}
+
+func copyValue(value interface{}) interface{} {
+	if m, ok := asStringMap(value); ok {
+		return copyMap(m)
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice {
+		return value
+	}
+
+	items := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i).Interface()
+		if _, ok := item.(unsetMarker); ok {
+			continue
+		}
+		items = append(items, copyValue(item))
+	}
+	return items
+}
+
+// asStringMap converts any map keyed by string (map[string]string,
+// map[string]interface{}, ...) into a map[string]interface{}.
+func asStringMap(value interface{}) (map[string]interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+
+	if m, ok := value.(map[string]interface{}); ok {
+		return m, true
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	m := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		m[iter.Key().String()] = iter.Value().Interface()
+	}
+	return m, true
+}
+
+// FakeResourceID returns a syntactically valid Azure resource ID in the
+// all-zero test subscription.
+func FakeResourceID(provider, resourcePath string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/%s/%s", TestSubscriptionID, TestResourceGroup, provider, resourcePath)
+}
@@ -0,0 +1,135 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - TERRAFORM HELPER TESTS
+// =============================================================================
+//
+// Run with: go test -v ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMergeVarsDeepMerge tests that nested maps are merged key by key
+func TestMergeVarsDeepMerge(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]interface{}{
+		"customer_name": "base",
+		"redis_config": map[string]interface{}{
+			"enabled":  true,
+			"sku_name": "Standard",
+			"capacity": 1,
+		},
+		"zones": []string{"1", "2", "3"},
+	}
+
+	merged := MergeVars(base, map[string]interface{}{
+		"redis_config": map[string]interface{}{
+			"sku_name": "Premium",
+		},
+		"zones": []string{"1"},
+	})
+
+	assert.Equal(t, "base", merged["customer_name"])
+	assert.Equal(t, map[string]interface{}{
+		"enabled":  true,
+		"sku_name": "Premium",
+		"capacity": 1,
+	}, merged["redis_config"])
+	assert.Equal(t, []interface{}{"1"}, merged["zones"], "lists are replaced, not merged")
+
+	// Inputs must not be modified
+	assert.Equal(t, "Standard", base["redis_config"].(map[string]interface{})["sku_name"])
+}
+
+// TestMergeVarsTypedMaps tests that typed string-keyed maps are merged too
+func TestMergeVarsTypedMaps(t *testing.T) {
+	t.Parallel()
+
+	merged := MergeVars(
+		map[string]interface{}{"tags": map[string]string{"Environment": "test"}},
+		map[string]interface{}{"tags": map[string]string{"Owner": "platform"}},
+	)
+
+	assert.Equal(t, map[string]interface{}{
+		"Environment": "test",
+		"Owner":       "platform",
+	}, merged["tags"])
+}
+
+// TestMergeVarsUnset tests that Unset removes keys from the baseline
+func TestMergeVarsUnset(t *testing.T) {
+	t.Parallel()
+
+	merged := MergeVars(
+		map[string]interface{}{
+			"resource_group_name": "rg-test",
+			"location":            "brazilsouth",
+			"network_config":      map[string]interface{}{"vnet_id": "x", "service_cidr": "y"},
+		},
+		map[string]interface{}{
+			"resource_group_name": Unset,
+			"network_config":      map[string]interface{}{"service_cidr": Unset},
+		},
+	)
+
+	assert.NotContains(t, merged, "resource_group_name")
+	assert.Equal(t, map[string]interface{}{"vnet_id": "x"}, merged["network_config"])
+}
+
+// TestMergeVarsUnsetWithoutBase tests that Unset never survives a merge when
+// there is no base key to delete
+func TestMergeVarsUnsetWithoutBase(t *testing.T) {
+	t.Parallel()
+
+	merged := MergeVars(
+		map[string]interface{}{"network_config": "none"},
+		map[string]interface{}{
+			"network_config": map[string]interface{}{
+				"vnet_id": "x",
+				"subnets": map[string]interface{}{"aks": "y", "pods": Unset},
+				"cidrs":   []interface{}{"10.0.0.0/16", Unset},
+				"dns":     Unset,
+			},
+			"missing": Unset,
+		},
+	)
+
+	assert.Equal(t, map[string]interface{}{
+		"network_config": map[string]interface{}{
+			"vnet_id": "x",
+			"subnets": map[string]interface{}{"aks": "y"},
+			"cidrs":   []interface{}{"10.0.0.0/16"},
+		},
+	}, merged)
+}
+
+// TestModuleBaselines tests that every baseline points to an existing module
+func TestModuleBaselines(t *testing.T) {
+	t.Parallel()
+
+	for _, module := range Modules() {
+		module := module
+		t.Run(module, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := os.Stat(ModuleDir(module))
+			require.NoError(t, err)
+			assert.True(t, info.IsDir())
+
+			options := OptionsFor(t, module, map[string]interface{}{"environment": "prd"})
+			assert.NotEqual(t, ModuleDir(module), options.TerraformDir)
+			assert.FileExists(t, filepath.Join(options.TerraformDir, "variables.tf"))
+			assert.True(t, options.NoColor)
+			assert.NotEmpty(t, options.RetryableTerraformErrors)
+		})
+	}
+}
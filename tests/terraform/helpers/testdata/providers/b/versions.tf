@@ -0,0 +1,9 @@
+terraform {
+  required_providers {
+    azurerm = {
+      source  = "registry.terraform.io/hashicorp/azurerm"
+      version = ">= 3.80.0"
+    }
+    random = "~> 3.6"
+  }
+}
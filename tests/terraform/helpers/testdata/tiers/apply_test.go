@@ -0,0 +1,14 @@
+//go:build integration
+
+package fixture
+
+import "testing"
+
+func TestApplies(t *testing.T) {
+	defer terraform.Destroy(t, nil)
+	terraform.InitAndApply(t, nil)
+}
+
+func TestDestroysOnly(t *testing.T) {
+	terraform.DestroyE(t, nil)
+}
@@ -0,0 +1,7 @@
+//go:build unit || integration
+
+package fixture
+
+import "testing"
+
+func TestBothTiers(t *testing.T) {}
@@ -0,0 +1,7 @@
+//go:build unit
+
+package fixture
+
+import "testing"
+
+func TestMain(m *testing.M) {}
@@ -0,0 +1,28 @@
+//go:build unit
+
+package fixture
+
+import "testing"
+
+func TestPlanOnly(t *testing.T) {
+	helpers.InitAndPlan(t, nil)
+}
+
+func TestTerratestPlan(t *testing.T) {
+	terraform.Init(t, nil)
+	terraform.Plan(t, nil)
+}
+
+func FuzzPlanOnly(f *testing.F) {
+	f.Fuzz(func(t *testing.T, name string) {
+		helpers.PlanE(t, nil)
+	})
+}
+
+func FuzzWithoutF(t *testing.T) {}
+
+func TestMainHelper(t *testing.T, extra int) {}
+
+func helperNotATest(t *testing.T) {
+	terraform.Apply(t, nil)
+}
@@ -0,0 +1,5 @@
+package fixture
+
+import "testing"
+
+func TestUntagged(t *testing.T) {}
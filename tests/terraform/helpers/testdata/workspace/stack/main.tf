@@ -0,0 +1,12 @@
+module "child" {
+  source = "./child"
+}
+
+module "shared" {
+  source = "../shared"
+}
+
+module "registry" {
+  source  = "Azure/naming/azurerm"
+  version = "0.4.0"
+}
@@ -0,0 +1,208 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - TEST TIERS
+// =============================================================================
+//
+// Classifies module tests by build tag:
+//
+//	//go:build unit         plan/validate only, no real resources
+//	//go:build integration  may apply and destroy real Azure resources
+//
+// ClassifyTests reads the test files without compiling them, so the
+// listing command and the tier checks see every tier at once.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Test tiers, matching the build tags.
+const (
+	TierUnit        = "unit"
+	TierIntegration = "integration"
+	TierUntagged    = "untagged"
+)
+
+// Tiers returns the tiers in the order they run in CI.
+func Tiers() []string {
+	return []string{TierUnit, TierIntegration}
+}
+
+// TestInfo describes one top-level Test function or Fuzz target.
+type TestInfo struct {
+	Name string
+	File string
+	// Tier is TierUnit, TierIntegration or TierUntagged.
+	Tier string
+	// Applies is true when the test calls a terratest Apply or Destroy
+	// function directly.
+	Applies bool
+	// PlansDirectly is true when the test calls a terratest Plan function
+	// instead of helpers.InitAndPlan, so its plan needs real providers and
+	// skips the policy and naming checks.
+	PlansDirectly bool
+}
+
+// ModuleTestsDir returns the absolute path of tests/terraform/modules.
+func ModuleTestsDir() string {
+	return filepath.Join(RepoRoot(), "tests", "terraform", "modules")
+}
+
+// ClassifyTests returns every Test function and Fuzz target in the _test.go
+// files of dir, sorted by tier and name.
+func ClassifyTests(dir string) ([]TestInfo, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var tests []TestInfo
+
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		tier, err := fileTier(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !isTestFunc(fn) {
+				continue
+			}
+
+			tests = append(tests, TestInfo{
+				Name:          fn.Name.Name,
+				File:          filepath.Base(path),
+				Tier:          tier,
+				Applies:       callsTerraform(fn, isApplyOrDestroy),
+				PlansDirectly: callsTerraform(fn, isPlan),
+			})
+		}
+	}
+
+	order := map[string]int{TierUnit: 0, TierIntegration: 1, TierUntagged: 2}
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].Tier != tests[j].Tier {
+			return order[tests[i].Tier] < order[tests[j].Tier]
+		}
+		return tests[i].Name < tests[j].Name
+	})
+
+	return tests, nil
+}
+
+// fileTier evaluates the file's //go:build line. A file that builds in the
+// integration tier but not the unit tier is integration, and the reverse is
+// unit; anything else (no constraint, or both) is untagged.
+func fileTier(file *ast.File) (string, error) {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if !constraint.IsGoBuild(comment.Text) {
+				continue
+			}
+
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				return "", err
+			}
+
+			unit := expr.Eval(func(tag string) bool { return tag == TierUnit })
+			integration := expr.Eval(func(tag string) bool { return tag == TierIntegration })
+
+			switch {
+			case unit && !integration:
+				return TierUnit, nil
+			case integration && !unit:
+				return TierIntegration, nil
+			}
+			return TierUntagged, nil
+		}
+	}
+
+	return TierUntagged, nil
+}
+
+// isTestFunc reports Test functions taking *testing.T and Fuzz targets
+// taking *testing.F.
+func isTestFunc(fn *ast.FuncDecl) bool {
+	name := fn.Name.Name
+	param := "T"
+	switch {
+	case name == "TestMain":
+		return false
+	case strings.HasPrefix(name, "Fuzz"):
+		param = "F"
+	case !strings.HasPrefix(name, "Test"):
+		return false
+	}
+
+	params := fn.Type.Params.List
+	if len(params) != 1 {
+		return false
+	}
+
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == param
+}
+
+// isApplyOrDestroy matches terraform.Apply, terraform.InitAndApplyE,
+// terraform.Destroy and the like.
+func isApplyOrDestroy(name string) bool {
+	return strings.Contains(name, "Apply") || strings.HasPrefix(name, "Destroy")
+}
+
+// isPlan matches terraform.Plan, terraform.PlanE, terraform.InitAndPlan and
+// the like.
+func isPlan(name string) bool {
+	return strings.Contains(name, "Plan")
+}
+
+// callsTerraform reports a call to a function of the terratest terraform
+// package whose name matches in the function body.
+func callsTerraform(fn *ast.FuncDecl, matches func(name string) bool) bool {
+	found := false
+
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return !found
+		}
+
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok || pkg.Name != "terraform" {
+			return true
+		}
+
+		if matches(sel.Sel.Name) {
+			found = true
+		}
+		return !found
+	})
+
+	return found
+}
@@ -0,0 +1,56 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - TEST TIER TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestClassifyTests|TestModuleTestTiers' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClassifyTests tests tier detection from build tags and Apply and Plan
+// detection
+func TestClassifyTests(t *testing.T) {
+	t.Parallel()
+
+	tests, err := ClassifyTests("testdata/tiers")
+	require.NoError(t, err)
+
+	assert.Equal(t, []TestInfo{
+		{Name: "FuzzPlanOnly", File: "plan_test.go", Tier: TierUnit},
+		{Name: "TestPlanOnly", File: "plan_test.go", Tier: TierUnit},
+		{Name: "TestTerratestPlan", File: "plan_test.go", Tier: TierUnit, PlansDirectly: true},
+		{Name: "TestApplies", File: "apply_test.go", Tier: TierIntegration, Applies: true},
+		{Name: "TestDestroysOnly", File: "apply_test.go", Tier: TierIntegration, Applies: true},
+		{Name: "TestBothTiers", File: "both_test.go", Tier: TierUntagged},
+		{Name: "TestUntagged", File: "untagged_test.go", Tier: TierUntagged},
+	}, tests)
+}
+
+// TestModuleTestTiers tests that every module test has a tier, that only
+// integration tests apply or destroy, and that unit tests plan through
+// InitAndPlan
+func TestModuleTestTiers(t *testing.T) {
+	t.Parallel()
+
+	tests, err := ClassifyTests(ModuleTestsDir())
+	require.NoError(t, err)
+	require.NotEmpty(t, tests)
+
+	for _, test := range tests {
+		assert.NotEqual(t, TierUntagged, test.Tier, "%s (%s) has no unit or integration build tag", test.Name, test.File)
+		if test.Applies {
+			assert.Equal(t, TierIntegration, test.Tier, "%s (%s) applies but is not an integration test", test.Name, test.File)
+		}
+		if test.Tier == TierUnit {
+			assert.False(t, test.PlansDirectly, "%s (%s) calls terraform Plan instead of helpers.InitAndPlan", test.Name, test.File)
+		}
+	}
+}
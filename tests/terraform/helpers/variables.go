@@ -0,0 +1,284 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - VARIABLE DRIFT CHECKER
+// =============================================================================
+//
+// Parses each module's variables.tf with hcl/v2 and compares it against the
+// Vars a test is about to send to Terraform.
+//
+// A test that passes an undeclared variable, forgets a required one or sends
+// the wrong shape for an object/list variable is silently testing something
+// else. ModuleOptions runs this check for every builder so those tests fail
+// before Terraform even starts.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Variable is a single `variable` block from a module's variables.tf.
+type Variable struct {
+	Name     string
+	Type     cty.Type
+	Required bool
+}
+
+// variableFileSchema matches the top-level blocks of a variables.tf file.
+var variableFileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+	},
+}
+
+// variableBlockSchema matches the attributes of a `variable` block we care
+// about. Validation blocks and the rest are ignored.
+var variableBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "type"},
+		{Name: "default"},
+	},
+}
+
+var moduleVariablesCache sync.Map // module name -> map[string]Variable
+
+// ModuleVariables returns the variables declared in a module's variables.tf,
+// keyed by name. Results are cached for the lifetime of the test binary.
+func ModuleVariables(module string) (map[string]Variable, error) {
+	if cached, ok := moduleVariablesCache.Load(module); ok {
+		return cached.(map[string]Variable), nil
+	}
+
+	vars, err := ParseVariablesFile(filepath.Join(ModuleDir(module), "variables.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	moduleVariablesCache.Store(module, vars)
+	return vars, nil
+}
+
+// ParseVariablesFile parses the `variable` blocks of a single .tf file.
+func ParseVariablesFile(path string) (map[string]Variable, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	// PartialContent so that locals, validation blocks etc. never trip us up
+	content, _, diags := file.Body.PartialContent(variableFileSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	vars := make(map[string]Variable, len(content.Blocks))
+	for _, block := range content.Blocks {
+		attrs, _, diags := block.Body.PartialContent(variableBlockSchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		v := Variable{
+			Name:     block.Labels[0],
+			Type:     cty.DynamicPseudoType,
+			Required: true,
+		}
+
+		if attr, ok := attrs.Attributes["type"]; ok {
+			ty, _, diags := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+			v.Type = ty
+		}
+
+		if _, ok := attrs.Attributes["default"]; ok {
+			v.Required = false
+		}
+
+		vars[v.Name] = v
+	}
+
+	return vars, nil
+}
+
+// CheckModuleVars compares vars against the module's variables.tf and returns
+// one problem per undeclared key, missing required variable or shape
+// mismatch. Required variables listed in allowMissing are not reported.
+func CheckModuleVars(module string, vars map[string]interface{}, allowMissing ...string) ([]string, error) {
+	declared, err := ModuleVariables(module)
+	if err != nil {
+		return nil, err
+	}
+
+	return CheckVars(declared, vars, allowMissing...), nil
+}
+
+// CheckVars compares vars against a set of declared variables. Problems are
+// returned sorted so failures are stable between runs.
+func CheckVars(declared map[string]Variable, vars map[string]interface{}, allowMissing ...string) []string {
+	var problems []string
+
+	for name, value := range vars {
+		v, ok := declared[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: not declared in variables.tf", name))
+			continue
+		}
+		problems = append(problems, checkShape(name, v.Type, value)...)
+	}
+
+	skip := make(map[string]bool, len(allowMissing))
+	for _, name := range allowMissing {
+		skip[name] = true
+	}
+
+	for name, v := range declared {
+		if !v.Required || skip[name] {
+			continue
+		}
+		if _, ok := vars[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: required variable is missing", name))
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+// checkShape reports where value does not fit the type constraint ty. Only
+// structure is checked; Terraform's validation blocks still own the values.
+func checkShape(path string, ty cty.Type, value interface{}) []string {
+	if value == nil || ty == cty.DynamicPseudoType {
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+
+	switch {
+	case ty.IsPrimitiveType():
+		if !primitiveFits(ty, rv) {
+			return []string{fmt.Sprintf("%s: expected %s, got %T", path, ty.FriendlyName(), value)}
+		}
+		return nil
+
+	case ty.IsObjectType():
+		m, ok := asStringMap(value)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object, got %T", path, value)}
+		}
+
+		var problems []string
+		for key, item := range m {
+			if !ty.HasAttribute(key) {
+				problems = append(problems, fmt.Sprintf("%s.%s: attribute not declared in object type", path, key))
+				continue
+			}
+			problems = append(problems, checkShape(path+"."+key, ty.AttributeType(key), item)...)
+		}
+		for key := range ty.AttributeTypes() {
+			if _, ok := m[key]; !ok && !ty.AttributeOptional(key) {
+				problems = append(problems, fmt.Sprintf("%s.%s: required attribute is missing", path, key))
+			}
+		}
+		return problems
+
+	case ty.IsMapType():
+		m, ok := asStringMap(value)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected %s, got %T", path, ty.FriendlyName(), value)}
+		}
+
+		var problems []string
+		for key, item := range m {
+			problems = append(problems, checkShape(path+"."+key, ty.ElementType(), item)...)
+		}
+		return problems
+
+	case ty.IsListType() || ty.IsSetType():
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return []string{fmt.Sprintf("%s: expected %s, got %T", path, ty.FriendlyName(), value)}
+		}
+
+		var problems []string
+		for i := 0; i < rv.Len(); i++ {
+			problems = append(problems, checkShape(fmt.Sprintf("%s[%d]", path, i), ty.ElementType(), rv.Index(i).Interface())...)
+		}
+		return problems
+
+	case ty.IsTupleType():
+		elems := ty.TupleElementTypes()
+		if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Len() != len(elems) {
+			return []string{fmt.Sprintf("%s: expected %s, got %T", path, ty.FriendlyName(), value)}
+		}
+
+		var problems []string
+		for i, elemType := range elems {
+			problems = append(problems, checkShape(fmt.Sprintf("%s[%d]", path, i), elemType, rv.Index(i).Interface())...)
+		}
+		return problems
+	}
+
+	return nil
+}
+
+// primitiveFits mirrors Terraform's own conversions: numbers and bools are
+// accepted for strings, and strings that parse are accepted for numbers and
+// bools.
+func primitiveFits(ty cty.Type, rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.String:
+		switch ty {
+		case cty.Number:
+			_, ok := new(big.Float).SetString(rv.String())
+			return ok
+		case cty.Bool:
+			return rv.String() == "true" || rv.String() == "false"
+		}
+		return true
+	case reflect.Bool:
+		return ty == cty.Bool || ty == cty.String
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return ty == cty.Number || ty == cty.String
+	}
+	return false
+}
+
+// unsetKeys returns the top-level keys an override explicitly drops with
+// Unset. Those are deliberate omissions, not drift.
+func unsetKeys(overrides []map[string]interface{}) []string {
+	var keys []string
+	for _, override := range overrides {
+		for key, value := range override {
+			if _, ok := value.(unsetMarker); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// formatProblems renders drift problems for a test failure message.
+func formatProblems(module string, problems []string) string {
+	return fmt.Sprintf("Vars for module %q drift from its variables.tf:\n  - %s", module, strings.Join(problems, "\n  - "))
+}
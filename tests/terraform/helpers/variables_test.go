@@ -0,0 +1,175 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - VARIABLE DRIFT CHECKER TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestParseVariables|TestCheckVars|TestModuleVariables|TestBaselines' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const driftFixture = `
+variable "name" {
+  type = string
+}
+
+variable "replicas" {
+  type    = number
+  default = 1
+}
+
+variable "zones" {
+  type    = list(string)
+  default = []
+}
+
+variable "pool" {
+  type = object({
+    vm_size = string
+    count   = number
+    labels  = optional(map(string))
+  })
+}
+
+variable "untyped" {
+  default = null
+}
+`
+
+func parseFixture(t *testing.T) map[string]Variable {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "variables.tf")
+	require.NoError(t, os.WriteFile(path, []byte(driftFixture), 0o644))
+
+	vars, err := ParseVariablesFile(path)
+	require.NoError(t, err)
+	return vars
+}
+
+// TestParseVariablesFile tests that required/optional variables are detected
+func TestParseVariablesFile(t *testing.T) {
+	t.Parallel()
+
+	vars := parseFixture(t)
+
+	require.Len(t, vars, 5)
+	assert.True(t, vars["name"].Required)
+	assert.True(t, vars["pool"].Required)
+	assert.False(t, vars["replicas"].Required)
+	assert.False(t, vars["untyped"].Required)
+	assert.True(t, vars["pool"].Type.IsObjectType())
+}
+
+// TestCheckVars tests detection of undeclared, missing and misshapen variables
+func TestCheckVars(t *testing.T) {
+	t.Parallel()
+
+	declared := parseFixture(t)
+	validPool := map[string]interface{}{"vm_size": "Standard_D2s_v5", "count": 3}
+
+	testCases := []struct {
+		name     string
+		vars     map[string]interface{}
+		allow    []string
+		expected []string
+	}{
+		{
+			name:     "valid",
+			vars:     map[string]interface{}{"name": "x", "pool": validPool, "zones": []string{"1"}, "untyped": []int{1}},
+			expected: nil,
+		},
+		{
+			name:     "primitive_conversions",
+			vars:     map[string]interface{}{"name": 42, "replicas": "3", "pool": validPool},
+			expected: nil,
+		},
+		{
+			name:     "undeclared_key",
+			vars:     map[string]interface{}{"name": "x", "pool": validPool, "region": "eastus"},
+			expected: []string{"region: not declared in variables.tf"},
+		},
+		{
+			name:     "missing_required",
+			vars:     map[string]interface{}{"pool": validPool},
+			expected: []string{"name: required variable is missing"},
+		},
+		{
+			name:     "missing_required_allowed",
+			vars:     map[string]interface{}{"pool": validPool},
+			allow:    []string{"name"},
+			expected: nil,
+		},
+		{
+			name: "object_attribute_drift",
+			vars: map[string]interface{}{
+				"name": "x",
+				"pool": map[string]interface{}{"vm_size": "Standard_D2s_v5", "node_count": 3},
+			},
+			expected: []string{
+				"pool.count: required attribute is missing",
+				"pool.node_count: attribute not declared in object type",
+			},
+		},
+		{
+			name:     "list_given_scalar",
+			vars:     map[string]interface{}{"name": "x", "pool": validPool, "zones": "1"},
+			expected: []string{"zones: expected list of string, got string"},
+		},
+		{
+			name:     "number_given_word",
+			vars:     map[string]interface{}{"name": "x", "pool": validPool, "replicas": "three"},
+			expected: []string{"replicas: expected number, got string"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, CheckVars(declared, tc.vars, tc.allow...))
+		})
+	}
+}
+
+// TestModuleVariables tests that every module's variables.tf parses
+func TestModuleVariables(t *testing.T) {
+	t.Parallel()
+
+	for _, module := range Modules() {
+		module := module
+		t.Run(module, func(t *testing.T) {
+			t.Parallel()
+
+			vars, err := ModuleVariables(module)
+			require.NoError(t, err)
+			assert.NotEmpty(t, vars)
+		})
+	}
+}
+
+// TestBaselinesMatchVariables tests that no baseline drifts from its module
+func TestBaselinesMatchVariables(t *testing.T) {
+	t.Parallel()
+
+	for _, module := range Modules() {
+		module := module
+		t.Run(module, func(t *testing.T) {
+			t.Parallel()
+
+			problems, err := CheckModuleVars(module, Baseline(module))
+			require.NoError(t, err)
+			assert.Empty(t, problems)
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - ISOLATED MODULE WORKSPACES
+// =============================================================================
+//
+// Every test gets its own copy of the module under test so parallel tests
+// never share .terraform/, the lock file or local state.
+//
+// The copy keeps the module's path relative to the repository root, and
+// every module referenced with a relative `source` ("./..." or "../...") is
+// copied next to it, so relative sources resolve exactly as in the repo.
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// moduleCallSchema matches `module` blocks and their source attribute.
+var moduleCallSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "module", LabelNames: []string{"name"}},
+	},
+}
+
+var moduleSourceSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "source"},
+	},
+}
+
+// IsolatedModuleDir copies a module from terraform/modules into a temporary
+// workspace and returns the path of the copy. The workspace is removed when
+// the test and all its subtests complete.
+func IsolatedModuleDir(t testing.TB, module string) string {
+	t.Helper()
+
+	return IsolatedDir(t, ModuleDir(module))
+}
+
+// IsolatedDir copies any Terraform directory inside the repository into a
+// temporary workspace, together with its relative module sources, and
+// returns the path of the copy.
+func IsolatedDir(t testing.TB, dir string) string {
+	t.Helper()
+
+	root := RepoRoot()
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		t.Fatalf("Terraform directory %s is outside the repository", dir)
+	}
+
+	workspace, err := os.MkdirTemp("", "terratest-"+filepath.Base(dir)+"-")
+	if err != nil {
+		t.Fatalf("Failed to create workspace for %s: %v", rel, err)
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(workspace); err != nil {
+			t.Logf("Failed to remove workspace %s: %v", workspace, err)
+		}
+	})
+
+	if err := copyModuleTree(root, workspace, rel, map[string]bool{}); err != nil {
+		t.Fatalf("Failed to copy %s into workspace: %v", rel, err)
+	}
+
+	return filepath.Join(workspace, rel)
+}
+
+// copyModuleTree copies root/rel to workspace/rel and then follows every
+// relative module source found in its .tf files.
+func copyModuleTree(root, workspace, rel string, copied map[string]bool) error {
+	if copied[rel] {
+		return nil
+	}
+	copied[rel] = true
+
+	src := filepath.Join(root, rel)
+	if err := copyDir(src, filepath.Join(workspace, rel)); err != nil {
+		return err
+	}
+
+	sources, err := localModuleSources(src)
+	if err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		target := filepath.Clean(filepath.Join(rel, source))
+		if strings.HasPrefix(target, "..") {
+			return fmt.Errorf("%s: module source %q points outside the repository", rel, source)
+		}
+		if err := copyModuleTree(root, workspace, target, copied); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// localModuleSources returns the relative `source` of every module block in
+// the .tf files of dir. Registry and remote sources are ignored.
+func localModuleSources(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []string
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		content, _, _ := file.Body.PartialContent(moduleCallSchema)
+		for _, block := range content.Blocks {
+			attrs, _, _ := block.Body.PartialContent(moduleSourceSchema)
+			attr, ok := attrs.Attributes["source"]
+			if !ok {
+				continue
+			}
+
+			value, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || value.Type() != cty.String {
+				return nil, fmt.Errorf("%s: module %q must have a literal string source", path, block.Labels[0])
+			}
+
+			source := value.AsString()
+			if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+				sources = append(sources, source)
+			}
+		}
+	}
+
+	return sources, nil
+}
+
+// copyDir copies a directory tree, leaving out anything Terraform generates
+// locally so the copy always starts clean.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		if skipWorkspaceEntry(info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+func skipWorkspaceEntry(info os.FileInfo) bool {
+	name := info.Name()
+
+	if info.IsDir() {
+		return name == ".terraform"
+	}
+
+	return strings.HasPrefix(name, "terraform.tfstate") ||
+		strings.HasSuffix(name, ".tfplan") ||
+		name == ".terraform.tfstate.lock.info"
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
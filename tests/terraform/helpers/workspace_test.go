@@ -0,0 +1,80 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - ISOLATED WORKSPACE TESTS
+// =============================================================================
+//
+// Run with: go test -v -run 'TestIsolated|TestCopyDir' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIsolatedDirCopiesRelativeSources tests that relative module sources are
+// copied next to the module and remote sources are left alone
+func TestIsolatedDirCopiesRelativeSources(t *testing.T) {
+	t.Parallel()
+
+	fixture, err := filepath.Abs(filepath.Join("testdata", "workspace", "stack"))
+	require.NoError(t, err)
+
+	dir := IsolatedDir(t, fixture)
+
+	assert.NotEqual(t, fixture, dir)
+	assert.Equal(t, "stack", filepath.Base(dir))
+	assert.FileExists(t, filepath.Join(dir, "main.tf"))
+	assert.FileExists(t, filepath.Join(dir, "child", "main.tf"))
+	assert.FileExists(t, filepath.Join(dir, "..", "shared", "main.tf"))
+	assert.NoDirExists(t, filepath.Join(dir, "..", "unused"))
+}
+
+// TestIsolatedDirCleanup tests that the workspace is removed with the test
+func TestIsolatedDirCleanup(t *testing.T) {
+	t.Parallel()
+
+	var dir string
+	t.Run("workspace", func(t *testing.T) {
+		dir = IsolatedModuleDir(t, ModuleNaming)
+		assert.FileExists(t, filepath.Join(dir, "variables.tf"))
+	})
+
+	assert.NoDirExists(t, dir)
+}
+
+// TestIsolatedDirPerTest tests that two tests never share a workspace
+func TestIsolatedDirPerTest(t *testing.T) {
+	t.Parallel()
+
+	first := IsolatedModuleDir(t, ModuleNaming)
+	second := IsolatedModuleDir(t, ModuleNaming)
+
+	assert.NotEqual(t, first, second)
+}
+
+// TestCopyDirSkipsLocalState tests that generated Terraform files are not copied
+func TestCopyDirSkipsLocalState(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, ".terraform", "providers"), 0o755))
+	for _, name := range []string{"main.tf", ".terraform.lock.hcl", "terraform.tfstate", "terraform.tfstate.backup", "test.tfplan"} {
+		require.NoError(t, os.WriteFile(filepath.Join(src, name), []byte("# test"), 0o644))
+	}
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	require.NoError(t, copyDir(src, dst))
+
+	assert.FileExists(t, filepath.Join(dst, "main.tf"))
+	assert.FileExists(t, filepath.Join(dst, ".terraform.lock.hcl"))
+	assert.NoDirExists(t, filepath.Join(dst, ".terraform"))
+	assert.NoFileExists(t, filepath.Join(dst, "terraform.tfstate"))
+	assert.NoFileExists(t, filepath.Join(dst, "terraform.tfstate.backup"))
+	assert.NoFileExists(t, filepath.Join(dst, "test.tfplan"))
+}
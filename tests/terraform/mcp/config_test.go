@@ -0,0 +1,63 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - MCP SERVER CONFIGURATION TESTS
+// =============================================================================
+//
+// Validates mcp-servers/mcp-config.json before an agent starts a server from
+// it (see helpers/mcp.go):
+//
+//   - the file matches mcp-servers/mcp-config.schema.json and declares every
+//     server name once
+//   - every ${VAR} in env and args is documented in mcp-servers/USAGE.md or
+//     set by scripts/bootstrap.sh
+//   - every capability is a CLI command family in
+//     testdata/mcp/command-families.yaml, and every family there is used
+//
+// Run with: go test -v -run TestMCP ./mcp/
+//
+// =============================================================================
+
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// TestMCPConfig tests the schema, env references and capabilities of
+// mcp-config.json
+func TestMCPConfig(t *testing.T) {
+	t.Parallel()
+
+	config, err := helpers.LoadMCPConfig(helpers.MCPConfigPath(), helpers.MCPConfigSchemaPath())
+	require.NoError(t, err)
+	require.NotEmpty(t, config.Servers)
+
+	documented := map[string]bool{}
+	usage, err := helpers.UsageEnvVars(helpers.MCPUsagePath())
+	require.NoError(t, err)
+	bootstrap, err := helpers.ScriptEnvVars(helpers.BootstrapScriptPath())
+	require.NoError(t, err)
+	for _, name := range append(usage, bootstrap...) {
+		documented[name] = true
+	}
+
+	families, err := helpers.LoadCommandFamilies(helpers.CommandFamiliesPath())
+	require.NoError(t, err)
+
+	t.Run("env", func(t *testing.T) {
+		for _, problem := range config.CheckEnv(documented) {
+			t.Error(problem)
+		}
+	})
+
+	t.Run("capabilities", func(t *testing.T) {
+		for _, problem := range config.CheckCapabilities(families) {
+			t.Error(problem)
+		}
+		for _, entry := range families.Unused(config) {
+			t.Errorf("command-families.yaml: %s is not used by any capability; remove it", entry)
+		}
+	})
+}
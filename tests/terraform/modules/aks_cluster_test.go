@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - AKS CLUSTER MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform AKS cluster module.
 //
-// Run with: go test -v -run TestAKS ./modules/
+// Run with: go test -v -tags=unit -run TestAKS ./modules/
 //
 // =============================================================================
 
@@ -13,55 +15,39 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestAKSClusterModuleBasic tests basic AKS cluster configuration
 func TestAKSClusterModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/aks-cluster",
-		Vars: map[string]interface{}{
-			"resource_group_name": "rg-test-aks",
-			"location":            "brazilsouth",
-			"customer_name":       "testaks",
-			"environment":         "dev",
-			"kubernetes_version":  "1.29",
-			"sku_tier":            "Standard",
-			"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-			"system_node_pool": map[string]interface{}{
-				"name":       "system",
-				"vm_size":    "Standard_D4s_v5",
-				"node_count": 3,
-				"zones":      []string{"1", "2", "3"},
-			},
-			"workload_identity": true,
-			"addons": map[string]interface{}{
-				"azure_policy":           true,
-				"azure_keyvault_secrets": true,
-				"oms_agent":              false,
-			},
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
+	terraformOptions := helpers.AKSOptions(t, map[string]interface{}{
+		"customer_name": "testaks",
+		"sku_tier":      "Standard",
+		"default_node_pool": map[string]interface{}{
+			"name":       "system",
+			"vm_size":    "Standard_D4s_v5",
+			"node_count": 3,
+			"min_count":  3,
+			"zones":      []string{"1", "2", "3"},
 		},
-		NoColor: true,
+		"enable_workload_identity": true,
+		"enable_azure_policy":      true,
+		"key_vault_id":             helpers.KeyVaultID(),
 	})
 
-	// Initialize and validate
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
 	// Plan only (no actual resources created in unit test)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify plan contains expected resources
-	assert.Contains(t, planOutput, "azurerm_kubernetes_cluster.main")
-	assert.Contains(t, planOutput, "aks-testaks-dev")
+	plan.AssertCreated(t, "azurerm_kubernetes_cluster.main").
+		HasAttribute("name", "aks-testaks-"+helpers.TestEnvironment).
+		HasAttribute("default_node_pool.0.vm_size", "Standard_D4s_v5").
+		HasAttribute("default_node_pool.0.zones", []string{"1", "2", "3"}).
+		HasAttribute("azure_policy_enabled", true)
+	plan.AssertCreated(t, "azurerm_role_assignment.keyvault_secrets").
+		HasAttribute("scope", helpers.KeyVaultID())
 }
 
 // TestAKSClusterModuleKubernetesVersions tests different K8s versions
@@ -75,29 +61,15 @@ func TestAKSClusterModuleKubernetesVersions(t *testing.T) {
 		t.Run("k8s_"+version, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"resource_group_name": "rg-test-aks",
-					"location":            "brazilsouth",
-					"customer_name":       "vertest",
-					"environment":         "dev",
-					"kubernetes_version":  version,
-					"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-					"system_node_pool": map[string]interface{}{
-						"name":       "system",
-						"vm_size":    "Standard_D2s_v5",
-						"node_count": 1,
-						"zones":      []string{"1"},
-					},
-				},
-				NoColor: true,
-			}
+			terraformOptions := helpers.AKSOptions(t, map[string]interface{}{
+				"customer_name":      "vertest",
+				"kubernetes_version": version,
+			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, version)
+			plan.AssertCreated(t, "azurerm_kubernetes_cluster.main").
+				HasAttribute("kubernetes_version", version)
 		})
 	}
 }
@@ -120,29 +92,15 @@ func TestAKSClusterModuleSKUTiers(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"resource_group_name": "rg-test-aks",
-					"location":            "brazilsouth",
-					"customer_name":       "skutest",
-					"environment":         "dev",
-					"sku_tier":            tc.skuTier,
-					"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-					"system_node_pool": map[string]interface{}{
-						"name":       "system",
-						"vm_size":    "Standard_D2s_v5",
-						"node_count": 1,
-						"zones":      []string{"1"},
-					},
-				},
-				NoColor: true,
-			}
+			terraformOptions := helpers.AKSOptions(t, map[string]interface{}{
+				"customer_name": "skutest",
+				"sku_tier":      tc.skuTier,
+			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, tc.skuTier)
+			plan.AssertCreated(t, "azurerm_kubernetes_cluster.main").
+				HasAttribute("sku_tier", tc.skuTier)
 		})
 	}
 }
@@ -151,55 +109,57 @@ func TestAKSClusterModuleSKUTiers(t *testing.T) {
 func TestAKSClusterModuleNodePools(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/aks-cluster",
-		Vars: map[string]interface{}{
-			"resource_group_name": "rg-test-aks",
-			"location":            "brazilsouth",
-			"customer_name":       "nptest",
-			"environment":         "dev",
-			"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-			"system_node_pool": map[string]interface{}{
-				"name":       "system",
-				"vm_size":    "Standard_D4s_v5",
-				"node_count": 3,
-				"zones":      []string{"1", "2", "3"},
-			},
-			"user_node_pools": []map[string]interface{}{
-				{
-					"name":      "user1",
-					"vm_size":   "Standard_D8s_v5",
-					"min_count": 1,
-					"max_count": 10,
-					"zones":     []string{"1", "2", "3"},
-					"labels": map[string]string{
-						"workload": "general",
-					},
-					"taints": []string{},
+	terraformOptions := helpers.AKSOptions(t, map[string]interface{}{
+		"customer_name": "nptest",
+		"default_node_pool": map[string]interface{}{
+			"name":       "system",
+			"vm_size":    "Standard_D4s_v5",
+			"node_count": 3,
+			"min_count":  3,
+			"zones":      []string{"1", "2", "3"},
+		},
+		"additional_node_pools": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"name":                "user1",
+				"vm_size":             "Standard_D8s_v5",
+				"node_count":          1,
+				"min_count":           1,
+				"max_count":           10,
+				"enable_auto_scaling": true,
+				"max_pods":            110,
+				"zones":               []string{"1", "2", "3"},
+				"node_labels": map[string]string{
+					"workload": "general",
 				},
-				{
-					"name":      "gpu",
-					"vm_size":   "Standard_NC6s_v3",
-					"min_count": 0,
-					"max_count": 5,
-					"zones":     []string{"1"},
-					"labels": map[string]string{
-						"workload":       "gpu",
-						"accelerator":   "nvidia",
-					},
-					"taints": []string{"gpu=true:NoSchedule"},
+				"node_taints": []string{},
+			},
+			"gpu": map[string]interface{}{
+				"name":                "gpu",
+				"vm_size":             "Standard_NC6s_v3",
+				"node_count":          0,
+				"min_count":           0,
+				"max_count":           5,
+				"enable_auto_scaling": true,
+				"max_pods":            110,
+				"zones":               []string{"1"},
+				"node_labels": map[string]string{
+					"workload":    "gpu",
+					"accelerator": "nvidia",
 				},
+				"node_taints": []string{"gpu=true:NoSchedule"},
 			},
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify node pools are planned
-	assert.Contains(t, planOutput, "azurerm_kubernetes_cluster.main")
-	assert.Contains(t, planOutput, "azurerm_kubernetes_cluster_node_pool.user")
+	plan.AssertCreated(t, "azurerm_kubernetes_cluster.main")
+	plan.AssertResourceCount(t, "azurerm_kubernetes_cluster_node_pool.user", 2)
+	plan.AssertCreated(t, `azurerm_kubernetes_cluster_node_pool.user["gpu"]`).
+		HasAttribute("vm_size", "Standard_NC6s_v3").
+		HasAttribute("node_taints", []string{"gpu=true:NoSchedule"}).
+		HasAttribute("node_labels.environment", helpers.TestEnvironment)
 }
 
 // TestAKSClusterModuleAddons tests AKS addon configurations
@@ -207,33 +167,14 @@ func TestAKSClusterModuleAddons(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
-		name   string
-		addons map[string]interface{}
+		name        string
+		azurePolicy bool
+		keyVault    bool
+		omsAgent    bool
 	}{
-		{
-			"all_addons_enabled",
-			map[string]interface{}{
-				"azure_policy":           true,
-				"azure_keyvault_secrets": true,
-				"oms_agent":              true,
-			},
-		},
-		{
-			"minimal_addons",
-			map[string]interface{}{
-				"azure_policy":           false,
-				"azure_keyvault_secrets": false,
-				"oms_agent":              false,
-			},
-		},
-		{
-			"security_addons_only",
-			map[string]interface{}{
-				"azure_policy":           true,
-				"azure_keyvault_secrets": true,
-				"oms_agent":              false,
-			},
-		},
+		{"all_addons_enabled", true, true, true},
+		{"minimal_addons", false, false, false},
+		{"security_addons_only", true, true, false},
 	}
 
 	for _, tc := range testCases {
@@ -241,27 +182,33 @@ func TestAKSClusterModuleAddons(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"resource_group_name": "rg-test-aks",
-					"location":            "brazilsouth",
-					"customer_name":       "addontest",
-					"environment":         "dev",
-					"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-					"system_node_pool": map[string]interface{}{
-						"name":       "system",
-						"vm_size":    "Standard_D2s_v5",
-						"node_count": 1,
-						"zones":      []string{"1"},
-					},
-					"addons": tc.addons,
-				},
-				NoColor: true,
+			vars := map[string]interface{}{
+				"customer_name":       "addontest",
+				"enable_azure_policy": tc.azurePolicy,
+			}
+			if tc.keyVault {
+				vars["key_vault_id"] = helpers.KeyVaultID()
 			}
+			if tc.omsAgent {
+				vars["log_analytics_id"] = helpers.LogAnalyticsWorkspaceID()
+			}
+
+			terraformOptions := helpers.AKSOptions(t, vars)
+
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan.AssertCreated(t, "azurerm_kubernetes_cluster.main").
+				HasAttribute("azure_policy_enabled", tc.azurePolicy)
+			if tc.keyVault {
+				plan.AssertCreated(t, "azurerm_role_assignment.keyvault_secrets")
+			} else {
+				plan.AssertAbsent(t, "azurerm_role_assignment.keyvault_secrets")
+			}
+			if tc.omsAgent {
+				plan.AssertCreated(t, "azurerm_monitor_diagnostic_setting.aks")
+			} else {
+				plan.AssertAbsent(t, "azurerm_monitor_diagnostic_setting.aks")
+			}
 		})
 	}
 }
@@ -283,70 +230,52 @@ func TestAKSClusterModuleWorkloadIdentity(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"resource_group_name": "rg-test-aks",
-					"location":            "brazilsouth",
-					"customer_name":       "witest",
-					"environment":         "dev",
-					"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-					"system_node_pool": map[string]interface{}{
-						"name":       "system",
-						"vm_size":    "Standard_D2s_v5",
-						"node_count": 1,
-						"zones":      []string{"1"},
-					},
-					"workload_identity": tc.workloadIdentity,
-				},
-				NoColor: true,
-			}
+			terraformOptions := helpers.AKSOptions(t, map[string]interface{}{
+				"customer_name":            "witest",
+				"enable_workload_identity": tc.workloadIdentity,
+			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			if tc.workloadIdentity {
-				assert.Contains(t, planOutput, "workload_identity_enabled")
-				assert.Contains(t, planOutput, "oidc_issuer_enabled")
-			}
+			plan.AssertCreated(t, "azurerm_kubernetes_cluster.main").
+				HasAttribute("sku_tier", "Standard").
+				HasAttribute("workload_identity_enabled", tc.workloadIdentity).
+				HasAttribute("oidc_issuer_enabled", tc.workloadIdentity)
 		})
 	}
 }
 
-// TestAKSClusterModuleEnvironments tests different environment configurations
+// TestAKSClusterModuleEnvironments tests what the module derives from the
+// environment. Sizing is held constant: the root module picks the tier and
+// zones from deployment_mode, not from the environment.
 func TestAKSClusterModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
+	zones := []string{"1", "2", "3"}
 
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"resource_group_name": "rg-test-aks-" + env,
-					"location":            "brazilsouth",
-					"customer_name":       "envtest",
-					"environment":         env,
-					"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-					"system_node_pool": map[string]interface{}{
-						"name":       "system",
-						"vm_size":    "Standard_D2s_v5",
-						"node_count": 1,
-						"zones":      []string{"1"},
-					},
+			terraformOptions := helpers.AKSOptions(t, env.Vars(helpers.ModuleAKSCluster), map[string]interface{}{
+				"customer_name":       "envtest",
+				"resource_group_name": "rg-test-aks-" + env.Name,
+				"sku_tier":            "Standard",
+				"default_node_pool": map[string]interface{}{
+					"zones": zones,
 				},
-				NoColor: true,
-			}
+			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			// Verify environment is reflected in naming
-			assert.Contains(t, planOutput, "aks-envtest-"+env)
+			// Naming and node labels follow the environment; sizing does not
+			plan.AssertCreated(t, "azurerm_kubernetes_cluster.main").
+				HasAttribute("name", "aks-envtest-"+env.Value(helpers.ModuleAKSCluster)).
+				HasAttribute("dns_prefix", "envtest-"+env.Value(helpers.ModuleAKSCluster)).
+				HasAttribute("default_node_pool.0.node_labels.environment", env.Value(helpers.ModuleAKSCluster)).
+				HasAttribute("sku_tier", "Standard").
+				HasAttribute("default_node_pool.0.zones", zones)
 		})
 	}
 }
@@ -363,26 +292,16 @@ func TestAKSClusterModuleValidation(t *testing.T) {
 		{
 			name: "valid_inputs",
 			vars: map[string]interface{}{
-				"resource_group_name": "rg-test-aks",
-				"location":            "brazilsouth",
-				"customer_name":       "valid",
-				"environment":         "dev",
-				"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-				"system_node_pool": map[string]interface{}{
-					"name":       "system",
-					"vm_size":    "Standard_D2s_v5",
-					"node_count": 1,
-					"zones":      []string{"1"},
-				},
+				"customer_name": "valid",
 			},
 			shouldError: false,
 		},
 		{
 			name: "missing_required_var",
 			vars: map[string]interface{}{
-				"location":      "brazilsouth",
-				"customer_name": "test",
-				"environment":   "dev",
+				"customer_name":       "test",
+				"resource_group_name": helpers.Unset,
+				"network_config":      helpers.Unset,
 			},
 			shouldError: true,
 		},
@@ -393,19 +312,13 @@ func TestAKSClusterModuleValidation(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars:         tc.vars,
-				NoColor:      true,
-			}
-
-			terraform.Init(t, terraformOptions)
+			terraformOptions := helpers.AKSOptions(t, tc.vars)
 
 			if tc.shouldError {
-				_, err := terraform.PlanE(t, terraformOptions)
-				require.Error(t, err, "Expected validation error but got none")
+				_, err := helpers.InitAndPlanE(t, terraformOptions)
+				helpers.AssertPlanError(t, err, "No value for required variable")
 			} else {
-				terraform.Plan(t, terraformOptions)
+				helpers.InitAndPlan(t, terraformOptions)
 			}
 		})
 	}
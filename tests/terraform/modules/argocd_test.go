@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - ARGOCD MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform ArgoCD module.
 //
-// Run with: go test -v -run TestArgoCD ./modules/
+// Run with: go test -v -tags=unit -run TestArgoCD ./modules/
 //
 // =============================================================================
 
@@ -13,41 +15,29 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestArgoCDModuleBasic tests basic ArgoCD configuration
 func TestArgoCDModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/argocd",
-		Vars: map[string]interface{}{
-			"customer_name": "testargocd",
-			"environment":   "dev",
-			"github_org":    "test-org",
-			"github_repo":   "test-repo",
-			"argocd_config": map[string]interface{}{
-				"namespace":   "argocd",
-				"ha_enabled":  false,
-				"server_host": "argocd.example.com",
-			},
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.ArgoCDOptions(t, map[string]interface{}{
+		"customer_name": "testargocd",
+		"namespace":     "argocd",
+		"ha_enabled":    false,
+		"domain_name":   "example.com",
 	})
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify ArgoCD resources are planned
-	assert.Contains(t, planOutput, "helm_release.argocd")
+	plan.AssertCreated(t, "kubernetes_namespace.argocd").
+		HasAttribute("metadata.0.name", "argocd")
+	plan.AssertCreated(t, "helm_release.argocd").
+		HasAttribute("chart", "argo-cd").
+		HasHelmValue("global.domain", "argocd.example.com")
+	plan.AssertAbsent(t, "kubernetes_secret.notifications")
 }
 
 // TestArgoCDModuleHAConfiguration tests high availability setup
@@ -67,24 +57,25 @@ func TestArgoCDModuleHAConfiguration(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/argocd",
-				Vars: map[string]interface{}{
-					"customer_name": "hatest",
-					"environment":   "prod",
-					"github_org":    "test-org",
-					"github_repo":   "test-repo",
-					"argocd_config": map[string]interface{}{
-						"namespace":   "argocd",
-						"ha_enabled":  tc.haEnabled,
-						"server_host": "argocd.example.com",
-					},
-				},
-				NoColor: true,
+			terraformOptions := helpers.ArgoCDOptions(t, map[string]interface{}{
+				"customer_name": "hatest",
+				"environment":   "prod",
+				"namespace":     "argocd",
+				"ha_enabled":    tc.haEnabled,
+				"domain_name":   "example.com",
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			replicas := 1
+			if tc.haEnabled {
+				replicas = 3
+			}
+			plan.AssertCreated(t, "helm_release.argocd").
+				HasHelmValue("controller.replicas", replicas).
+				HasHelmValue("server.autoscaling.enabled", tc.haEnabled).
+				HasHelmValue("redis-ha.enabled", tc.haEnabled).
+				HasHelmValue("redis.enabled", !tc.haEnabled)
 		})
 	}
 }
@@ -93,61 +84,42 @@ func TestArgoCDModuleHAConfiguration(t *testing.T) {
 func TestArgoCDModuleApplicationSets(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/argocd",
-		Vars: map[string]interface{}{
-			"customer_name": "appsettest",
-			"environment":   "dev",
-			"github_org":    "test-org",
-			"github_repo":   "test-repo",
-			"argocd_config": map[string]interface{}{
-				"namespace":             "argocd",
-				"ha_enabled":            false,
-				"server_host":           "argocd.example.com",
-				"enable_applicationset": true,
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.ArgoCDOptions(t, map[string]interface{}{
+		"customer_name": "appsettest",
+		"namespace":     "argocd",
+		"ha_enabled":    false,
+		"domain_name":   "example.com",
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify ApplicationSet controller is planned
-	assert.Contains(t, planOutput, "argocd")
+	plan.AssertCreated(t, "helm_release.argocd").
+		HasHelmValue("applicationSet.enabled", true).
+		HasHelmValue("applicationSet.replicas", 1)
 }
 
 // TestArgoCDModuleEnvironments tests different environments
 func TestArgoCDModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/argocd",
-				Vars: map[string]interface{}{
-					"customer_name": "envtest",
-					"environment":   env,
-					"github_org":    "test-org",
-					"github_repo":   "test-repo",
-					"argocd_config": map[string]interface{}{
-						"namespace":   "argocd",
-						"ha_enabled":  env == "prod",
-						"server_host": "argocd-" + env + ".example.com",
-					},
-				},
-				NoColor: true,
+			terraformOptions := helpers.ArgoCDOptions(t, env.Vars(helpers.ModuleArgoCD), map[string]interface{}{
+				"customer_name": "envtest",
+				"namespace":     "argocd",
+				"domain_name":   env.Name + ".example.com",
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, env)
+			plan.AssertCreated(t, "kubernetes_namespace.argocd").
+				HasAttribute("metadata.0.labels.three-horizons/environment", env.Value(helpers.ModuleArgoCD))
+			plan.AssertCreated(t, "helm_release.argocd").
+				HasHelmValue("global.domain", "argocd."+env.Name+".example.com")
 		})
 	}
 }
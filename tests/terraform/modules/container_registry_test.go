@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - CONTAINER REGISTRY MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform container registry module.
 //
-// Run with: go test -v -run TestContainerRegistry ./modules/
+// Run with: go test -v -tags=unit -run TestContainerRegistry ./modules/
 //
 // =============================================================================
 
@@ -13,40 +15,23 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestContainerRegistryModuleBasic tests basic ACR configuration
 func TestContainerRegistryModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/container-registry",
-		Vars: map[string]interface{}{
-			"customer_name":                   "testacr",
-			"environment":                     "dev",
-			"location":                        "brazilsouth",
-			"resource_group_name":             "rg-test-acr",
-			"sku":                             "Premium",
-			"subnet_id":                       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_id":             "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-			"aks_kubelet_identity_object_id":  "00000000-0000-0000-0000-000000000001",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.ContainerRegistryOptions(t, map[string]interface{}{
+		"customer_name": "testacr",
 	})
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify ACR is planned
-	assert.Contains(t, planOutput, "azurerm_container_registry.main")
+	plan.AssertCreated(t, "azurerm_container_registry.main").
+		HasAttribute("sku", "Premium").
+		HasAttribute("admin_enabled", false)
 }
 
 // TestContainerRegistryModuleSKUs tests different SKU configurations
@@ -67,25 +52,22 @@ func TestContainerRegistryModuleSKUs(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/container-registry",
-				Vars: map[string]interface{}{
-					"customer_name":                  "skutest",
-					"environment":                    "dev",
-					"location":                       "brazilsouth",
-					"resource_group_name":            "rg-test-sku-" + tc.name,
-					"sku":                            tc.sku,
-					"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-					"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-				},
-				NoColor: true,
+			terraformOptions := helpers.ContainerRegistryOptions(t, map[string]interface{}{
+				"customer_name":       "skutest",
+				"resource_group_name": "rg-test-sku-" + tc.name,
+				"sku":                 tc.sku,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, "azurerm_container_registry.main")
+			plan.AssertCreated(t, "azurerm_container_registry.main").
+				HasAttribute("sku", tc.sku).
+				HasAttribute("quarantine_policy_enabled", tc.sku == "Premium")
+			if tc.sku == "Premium" {
+				plan.AssertCreated(t, "azurerm_container_registry_task.purge_old_images")
+			} else {
+				plan.AssertAbsent(t, "azurerm_container_registry_task")
+			}
 		})
 	}
 }
@@ -94,107 +76,70 @@ func TestContainerRegistryModuleSKUs(t *testing.T) {
 func TestContainerRegistryModuleNaming(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/container-registry",
-		Vars: map[string]interface{}{
-			"customer_name":                  "nametest",
-			"environment":                    "dev",
-			"location":                       "brazilsouth",
-			"resource_group_name":            "rg-test-naming",
-			"sku":                            "Premium",
-			"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-			"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-		},
-		NoColor: true,
+	terraformOptions := helpers.ContainerRegistryOptions(t, map[string]interface{}{
+		"customer_name": "nametest",
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// ACR names must be alphanumeric only (no hyphens)
-	assert.Contains(t, planOutput, "crnametestdev")
+	plan.AssertCreated(t, "azurerm_container_registry.main").
+		HasAttribute("name", "crnametest"+helpers.TestEnvironment)
 }
 
 // TestContainerRegistryModuleGeoReplication tests geo-replication
 func TestContainerRegistryModuleGeoReplication(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/container-registry",
-		Vars: map[string]interface{}{
-			"customer_name":                  "georep",
-			"environment":                    "prod",
-			"location":                       "brazilsouth",
-			"resource_group_name":            "rg-test-georep",
-			"sku":                            "Premium",
-			"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-			"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-			"geo_replication_locations":      []string{"eastus", "westeurope"},
-		},
-		NoColor: true,
+	terraformOptions := helpers.ContainerRegistryOptions(t, map[string]interface{}{
+		"customer_name":             "georep",
+		"environment":               "prod",
+		"geo_replication_locations": []string{"eastus", "westeurope"},
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
-	// Verify geo-replication is planned for Premium SKU
-	assert.Contains(t, planOutput, "azurerm_container_registry_replication")
+	// One replica per geo-replication location for Premium SKU
+	plan.AssertResourceCount(t, "azurerm_container_registry_replication", 2)
+	plan.AssertCreated(t, `azurerm_container_registry_replication.replicas["eastus"]`).
+		HasAttribute("location", "eastus")
 }
 
 // TestContainerRegistryModulePrivateEndpoint tests private endpoint
 func TestContainerRegistryModulePrivateEndpoint(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/container-registry",
-		Vars: map[string]interface{}{
-			"customer_name":                  "petest",
-			"environment":                    "prod",
-			"location":                       "brazilsouth",
-			"resource_group_name":            "rg-test-pe",
-			"sku":                            "Premium",
-			"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-			"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-		},
-		NoColor: true,
+	terraformOptions := helpers.ContainerRegistryOptions(t, map[string]interface{}{
+		"customer_name": "petest",
+		"environment":   "prod",
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify private endpoint is planned
-	assert.Contains(t, planOutput, "azurerm_private_endpoint.acr")
+	plan.AssertCreated(t, "azurerm_private_endpoint.acr").
+		HasAttribute("name", "pe-crpetestprod").
+		HasAttribute("private_service_connection.0.subresource_names", []string{"registry"})
 }
 
 // TestContainerRegistryModuleRBAC tests RBAC role assignments
 func TestContainerRegistryModuleRBAC(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/container-registry",
-		Vars: map[string]interface{}{
-			"customer_name":                  "rbactest",
-			"environment":                    "dev",
-			"location":                       "brazilsouth",
-			"resource_group_name":            "rg-test-rbac",
-			"sku":                            "Premium",
-			"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-			"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-			"github_actions_identity_ids":    []string{"00000000-0000-0000-0000-000000000002"},
-		},
-		NoColor: true,
+	terraformOptions := helpers.ContainerRegistryOptions(t, map[string]interface{}{
+		"customer_name":               "rbactest",
+		"github_actions_identity_ids": []string{"00000000-0000-0000-0000-000000000002"},
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify role assignments are planned
-	assert.Contains(t, planOutput, "azurerm_role_assignment.aks_acr_pull")
-	assert.Contains(t, planOutput, "azurerm_role_assignment.github_actions_push")
+	plan.AssertCreated(t, "azurerm_role_assignment.aks_acr_pull").
+		HasAttribute("role_definition_name", "AcrPull").
+		HasAttribute("principal_id", helpers.TestObjectID)
+	plan.AssertCreated(t, "azurerm_role_assignment.github_actions_push").
+		HasAttribute("role_definition_name", "AcrPush").
+		HasAttribute("principal_id", "00000000-0000-0000-0000-000000000002")
 }
 
 // TestContainerRegistryModuleWebhook tests optional webhook configuration
@@ -216,30 +161,19 @@ func TestContainerRegistryModuleWebhook(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/container-registry",
-				Vars: map[string]interface{}{
-					"customer_name":                  "whtest",
-					"environment":                    "dev",
-					"location":                       "brazilsouth",
-					"resource_group_name":            "rg-test-wh",
-					"sku":                            "Premium",
-					"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-					"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-					"enable_webhook":                 tc.enableWebhook,
-					"webhook_service_uri":            tc.webhookURI,
-				},
-				NoColor: true,
+			terraformOptions := helpers.ContainerRegistryOptions(t, map[string]interface{}{
+				"customer_name":       "whtest",
+				"enable_webhook":      tc.enableWebhook,
+				"webhook_service_uri": tc.webhookURI,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
 			if tc.expectWebhook {
-				assert.Contains(t, planOutput, "azurerm_container_registry_webhook")
+				plan.AssertCreated(t, "azurerm_container_registry_webhook.image_push").
+					HasAttribute("service_uri", tc.webhookURI)
 			} else {
-				assert.NotContains(t, planOutput, "azurerm_container_registry_webhook.image_push[0]")
+				plan.AssertAbsent(t, "azurerm_container_registry_webhook")
 			}
 		})
 	}
@@ -249,32 +183,21 @@ func TestContainerRegistryModuleWebhook(t *testing.T) {
 func TestContainerRegistryModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/container-registry",
-				Vars: map[string]interface{}{
-					"customer_name":                  "envtest",
-					"environment":                    env,
-					"location":                       "brazilsouth",
-					"resource_group_name":            "rg-test-acr-" + env,
-					"sku":                            "Premium",
-					"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-					"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-				},
-				NoColor: true,
+			terraformOptions := helpers.ContainerRegistryOptions(t, env.Vars(helpers.ModuleContainerRegistry), map[string]interface{}{
+				"customer_name":       "envtest",
+				"resource_group_name": "rg-test-acr-" + env.Name,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, env)
+			// Premium registries are zone redundant in prod only
+			plan.AssertCreated(t, "azurerm_container_registry.main").
+				HasAttribute("zone_redundancy_enabled", env.HighAvailability())
 		})
 	}
 }
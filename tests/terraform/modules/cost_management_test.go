@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - COST MANAGEMENT MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform Cost Management module.
 //
-// Run with: go test -v -run TestCostManagement ./modules/
+// Run with: go test -v -tags=unit -run TestCostManagement ./modules/
 //
 // =============================================================================
 
@@ -13,38 +15,26 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestCostManagementModuleBasic tests basic cost management configuration
 func TestCostManagementModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/cost-management",
-		Vars: map[string]interface{}{
-			"customer_name":         "testcost",
-			"environment":           "dev",
-			"location":              "brazilsouth",
-			"resource_group_name":   "rg-test-cost",
-			"monthly_budget":        5000,
-			"alert_email_addresses": []string{"ops@example.com"},
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.CostManagementOptions(t, map[string]interface{}{
+		"customer_name":         "testcost",
+		"monthly_budget":        5000,
+		"alert_email_addresses": []string{"ops@example.com"},
 	})
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify budget is planned
-	assert.Contains(t, planOutput, "azurerm_consumption_budget")
+	plan.AssertCreated(t, "azurerm_consumption_budget_resource_group.main").
+		HasAttribute("name", "budget-testcost-"+helpers.TestEnvironment+"-rg").
+		HasAttribute("amount", 5000)
+	plan.AssertAbsent(t, "azurerm_consumption_budget_subscription")
 }
 
 // TestCostManagementModuleBudgetThresholds tests different budget levels
@@ -58,21 +48,17 @@ func TestCostManagementModuleBudgetThresholds(t *testing.T) {
 		t.Run(string(rune(budget)), func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/cost-management",
-				Vars: map[string]interface{}{
-					"customer_name":         "budgettest",
-					"environment":           "prod",
-					"location":              "brazilsouth",
-					"resource_group_name":   "rg-test-cost-budget",
-					"monthly_budget":        budget,
-					"alert_email_addresses": []string{"ops@example.com", "finance@example.com"},
-				},
-				NoColor: true,
+			terraformOptions := helpers.CostManagementOptions(t, map[string]interface{}{
+				"customer_name":         "budgettest",
+				"environment":           "prod",
+				"monthly_budget":        budget,
+				"alert_email_addresses": []string{"ops@example.com", "finance@example.com"},
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			plan.AssertCreated(t, "azurerm_consumption_budget_resource_group.main").
+				HasAttribute("amount", budget)
 		})
 	}
 }
@@ -81,29 +67,23 @@ func TestCostManagementModuleBudgetThresholds(t *testing.T) {
 func TestCostManagementModuleMultipleAlertRecipients(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/cost-management",
-		Vars: map[string]interface{}{
-			"customer_name":       "alerttest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-cost-alerts",
-			"monthly_budget":      10000,
-			"alert_email_addresses": []string{
-				"ops@example.com",
-				"finance@example.com",
-				"manager@example.com",
-				"director@example.com",
-			},
+	terraformOptions := helpers.CostManagementOptions(t, map[string]interface{}{
+		"customer_name":  "alerttest",
+		"environment":    "prod",
+		"monthly_budget": 10000,
+		"alert_email_addresses": []string{
+			"ops@example.com",
+			"finance@example.com",
+			"manager@example.com",
+			"director@example.com",
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify action group is planned
-	assert.Contains(t, planOutput, "azurerm_monitor_action_group")
+	plan.AssertCreated(t, "azurerm_monitor_action_group.cost_alerts").
+		HasAttribute("email_receiver.3.email_address", "director@example.com")
 }
 
 // TestCostManagementModuleCostExport tests cost export configuration
@@ -117,23 +97,22 @@ func TestCostManagementModuleCostExport(t *testing.T) {
 		t.Run(recurrence, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/cost-management",
-				Vars: map[string]interface{}{
-					"customer_name":         "exporttest",
-					"environment":           "prod",
-					"location":              "brazilsouth",
-					"resource_group_name":   "rg-test-cost-export",
-					"monthly_budget":        10000,
-					"alert_email_addresses": []string{"ops@example.com"},
-					"enable_cost_export":    true,
-					"export_recurrence":     recurrence,
-				},
-				NoColor: true,
+			terraformOptions := helpers.CostManagementOptions(t, map[string]interface{}{
+				"customer_name":         "exporttest",
+				"environment":           "prod",
+				"monthly_budget":        10000,
+				"alert_email_addresses": []string{"ops@example.com"},
+				"enable_cost_export":    true,
+				"export_recurrence":     recurrence,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			plan.AssertCreated(t, "azurerm_storage_account.cost_export").
+				HasAttribute("name", "stexporttestcostprod").
+				HasAttribute("min_tls_version", "TLS1_2")
+			plan.AssertCreated(t, "azurerm_resource_group_cost_management_export.main").
+				HasAttribute("recurrence_type", recurrence)
 		})
 	}
 }
@@ -155,23 +134,23 @@ func TestCostManagementModuleSubscriptionBudget(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/cost-management",
-				Vars: map[string]interface{}{
-					"customer_name":              "subtest",
-					"environment":                "prod",
-					"location":                   "brazilsouth",
-					"resource_group_name":        "rg-test-cost-sub",
-					"monthly_budget":             10000,
-					"alert_email_addresses":      []string{"ops@example.com"},
-					"create_subscription_budget": tc.enabled,
-					"subscription_monthly_budget": 50000,
-				},
-				NoColor: true,
+			terraformOptions := helpers.CostManagementOptions(t, map[string]interface{}{
+				"customer_name":               "subtest",
+				"environment":                 "prod",
+				"monthly_budget":              10000,
+				"alert_email_addresses":       []string{"ops@example.com"},
+				"create_subscription_budget":  tc.enabled,
+				"subscription_monthly_budget": 50000,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			if tc.enabled {
+				plan.AssertCreated(t, "azurerm_consumption_budget_subscription.main").
+					HasAttribute("amount", 50000)
+			} else {
+				plan.AssertAbsent(t, "azurerm_consumption_budget_subscription")
+			}
 		})
 	}
 }
@@ -180,28 +159,23 @@ func TestCostManagementModuleSubscriptionBudget(t *testing.T) {
 func TestCostManagementModuleWebhooks(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/cost-management",
-		Vars: map[string]interface{}{
-			"customer_name":         "webhooktest",
-			"environment":           "prod",
-			"location":              "brazilsouth",
-			"resource_group_name":   "rg-test-cost-webhook",
-			"monthly_budget":        10000,
-			"alert_email_addresses": []string{"ops@example.com"},
-			"webhook_urls": []string{
-				"https://hooks.slack.com/services/xxx/yyy/zzz",
-				"https://teams.webhook.office.com/xxx",
-			},
+	terraformOptions := helpers.CostManagementOptions(t, map[string]interface{}{
+		"customer_name":         "webhooktest",
+		"environment":           "prod",
+		"monthly_budget":        10000,
+		"alert_email_addresses": []string{"ops@example.com"},
+		"webhook_urls": []string{
+			"https://hooks.slack.com/services/xxx/yyy/zzz",
+			"https://teams.webhook.office.com/xxx",
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify webhook configuration
-	assert.Contains(t, planOutput, "cost")
+	plan.AssertCreated(t, "azurerm_monitor_action_group.cost_alerts").
+		HasAttribute("webhook_receiver.0.service_uri", "https://hooks.slack.com/services/xxx/yyy/zzz").
+		HasAttribute("webhook_receiver.1.service_uri", "https://teams.webhook.office.com/xxx")
 }
 
 // TestCostManagementModuleCustomAlerts tests custom cost alert rules
@@ -221,22 +195,21 @@ func TestCostManagementModuleCustomAlerts(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/cost-management",
-				Vars: map[string]interface{}{
-					"customer_name":              "customtest",
-					"environment":                "prod",
-					"location":                   "brazilsouth",
-					"resource_group_name":        "rg-test-cost-custom",
-					"monthly_budget":             10000,
-					"alert_email_addresses":      []string{"ops@example.com"},
-					"enable_custom_cost_alerts":  tc.enabled,
-				},
-				NoColor: true,
+			terraformOptions := helpers.CostManagementOptions(t, map[string]interface{}{
+				"customer_name":             "customtest",
+				"environment":               "prod",
+				"monthly_budget":            10000,
+				"alert_email_addresses":     []string{"ops@example.com"},
+				"enable_custom_cost_alerts": tc.enabled,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			if tc.enabled {
+				plan.AssertCreated(t, "azurerm_monitor_scheduled_query_rules_alert_v2.high_cost_resources")
+			} else {
+				plan.AssertAbsent(t, "azurerm_monitor_scheduled_query_rules_alert_v2")
+			}
 		})
 	}
 }
@@ -245,30 +218,24 @@ func TestCostManagementModuleCustomAlerts(t *testing.T) {
 func TestCostManagementModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/cost-management",
-				Vars: map[string]interface{}{
-					"customer_name":         "envtest",
-					"environment":           env,
-					"location":              "brazilsouth",
-					"resource_group_name":   "rg-test-cost-" + env,
-					"monthly_budget":        5000,
-					"alert_email_addresses": []string{"ops@example.com"},
-				},
-				NoColor: true,
+			terraformOptions := helpers.CostManagementOptions(t, env.Vars(helpers.ModuleCostManagement), map[string]interface{}{
+				"customer_name":         "envtest",
+				"resource_group_name":   "rg-test-cost-" + env.Name,
+				"monthly_budget":        5000,
+				"alert_email_addresses": []string{"ops@example.com"},
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, env)
+			plan.AssertCreated(t, "azurerm_consumption_budget_resource_group.main").
+				HasAttribute("name", "budget-envtest-"+env.Value(helpers.ModuleCostManagement)+"-rg")
+			plan.AssertCreated(t, "azurerm_cost_anomaly_alert.main").
+				HasAttribute("name", "anomaly-envtest-"+env.Value(helpers.ModuleCostManagement))
 		})
 	}
 }
@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - DATABASES MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform databases module.
 //
-// Run with: go test -v -run TestDatabases ./modules/
+// Run with: go test -v -tags=unit -run TestDatabases ./modules/
 //
 // =============================================================================
 
@@ -13,84 +15,82 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestDatabasesModuleBasic tests basic databases module configuration
 func TestDatabasesModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/databases",
-		Vars: map[string]interface{}{
-			"customer_name":       "testdb",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-databases",
-			"subnet_id":           "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-db",
-			"key_vault_id":        "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.KeyVault/vaults/kv-test",
-			"private_dns_zone_ids": map[string]interface{}{
-				"postgres": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/privatelink.postgres.database.azure.com",
-				"redis":    "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/privatelink.redis.cache.windows.net",
-			},
-			"enable_postgresql": true,
-			"enable_redis":      true,
-			"enable_cosmosdb":   false,
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
+	terraformOptions := helpers.DatabasesOptions(t, map[string]interface{}{
+		"customer_name": "testdb",
+		"postgresql_config": map[string]interface{}{
+			"enabled": true,
+		},
+		"redis_config": map[string]interface{}{
+			"enabled": true,
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify PostgreSQL and Redis are planned
-	assert.Contains(t, planOutput, "azurerm_postgresql_flexible_server")
-	assert.Contains(t, planOutput, "azurerm_redis_cache")
+	plan.AssertCreated(t, "azurerm_postgresql_flexible_server.main").
+		HasAttribute("name", "psql-testdb-"+helpers.TestEnvironment)
+	plan.AssertResourceCount(t, "azurerm_postgresql_flexible_server_database", 2)
+	plan.AssertCreated(t, "azurerm_redis_cache.main").
+		HasAttribute("public_network_access_enabled", false)
 }
 
 // TestDatabasesModulePostgreSQLConfig tests PostgreSQL configuration
 func TestDatabasesModulePostgreSQLConfig(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/databases",
-		Vars: map[string]interface{}{
-			"customer_name":       "psqltest",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-psql",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"private_dns_zone_ids": map[string]interface{}{
-				"postgres": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/postgres",
-			},
-			"enable_postgresql": true,
-			"enable_redis":      false,
-			"postgresql_config": map[string]interface{}{
-				"sku_name":         "GP_Standard_D2s_v3",
-				"version":          "15",
-				"storage_mb":       32768,
-				"backup_retention": 7,
-				"geo_redundant":    false,
-				"high_availability": false,
-			},
+	terraformOptions := helpers.DatabasesOptions(t, map[string]interface{}{
+		"customer_name": "psqltest",
+		"postgresql_config": map[string]interface{}{
+			"enabled":               true,
+			"sku_name":              "GP_Standard_D2s_v3",
+			"version":               "15",
+			"storage_mb":            32768,
+			"backup_retention_days": 7,
+			"geo_redundant_backup":  false,
+			"high_availability":     false,
+		},
+		"redis_config": map[string]interface{}{
+			"enabled": false,
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify PostgreSQL configuration
-	assert.Contains(t, planOutput, "azurerm_postgresql_flexible_server")
-	assert.Contains(t, planOutput, "psql-")
+	plan.AssertCreated(t, "azurerm_postgresql_flexible_server.main").
+		HasAttribute("sku_name", "GP_Standard_D2s_v3").
+		HasAttribute("version", "15").
+		HasAttribute("storage_mb", 32768)
+	plan.AssertAbsent(t, "azurerm_redis_cache")
+
+	plan.AssertGolden(t, helpers.ModuleDatabases, "postgresql")
+}
+
+// TestDatabasesModulePostgreSQLUsers tests application roles
+func TestDatabasesModulePostgreSQLUsers(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := helpers.DatabasesOptions(t, map[string]interface{}{
+		"customer_name":    "pgusers",
+		"postgresql_users": []string{"rhdh"},
+	})
+
+	plan := helpers.InitAndPlan(t, terraformOptions)
+
+	plan.AssertCreated(t, `random_password.postgresql_users["rhdh"]`)
+	plan.AssertCreated(t, `azurerm_key_vault_secret.postgresql_users["rhdh"]`).
+		HasAttribute("name", "postgresql-rhdh-password")
+	plan.AssertCreated(t, "kubernetes_job_v1.postgresql_users").
+		HasAttribute("metadata.0.namespace", "kube-system").
+		HasAttribute("wait_for_completion", true)
 }
 
 // TestDatabasesModuleRedisConfig tests Redis configuration
@@ -113,134 +113,100 @@ func TestDatabasesModuleRedisConfig(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/databases",
-				Vars: map[string]interface{}{
-					"customer_name":       "redistest",
-					"environment":         "dev",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-redis",
-					"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"private_dns_zone_ids": map[string]interface{}{
-						"redis": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/redis",
-					},
-					"enable_postgresql": false,
-					"enable_redis":      true,
-					"redis_config": map[string]interface{}{
-						"sku_name": tc.sku,
-						"family":   tc.family,
-						"capacity": tc.capacity,
-					},
+			terraformOptions := helpers.DatabasesOptions(t, map[string]interface{}{
+				"customer_name": "redistest",
+				"postgresql_config": map[string]interface{}{
+					"enabled": false,
+				},
+				"redis_config": map[string]interface{}{
+					"enabled":  true,
+					"sku_name": tc.sku,
+					"family":   tc.family,
+					"capacity": tc.capacity,
 				},
-				NoColor: true,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, "azurerm_redis_cache")
+			plan.AssertCreated(t, "azurerm_redis_cache.main").
+				HasAttribute("sku_name", tc.sku).
+				HasAttribute("family", tc.family).
+				HasAttribute("capacity", tc.capacity)
+			plan.AssertAbsent(t, "azurerm_postgresql_flexible_server")
 		})
 	}
 }
 
-// TestDatabasesModuleCosmosDB tests Cosmos DB configuration
-func TestDatabasesModuleCosmosDB(t *testing.T) {
-	t.Parallel()
-
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/databases",
-		Vars: map[string]interface{}{
-			"customer_name":       "cosmostest",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-cosmos",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"private_dns_zone_ids": map[string]interface{}{
-				"cosmosdb": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/cosmos",
-			},
-			"enable_postgresql": false,
-			"enable_redis":      false,
-			"enable_cosmosdb":   true,
-			"cosmosdb_config": map[string]interface{}{
-				"offer_type":  "Standard",
-				"kind":        "MongoDB",
-				"consistency": "Session",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
-
-	// Verify Cosmos DB is planned when enabled
-	assert.Contains(t, planOutput, "azurerm_cosmosdb_account")
-}
-
 // TestDatabasesModulePrivateEndpoints tests private endpoint creation
 func TestDatabasesModulePrivateEndpoints(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/databases",
-		Vars: map[string]interface{}{
-			"customer_name":       "petest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-pe",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"private_dns_zone_ids": map[string]interface{}{
-				"postgres": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/postgres",
-			},
-			"enable_postgresql": true,
-			"enable_redis":      false,
+	terraformOptions := helpers.DatabasesOptions(t, map[string]interface{}{
+		"customer_name": "petest",
+		"environment":   "prod",
+		"postgresql_config": map[string]interface{}{
+			"enabled":              true,
+			"geo_redundant_backup": true,
+		},
+		"redis_config": map[string]interface{}{
+			"enabled": true,
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify private endpoints are planned
-	assert.Contains(t, planOutput, "azurerm_private_endpoint")
+	plan.AssertCreated(t, "azurerm_private_endpoint.redis").
+		HasAttribute("private_service_connection.0.subresource_names", []string{"redisCache"})
+	plan.AssertCreated(t, "azurerm_postgresql_flexible_server.main").
+		HasAttribute("delegated_subnet_id", helpers.SubnetID("snet-pe"))
 }
 
-// TestDatabasesModuleEnvironments tests different environment configurations
+// TestDatabasesModuleEnvironments tests what the module derives from the
+// environment. Geo-redundant backup, high availability and a Premium Redis
+// are requested everywhere; the module grants them in prod only.
 func TestDatabasesModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/databases",
-				Vars: map[string]interface{}{
-					"customer_name":       "dbenv",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-db-" + env,
-					"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"private_dns_zone_ids": map[string]interface{}{
-						"postgres": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/postgres",
-					},
-					"enable_postgresql": true,
-					"enable_redis":      false,
+			terraformOptions := helpers.DatabasesOptions(t, env.Vars(helpers.ModuleDatabases), map[string]interface{}{
+				"customer_name":       "dbenv",
+				"resource_group_name": "rg-test-db-" + env.Name,
+				"postgresql_config": map[string]interface{}{
+					"enabled":              true,
+					"geo_redundant_backup": true,
+					"high_availability":    true,
+				},
+				"redis_config": map[string]interface{}{
+					"enabled":  true,
+					"sku_name": "Premium",
+					"family":   "P",
+					"capacity": 1,
 				},
-				NoColor: true,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			prod := env == helpers.EnvironmentProd
+			server := plan.AssertCreated(t, "azurerm_postgresql_flexible_server.main").
+				HasAttribute("name", "psql-dbenv-"+env.Name).
+				HasAttribute("geo_redundant_backup_enabled", prod)
+			if prod {
+				server.HasAttribute("high_availability.0.mode", "ZoneRedundant")
+			} else {
+				server.HasAttribute("high_availability", []string{})
+			}
+
+			var zones interface{}
+			if prod {
+				zones = []string{"1", "2", "3"}
+			}
+			plan.AssertCreated(t, "azurerm_redis_cache.main").
+				HasAttribute("zones", zones)
 		})
 	}
 }
@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - DEFENDER MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform Microsoft Defender module.
 //
-// Run with: go test -v -run TestDefender ./modules/
+// Run with: go test -v -tags=unit -run TestDefender ./modules/
 //
 // =============================================================================
 
@@ -13,65 +15,54 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestDefenderModuleBasic tests basic Defender configuration
 func TestDefenderModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/defender",
-		Vars: map[string]interface{}{
-			"subscription_id":            "00000000-0000-0000-0000-000000000000",
-			"customer_name":              "testdef",
-			"environment":                "dev",
-			"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-			"security_contact_email":     "security@example.com",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.DefenderOptions(t, map[string]interface{}{
+		"customer_name": "testdef",
 	})
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify Defender for Cloud is planned
-	assert.Contains(t, planOutput, "azurerm_security_center")
+	plan.AssertCreated(t, "azurerm_security_center_contact.main").
+		HasAttribute("email", "security@example.com")
+	plan.AssertCreated(t, "azurerm_security_center_subscription_pricing.containers").
+		HasAttribute("tier", "Standard")
 }
 
 // TestDefenderModuleSizingProfiles tests different sizing profiles
 func TestDefenderModuleSizingProfiles(t *testing.T) {
 	t.Parallel()
 
-	profiles := []string{"small", "medium", "large", "xlarge"}
+	profiles, err := helpers.LoadSizingProfiles()
+	require.NoError(t, err)
 
-	for _, profile := range profiles {
+	for _, profile := range profiles.Names() {
 		profile := profile
 		t.Run(profile, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/defender",
-				Vars: map[string]interface{}{
-					"subscription_id":            "00000000-0000-0000-0000-000000000000",
-					"customer_name":              "sizetest",
-					"environment":                "prod",
-					"sizing_profile":             profile,
-					"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-					"security_contact_email":     "security@example.com",
-				},
-				NoColor: true,
+			terraformOptions := helpers.DefenderOptions(t, map[string]interface{}{
+				"customer_name":  "sizetest",
+				"environment":    "prod",
+				"sizing_profile": profile,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			// Only the small profile stays on the free CSPM tier
+			cspmTier := "Standard"
+			if profile == "small" {
+				cspmTier = "Free"
+			}
+			plan.AssertCreated(t, "azurerm_security_center_subscription_pricing.cspm").
+				HasAttribute("tier", cspmTier)
 		})
 	}
 }
@@ -80,51 +71,37 @@ func TestDefenderModuleSizingProfiles(t *testing.T) {
 func TestDefenderModuleComplianceStandards(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/defender",
-		Vars: map[string]interface{}{
-			"subscription_id":                  "00000000-0000-0000-0000-000000000000",
-			"customer_name":                    "comptest",
-			"environment":                      "prod",
-			"log_analytics_workspace_id":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-			"security_contact_email":           "security@example.com",
-			"regulatory_compliance_standards":  []string{"Azure-CIS-1.4.0", "SOC-2", "ISO-27001"},
-		},
-		NoColor: true,
+	terraformOptions := helpers.DefenderOptions(t, map[string]interface{}{
+		"customer_name":                   "comptest",
+		"environment":                     "prod",
+		"regulatory_compliance_standards": []string{"Azure-CIS-1.4.0", "SOC-2", "ISO-27001"},
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify compliance standards are configured
-	assert.Contains(t, planOutput, "security")
+	plan.AssertResourceCount(t, "azapi_resource.regulatory_compliance", 3)
+	plan.AssertCreated(t, `azapi_resource.regulatory_compliance["SOC-2"]`).
+		HasAttribute("type", "Microsoft.Security/regulatoryComplianceStandards@2019-01-01-preview")
 }
 
 // TestDefenderModuleAKSIntegration tests Defender for Containers with AKS
 func TestDefenderModuleAKSIntegration(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/defender",
-		Vars: map[string]interface{}{
-			"subscription_id":            "00000000-0000-0000-0000-000000000000",
-			"customer_name":              "aksdeftest",
-			"environment":                "prod",
-			"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-			"security_contact_email":     "security@example.com",
-			"aks_cluster_ids": []string{
-				"/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks1",
-				"/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks2",
-			},
+	terraformOptions := helpers.DefenderOptions(t, map[string]interface{}{
+		"customer_name": "aksdeftest",
+		"environment":   "prod",
+		"aks_cluster_ids": []string{
+			"/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks1",
+			"/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks2",
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify AKS integration is planned
-	assert.Contains(t, planOutput, "defender")
+	plan.AssertResourceCount(t, "azapi_update_resource.defender_for_aks", 2)
 }
 
 // TestDefenderModuleJITAccess tests Just-In-Time access configuration
@@ -132,11 +109,14 @@ func TestDefenderModuleJITAccess(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
-		name       string
-		jitEnabled bool
+		name          string
+		sizingProfile string
+		jitEnabled    bool
+		expectJIT     bool
 	}{
-		{"jit_enabled", true},
-		{"jit_disabled", false},
+		{"jit_enabled", "xlarge", true, true},
+		{"jit_disabled", "xlarge", false, false},
+		{"jit_below_xlarge", "large", true, false},
 	}
 
 	for _, tc := range testCases {
@@ -144,21 +124,22 @@ func TestDefenderModuleJITAccess(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/defender",
-				Vars: map[string]interface{}{
-					"subscription_id":            "00000000-0000-0000-0000-000000000000",
-					"customer_name":              "jittest",
-					"environment":                "prod",
-					"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-					"security_contact_email":     "security@example.com",
-					"enable_jit_access":          tc.jitEnabled,
-				},
-				NoColor: true,
+			terraformOptions := helpers.DefenderOptions(t, map[string]interface{}{
+				"customer_name":     "jittest",
+				"environment":       "prod",
+				"sizing_profile":    tc.sizingProfile,
+				"enable_jit_access": tc.jitEnabled,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			// JIT network access is only enabled for the xlarge profile
+			if tc.expectJIT {
+				plan.AssertCreated(t, "azapi_resource.jit_policy").
+					HasAttribute("type", "Microsoft.Security/locations/jitNetworkAccessPolicies@2020-01-01")
+			} else {
+				plan.AssertAbsent(t, "azapi_resource.jit_policy")
+			}
 		})
 	}
 }
@@ -167,57 +148,40 @@ func TestDefenderModuleJITAccess(t *testing.T) {
 func TestDefenderModuleAutoProvisioning(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/defender",
-		Vars: map[string]interface{}{
-			"subscription_id":            "00000000-0000-0000-0000-000000000000",
-			"customer_name":              "autoprovtest",
-			"environment":                "prod",
-			"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-			"security_contact_email":     "security@example.com",
-			"auto_provisioning_settings": map[string]interface{}{
-				"log_analytics_agent":      true,
-				"vulnerability_assessment": true,
-				"defender_for_containers":  true,
-			},
+	terraformOptions := helpers.DefenderOptions(t, map[string]interface{}{
+		"customer_name": "autoprovtest",
+		"environment":   "prod",
+		"auto_provisioning_settings": map[string]interface{}{
+			"log_analytics_agent":      true,
+			"vulnerability_assessment": true,
+			"defender_for_containers":  true,
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify auto-provisioning is configured
-	assert.Contains(t, planOutput, "security")
+	plan.AssertCreated(t, "azurerm_security_center_auto_provisioning.log_analytics").
+		HasAttribute("auto_provision", "On")
 }
 
 // TestDefenderModuleEnvironments tests different environments
 func TestDefenderModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/defender",
-				Vars: map[string]interface{}{
-					"subscription_id":            "00000000-0000-0000-0000-000000000000",
-					"customer_name":              "envtest",
-					"environment":                env,
-					"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-					"security_contact_email":     "security@example.com",
-				},
-				NoColor: true,
+			terraformOptions := helpers.DefenderOptions(t, env.Vars(helpers.ModuleDefender), map[string]interface{}{
+				"customer_name": "envtest",
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, env)
+			plan.AssertCreated(t, "azurerm_security_center_automation.export_to_log_analytics").
+				HasAttribute("resource_group_name", "rg-envtest-"+env.Value(helpers.ModuleDefender)+"-security")
 		})
 	}
 }
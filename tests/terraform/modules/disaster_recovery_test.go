@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - DISASTER RECOVERY MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform Disaster Recovery module.
 //
-// Run with: go test -v -run TestDisasterRecovery ./modules/
+// Run with: go test -v -tags=unit -run TestDisasterRecovery ./modules/
 //
 // =============================================================================
 
@@ -13,37 +15,24 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestDisasterRecoveryModuleBasic tests basic DR configuration
 func TestDisasterRecoveryModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/disaster-recovery",
-		Vars: map[string]interface{}{
-			"customer_name":               "testdr",
-			"environment":                 "dev",
-			"primary_location":            "brazilsouth",
-			"primary_region_short":        "brz",
-			"primary_resource_group_name": "rg-test-dr-primary",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.DisasterRecoveryOptions(t, map[string]interface{}{
+		"customer_name": "testdr",
 	})
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify Recovery Services Vault is planned
-	assert.Contains(t, planOutput, "azurerm_recovery_services_vault")
+	plan.AssertCreated(t, "azurerm_recovery_services_vault.main").
+		HasAttribute("name", "rsv-testdr-"+helpers.TestEnvironment+"-brs").
+		HasAttribute("soft_delete_enabled", true)
+	plan.AssertAbsent(t, "azurerm_site_recovery_fabric")
 }
 
 // TestDisasterRecoveryModuleRPORTO tests RPO/RTO configuration
@@ -65,22 +54,18 @@ func TestDisasterRecoveryModuleRPORTO(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/disaster-recovery",
-				Vars: map[string]interface{}{
-					"customer_name":               "rpotest",
-					"environment":                 "prod",
-					"primary_location":            "brazilsouth",
-					"primary_region_short":        "brz",
-					"primary_resource_group_name": "rg-test-dr-rpo",
-					"recovery_point_objective":    tc.rpo,
-					"recovery_time_objective":     tc.rto,
-				},
-				NoColor: true,
+			terraformOptions := helpers.DisasterRecoveryOptions(t, map[string]interface{}{
+				"customer_name":            "rpotest",
+				"environment":              "prod",
+				"recovery_point_objective": tc.rpo,
+				"recovery_time_objective":  tc.rto,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			plan.AssertCreated(t, "azurerm_recovery_services_vault.main").
+				HasAttribute("tags.disaster-recovery/rpo", tc.rpo).
+				HasAttribute("tags.disaster-recovery/rto", tc.rto)
 		})
 	}
 }
@@ -89,28 +74,25 @@ func TestDisasterRecoveryModuleRPORTO(t *testing.T) {
 func TestDisasterRecoveryModuleRetention(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/disaster-recovery",
-		Vars: map[string]interface{}{
-			"customer_name":               "rettest",
-			"environment":                 "prod",
-			"primary_location":            "brazilsouth",
-			"primary_region_short":        "brz",
-			"primary_resource_group_name": "rg-test-dr-retention",
-			"retention_daily_count":       14,
-			"retention_weekly_count":      8,
-			"retention_monthly_count":     24,
-			"retention_yearly_count":      5,
-			"instant_restore_days":        3,
-		},
-		NoColor: true,
+	terraformOptions := helpers.DisasterRecoveryOptions(t, map[string]interface{}{
+		"customer_name":           "rettest",
+		"environment":             "prod",
+		"retention_daily_count":   14,
+		"retention_weekly_count":  8,
+		"retention_monthly_count": 24,
+		"retention_yearly_count":  5,
+		"instant_restore_days":    3,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify backup policy is planned
-	assert.Contains(t, planOutput, "azurerm_backup_policy")
+	plan.AssertCreated(t, "azurerm_backup_policy_vm.daily").
+		HasAttribute("retention_daily.0.count", 14).
+		HasAttribute("retention_weekly.0.count", 8).
+		HasAttribute("retention_monthly.0.count", 24).
+		HasAttribute("retention_yearly.0.count", 5).
+		HasAttribute("instant_restore_retention_days", 3)
 }
 
 // TestDisasterRecoveryModuleStorageRedundancy tests storage redundancy options
@@ -124,21 +106,16 @@ func TestDisasterRecoveryModuleStorageRedundancy(t *testing.T) {
 		t.Run(redundancy, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/disaster-recovery",
-				Vars: map[string]interface{}{
-					"customer_name":               "redtest",
-					"environment":                 "prod",
-					"primary_location":            "brazilsouth",
-					"primary_region_short":        "brz",
-					"primary_resource_group_name": "rg-test-dr-redundancy",
-					"storage_redundancy":          redundancy,
-				},
-				NoColor: true,
+			terraformOptions := helpers.DisasterRecoveryOptions(t, map[string]interface{}{
+				"customer_name":      "redtest",
+				"environment":        "prod",
+				"storage_redundancy": redundancy,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			plan.AssertCreated(t, "azurerm_recovery_services_vault.main").
+				HasAttribute("storage_mode_type", redundancy)
 		})
 	}
 }
@@ -160,23 +137,27 @@ func TestDisasterRecoveryModuleSiteRecovery(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/disaster-recovery",
-				Vars: map[string]interface{}{
-					"customer_name":               "asrtest",
-					"environment":                 "prod",
-					"primary_location":            "brazilsouth",
-					"primary_region_short":        "brz",
-					"primary_resource_group_name": "rg-test-dr-asr",
-					"enable_site_recovery":        tc.enabled,
-					"dr_location":                 "eastus2",
-					"dr_region_short":             "eu2",
-				},
-				NoColor: true,
+			terraformOptions := helpers.DisasterRecoveryOptions(t, map[string]interface{}{
+				"customer_name":        "asrtest",
+				"environment":          "prod",
+				"enable_site_recovery": tc.enabled,
+				"dr_location":          "eastus2",
+				"dr_region_short":      "eu2",
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			if tc.enabled {
+				plan.AssertResourceCount(t, "azurerm_site_recovery_fabric", 2)
+				plan.AssertCreated(t, "azurerm_site_recovery_fabric.secondary").
+					HasAttribute("name", "fabric-eu2").
+					HasAttribute("location", "eastus2")
+				plan.AssertCreated(t, "azurerm_storage_account.dr_cache").
+					HasAttribute("name", "stasrtestdrcache")
+			} else {
+				plan.AssertAbsent(t, "azurerm_site_recovery_fabric")
+				plan.AssertAbsent(t, "azurerm_storage_account")
+			}
 		})
 	}
 }
@@ -185,27 +166,21 @@ func TestDisasterRecoveryModuleSiteRecovery(t *testing.T) {
 func TestDisasterRecoveryModuleCrossRegion(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/disaster-recovery",
-		Vars: map[string]interface{}{
-			"customer_name":               "crosstest",
-			"environment":                 "prod",
-			"primary_location":            "brazilsouth",
-			"primary_region_short":        "brz",
-			"primary_resource_group_name": "rg-test-dr-cross",
-			"dr_location":                 "eastus2",
-			"dr_region_short":             "eu2",
-			"enable_cross_region_restore": true,
-			"storage_redundancy":          "GeoRedundant",
-		},
-		NoColor: true,
+	terraformOptions := helpers.DisasterRecoveryOptions(t, map[string]interface{}{
+		"customer_name":               "crosstest",
+		"environment":                 "prod",
+		"dr_location":                 "eastus2",
+		"dr_region_short":             "eu2",
+		"enable_cross_region_restore": true,
+		"storage_redundancy":          "GeoRedundant",
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify cross-region configuration
-	assert.Contains(t, planOutput, "recovery")
+	plan.AssertCreated(t, "azurerm_recovery_services_vault.main").
+		HasAttribute("cross_region_restore_enabled", true).
+		HasAttribute("storage_mode_type", "GeoRedundant")
 }
 
 // TestDisasterRecoveryModuleImmutability tests immutability configuration
@@ -225,21 +200,20 @@ func TestDisasterRecoveryModuleImmutability(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/disaster-recovery",
-				Vars: map[string]interface{}{
-					"customer_name":               "immtest",
-					"environment":                 "prod",
-					"primary_location":            "brazilsouth",
-					"primary_region_short":        "brz",
-					"primary_resource_group_name": "rg-test-dr-immutable",
-					"enable_immutability":         tc.enabled,
-				},
-				NoColor: true,
+			terraformOptions := helpers.DisasterRecoveryOptions(t, map[string]interface{}{
+				"customer_name":       "immtest",
+				"environment":         "prod",
+				"enable_immutability": tc.enabled,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			vault := plan.AssertCreated(t, "azurerm_recovery_services_vault.main")
+			if tc.enabled {
+				vault.HasAttribute("immutability.0.state", "Unlocked")
+			} else {
+				vault.HasAttribute("immutability", []string{})
+			}
 		})
 	}
 }
@@ -248,29 +222,20 @@ func TestDisasterRecoveryModuleImmutability(t *testing.T) {
 func TestDisasterRecoveryModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/disaster-recovery",
-				Vars: map[string]interface{}{
-					"customer_name":               "envtest",
-					"environment":                 env,
-					"primary_location":            "brazilsouth",
-					"primary_region_short":        "brz",
-					"primary_resource_group_name": "rg-test-dr-" + env,
-				},
-				NoColor: true,
+			terraformOptions := helpers.DisasterRecoveryOptions(t, env.Vars(helpers.ModuleDisasterRecovery), map[string]interface{}{
+				"customer_name":               "envtest",
+				"primary_resource_group_name": "rg-test-dr-" + env.Name,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, env)
+			plan.AssertCreated(t, "azurerm_recovery_services_vault.main").
+				HasAttribute("name", "rsv-envtest-"+env.Value(helpers.ModuleDisasterRecovery)+"-brs")
 		})
 	}
 }
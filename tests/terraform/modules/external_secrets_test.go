@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - EXTERNAL SECRETS MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform External Secrets Operator module.
 //
-// Run with: go test -v -run TestExternalSecrets ./modules/
+// Run with: go test -v -tags=unit -run TestExternalSecrets ./modules/
 //
 // =============================================================================
 
@@ -13,39 +15,26 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestExternalSecretsModuleBasic tests basic ESO configuration
 func TestExternalSecretsModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/external-secrets",
-		Vars: map[string]interface{}{
-			"customer_name":       "testeso",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-eso",
-			"aks_cluster_name":    "aks-test-eso",
-			"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"key_vault_uri":       "https://kv-test.vault.azure.net/",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.ExternalSecretsOptions(t, map[string]interface{}{
+		"customer_name": "testeso",
 	})
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify ESO Helm release is planned
-	assert.Contains(t, planOutput, "helm_release")
+	plan.AssertCreated(t, "azurerm_user_assigned_identity.eso").
+		HasAttribute("name", "id-testeso-"+helpers.TestEnvironment+"-eso")
+	plan.AssertCreated(t, "helm_release.external_secrets").
+		HasAttribute("chart", "external-secrets")
+	plan.AssertCreated(t, "kubernetes_manifest.cluster_secret_store").
+		HasAttribute("manifest.metadata.name", "testeso-"+helpers.TestEnvironment+"-secret-store")
 }
 
 // TestExternalSecretsModuleRBAC tests RBAC vs Access Policy configuration
@@ -53,8 +42,8 @@ func TestExternalSecretsModuleRBAC(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
-		name     string
-		useRBAC  bool
+		name    string
+		useRBAC bool
 	}{
 		{"rbac_enabled", true},
 		{"access_policy", false},
@@ -65,23 +54,20 @@ func TestExternalSecretsModuleRBAC(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/external-secrets",
-				Vars: map[string]interface{}{
-					"customer_name":       "rbactest",
-					"environment":         "prod",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-eso-rbac",
-					"aks_cluster_name":    "aks-test-eso-rbac",
-					"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"key_vault_uri":       "https://kv-test.vault.azure.net/",
-					"use_key_vault_rbac":  tc.useRBAC,
-				},
-				NoColor: true,
+			terraformOptions := helpers.ExternalSecretsOptions(t, map[string]interface{}{
+				"customer_name":      "rbactest",
+				"environment":        "prod",
+				"use_key_vault_rbac": tc.useRBAC,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			if tc.useRBAC {
+				plan.AssertCreated(t, "azurerm_role_assignment.eso_secrets_user").
+					HasAttribute("role_definition_name", "Key Vault Secrets User")
+			} else {
+				plan.AssertAbsent(t, "azurerm_role_assignment")
+			}
 		})
 	}
 }
@@ -103,23 +89,18 @@ func TestExternalSecretsModuleMetrics(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/external-secrets",
-				Vars: map[string]interface{}{
-					"customer_name":              "metricstest",
-					"environment":                "prod",
-					"location":                   "brazilsouth",
-					"resource_group_name":        "rg-test-eso-metrics",
-					"aks_cluster_name":           "aks-test-eso-metrics",
-					"key_vault_id":               "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"key_vault_uri":              "https://kv-test.vault.azure.net/",
-					"enable_prometheus_metrics":  tc.enabled,
-				},
-				NoColor: true,
+			terraformOptions := helpers.ExternalSecretsOptions(t, map[string]interface{}{
+				"customer_name":             "metricstest",
+				"environment":               "prod",
+				"enable_prometheus_metrics": tc.enabled,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			// The values embed the identity client ID, so they are only
+			// known after apply
+			plan.AssertCreated(t, "helm_release.external_secrets").
+				HasAttributeKnownAfterApply("values.0")
 		})
 	}
 }
@@ -141,23 +122,20 @@ func TestExternalSecretsModulePushSecrets(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/external-secrets",
-				Vars: map[string]interface{}{
-					"customer_name":        "pushtest",
-					"environment":          "prod",
-					"location":             "brazilsouth",
-					"resource_group_name":  "rg-test-eso-push",
-					"aks_cluster_name":     "aks-test-eso-push",
-					"key_vault_id":         "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"key_vault_uri":        "https://kv-test.vault.azure.net/",
-					"enable_push_secrets":  tc.enabled,
-				},
-				NoColor: true,
+			terraformOptions := helpers.ExternalSecretsOptions(t, map[string]interface{}{
+				"customer_name":       "pushtest",
+				"environment":         "prod",
+				"enable_push_secrets": tc.enabled,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			if tc.enabled {
+				plan.AssertCreated(t, "kubernetes_manifest.push_secret_store").
+					HasAttribute("manifest.kind", "PushSecret")
+			} else {
+				plan.AssertAbsent(t, "kubernetes_manifest.push_secret_store")
+			}
 		})
 	}
 }
@@ -166,93 +144,66 @@ func TestExternalSecretsModulePushSecrets(t *testing.T) {
 func TestExternalSecretsModuleExampleSecret(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/external-secrets",
-		Vars: map[string]interface{}{
-			"customer_name":         "exampletest",
-			"environment":           "dev",
-			"location":              "brazilsouth",
-			"resource_group_name":   "rg-test-eso-example",
-			"aks_cluster_name":      "aks-test-eso-example",
-			"key_vault_id":          "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"key_vault_uri":         "https://kv-test.vault.azure.net/",
-			"create_example_secret": true,
-		},
-		NoColor: true,
+	terraformOptions := helpers.ExternalSecretsOptions(t, map[string]interface{}{
+		"customer_name":         "exampletest",
+		"create_example_secret": true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify example secret is planned
-	assert.Contains(t, planOutput, "external")
+	plan.AssertCreated(t, "kubernetes_manifest.example_external_secret").
+		HasAttribute("manifest.kind", "ExternalSecret").
+		HasAttribute("manifest.spec.secretStoreRef.name", "exampletest-"+helpers.TestEnvironment+"-secret-store")
 }
 
 // TestExternalSecretsModuleNodeSelector tests node selector configuration
 func TestExternalSecretsModuleNodeSelector(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/external-secrets",
-		Vars: map[string]interface{}{
-			"customer_name":       "nodetest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-eso-node",
-			"aks_cluster_name":    "aks-test-eso-node",
-			"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"key_vault_uri":       "https://kv-test.vault.azure.net/",
-			"node_selector": map[string]interface{}{
-				"workload-type": "platform",
-			},
-			"tolerations": []map[string]interface{}{
-				{
-					"key":      "platform",
-					"operator": "Equal",
-					"value":    "true",
-					"effect":   "NoSchedule",
-				},
+	terraformOptions := helpers.ExternalSecretsOptions(t, map[string]interface{}{
+		"customer_name": "nodetest",
+		"environment":   "prod",
+		"node_selector": map[string]interface{}{
+			"workload-type": "platform",
+		},
+		"tolerations": []map[string]interface{}{
+			{
+				"key":      "platform",
+				"operator": "Equal",
+				"value":    "true",
+				"effect":   "NoSchedule",
 			},
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
-	// Verify node configuration
-	assert.Contains(t, planOutput, "external")
+	// Verify node configuration; the values embed the identity client ID,
+	// so they are only known after apply
+	plan.AssertCreated(t, "helm_release.external_secrets").
+		HasAttributeKnownAfterApply("values.0")
 }
 
 // TestExternalSecretsModuleEnvironments tests different environments
 func TestExternalSecretsModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/external-secrets",
-				Vars: map[string]interface{}{
-					"customer_name":       "envtest",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-eso-" + env,
-					"aks_cluster_name":    "aks-test-eso-" + env,
-					"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"key_vault_uri":       "https://kv-test.vault.azure.net/",
-				},
-				NoColor: true,
+			terraformOptions := helpers.ExternalSecretsOptions(t, env.Vars(helpers.ModuleExternalSecrets), map[string]interface{}{
+				"customer_name":       "envtest",
+				"resource_group_name": "rg-test-eso-" + env.Name,
+				"aks_cluster_name":    "aks-test-eso-" + env.Name,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, env)
+			plan.AssertCreated(t, "azurerm_user_assigned_identity.eso").
+				HasAttribute("name", "id-envtest-"+env.Value(helpers.ModuleExternalSecrets)+"-eso")
 		})
 	}
 }
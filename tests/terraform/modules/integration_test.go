@@ -1,10 +1,13 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - INTEGRATION TESTS
 // =============================================================================
 //
-// Cross-module integration tests to validate module interactions.
+// Cross-module integration tests to validate module interactions. They only
+// validate and plan, so they run in the unit tier.
 //
-// Run with: go test -v -run TestIntegration ./modules/
+// Run with: go test -v -tags=unit -run TestIntegration ./modules/
 //
 // =============================================================================
 
@@ -14,7 +17,8 @@ import (
 	"testing"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestIntegrationH1Foundation tests H1 Foundation tier modules together
@@ -25,20 +29,12 @@ func TestIntegrationH1Foundation(t *testing.T) {
 	t.Run("networking", func(t *testing.T) {
 		t.Parallel()
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-			TerraformDir: "../../../terraform/modules/networking",
-			Vars: map[string]interface{}{
-				"customer_name":       "inttest",
-				"environment":         "dev",
-				"location":            "brazilsouth",
-				"resource_group_name": "rg-int-test-net",
-				"address_space":       []string{"10.0.0.0/16"},
-				"tags": map[string]interface{}{
-					"Environment": "test",
-					"Horizon":     "H1",
-				},
+		terraformOptions := helpers.NetworkingOptions(t, map[string]interface{}{
+			"customer_name": "inttest",
+			"vnet_cidr":     "10.0.0.0/16",
+			"tags": map[string]interface{}{
+				"Horizon": "H1",
 			},
-			NoColor: true,
 		})
 
 		terraform.Init(t, terraformOptions)
@@ -49,21 +45,12 @@ func TestIntegrationH1Foundation(t *testing.T) {
 	t.Run("aks-cluster", func(t *testing.T) {
 		t.Parallel()
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-			TerraformDir: "../../../terraform/modules/aks-cluster",
-			Vars: map[string]interface{}{
-				"customer_name":       "inttest",
-				"environment":         "dev",
-				"location":            "brazilsouth",
-				"resource_group_name": "rg-int-test-aks",
-				"kubernetes_version":  "1.29",
-				"aks_subnet_id":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-				"tags": map[string]interface{}{
-					"Environment": "test",
-					"Horizon":     "H1",
-				},
+		terraformOptions := helpers.AKSOptions(t, map[string]interface{}{
+			"customer_name":      "inttest",
+			"kubernetes_version": "1.29",
+			"tags": map[string]interface{}{
+				"Horizon": "H1",
 			},
-			NoColor: true,
 		})
 
 		terraform.Init(t, terraformOptions)
@@ -79,19 +66,11 @@ func TestIntegrationH2Enhancement(t *testing.T) {
 	t.Run("observability", func(t *testing.T) {
 		t.Parallel()
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-			TerraformDir: "../../../terraform/modules/observability",
-			Vars: map[string]interface{}{
-				"customer_name":       "inttest",
-				"environment":         "dev",
-				"location":            "brazilsouth",
-				"resource_group_name": "rg-int-test-obs",
-				"tags": map[string]interface{}{
-					"Environment": "test",
-					"Horizon":     "H2",
-				},
+		terraformOptions := helpers.ObservabilityOptions(t, map[string]interface{}{
+			"customer_name": "inttest",
+			"tags": map[string]interface{}{
+				"Horizon": "H2",
 			},
-			NoColor: true,
 		})
 
 		terraform.Init(t, terraformOptions)
@@ -102,20 +81,11 @@ func TestIntegrationH2Enhancement(t *testing.T) {
 	t.Run("databases", func(t *testing.T) {
 		t.Parallel()
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-			TerraformDir: "../../../terraform/modules/databases",
-			Vars: map[string]interface{}{
-				"customer_name":       "inttest",
-				"environment":         "dev",
-				"location":            "brazilsouth",
-				"resource_group_name": "rg-int-test-db",
-				"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-				"tags": map[string]interface{}{
-					"Environment": "test",
-					"Horizon":     "H2",
-				},
+		terraformOptions := helpers.DatabasesOptions(t, map[string]interface{}{
+			"customer_name": "inttest",
+			"tags": map[string]interface{}{
+				"Horizon": "H2",
 			},
-			NoColor: true,
 		})
 
 		terraform.Init(t, terraformOptions)
@@ -131,35 +101,20 @@ func TestIntegrationH3Innovation(t *testing.T) {
 	t.Run("ai-foundry", func(t *testing.T) {
 		t.Parallel()
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-			TerraformDir: "../../../terraform/modules/ai-foundry",
-			Vars: map[string]interface{}{
-				"customer_name":       "inttest",
-				"environment":         "dev",
-				"location":            "eastus",
-				"resource_group_name": "rg-int-test-ai",
-				"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-				"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-				"private_dns_zone_ids": map[string]interface{}{
-					"openai":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/openai",
-					"cognitiveservices": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/cognitive",
-					"search":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/search",
-				},
-				"openai_config": map[string]interface{}{
-					"enabled": false,
-				},
-				"ai_search_config": map[string]interface{}{
-					"enabled": false,
-				},
-				"content_safety_config": map[string]interface{}{
-					"enabled": false,
-				},
-				"tags": map[string]interface{}{
-					"Environment": "test",
-					"Horizon":     "H3",
-				},
+		terraformOptions := helpers.AIFoundryOptions(t, map[string]interface{}{
+			"customer_name": "inttest",
+			"openai_config": map[string]interface{}{
+				"enabled": false,
+			},
+			"ai_search_config": map[string]interface{}{
+				"enabled": false,
+			},
+			"content_safety_config": map[string]interface{}{
+				"enabled": false,
+			},
+			"tags": map[string]interface{}{
+				"Horizon": "H3",
 			},
-			NoColor: true,
 		})
 
 		terraform.Init(t, terraformOptions)
@@ -175,20 +130,9 @@ func TestIntegrationSecurityStack(t *testing.T) {
 	t.Run("security", func(t *testing.T) {
 		t.Parallel()
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-			TerraformDir: "../../../terraform/modules/security",
-			Vars: map[string]interface{}{
-				"customer_name":       "inttest",
-				"environment":         "dev",
-				"location":            "brazilsouth",
-				"resource_group_name": "rg-int-test-sec",
-				"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-				"tenant_id":           "00000000-0000-0000-0000-000000000000",
-				"tags": map[string]interface{}{
-					"Environment": "test",
-				},
-			},
-			NoColor: true,
+		terraformOptions := helpers.SecurityOptions(t, map[string]interface{}{
+			"customer_name": "inttest",
+			"tags":          map[string]interface{}{},
 		})
 
 		terraform.Init(t, terraformOptions)
@@ -199,19 +143,9 @@ func TestIntegrationSecurityStack(t *testing.T) {
 	t.Run("defender", func(t *testing.T) {
 		t.Parallel()
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-			TerraformDir: "../../../terraform/modules/defender",
-			Vars: map[string]interface{}{
-				"subscription_id":            "00000000-0000-0000-0000-000000000000",
-				"customer_name":              "inttest",
-				"environment":                "dev",
-				"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-				"security_contact_email":     "security@example.com",
-				"tags": map[string]interface{}{
-					"Environment": "test",
-				},
-			},
-			NoColor: true,
+		terraformOptions := helpers.DefenderOptions(t, map[string]interface{}{
+			"customer_name": "inttest",
+			"tags":          map[string]interface{}{},
 		})
 
 		terraform.Init(t, terraformOptions)
@@ -227,23 +161,12 @@ func TestIntegrationGitOpsStack(t *testing.T) {
 	t.Run("argocd", func(t *testing.T) {
 		t.Parallel()
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-			TerraformDir: "../../../terraform/modules/argocd",
-			Vars: map[string]interface{}{
-				"customer_name": "inttest",
-				"environment":   "dev",
-				"github_org":    "test-org",
-				"github_repo":   "test-repo",
-				"argocd_config": map[string]interface{}{
-					"namespace":   "argocd",
-					"ha_enabled":  false,
-					"server_host": "argocd.test.example.com",
-				},
-				"tags": map[string]interface{}{
-					"Environment": "test",
-				},
-			},
-			NoColor: true,
+		terraformOptions := helpers.ArgoCDOptions(t, map[string]interface{}{
+			"customer_name": "inttest",
+			"namespace":     "argocd",
+			"ha_enabled":    false,
+			"domain_name":   "test.example.com",
+			"tags":          map[string]interface{}{},
 		})
 
 		terraform.Init(t, terraformOptions)
@@ -254,21 +177,9 @@ func TestIntegrationGitOpsStack(t *testing.T) {
 	t.Run("external-secrets", func(t *testing.T) {
 		t.Parallel()
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-			TerraformDir: "../../../terraform/modules/external-secrets",
-			Vars: map[string]interface{}{
-				"customer_name":       "inttest",
-				"environment":         "dev",
-				"location":            "brazilsouth",
-				"resource_group_name": "rg-int-test-eso",
-				"aks_cluster_name":    "aks-int-test",
-				"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-				"key_vault_uri":       "https://kv-test.vault.azure.net/",
-				"tags": map[string]interface{}{
-					"Environment": "test",
-				},
-			},
-			NoColor: true,
+		terraformOptions := helpers.ExternalSecretsOptions(t, map[string]interface{}{
+			"customer_name": "inttest",
+			"tags":          map[string]interface{}{},
 		})
 
 		terraform.Init(t, terraformOptions)
@@ -280,62 +191,46 @@ func TestIntegrationGitOpsStack(t *testing.T) {
 func TestIntegrationEnvironmentParity(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-	modules := []string{"networking", "security", "observability"}
+	modules := []string{helpers.ModuleNetworking, helpers.ModuleSecurity, helpers.ModuleObservability}
+
+	// The main resource of each module, tagged with its environment
+	tagged := map[string]string{
+		helpers.ModuleNetworking:    "azurerm_virtual_network.main",
+		helpers.ModuleSecurity:      "azurerm_key_vault.main",
+		helpers.ModuleObservability: "azurerm_monitor_workspace.prometheus",
+	}
 
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
 		for _, module := range modules {
 			module := module
-			t.Run(env+"_"+module, func(t *testing.T) {
+			t.Run(env.Name+"_"+module, func(t *testing.T) {
 				t.Parallel()
 
 				var terraformOptions *terraform.Options
 
 				switch module {
-				case "networking":
-					terraformOptions = terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-						TerraformDir: "../../../terraform/modules/networking",
-						Vars: map[string]interface{}{
-							"customer_name":       "paritytest",
-							"environment":         env,
-							"location":            "brazilsouth",
-							"resource_group_name": "rg-parity-" + env + "-net",
-							"address_space":       []string{"10.0.0.0/16"},
-						},
-						NoColor: true,
+				case helpers.ModuleNetworking:
+					terraformOptions = helpers.NetworkingOptions(t, env.Vars(module), map[string]interface{}{
+						"customer_name":       "paritytest",
+						"resource_group_name": "rg-parity-" + env.Name + "-net",
+						"vnet_cidr":           "10.0.0.0/16",
 					})
-				case "security":
-					terraformOptions = terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-						TerraformDir: "../../../terraform/modules/security",
-						Vars: map[string]interface{}{
-							"customer_name":       "paritytest",
-							"environment":         env,
-							"location":            "brazilsouth",
-							"resource_group_name": "rg-parity-" + env + "-sec",
-							"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-							"tenant_id":           "00000000-0000-0000-0000-000000000000",
-						},
-						NoColor: true,
+				case helpers.ModuleSecurity:
+					terraformOptions = helpers.SecurityOptions(t, env.Vars(module), map[string]interface{}{
+						"customer_name":       "paritytest",
+						"resource_group_name": "rg-parity-" + env.Name + "-sec",
 					})
-				case "observability":
-					terraformOptions = terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-						TerraformDir: "../../../terraform/modules/observability",
-						Vars: map[string]interface{}{
-							"customer_name":       "paritytest",
-							"environment":         env,
-							"location":            "brazilsouth",
-							"resource_group_name": "rg-parity-" + env + "-obs",
-						},
-						NoColor: true,
+				case helpers.ModuleObservability:
+					terraformOptions = helpers.ObservabilityOptions(t, env.Vars(module), map[string]interface{}{
+						"customer_name":       "paritytest",
+						"resource_group_name": "rg-parity-" + env.Name + "-obs",
 					})
 				}
 
-				terraform.Init(t, terraformOptions)
-				terraform.Validate(t, terraformOptions)
-
-				planOutput := terraform.Plan(t, terraformOptions)
-				assert.Contains(t, planOutput, env)
+				plan := helpers.InitAndPlan(t, terraformOptions)
+				plan.AssertCreated(t, tagged[module]).
+					HasAttribute("tags.three-horizons/environment", env.Value(module))
 			})
 		}
 	}
@@ -345,14 +240,8 @@ func TestIntegrationEnvironmentParity(t *testing.T) {
 func TestIntegrationNamingConsistency(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/naming",
-		Vars: map[string]interface{}{
-			"customer_name": "nametest",
-			"environment":   "dev",
-			"location":      "brazilsouth",
-		},
-		NoColor: true,
+	terraformOptions := helpers.NamingOptions(t, map[string]interface{}{
+		"project_name": "nametest",
 	})
 
 	terraform.Init(t, terraformOptions)
@@ -363,27 +252,20 @@ func TestIntegrationNamingConsistency(t *testing.T) {
 func TestIntegrationSizingProfiles(t *testing.T) {
 	t.Parallel()
 
-	profiles := []string{"small", "medium", "large"}
+	// Every profile in config/sizing-profiles.yaml
+	profiles, err := helpers.LoadSizingProfiles()
+	require.NoError(t, err)
 
-	for _, profile := range profiles {
-		profile := profile
-		t.Run(profile, func(t *testing.T) {
+	for _, name := range profiles.Names() {
+		name, profile := name, profiles.Get(name)
+		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			// Test AKS with sizing profile
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"customer_name":       "sizetest",
-					"environment":         "dev",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-size-" + profile,
-					"kubernetes_version":  "1.29",
-					"sizing_profile":      profile,
-					"aks_subnet_id":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-				},
-				NoColor: true,
-			})
+			// Test AKS with the profile's node pools
+			terraformOptions := helpers.AKSOptions(t, map[string]interface{}{
+				"customer_name":       "sizetest",
+				"resource_group_name": "rg-size-" + name,
+			}, profile.AKSVars())
 
 			terraform.Init(t, terraformOptions)
 			terraform.Validate(t, terraformOptions)
@@ -0,0 +1,52 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - MODULE TEST SETUP
+// =============================================================================
+//
+// Suite-wide setup shared by every module test.
+//
+// Set TERRATEST_PROVIDER_MIRROR to run terraform init fully offline from a
+// filesystem mirror (see cmd/mirror-providers). Providers are always cached
+// in TF_PLUGIN_CACHE_DIR and shared between tests.
+//
+// terraform apply and destroy are refused unless the suite is built with
+// -tags=integration and ARM_SUBSCRIPTION_ID, ARM_TENANT_ID and ARM_CLIENT_ID
+// are set. List the tests of each tier with `go run ./cmd/list-tests`.
+//
+// =============================================================================
+
+package modules
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+var update = flag.Bool("update", false, "rewrite golden plan snapshots in testdata/golden")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	helpers.UpdateGolden = *update
+
+	mode, releaseGuard, err := helpers.EnforceTier(integrationTier)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to guard terraform apply/destroy: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Terraform tests: %s\n", mode)
+
+	cleanup, err := helpers.ConfigureProviders()
+	if err != nil {
+		releaseGuard()
+		fmt.Fprintf(os.Stderr, "Failed to configure Terraform providers: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	cleanup()
+	releaseGuard()
+	os.Exit(code)
+}
@@ -0,0 +1,38 @@
+//go:build unit
+
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - MOCK PROVIDER PLAN TESTS
+// =============================================================================
+//
+// Plans every module baseline with mock providers, without Azure credentials.
+//
+// Run with: TERRATEST_MOCK_PROVIDERS=true go test -v -tags=unit -run TestModuleBaselinesPlanWithMockProviders ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// TestModuleBaselinesPlanWithMockProviders tests that every module plans
+// from its baseline with no credentials
+func TestModuleBaselinesPlanWithMockProviders(t *testing.T) {
+	t.Parallel()
+
+	if !helpers.MockProvidersEnabled() {
+		t.Skipf("Set %s=true to plan with mock providers", helpers.MockProvidersEnv)
+	}
+
+	for _, module := range helpers.Modules() {
+		module := module
+		t.Run(module, func(t *testing.T) {
+			t.Parallel()
+
+			helpers.InitAndPlan(t, helpers.OptionsFor(t, module))
+		})
+	}
+}
@@ -0,0 +1,172 @@
+//go:build unit
+
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - NAMING MODULE FUZZ TESTS
+// =============================================================================
+//
+// Property-based tests of the naming module. Every generated input is
+// planned (the module has no resources, so no Azure is needed) and its
+// outputs must:
+//
+//   - be rejected by the variable validation when the input is invalid
+//   - satisfy the Azure naming rules (helpers.AzureNamingRules)
+//   - be the same every time the input is planned
+//   - differ between two deployments of one organization for globally
+//     unique resources such as storage accounts and key vaults
+//
+// The checked-in corpus is in testdata/fuzz/FuzzNamingModule and runs with
+// the unit tests. Add every failing input the fuzzer finds to it.
+//
+// Run with: go test -v -tags=unit -run FuzzNamingModule ./modules/
+// Run with: go test -tags=unit -run '^$' -fuzz FuzzNamingModule -fuzztime 10m ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// The validation blocks of terraform/modules/naming/variables.tf.
+var (
+	namingEnvironments    = []string{"dev", "stg", "prd", "sbx", "tst"}
+	namingProjectPattern  = regexp.MustCompile(`^[a-z][a-z0-9]{1,9}$`)
+	namingOrgCodePattern  = regexp.MustCompile(`^([a-z0-9]{2,4})?$`)
+	namingInstancePattern = regexp.MustCompile(`^[0-9]{3}$`)
+)
+
+// namingInput is one set of naming module variables.
+type namingInput struct {
+	OrgCode     string
+	Project     string
+	Environment string
+	Location    string
+	Instance    string
+}
+
+func (in namingInput) vars() map[string]interface{} {
+	return map[string]interface{}{
+		"org_code":     in.OrgCode,
+		"project_name": in.Project,
+		"environment":  in.Environment,
+		"location":     in.Location,
+		"instance":     in.Instance,
+	}
+}
+
+// valid reports whether the module's variable validation accepts in.
+func (in namingInput) valid() bool {
+	accepted := false
+	for _, environment := range namingEnvironments {
+		accepted = accepted || in.Environment == environment
+	}
+	return accepted &&
+		namingProjectPattern.MatchString(in.Project) &&
+		namingOrgCodePattern.MatchString(in.OrgCode) &&
+		namingInstancePattern.MatchString(in.Instance)
+}
+
+// distinct reports whether the name output gets must differ between in and
+// other. Only storage_account includes the instance; the other names are
+// shared by the instances of a deployment.
+func (in namingInput) distinct(other namingInput, output string) bool {
+	if in.Project != other.Project || in.Environment != other.Environment || in.Location != other.Location {
+		return true
+	}
+	return output == "storage_account" && in.Instance != other.Instance
+}
+
+// FuzzNamingModule tests the naming module invariants on generated inputs
+func FuzzNamingModule(f *testing.F) {
+	// Every region the module has a short code for
+	codes, err := helpers.NamingRegionCodes()
+	require.NoError(f, err)
+
+	options := helpers.NamingOptions(f)
+	terraform.Init(f, options)
+
+	// Fuzz inputs run one at a time, so no lock is needed
+	planned := map[namingInput]map[string]interface{}{}
+
+	f.Fuzz(func(t *testing.T, orgCode, projectA, environmentA, locationA, instanceA, projectB, environmentB, locationB, instanceB string) {
+		for _, value := range []string{orgCode, projectA, environmentA, instanceA, projectB, environmentB, instanceB} {
+			if !utf8.ValidString(value) || strings.ContainsRune(value, 0) {
+				t.Skip("not a command-line argument")
+			}
+		}
+		// location is not validated by the module; Azure rejects unknown
+		// regions
+		for _, location := range []string{locationA, locationB} {
+			if _, ok := codes[location]; !ok {
+				t.Skip("not a region the module has a short code for")
+			}
+		}
+
+		a := namingInput{
+			OrgCode:     orgCode,
+			Project:     projectA,
+			Environment: environmentA,
+			Location:    locationA,
+			Instance:    instanceA,
+		}
+		b := namingInput{
+			OrgCode:     orgCode,
+			Project:     projectB,
+			Environment: environmentB,
+			Location:    locationB,
+			Instance:    instanceB,
+		}
+
+		outputsA := planNaming(t, options, a, planned)
+		outputsB := planNaming(t, options, b, planned)
+		if outputsA == nil || outputsB == nil {
+			return
+		}
+
+		for output, resourceType := range helpers.NamingOutputResourceTypes {
+			if helpers.AzureNamingRules[resourceType].Scope != helpers.ScopeGlobal || !a.distinct(b, output) {
+				continue
+			}
+			assert.NotEqual(t, outputsA[output], outputsB[output], "%s collides for %+v and %+v", output, a, b)
+		}
+	})
+}
+
+// planNaming plans in and checks its outputs against the naming rules and
+// against the outputs of an earlier plan of in. It returns nil when the
+// module rejects in.
+func planNaming(t *testing.T, options *terraform.Options, in namingInput, planned map[namingInput]map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	planOptions := *options
+	planOptions.Vars = in.vars()
+	plan, err := helpers.PlanE(t, &planOptions)
+
+	if !in.valid() {
+		assert.Error(t, err, "%+v must be rejected by the variable validation", in)
+		return nil
+	}
+	require.NoError(t, err, "%+v must be accepted", in)
+
+	outputs := plan.Outputs()
+	for output, resourceType := range helpers.NamingOutputResourceTypes {
+		name, _ := outputs[output].(string)
+		problems := helpers.AzureNamingRules[resourceType].Check(name)
+		assert.Empty(t, problems, "%+v: %s %q: %s", in, output, name, strings.Join(problems, "; "))
+	}
+
+	if previous, ok := planned[in]; ok {
+		assert.Equal(t, previous, outputs, "%+v: outputs differ between plans", in)
+	}
+	planned[in] = outputs
+	return outputs
+}
@@ -0,0 +1,179 @@
+//go:build integration
+
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - NAMING MODULE INTEGRATION TESTS
+// =============================================================================
+//
+// Integration tests for the Terraform naming module. These apply the module
+// and read its outputs.
+//
+// Run with: go test -v -tags=integration -run TestNaming ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// TestNamingModuleBasic tests basic naming conventions
+func TestNamingModuleBasic(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := helpers.NamingOptions(t, map[string]interface{}{
+		"project_name": "contoso",
+		"location":     "brazilsouth",
+	})
+
+	// Initialize and plan only (no resources created)
+	terraform.Init(t, terraformOptions)
+	terraform.Plan(t, terraformOptions)
+
+	// Apply to get outputs
+	terraform.Apply(t, terraformOptions)
+	defer terraform.Destroy(t, terraformOptions)
+
+	// Test resource group naming
+	rgName := terraform.Output(t, terraformOptions, "resource_group")
+	assert.Contains(t, rgName, "contoso")
+	assert.Contains(t, rgName, "dev")
+	assert.Contains(t, rgName, "rg")
+
+	// Test AKS cluster naming
+	aksName := terraform.Output(t, terraformOptions, "aks_cluster")
+	assert.Contains(t, aksName, "aks")
+	assert.NotContains(t, aksName, "_") // AKS names cannot contain underscores
+
+	// Test Storage Account naming (no hyphens, max 24 chars)
+	storageName := terraform.Output(t, terraformOptions, "storage_account")
+	assert.NotContains(t, storageName, "-")
+	assert.LessOrEqual(t, len(storageName), 24)
+
+	// Test ACR naming (no hyphens)
+	acrName := terraform.Output(t, terraformOptions, "container_registry")
+	assert.NotContains(t, acrName, "-")
+
+	// Test Key Vault naming (max 24 chars)
+	kvName := terraform.Output(t, terraformOptions, "key_vault")
+	assert.LessOrEqual(t, len(kvName), 24)
+}
+
+// TestNamingModuleRegionCodes tests region short codes
+func TestNamingModuleRegionCodes(t *testing.T) {
+	t.Parallel()
+
+	// Every region of config/region-availability.yaml
+	matrix, err := helpers.LoadRegionAvailability()
+	require.NoError(t, err)
+	codes, err := helpers.NamingRegionCodes()
+	require.NoError(t, err)
+
+	for _, region := range matrix.Names() {
+		region := region
+		expectedCode, ok := codes[region]
+		require.True(t, ok, "naming module has no region code for %s", region)
+
+		t.Run(region, func(t *testing.T) {
+			t.Parallel()
+
+			terraformOptions := helpers.NamingOptions(t, map[string]interface{}{
+				"project_name": "test",
+				"location":     region,
+			})
+
+			terraform.Init(t, terraformOptions)
+			terraform.Apply(t, terraformOptions)
+			defer terraform.Destroy(t, terraformOptions)
+
+			regionCode := terraform.Output(t, terraformOptions, "region_code")
+			assert.Equal(t, expectedCode, regionCode)
+		})
+	}
+}
+
+// TestNamingModuleEnvironments tests different environment configurations
+func TestNamingModuleEnvironments(t *testing.T) {
+	t.Parallel()
+
+	for _, env := range helpers.Environments() {
+		env := env
+		t.Run(env.Name, func(t *testing.T) {
+			t.Parallel()
+
+			terraformOptions := helpers.NamingOptions(t, env.Vars(helpers.ModuleNaming), map[string]interface{}{
+				"project_name": "test",
+			})
+
+			terraform.Init(t, terraformOptions)
+			terraform.Apply(t, terraformOptions)
+			defer terraform.Destroy(t, terraformOptions)
+
+			rgName := terraform.Output(t, terraformOptions, "resource_group")
+			assert.Contains(t, rgName, env.Value(helpers.ModuleNaming))
+		})
+	}
+}
+
+// TestNamingModuleOutputConsistency tests that outputs are consistent across runs
+func TestNamingModuleOutputConsistency(t *testing.T) {
+	t.Parallel()
+
+	vars := map[string]interface{}{
+		"project_name": "consistent",
+		"environment":  "dev",
+	}
+
+	// First run
+	terraformOptions1 := helpers.NamingOptions(t, vars)
+
+	terraform.Init(t, terraformOptions1)
+	terraform.Apply(t, terraformOptions1)
+	outputs1 := terraform.OutputAll(t, terraformOptions1)
+	terraform.Destroy(t, terraformOptions1)
+
+	// Second run with same inputs
+	terraformOptions2 := helpers.NamingOptions(t, vars)
+
+	terraform.Init(t, terraformOptions2)
+	terraform.Apply(t, terraformOptions2)
+	outputs2 := terraform.OutputAll(t, terraformOptions2)
+	defer terraform.Destroy(t, terraformOptions2)
+
+	// Compare outputs
+	for key, value1 := range outputs1 {
+		value2, exists := outputs2[key]
+		require.True(t, exists, "Output %s missing in second run", key)
+		assert.Equal(t, value1, value2, "Output %s differs between runs", key)
+	}
+}
+
+// TestNamingModuleAzureCompliance tests Azure naming rules compliance
+func TestNamingModuleAzureCompliance(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := helpers.NamingOptions(t, map[string]interface{}{
+		"project_name": "compliance",
+		"environment":  "prd",
+	})
+
+	terraform.Init(t, terraformOptions)
+	terraform.Apply(t, terraformOptions)
+	defer terraform.Destroy(t, terraformOptions)
+
+	// Every name output against the rule of the resource type it names
+	outputs := terraform.OutputAll(t, terraformOptions)
+
+	for output, resourceType := range helpers.NamingOutputResourceTypes {
+		name := fmt.Sprintf("%v", outputs[output])
+		problems := helpers.AzureNamingRules[resourceType].Check(name)
+		assert.Empty(t, problems, "%s (%s) %q: %s", output, resourceType, name, strings.Join(problems, "; "))
+	}
+}
@@ -0,0 +1,144 @@
+//go:build unit
+
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - NAMING MODULE REFERENCE TESTS
+// =============================================================================
+//
+// Differential test of the naming module against the Go naming convention
+// (package naming) that other tools use. testdata/naming-table calls the
+// module once per input, so a single plan evaluates the whole table.
+//
+// Run with: go test -v -tags=unit -run TestNamingModuleMatchesReference ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/three-horizons/accelerator/tests/helpers"
+	"github.com/three-horizons/accelerator/tests/naming"
+)
+
+// referenceInputs returns the input table: every environment in every
+// region of config/region-availability.yaml and of naming.RegionCodes plus
+// a region without a short code, and every project, org code and instance
+// length in the regions with the shortest and longest codes.
+func referenceInputs(t *testing.T) map[string]naming.Input {
+	t.Helper()
+
+	matrix, err := helpers.LoadRegionAvailability()
+	require.NoError(t, err)
+
+	seen := map[string]bool{"qatarcentral": true}
+	for _, region := range matrix.Names() {
+		seen[region] = true
+	}
+	for region := range naming.RegionCodes {
+		seen[region] = true
+	}
+	var locations []string
+	for region := range seen {
+		locations = append(locations, region)
+	}
+	sort.Strings(locations)
+
+	projects := []string{"ab", "contoso", "a1b2c3d4", "abcdefghij"}
+	orgCodes := []string{"", "ms", "abcd"}
+	instances := []string{"001", "042", "999"}
+
+	inputs := map[string]naming.Input{}
+	add := func(in naming.Input) {
+		inputs[fmt.Sprintf("%04d", len(inputs))] = in
+	}
+
+	for i, location := range locations {
+		for j, environment := range naming.Environments {
+			add(naming.Input{
+				ProjectName: projects[(i+j)%len(projects)],
+				Environment: environment,
+				Location:    location,
+				Instance:    instances[j%len(instances)],
+				OrgCode:     orgCodes[(i+j)%len(orgCodes)],
+			})
+		}
+	}
+
+	for _, location := range []string{"eastasia", "australiasoutheast"} {
+		for _, project := range projects {
+			for _, orgCode := range orgCodes {
+				for _, instance := range instances {
+					add(naming.Input{ProjectName: project, Environment: "prd", Location: location, Instance: instance, OrgCode: orgCode})
+				}
+			}
+		}
+	}
+
+	return inputs
+}
+
+// TestNamingModuleMatchesReference tests that the module and package naming
+// produce the same names for every input of the table
+func TestNamingModuleMatchesReference(t *testing.T) {
+	t.Parallel()
+
+	inputs := referenceInputs(t)
+
+	vars := map[string]interface{}{}
+	for key, in := range inputs {
+		vars[key] = map[string]string{
+			"project_name": in.ProjectName,
+			"environment":  in.Environment,
+			"location":     in.Location,
+			"instance":     in.Instance,
+			"org_code":     in.OrgCode,
+		}
+	}
+	tfvars, err := json.Marshal(map[string]interface{}{"inputs": vars})
+	require.NoError(t, err)
+
+	// The table is too large for -var arguments
+	dir := helpers.IsolatedDir(t, filepath.Join(helpers.TestDataDir(), "naming-table"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform.tfvars.json"), tfvars, 0o644))
+
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: dir,
+		NoColor:      true,
+	})
+	terraform.Init(t, terraformOptions)
+	plan, err := helpers.PlanE(t, terraformOptions)
+	require.NoError(t, err)
+
+	calls, ok := plan.Outputs()["names"].(map[string]interface{})
+	require.True(t, ok, "names output is not a map")
+	require.Len(t, calls, len(inputs))
+
+	for key, in := range inputs {
+		outputs, ok := calls[key].(map[string]interface{})
+		require.True(t, ok, "no outputs for %+v", in)
+
+		expected, err := naming.Names(in)
+		require.NoError(t, err)
+		assert.Len(t, outputs, len(expected)+1, "%+v: module and package naming have different outputs", in)
+
+		for output, name := range expected {
+			assert.Equal(t, name, outputs[output], "%s for %+v", output, in)
+		}
+
+		tags := map[string]interface{}{}
+		for tag, value := range naming.Tags(in) {
+			tags[tag] = value
+		}
+		assert.Equal(t, tags, outputs["tags"], "tags for %+v", in)
+	}
+}
@@ -1,82 +1,107 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - NETWORKING MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform networking module.
 //
-// Run with: go test -v -run TestNetworking ./modules/
+// Run with: go test -v -tags=unit -run TestNetworking ./modules/
 //
 // =============================================================================
 
 package modules
 
 import (
+	"net/netip"
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/three-horizons/accelerator/tests/cidrplan"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
+// networkingSizing is the address demand of the aks-cluster baseline: up to
+// 3 system nodes plus a surge node, 110 pods each.
+var networkingSizing = cidrplan.Sizing{Nodes: 4, Pods: 440}
+
+// allocateSubnets lays out subnet_config in vnetCIDR for networkingSizing.
+func allocateSubnets(t *testing.T, vnetCIDR string) cidrplan.Layout {
+	t.Helper()
+
+	layout, err := cidrplan.Allocate(netip.MustParsePrefix(vnetCIDR), networkingSizing)
+	require.NoError(t, err)
+	return layout
+}
+
+// baselineSubnets returns the subnet_config of the networking baseline.
+func baselineSubnets(t *testing.T) cidrplan.Layout {
+	t.Helper()
+
+	subnets := map[string]string{}
+	for key, value := range helpers.Baseline(helpers.ModuleNetworking)["subnet_config"].(map[string]interface{}) {
+		subnets[key] = value.(string)
+	}
+	layout, err := cidrplan.ParseLayout(subnets)
+	require.NoError(t, err)
+	return layout
+}
+
 // TestNetworkingModuleBasic tests basic networking configuration
 func TestNetworkingModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/networking",
-		Vars: map[string]interface{}{
-			"customer_name":       "testcustomer",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-networking",
-			"vnet_cidr":           "10.0.0.0/16",
-			"dns_zone_name":       "test.example.com",
-			"subnet_config": map[string]interface{}{
-				"aks_nodes_cidr":         "10.0.0.0/22",
-				"aks_pods_cidr":          "10.0.16.0/20",
-				"private_endpoints_cidr": "10.0.4.0/24",
-				"bastion_cidr":           "10.0.5.0/26",
-				"app_gateway_cidr":       "10.0.6.0/24",
-			},
-			"enable_bastion":     false,
-			"enable_app_gateway": false,
-			"create_dns_zone":    false,
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
+	layout := allocateSubnets(t, "10.0.0.0/16")
 
-	// Initialize and validate
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
+	terraformOptions := helpers.NetworkingOptions(t, map[string]interface{}{
+		"customer_name":      "testcustomer",
+		"vnet_cidr":          "10.0.0.0/16",
+		"subnet_config":      layout.Vars(),
+		"enable_bastion":     false,
+		"enable_app_gateway": false,
+	})
 
 	// Plan only (no actual resources created in unit test)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify plan contains expected resources
-	assert.Contains(t, planOutput, "azurerm_virtual_network.main")
-	assert.Contains(t, planOutput, "azurerm_subnet.aks_nodes")
-	assert.Contains(t, planOutput, "azurerm_subnet.aks_pods")
-	assert.Contains(t, planOutput, "azurerm_subnet.private_endpoints")
-	assert.Contains(t, planOutput, "azurerm_network_security_group.aks_nodes")
-	assert.Contains(t, planOutput, "azurerm_network_security_group.private_endpoints")
+	plan.AssertCreated(t, "azurerm_virtual_network.main").
+		HasAttribute("address_space", []string{"10.0.0.0/16"})
+	subnets := map[string]string{
+		"azurerm_subnet.aks_nodes":         cidrplan.AKSNodes,
+		"azurerm_subnet.aks_pods":          cidrplan.AKSPods,
+		"azurerm_subnet.private_endpoints": cidrplan.PrivateEndpoints,
+	}
+	for address, key := range subnets {
+		plan.AssertCreated(t, address).
+			HasAttribute("address_prefixes", []string{layout[key].String()})
+	}
+	plan.AssertCreated(t, "azurerm_network_security_group.aks_nodes")
+	plan.AssertCreated(t, "azurerm_network_security_group.private_endpoints")
+	plan.AssertAbsent(t, "azurerm_subnet.bastion")
+	plan.AssertAbsent(t, "azurerm_subnet.app_gateway")
 }
 
 // TestNetworkingModuleVNetCIDRValidation tests VNet CIDR validation
 func TestNetworkingModuleVNetCIDRValidation(t *testing.T) {
 	t.Parallel()
 
+	const invalidVNet = "VNet CIDR must be an IPv4 network prefix without host bits"
+
 	testCases := []struct {
-		name      string
-		vnetCIDR  string
-		shouldErr bool
+		name     string
+		vnetCIDR string
+		allocate bool
+		wantErr  string
 	}{
-		{"valid_16", "10.0.0.0/16", false},
-		{"valid_8", "10.0.0.0/8", false},
-		{"valid_24", "10.0.0.0/24", false},
+		{"valid_16", "10.0.0.0/16", true, ""},
+		{"valid_8", "10.0.0.0/8", true, ""},
+		{"valid_21", "10.0.0.0/21", true, ""},
+		{"not_a_cidr", "10.0.0.0", false, invalidVNet},
+		{"host_bits_set", "10.0.0.1/16", false, invalidVNet},
+		{"ipv6", "fd00::/48", false, invalidVNet},
+		{"subnets_outside_vnet", "10.0.0.0/24", false, "Subnets outside vnet_cidr 10.0.0.0/24: aks_nodes_cidr (10.0.0.0/22)"},
 	}
 
 	for _, tc := range testCases {
@@ -84,27 +109,137 @@ func TestNetworkingModuleVNetCIDRValidation(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/networking",
-				Vars: map[string]interface{}{
-					"customer_name":       "test",
-					"environment":         "dev",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test",
-					"vnet_cidr":           tc.vnetCIDR,
-					"dns_zone_name":       "test.example.com",
-					"create_dns_zone":     false,
-				},
-				NoColor: true,
+			layout := baselineSubnets(t)
+			if tc.allocate {
+				layout = allocateSubnets(t, tc.vnetCIDR)
+			}
+			if vnet, err := netip.ParsePrefix(tc.vnetCIDR); err == nil {
+				problems := cidrplan.Validate(vnet, layout, cidrplan.Features{})
+				require.Equal(t, tc.wantErr != "", len(problems) > 0, "cidrplan disagrees: %v", problems)
 			}
 
-			terraform.Init(t, terraformOptions)
+			terraformOptions := helpers.NetworkingOptions(t, map[string]interface{}{
+				"customer_name": "test",
+				"vnet_cidr":     tc.vnetCIDR,
+				"subnet_config": layout.Vars(),
+			})
+
+			if tc.wantErr != "" {
+				_, err := helpers.InitAndPlanE(t, terraformOptions)
+				helpers.AssertPlanError(t, err, tc.wantErr)
+			} else {
+				helpers.InitAndPlan(t, terraformOptions)
+			}
+		})
+	}
+}
+
+// TestNetworkingModuleSubnetValidation tests that the module rejects the
+// subnet layouts cidrplan rejects
+func TestNetworkingModuleSubnetValidation(t *testing.T) {
+	t.Parallel()
+
+	all := cidrplan.Features{Bastion: true, AppGateway: true}
+
+	const invalidSubnet = "Subnet CIDRs must be IPv4 network prefixes without host bits, /29 or larger"
+
+	testCases := []struct {
+		name     string
+		subnet   string
+		prefix   string
+		features cidrplan.Features
+		wantErr  string
+	}{
+		{"valid", "", "", all, ""},
+		{"overlapping_nodes_and_pods", cidrplan.AKSPods, "10.0.0.0/20", all, "Overlapping subnets: aks_nodes_cidr and aks_pods_cidr"},
+		{"outside_vnet", cidrplan.PrivateEndpoints, "10.1.4.0/24", all, "Subnets outside vnet_cidr 10.0.0.0/16: private_endpoints_cidr (10.1.4.0/24)"},
+		{"host_bits_set", cidrplan.PrivateEndpoints, "10.0.4.1/24", all, invalidSubnet},
+		{"undersized_subnet", cidrplan.PrivateEndpoints, "10.0.4.0/30", all, invalidSubnet},
+		{"undersized_bastion", cidrplan.Bastion, "10.0.5.0/27", all, "AzureBastionSubnet must be /26 or larger"},
+		{"undersized_bastion_disabled", cidrplan.Bastion, "10.0.5.0/27", cidrplan.Features{AppGateway: true}, ""},
+		{"shared_app_gateway", cidrplan.AppGateway, "10.0.4.0/24", all, "Overlapping subnets: app_gateway_cidr and private_endpoints_cidr. Application Gateway needs a dedicated subnet."},
+		{"shared_app_gateway_disabled", cidrplan.AppGateway, "10.0.4.0/24", cidrplan.Features{Bastion: true}, ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
 
-			if tc.shouldErr {
-				_, err := terraform.PlanE(t, terraformOptions)
-				require.Error(t, err)
+			layout := baselineSubnets(t)
+			if tc.subnet != "" {
+				layout[tc.subnet] = netip.MustParsePrefix(tc.prefix)
+			}
+			problems := cidrplan.Validate(netip.MustParsePrefix("10.0.0.0/16"), layout, tc.features)
+			require.Equal(t, tc.wantErr != "", len(problems) > 0, "cidrplan disagrees: %v", problems)
+
+			terraformOptions := helpers.NetworkingOptions(t, map[string]interface{}{
+				"customer_name":      "subnets",
+				"vnet_cidr":          "10.0.0.0/16",
+				"subnet_config":      layout.Vars(),
+				"enable_bastion":     tc.features.Bastion,
+				"enable_app_gateway": tc.features.AppGateway,
+			})
+
+			if tc.wantErr != "" {
+				_, err := helpers.InitAndPlanE(t, terraformOptions)
+				helpers.AssertPlanError(t, err, tc.wantErr)
 			} else {
-				terraform.Plan(t, terraformOptions)
+				helpers.InitAndPlan(t, terraformOptions)
+			}
+		})
+	}
+}
+
+// TestNetworkingModuleSizingProfiles tests the layout cidrplan allocates
+// for each sizing profile
+func TestNetworkingModuleSizingProfiles(t *testing.T) {
+	t.Parallel()
+
+	profiles, err := helpers.LoadSizingProfiles()
+	require.NoError(t, err)
+
+	for _, name := range profiles.Names() {
+		name, profile := name, profiles.Get(name)
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			vnetCIDR := "10.0.0.0/16"
+			appGateway := false
+			if networking := profile.Infrastructure.Networking; networking != nil {
+				if networking.VNetCIDR != "" {
+					vnetCIDR = networking.VNetCIDR
+				}
+				appGateway = networking.ApplicationGateway != nil && networking.ApplicationGateway.Enabled
+			}
+			nodes, pods := profile.AddressDemand()
+			layout, err := cidrplan.Allocate(netip.MustParsePrefix(vnetCIDR), cidrplan.Sizing{Nodes: nodes, Pods: pods})
+			require.NoError(t, err)
+
+			terraformOptions := helpers.NetworkingOptions(t, map[string]interface{}{
+				"customer_name":      "size" + name,
+				"vnet_cidr":          vnetCIDR,
+				"subnet_config":      layout.Vars(),
+				"enable_bastion":     true,
+				"enable_app_gateway": appGateway,
+			})
+
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			plan.AssertCreated(t, "azurerm_virtual_network.main").
+				HasAttribute("address_space", []string{vnetCIDR})
+			subnets := map[string]string{
+				"azurerm_subnet.aks_nodes":         cidrplan.AKSNodes,
+				"azurerm_subnet.aks_pods":          cidrplan.AKSPods,
+				"azurerm_subnet.private_endpoints": cidrplan.PrivateEndpoints,
+				"azurerm_subnet.bastion":           cidrplan.Bastion,
+			}
+			if appGateway {
+				subnets["azurerm_subnet.app_gateway"] = cidrplan.AppGateway
+			}
+			for address, key := range subnets {
+				plan.AssertCreated(t, address).
+					HasAttribute("address_prefixes", []string{layout[key].String()})
 			}
 		})
 	}
@@ -114,90 +249,82 @@ func TestNetworkingModuleVNetCIDRValidation(t *testing.T) {
 func TestNetworkingModuleSubnetConfiguration(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/networking",
-		Vars: map[string]interface{}{
-			"customer_name":       "subnet",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-subnet",
-			"vnet_cidr":           "10.0.0.0/16",
-			"dns_zone_name":       "test.example.com",
-			"subnet_config": map[string]interface{}{
-				"aks_nodes_cidr":         "10.0.0.0/22",
-				"aks_pods_cidr":          "10.0.16.0/20",
-				"private_endpoints_cidr": "10.0.4.0/24",
-				"bastion_cidr":           "10.0.5.0/26",
-				"app_gateway_cidr":       "10.0.6.0/24",
-			},
-			"enable_bastion":     true,
-			"enable_app_gateway": true,
-			"create_dns_zone":    false,
+	terraformOptions := helpers.NetworkingOptions(t, map[string]interface{}{
+		"customer_name": "subnet",
+		"subnet_config": map[string]interface{}{
+			"aks_nodes_cidr":         "10.0.0.0/22",
+			"aks_pods_cidr":          "10.0.16.0/20",
+			"private_endpoints_cidr": "10.0.4.0/24",
+			"bastion_cidr":           "10.0.5.0/26",
+			"app_gateway_cidr":       "10.0.6.0/24",
 		},
-		NoColor: true,
+		"enable_bastion":     true,
+		"enable_app_gateway": true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify all subnets are planned when enabled
-	assert.Contains(t, planOutput, "azurerm_subnet.aks_nodes")
-	assert.Contains(t, planOutput, "azurerm_subnet.aks_pods")
-	assert.Contains(t, planOutput, "azurerm_subnet.private_endpoints")
-	assert.Contains(t, planOutput, "azurerm_subnet.bastion")
-	assert.Contains(t, planOutput, "azurerm_subnet.app_gateway")
+	plan.AssertResourceCount(t, "azurerm_subnet", 5)
+	plan.AssertCreated(t, "azurerm_subnet.bastion").
+		HasAttribute("address_prefixes", []string{"10.0.5.0/26"})
+	plan.AssertCreated(t, "azurerm_subnet.app_gateway").
+		HasAttribute("name", "snet-app-gateway").
+		HasAttribute("address_prefixes", []string{"10.0.6.0/24"})
 }
 
 // TestNetworkingModulePrivateDNSZones tests private DNS zone creation
 func TestNetworkingModulePrivateDNSZones(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/networking",
-		Vars: map[string]interface{}{
-			"customer_name":       "dns",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-dns",
-			"vnet_cidr":           "10.0.0.0/16",
-			"dns_zone_name":       "test.example.com",
-			"create_dns_zone":     false,
-		},
-		NoColor: true,
+	terraformOptions := helpers.NetworkingOptions(t, map[string]interface{}{
+		"customer_name": "dns",
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify private DNS zones are created
-	assert.Contains(t, planOutput, "azurerm_private_dns_zone.zones")
-	assert.Contains(t, planOutput, "azurerm_private_dns_zone_virtual_network_link.links")
+	plan.AssertResourceCount(t, "azurerm_private_dns_zone.zones", 8)
+	plan.AssertCreated(t, `azurerm_private_dns_zone.zones["keyvault"]`).
+		HasAttribute("name", "privatelink.vaultcore.azure.net")
+	plan.AssertResourceCount(t, "azurerm_private_dns_zone_virtual_network_link.links", 8)
+	plan.AssertAbsent(t, `azurerm_private_dns_zone.zones["purview"]`)
+}
+
+// TestNetworkingModulePurviewDNSZones tests the zones added for Purview
+func TestNetworkingModulePurviewDNSZones(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := helpers.NetworkingOptions(t, map[string]interface{}{
+		"customer_name":            "pvdns",
+		"enable_purview_dns_zones": true,
+	})
+
+	plan := helpers.InitAndPlan(t, terraformOptions)
+
+	plan.AssertResourceCount(t, "azurerm_private_dns_zone.zones", 13)
+	plan.AssertCreated(t, `azurerm_private_dns_zone.zones["purview_studio"]`).
+		HasAttribute("name", "privatelink.purviewstudio.azure.com")
+	plan.AssertResourceCount(t, "azurerm_private_dns_zone_virtual_network_link.links", 13)
 }
 
 // TestNetworkingModuleNSGRules tests NSG rule configurations
 func TestNetworkingModuleNSGRules(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/networking",
-		Vars: map[string]interface{}{
-			"customer_name":       "nsg",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-nsg",
-			"vnet_cidr":           "10.0.0.0/16",
-			"dns_zone_name":       "test.example.com",
-			"create_dns_zone":     false,
-		},
-		NoColor: true,
+	terraformOptions := helpers.NetworkingOptions(t, map[string]interface{}{
+		"customer_name": "nsg",
+		"environment":   "prod",
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify NSGs are created with proper naming
-	assert.Contains(t, planOutput, "nsg-aks-nodes")
-	assert.Contains(t, planOutput, "nsg-private-endpoints")
+	plan.AssertCreated(t, "azurerm_network_security_group.aks_nodes").
+		HasAttribute("name", "nsg-aks-nodes-nsg-prod")
+	plan.AssertCreated(t, "azurerm_network_security_group.private_endpoints").
+		HasAttribute("name", "nsg-private-endpoints-nsg-prod")
+	plan.AssertResourceCount(t, "azurerm_subnet_network_security_group_association", 2)
 }
 
 // TestNetworkingModuleBastionConfiguration tests Azure Bastion configuration
@@ -218,30 +345,27 @@ func TestNetworkingModuleBastionConfiguration(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/networking",
-				Vars: map[string]interface{}{
-					"customer_name":       "bastion",
-					"environment":         "dev",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-bastion",
-					"vnet_cidr":           "10.0.0.0/16",
-					"dns_zone_name":       "test.example.com",
-					"enable_bastion":      tc.enableBastion,
-					"create_dns_zone":     false,
-				},
-				NoColor: true,
+			terraformOptions := helpers.NetworkingOptions(t, map[string]interface{}{
+				"customer_name":  "bastion",
+				"enable_bastion": tc.enableBastion,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
 			if tc.expectBastion {
-				assert.Contains(t, planOutput, "azurerm_bastion_host.main")
-				assert.Contains(t, planOutput, "azurerm_public_ip.bastion")
-				assert.Contains(t, planOutput, "AzureBastionSubnet")
+				plan.AssertCreated(t, "azurerm_bastion_host.main").
+					HasAttribute("sku", "Standard").
+					HasAttribute("tunneling_enabled", true).
+					HasAttribute("shareable_link_enabled", false)
+				plan.AssertCreated(t, "azurerm_public_ip.bastion").
+					HasAttribute("allocation_method", "Static").
+					HasAttribute("sku", "Standard")
+				plan.AssertCreated(t, "azurerm_subnet.bastion").
+					HasAttribute("name", "AzureBastionSubnet")
 			} else {
-				assert.NotContains(t, planOutput, "azurerm_bastion_host.main")
+				plan.AssertAbsent(t, "azurerm_bastion_host.main")
+				plan.AssertAbsent(t, "azurerm_public_ip.bastion")
+				plan.AssertAbsent(t, "azurerm_subnet.bastion")
 			}
 		})
 	}
@@ -251,32 +375,21 @@ func TestNetworkingModuleBastionConfiguration(t *testing.T) {
 func TestNetworkingModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/networking",
-				Vars: map[string]interface{}{
-					"customer_name":       "envtest",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-" + env,
-					"vnet_cidr":           "10.0.0.0/16",
-					"dns_zone_name":       "test.example.com",
-					"create_dns_zone":     false,
-				},
-				NoColor: true,
+			terraformOptions := helpers.NetworkingOptions(t, env.Vars(helpers.ModuleNetworking), map[string]interface{}{
+				"customer_name":       "envtest",
+				"resource_group_name": "rg-test-" + env.Name,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
 			// Verify environment is reflected in naming
-			assert.Contains(t, planOutput, env)
+			plan.AssertCreated(t, "azurerm_virtual_network.main").
+				HasAttribute("name", "vnet-envtest-"+env.Value(helpers.ModuleNetworking))
 		})
 	}
 }
@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - OBSERVABILITY MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform observability module.
 //
-// Run with: go test -v -run TestObservability ./modules/
+// Run with: go test -v -tags=unit -run TestObservability ./modules/
 //
 // =============================================================================
 
@@ -13,148 +15,101 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestObservabilityModuleBasic tests basic observability configuration
 func TestObservabilityModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/observability",
-		Vars: map[string]interface{}{
-			"customer_name":       "testobs",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-obs",
-			"enable_prometheus":   true,
-			"enable_grafana":      true,
-			"enable_loki":         true,
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.ObservabilityOptions(t, map[string]interface{}{
+		"customer_name":             "testobs",
+		"enable_container_insights": true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify observability stack is planned
-	assert.Contains(t, planOutput, "azurerm_log_analytics_workspace")
+	plan.AssertCreated(t, "azurerm_monitor_workspace.prometheus").
+		HasAttribute("name", "amw-testobs-"+helpers.TestEnvironment)
+	plan.AssertCreated(t, "azurerm_log_analytics_workspace.main")
+	plan.AssertCreated(t, "azurerm_log_analytics_solution.container_insights")
 }
 
 // TestObservabilityModuleLogAnalytics tests Log Analytics workspace
 func TestObservabilityModuleLogAnalytics(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/observability",
-		Vars: map[string]interface{}{
-			"customer_name":       "latest",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-la",
-			"log_analytics_config": map[string]interface{}{
-				"sku":               "PerGB2018",
-				"retention_in_days": 30,
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.ObservabilityOptions(t, map[string]interface{}{
+		"customer_name":             "latest",
+		"enable_container_insights": true,
+		"retention_days":            30,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify Log Analytics workspace naming
-	assert.Contains(t, planOutput, "log-")
+	plan.AssertCreated(t, "azurerm_log_analytics_workspace.main").
+		HasAttribute("name", "law-latest-"+helpers.TestEnvironment).
+		HasAttribute("retention_in_days", 30)
 }
 
 // TestObservabilityModuleGrafana tests Azure Managed Grafana
 func TestObservabilityModuleGrafana(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/observability",
-		Vars: map[string]interface{}{
-			"customer_name":       "graftest",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-grafana",
-			"enable_grafana":      true,
-			"grafana_config": map[string]interface{}{
-				"sku":                    "Standard",
-				"api_key_enabled":        true,
-				"public_network_enabled": false,
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.ObservabilityOptions(t, map[string]interface{}{
+		"customer_name":           "graftest",
+		"grafana_viewer_group_id": "00000000-0000-0000-0000-000000000002",
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify Grafana is planned
-	assert.Contains(t, planOutput, "azurerm_dashboard_grafana")
+	plan.AssertCreated(t, "azurerm_dashboard_grafana.main").
+		HasAttribute("grafana_major_version", "10")
+
+	plan.AssertGolden(t, helpers.ModuleObservability, "grafana")
 }
 
 // TestObservabilityModuleAlerts tests alert configuration
 func TestObservabilityModuleAlerts(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/observability",
-		Vars: map[string]interface{}{
-			"customer_name":       "alerttest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-alerts",
-			"enable_alerts":       true,
-			"alert_config": map[string]interface{}{
-				"action_group_email": "ops@example.com",
-				"severity_levels":    []int{0, 1, 2},
-			},
+	terraformOptions := helpers.ObservabilityOptions(t, map[string]interface{}{
+		"customer_name": "alerttest",
+		"environment":   "prod",
+		"alert_email_receivers": []string{
+			"ops@example.com",
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify action group is planned
-	assert.Contains(t, planOutput, "azurerm_monitor_action_group")
+	plan.AssertCreated(t, "azurerm_monitor_action_group.alerts").
+		HasAttribute("name", "ag-alerttest-prod").
+		HasAttribute("email_receiver.0.email_address", "ops@example.com")
 }
 
 // TestObservabilityModuleEnvironments tests different environments
 func TestObservabilityModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/observability",
-				Vars: map[string]interface{}{
-					"customer_name":       "obsenv",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-obs-" + env,
-				},
-				NoColor: true,
+			terraformOptions := helpers.ObservabilityOptions(t, env.Vars(helpers.ModuleObservability), map[string]interface{}{
+				"customer_name":       "obsenv",
+				"resource_group_name": "rg-test-obs-" + env.Name,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, env)
+			plan.AssertCreated(t, "azurerm_dashboard_grafana.main").
+				HasAttribute("zone_redundancy_enabled", env.HighAvailability())
 		})
 	}
 }
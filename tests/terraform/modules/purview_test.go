@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - PURVIEW MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform Microsoft Purview module.
 //
-// Run with: go test -v -run TestPurview ./modules/
+// Run with: go test -v -tags=unit -run TestPurview ./modules/
 //
 // =============================================================================
 
@@ -13,83 +15,51 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestPurviewModuleBasic tests basic Purview configuration
 func TestPurviewModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/purview",
-		Vars: map[string]interface{}{
-			"customer_name":       "testpurv",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-purview",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_ids": map[string]interface{}{
-				"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-				"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-				"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-				"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-				"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-				"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
-			},
-			"admin_group_id": "00000000-0000-0000-0000-000000000000",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.PurviewOptions(t, map[string]interface{}{
+		"customer_name": "testpurv",
 	})
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify Purview account is planned
-	assert.Contains(t, planOutput, "azurerm_purview_account")
+	plan.AssertCreated(t, "azurerm_purview_account.main").
+		HasAttribute("name", "pvtestpurv"+helpers.TestEnvironment).
+		HasAttribute("public_network_enabled", false)
+	plan.AssertResourceCount(t, "azurerm_private_endpoint", 2)
 }
 
 // TestPurviewModuleSizingProfiles tests different sizing profiles
 func TestPurviewModuleSizingProfiles(t *testing.T) {
 	t.Parallel()
 
-	profiles := []string{"small", "medium", "large", "xlarge"}
+	// Every profile in config/sizing-profiles.yaml
+	profiles, err := helpers.LoadSizingProfiles()
+	require.NoError(t, err)
 
-	for _, profile := range profiles {
+	for _, profile := range profiles.Names() {
 		profile := profile
 		t.Run(profile, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/purview",
-				Vars: map[string]interface{}{
-					"customer_name":       "sizetest",
-					"environment":         "prod",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-purview-" + profile,
-					"sizing_profile":      profile,
-					"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_ids": map[string]interface{}{
-						"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-						"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-						"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-						"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-						"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-						"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
-					},
-					"admin_group_id": "00000000-0000-0000-0000-000000000000",
-				},
-				NoColor: true,
+			terraformOptions := helpers.PurviewOptions(t, map[string]interface{}{
+				"customer_name":       "sizetest",
+				"environment":         "prod",
+				"resource_group_name": "rg-test-purview-" + profile,
+				"sizing_profile":      profile,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			plan.AssertCreated(t, "azurerm_purview_account.main").
+				HasAttribute("tags.three-horizons/sizing", profile)
 		})
 	}
 }
@@ -98,46 +68,33 @@ func TestPurviewModuleSizingProfiles(t *testing.T) {
 func TestPurviewModuleDataSources(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/purview",
-		Vars: map[string]interface{}{
-			"customer_name":       "dstest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-purview-ds",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_ids": map[string]interface{}{
-				"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-				"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-				"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-				"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-				"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-				"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
+	terraformOptions := helpers.PurviewOptions(t, map[string]interface{}{
+		"customer_name": "dstest",
+		"environment":   "prod",
+		"data_sources": []map[string]interface{}{
+			{
+				"name":           "storage-account-1",
+				"type":           "AzureBlobStorage",
+				"resource_id":    "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/st1",
+				"scan_frequency": "Weekly",
 			},
-			"admin_group_id": "00000000-0000-0000-0000-000000000000",
-			"data_sources": []map[string]interface{}{
-				{
-					"name":           "storage-account-1",
-					"type":           "AzureBlobStorage",
-					"resource_id":    "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/st1",
-					"scan_frequency": "Weekly",
-				},
-				{
-					"name":           "sql-database-1",
-					"type":           "AzureSqlDatabase",
-					"resource_id":    "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Sql/servers/sql1/databases/db1",
-					"scan_frequency": "Daily",
-				},
+			{
+				"name":           "sql-database-1",
+				"type":           "AzureSqlDatabase",
+				"resource_id":    "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Sql/servers/sql1/databases/db1",
+				"scan_frequency": "Daily",
 			},
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify data sources are configured
-	assert.Contains(t, planOutput, "purview")
+	plan.AssertResourceCount(t, "azapi_resource.data_sources", 2)
+	plan.AssertCreated(t, `azurerm_role_assignment.purview_storage_reader["storage-account-1"]`).
+		HasAttribute("role_definition_name", "Storage Blob Data Reader")
+	plan.AssertCreated(t, `azurerm_role_assignment.purview_sql_reader["sql-database-1"]`).
+		HasAttribute("role_definition_name", "Reader")
 }
 
 // TestPurviewModuleLATAMClassifications tests LATAM-specific classifications
@@ -157,30 +114,21 @@ func TestPurviewModuleLATAMClassifications(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/purview",
-				Vars: map[string]interface{}{
-					"customer_name":       "latamtest",
-					"environment":         "prod",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-purview-latam",
-					"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_ids": map[string]interface{}{
-						"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-						"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-						"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-						"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-						"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-						"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
-					},
-					"admin_group_id":                "00000000-0000-0000-0000-000000000000",
-					"enable_latam_classifications": tc.enabled,
-				},
-				NoColor: true,
+			terraformOptions := helpers.PurviewOptions(t, map[string]interface{}{
+				"customer_name":                "latamtest",
+				"environment":                  "prod",
+				"enable_latam_classifications": tc.enabled,
 			})
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
+
+			if tc.enabled {
+				plan.AssertResourceCount(t, "azapi_resource.latam_classifications", 10)
+				plan.AssertCreated(t, `azapi_resource.latam_classifications["BRAZIL_CPF"]`).
+					HasAttribute("type", "Microsoft.Purview/accounts/classificationRules@2022-02-01-preview")
+			} else {
+				plan.AssertAbsent(t, "azapi_resource.latam_classifications")
+			}
 		})
 	}
 }
@@ -189,77 +137,44 @@ func TestPurviewModuleLATAMClassifications(t *testing.T) {
 func TestPurviewModuleCollectionHierarchy(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/purview",
-		Vars: map[string]interface{}{
-			"customer_name":       "colltest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-purview-coll",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_ids": map[string]interface{}{
-				"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-				"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-				"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-				"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-				"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-				"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
-			},
-			"admin_group_id": "00000000-0000-0000-0000-000000000000",
-			"collection_hierarchy": []map[string]interface{}{
-				{"name": "H1-Foundation", "parent": "", "description": "Foundation infrastructure assets"},
-				{"name": "H2-Enhancement", "parent": "", "description": "Enhanced platform assets"},
-				{"name": "H3-Innovation", "parent": "", "description": "AI/ML innovation assets"},
-				{"name": "Databases", "parent": "H1-Foundation", "description": "Database assets"},
-				{"name": "Storage", "parent": "H1-Foundation", "description": "Storage assets"},
-			},
+	terraformOptions := helpers.PurviewOptions(t, map[string]interface{}{
+		"customer_name": "colltest",
+		"environment":   "prod",
+		"collection_hierarchy": []map[string]interface{}{
+			{"name": "H1-Foundation", "parent": "", "description": "Foundation infrastructure assets"},
+			{"name": "H2-Enhancement", "parent": "", "description": "Enhanced platform assets"},
+			{"name": "H3-Innovation", "parent": "", "description": "AI/ML innovation assets"},
+			{"name": "Databases", "parent": "H1-Foundation", "description": "Database assets"},
+			{"name": "Storage", "parent": "H1-Foundation", "description": "Storage assets"},
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify collection hierarchy is planned
-	assert.Contains(t, planOutput, "purview")
+	plan.AssertResourceCount(t, "azapi_resource.collections", 5)
+	plan.AssertCreated(t, `azapi_resource.collections["H1-Foundation"]`).
+		HasAttribute("name", "h1foundation")
 }
 
 // TestPurviewModuleEnvironments tests different environments
 func TestPurviewModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/purview",
-				Vars: map[string]interface{}{
-					"customer_name":       "envtest",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-purview-" + env,
-					"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_ids": map[string]interface{}{
-						"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-						"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-						"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-						"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-						"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-						"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
-					},
-					"admin_group_id": "00000000-0000-0000-0000-000000000000",
-				},
-				NoColor: true,
+			terraformOptions := helpers.PurviewOptions(t, env.Vars(helpers.ModulePurview), map[string]interface{}{
+				"customer_name":       "envtest",
+				"resource_group_name": "rg-test-purview-" + env.Name,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
-			assert.Contains(t, planOutput, env)
+			plan.AssertCreated(t, "azurerm_purview_account.main").
+				HasAttribute("name", "pvenvtest"+env.Value(helpers.ModulePurview))
 		})
 	}
 }
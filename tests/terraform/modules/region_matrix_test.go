@@ -0,0 +1,155 @@
+//go:build unit
+
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - REGION MATRIX TESTS
+// =============================================================================
+//
+// Plans every regional module in every tier 1 and tier 2 region of
+// config/region-availability.yaml that lists the module's services. A module
+// relying on a service its region lacks must either be routed to the
+// documented spillover region by the root module or fail validation there.
+//
+// Run with: go test -v -tags=unit -run TestRegionMatrix ./modules/
+// Run with: TERRATEST_MOCK_PROVIDERS=true go test -v -tags=unit -run TestRegionMatrix ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// regionValidationErrors is the message of each module whose validation
+// refuses a region lacking one of its services. A placement without a
+// documented spillover must fail with it.
+var regionValidationErrors = map[string]string{}
+
+// spilloverRootVars are the root module Vars, beyond location, that deploy a
+// module the root routes to a spillover region.
+var spilloverRootVars = map[string]map[string]interface{}{
+	helpers.ModuleAIFoundry: {
+		"deployment_mode":   "standard",
+		"enable_ai_foundry": true,
+	},
+}
+
+// TestRegionMatrix tests every module placement of the region matrix
+func TestRegionMatrix(t *testing.T) {
+	t.Parallel()
+
+	matrix, err := helpers.LoadRegionAvailability()
+	require.NoError(t, err)
+
+	for _, placement := range matrix.Placements() {
+		placement := placement
+		t.Run(placement.Region+"/"+placement.Module, func(t *testing.T) {
+			t.Parallel()
+
+			switch {
+			case placement.Location == "":
+				assertRegionRefused(t, placement)
+			case placement.Spillover():
+				assertSpilloverRouting(t, placement)
+			default:
+				terraformOptions := helpers.OptionsFor(t, placement.Module, map[string]interface{}{
+					"location": placement.Region,
+				})
+				plan := helpers.InitAndPlan(t, terraformOptions)
+
+				assertPlannedLocation(t, plan, "", placement.Region)
+			}
+		})
+	}
+}
+
+// assertRegionRefused plans the module in a region that lacks one of its
+// services and has no spillover, and expects the module's own validation
+// error.
+func assertRegionRefused(t *testing.T, placement helpers.ModulePlacement) {
+	t.Helper()
+
+	unsupported := strings.Join(placement.Unsupported, ", ")
+	message, ok := regionValidationErrors[placement.Module]
+	if !ok {
+		t.Fatalf("%s needs %s, which %s does not offer: document a spillover region in %s, or add the validation refusing the region to regionValidationErrors",
+			placement.Module, unsupported, placement.Region, filepath.Base(helpers.RegionAvailabilityPath()))
+	}
+
+	terraformOptions := helpers.OptionsFor(t, placement.Module, map[string]interface{}{
+		"location": placement.Region,
+	})
+	_, err := helpers.InitAndPlanE(t, terraformOptions)
+	helpers.AssertPlanError(t, err, message)
+}
+
+// assertSpilloverRouting plans the root module in the chosen region and
+// checks that it deploys the module in the spillover region and everything
+// else in the chosen one.
+func assertSpilloverRouting(t *testing.T, placement helpers.ModulePlacement) {
+	t.Helper()
+
+	if !helpers.MockProvidersEnabled() {
+		t.Skipf("%s lacks %s: the routing to %s is checked on a root plan; set %s=true to plan with mock providers",
+			placement.Region, strings.Join(placement.Unsupported, ", "), placement.Location, helpers.MockProvidersEnv)
+	}
+
+	rootVars, ok := spilloverRootVars[placement.Module]
+	require.True(t, ok, "%s spills over to %s: add the root Vars that deploy it to spilloverRootVars", placement.Module, placement.Location)
+
+	calls, err := helpers.ParseModuleCalls(helpers.RootModuleDir())
+	require.NoError(t, err)
+	var call *helpers.ModuleCall
+	for _, candidate := range calls {
+		if candidate.Dir == helpers.ModuleDir(placement.Module) {
+			call = candidate
+		}
+	}
+	require.NotNil(t, call, "the root module does not call %s", placement.Module)
+
+	address := "module." + call.Name
+	if call.Repeated {
+		address += "[0]"
+	}
+
+	plan := helpers.InitAndPlan(t, helpers.RootOptions(t, rootPlaceholderVars(), rootVars, map[string]interface{}{
+		"location": placement.Region,
+	}))
+
+	plan.AssertCreated(t, "azurerm_resource_group.main").
+		HasAttribute("location", placement.Region)
+	assertPlannedLocation(t, plan, address, placement.Location)
+}
+
+// assertPlannedLocation checks that every planned resource with a location
+// in module, or in the whole plan when module is "", is in the expected
+// region.
+func assertPlannedLocation(t *testing.T, plan *helpers.Plan, module, location string) {
+	t.Helper()
+
+	located := 0
+	for _, change := range plan.RawPlan.ResourceChanges {
+		if change.Change == nil {
+			continue
+		}
+		if module != "" && change.ModuleAddress != module && !strings.HasPrefix(change.ModuleAddress, module+".") {
+			continue
+		}
+		after, ok := change.Change.After.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, ok := after["location"].(string); ok {
+			located++
+			assert.Equal(t, location, value, change.Address)
+		}
+	}
+
+	assert.NotZero(t, located, "no planned resource has a location")
+}
@@ -0,0 +1,88 @@
+//go:build unit
+
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - ROOT MODULE COMPOSITION TESTS
+// =============================================================================
+//
+// Checks how terraform/main.tf wires the modules together. The static check
+// needs no Terraform; the plan needs mock providers.
+//
+// Run with: go test -v -tags=unit -run TestRootComposition ./modules/
+// Run with: TERRATEST_MOCK_PROVIDERS=true go test -v -tags=unit -run TestRootPlanWithMockProviders ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// rootPlaceholderVars fills the values terraform.tfvars.example leaves
+// empty, and github_token, which is set through TF_VAR_github_token.
+func rootPlaceholderVars() map[string]interface{} {
+	return map[string]interface{}{
+		"azure_subscription_id": helpers.TestSubscriptionID,
+		"azure_tenant_id":       helpers.TestTenantID,
+		"admin_group_id":        helpers.TestObjectID,
+		"github_org":            "three-horizons",
+		"github_token":          "ghp_test",
+	}
+}
+
+// TestRootComposition tests that every module argument is a declared
+// variable of a compatible type, every module output reference exists and
+// terraform.tfvars.example sets only declared variables
+func TestRootComposition(t *testing.T) {
+	t.Parallel()
+
+	problems, err := helpers.CheckComposition(helpers.RootModuleDir())
+	require.NoError(t, err)
+	assert.Empty(t, problems, "terraform/ module wiring:\n  - %s", strings.Join(problems, "\n  - "))
+
+	declared, err := helpers.DirVariables(helpers.RootModuleDir())
+	require.NoError(t, err)
+	example, err := helpers.ParseVarsFile(helpers.RootExampleVarsPath())
+	require.NoError(t, err)
+
+	problems = helpers.CheckVars(declared, example, "github_token")
+	assert.Empty(t, problems, "terraform.tfvars.example:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+// TestRootPlanWithMockProviders tests that the root plans from
+// terraform.tfvars.example with no credentials
+func TestRootPlanWithMockProviders(t *testing.T) {
+	t.Parallel()
+
+	if !helpers.MockProvidersEnabled() {
+		t.Skipf("Set %s=true to plan with mock providers", helpers.MockProvidersEnv)
+	}
+
+	plan := helpers.InitAndPlan(t, helpers.RootOptions(t, rootPlaceholderVars()))
+
+	plan.AssertCreated(t, "azurerm_resource_group.main")
+	for _, module := range []string{"networking", "security", "aks", "databases[0]", "observability[0]", "argocd[0]", "container_registry[0]", "external_secrets[0]"} {
+		assert.NotEmpty(t, moduleChanges(plan, "module."+module), "module.%s plans no resources", module)
+	}
+	for _, module := range []string{"defender", "purview", "github_runners", "rhdh", "cost_management", "ai_foundry", "disaster_recovery"} {
+		assert.Empty(t, moduleChanges(plan, "module."+module+"[0]"), "module.%s is disabled in terraform.tfvars.example", module)
+	}
+}
+
+// moduleChanges returns the addresses of the resource changes in a module
+// instance and its child modules.
+func moduleChanges(plan *helpers.Plan, module string) []string {
+	var addresses []string
+	for _, change := range plan.RawPlan.ResourceChanges {
+		if change.ModuleAddress == module || strings.HasPrefix(change.ModuleAddress, module+".") {
+			addresses = append(addresses, change.Address)
+		}
+	}
+	return addresses
+}
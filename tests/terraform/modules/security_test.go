@@ -1,10 +1,12 @@
+//go:build unit
+
 // =============================================================================
 // THREE HORIZONS ACCELERATOR - SECURITY MODULE TESTS
 // =============================================================================
 //
 // Unit and integration tests for the Terraform security module.
 //
-// Run with: go test -v -run TestSecurity ./modules/
+// Run with: go test -v -tags=unit -run TestSecurity ./modules/
 //
 // =============================================================================
 
@@ -13,168 +15,128 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/three-horizons/accelerator/tests/helpers"
 )
 
 // TestSecurityModuleBasic tests basic security module configuration
 func TestSecurityModuleBasic(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/security",
-		Vars: map[string]interface{}{
-			"customer_name":       "testsec",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-security",
-			"tenant_id":           "00000000-0000-0000-0000-000000000000",
-			"admin_group_id":      "00000000-0000-0000-0000-000000000001",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
+	terraformOptions := helpers.SecurityOptions(t, map[string]interface{}{
+		"customer_name": "testsec",
 	})
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify Key Vault is planned
-	assert.Contains(t, planOutput, "azurerm_key_vault.main")
+	plan.AssertCreated(t, "azurerm_key_vault.main").
+		HasAttribute("purge_protection_enabled", true)
+	plan.AssertCreated(t, "azurerm_private_endpoint.key_vault")
 }
 
 // TestSecurityModuleKeyVaultNaming tests Key Vault naming convention
 func TestSecurityModuleKeyVaultNaming(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/security",
-		Vars: map[string]interface{}{
-			"customer_name":       "kvtest",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-kv",
-			"tenant_id":           "00000000-0000-0000-0000-000000000000",
-			"admin_group_id":      "00000000-0000-0000-0000-000000000001",
-		},
-		NoColor: true,
+	terraformOptions := helpers.SecurityOptions(t, map[string]interface{}{
+		"customer_name": "kvtest",
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Key Vault name must follow CAF naming convention
-	assert.Contains(t, planOutput, "kv-")
+	plan.AssertCreated(t, "azurerm_key_vault.main").
+		HasAttribute("name", "kv-kvtest-"+helpers.TestEnvironment)
 }
 
 // TestSecurityModuleManagedIdentities tests managed identity creation
 func TestSecurityModuleManagedIdentities(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/security",
-		Vars: map[string]interface{}{
-			"customer_name":        "idtest",
-			"environment":          "dev",
-			"location":             "brazilsouth",
-			"resource_group_name":  "rg-test-identity",
-			"tenant_id":            "00000000-0000-0000-0000-000000000000",
-			"admin_group_id":       "00000000-0000-0000-0000-000000000001",
-			"create_aks_identity":  true,
-			"create_app_identities": []string{"app1", "app2"},
+	terraformOptions := helpers.SecurityOptions(t, map[string]interface{}{
+		"customer_name": "idtest",
+		"workload_identities": map[string]interface{}{
+			"app1": map[string]interface{}{
+				"namespace":                   "app1",
+				"service_account":             "app1",
+				"key_vault_role":              "Key Vault Secrets User",
+				"additional_role_assignments": []interface{}{},
+			},
+			"app2": map[string]interface{}{
+				"namespace":                   "app2",
+				"service_account":             "app2",
+				"key_vault_role":              "Key Vault Secrets User",
+				"additional_role_assignments": []interface{}{},
+			},
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify managed identities are planned
-	assert.Contains(t, planOutput, "azurerm_user_assigned_identity")
+	plan.AssertResourceCount(t, "azurerm_user_assigned_identity.workload", 2)
+	plan.AssertCreated(t, `azurerm_user_assigned_identity.workload["app1"]`).
+		HasAttribute("name", "id-app1-idtest-"+helpers.TestEnvironment)
+	plan.AssertCreated(t, `azurerm_federated_identity_credential.workload["app2"]`).
+		HasAttribute("subject", "system:serviceaccount:app2:app2")
 }
 
 // TestSecurityModuleRBACAssignments tests RBAC role assignments
 func TestSecurityModuleRBACAssignments(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/security",
-		Vars: map[string]interface{}{
-			"customer_name":       "rbactest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-rbac",
-			"tenant_id":           "00000000-0000-0000-0000-000000000000",
-			"admin_group_id":      "00000000-0000-0000-0000-000000000001",
-		},
-		NoColor: true,
+	terraformOptions := helpers.SecurityOptions(t, map[string]interface{}{
+		"customer_name": "rbactest",
+		"environment":   "prod",
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// Verify RBAC assignments are planned
-	assert.Contains(t, planOutput, "azurerm_role_assignment")
+	plan.AssertCreated(t, "azurerm_role_assignment.kv_admin").
+		HasAttribute("role_definition_name", "Key Vault Administrator").
+		HasAttribute("principal_id", helpers.TestObjectID)
+	plan.AssertCreated(t, "azurerm_role_assignment.external_secrets_kv").
+		HasAttribute("role_definition_name", "Key Vault Secrets User")
 }
 
 // TestSecurityModuleKeyVaultAccessPolicies tests Key Vault access policies
 func TestSecurityModuleKeyVaultAccessPolicies(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/security",
-		Vars: map[string]interface{}{
-			"customer_name":       "kvaccess",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-kvaccess",
-			"tenant_id":           "00000000-0000-0000-0000-000000000000",
-			"admin_group_id":      "00000000-0000-0000-0000-000000000001",
+	terraformOptions := helpers.SecurityOptions(t, map[string]interface{}{
+		"customer_name": "kvaccess",
+		"key_vault_config": map[string]interface{}{
 			"enable_rbac_authorization": true,
 		},
-		NoColor: true,
 	})
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlan(t, terraformOptions)
 
 	// With RBAC, access policies should not be used
-	assert.Contains(t, planOutput, "enable_rbac_authorization")
+	plan.AssertCreated(t, "azurerm_key_vault.main").
+		HasAttribute("enable_rbac_authorization", true)
 }
 
 // TestSecurityModuleEnvironments tests different environment configurations
 func TestSecurityModuleEnvironments(t *testing.T) {
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
-
-	for _, env := range environments {
+	for _, env := range helpers.Environments() {
 		env := env
-		t.Run(env, func(t *testing.T) {
+		t.Run(env.Name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/security",
-				Vars: map[string]interface{}{
-					"customer_name":       "secenv",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-sec-" + env,
-					"tenant_id":           "00000000-0000-0000-0000-000000000000",
-					"admin_group_id":      "00000000-0000-0000-0000-000000000001",
-				},
-				NoColor: true,
+			terraformOptions := helpers.SecurityOptions(t, env.Vars(helpers.ModuleSecurity), map[string]interface{}{
+				"customer_name":       "secenv",
+				"resource_group_name": "rg-test-sec-" + env.Name,
 			})
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlan(t, terraformOptions)
 
 			// Verify environment is reflected
-			assert.Contains(t, planOutput, env)
+			plan.AssertCreated(t, "azurerm_key_vault.main").
+				HasAttribute("name", "kv-secenv-"+env.Value(helpers.ModuleSecurity))
 		})
 	}
 }
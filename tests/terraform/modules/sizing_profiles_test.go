@@ -0,0 +1,155 @@
+//go:build unit
+
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - SIZING PROFILE MATRIX TESTS
+// =============================================================================
+//
+// Plans every module that consumes config/sizing-profiles.yaml once per
+// profile and checks the plan carries the profile's values. Adding a profile
+// to the YAML adds it to the matrix.
+//
+// Run with: go test -v -tags=unit -run TestSizingProfile ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// sizingModuleOverrides are the extra inputs a module needs for the sized
+// resources to be planned at all.
+var sizingModuleOverrides = map[string]map[string]interface{}{
+	// HA and geo-redundant backups only apply in prod
+	helpers.ModuleDatabases: {"environment": "prod"},
+	// The workspace is only created when none is passed in
+	helpers.ModuleObservability: {
+		"enable_container_insights":  true,
+		"log_analytics_workspace_id": "",
+	},
+}
+
+// TestSizingProfileMatrix tests every sizing profile against every module it sizes
+func TestSizingProfileMatrix(t *testing.T) {
+	t.Parallel()
+
+	profiles, err := helpers.LoadSizingProfiles()
+	require.NoError(t, err)
+
+	for _, name := range profiles.Names() {
+		profile := profiles.Get(name)
+		for _, module := range helpers.SizingModules {
+			name, module := name, module
+			vars := profile.VarsFor(module)
+			if vars == nil {
+				continue
+			}
+
+			t.Run(name+"/"+module, func(t *testing.T) {
+				t.Parallel()
+
+				terraformOptions := helpers.OptionsFor(t, module, vars, sizingModuleOverrides[module])
+				plan := helpers.InitAndPlan(t, terraformOptions)
+
+				assertSizedPlan(t, plan, profile, module)
+			})
+		}
+	}
+}
+
+// assertSizedPlan checks the planned resources of module against profile.
+func assertSizedPlan(t *testing.T, plan *helpers.Plan, profile *helpers.SizingProfile, module string) {
+	t.Helper()
+
+	switch module {
+	case helpers.ModuleAKSCluster:
+		aks := profile.AKS()
+		system := aks.SystemPool()
+		cluster := plan.AssertCreated(t, "azurerm_kubernetes_cluster.main").
+			HasAttribute("default_node_pool.0.vm_size", system.VMSize).
+			HasAttribute("default_node_pool.0.node_count", system.NodeCount)
+		if system.AutoScaling != nil && system.AutoScaling.Enabled {
+			cluster.HasAttribute("default_node_pool.0.min_count", system.AutoScaling.MinNodes).
+				HasAttribute("default_node_pool.0.max_count", system.AutoScaling.MaxNodes)
+		}
+
+		plan.AssertResourceCount(t, "azurerm_kubernetes_cluster_node_pool.user", len(aks.UserPools()))
+		for _, pool := range aks.UserPools() {
+			userPool := plan.AssertCreated(t, fmt.Sprintf("azurerm_kubernetes_cluster_node_pool.user[%q]", pool.Name)).
+				HasAttribute("vm_size", pool.VMSize)
+			if pool.AutoScaling != nil && pool.AutoScaling.Enabled {
+				userPool.HasAttribute("min_count", pool.AutoScaling.MinNodes).
+					HasAttribute("max_count", pool.AutoScaling.MaxNodes)
+			} else {
+				userPool.HasAttribute("node_count", pool.NodeCount)
+			}
+		}
+
+	case helpers.ModuleDatabases:
+		postgres := profile.PostgreSQL()
+		if postgres.Enabled != nil && !*postgres.Enabled {
+			plan.AssertAbsent(t, "azurerm_postgresql_flexible_server.main")
+		} else {
+			plan.AssertCreated(t, "azurerm_postgresql_flexible_server.main").
+				HasAttribute("sku_name", helpers.FlexibleServerSKU(postgres.SKU)).
+				HasAttribute("version", postgres.Version).
+				HasAttribute("storage_mb", postgres.StorageGB*1024).
+				HasAttribute("geo_redundant_backup_enabled", postgres.GeoRedundantBackup)
+		}
+
+		redis := profile.Databases.Redis
+		if redis.SKU == "" || (redis.Enabled != nil && !*redis.Enabled) {
+			plan.AssertAbsent(t, "azurerm_redis_cache.main")
+		} else {
+			plan.AssertCreated(t, "azurerm_redis_cache.main").
+				HasAttribute("sku_name", redis.SKU).
+				HasAttribute("family", redis.Family).
+				HasAttribute("capacity", redis.Capacity)
+		}
+
+	case helpers.ModuleContainerRegistry:
+		acr := profile.Infrastructure.ACR
+		plan.AssertCreated(t, "azurerm_container_registry.main").
+			HasAttribute("sku", acr.SKU)
+
+		replicas := 0
+		if acr.SKU == "Premium" && acr.GeoReplication {
+			replicas = len(acr.Replications)
+		}
+		plan.AssertResourceCount(t, "azurerm_container_registry_replication.replicas", replicas)
+
+	case helpers.ModuleObservability:
+		plan.AssertCreated(t, "azurerm_log_analytics_workspace.main").
+			HasAttribute("retention_in_days", profile.Observability.LogAnalytics.RetentionDays)
+
+	case helpers.ModuleAIFoundry:
+		capacity := profile.OpenAIModelCapacity()
+		plan.AssertResourceCount(t, "azurerm_cognitive_deployment.models", len(capacity))
+		for key, tpm := range capacity {
+			model := helpers.OpenAIModels[key]
+			plan.AssertCreated(t, fmt.Sprintf("azurerm_cognitive_deployment.models[%q]", model.Name)).
+				HasAttribute("model.0.version", model.Version).
+				HasAttribute("scale.0.capacity", helpers.OpenAICapacityUnits(tpm))
+		}
+
+		if search := profile.AIFoundry.AISearch; search != nil {
+			plan.AssertCreated(t, "azurerm_search_service.main").
+				HasAttribute("sku", search.SKU).
+				HasAttribute("replica_count", search.Replicas).
+				HasAttribute("partition_count", search.Partitions)
+		} else {
+			plan.AssertAbsent(t, "azurerm_search_service.main")
+		}
+
+		if safety := profile.AIFoundry.ContentSafety; safety != nil && safety.Enabled {
+			plan.AssertCreated(t, "azurerm_cognitive_account.content_safety")
+		} else {
+			plan.AssertAbsent(t, "azurerm_cognitive_account.content_safety")
+		}
+	}
+}
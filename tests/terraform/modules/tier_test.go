@@ -0,0 +1,6 @@
+//go:build !integration
+
+package modules
+
+// integrationTier is true only when the suite is built with -tags=integration.
+const integrationTier = false
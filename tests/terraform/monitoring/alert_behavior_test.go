@@ -0,0 +1,85 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PROMETHEUS ALERT BEHAVIOR TESTS
+// =============================================================================
+//
+// Evaluates prometheus/alerting-rules.yaml over the synthetic series in
+// testdata/prometheus/*.yaml and checks which alerts fire when, with their
+// rendered labels and annotations (see helpers/alerttest.go). Nothing but
+// the Prometheus libraries is needed.
+//
+// Run with: go test -v -run TestPrometheusAlertBehavior ./monitoring/
+//
+// =============================================================================
+
+package monitoring
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// TestPrometheusAlertBehavior tests each fixture's alerts over simulated time
+func TestPrometheusAlertBehavior(t *testing.T) {
+	t.Parallel()
+
+	paths, err := filepath.Glob(filepath.Join(helpers.AlertTestDir(), "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		path := path
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".yaml"), func(t *testing.T) {
+			t.Parallel()
+
+			fixture, err := helpers.LoadAlertTest(path)
+			require.NoError(t, err)
+
+			problems, err := fixture.Evaluate(t, helpers.AlertingRulesPath())
+			require.NoError(t, err)
+			for _, problem := range problems {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+// TestPrometheusAlertBehaviorCoverage logs the alerts without a behavior
+// fixture
+func TestPrometheusAlertBehaviorCoverage(t *testing.T) {
+	t.Parallel()
+
+	paths, err := filepath.Glob(filepath.Join(helpers.AlertTestDir(), "*.yaml"))
+	require.NoError(t, err)
+
+	tested := map[string]bool{}
+	for _, path := range paths {
+		fixture, err := helpers.LoadAlertTest(path)
+		require.NoError(t, err)
+		for _, name := range fixture.Alertnames() {
+			tested[name] = true
+		}
+	}
+
+	groups, err := helpers.LoadRuleGroups(helpers.AlertingRulesPath())
+	require.NoError(t, err)
+
+	var untested []string
+	total := 0
+	for _, group := range groups.Groups {
+		for _, rule := range group.Rules {
+			total++
+			if !tested[rule.Alert.Value] {
+				untested = append(untested, rule.Alert.Value)
+			}
+		}
+	}
+
+	t.Logf("%d of %d alerts have behavior fixtures", total-len(untested), total)
+	if len(untested) > 0 {
+		t.Logf("Without fixtures: %s", strings.Join(untested, ", "))
+	}
+}
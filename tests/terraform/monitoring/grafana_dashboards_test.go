@@ -0,0 +1,119 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - GRAFANA DASHBOARD TESTS
+// =============================================================================
+//
+// Lints grafana/dashboards/*.json (see helpers/grafana.go):
+//
+//   - schema version, uid, title and unique panel ids
+//   - every datasource reference resolves to a datasource provisioned by
+//     deploy/helm/monitoring/values.yaml
+//   - every PromQL target, annotation and variable query parses and only
+//     selects metrics recorded in prometheus/recording-rules.yaml or listed
+//     in testdata/grafana/exporter-metrics.yaml
+//
+// Run with: go test -v -run TestGrafana ./monitoring/
+//
+// =============================================================================
+
+package monitoring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+func loadDashboards(t *testing.T) []*helpers.Dashboard {
+	t.Helper()
+
+	dashboards, err := helpers.LoadDashboards(helpers.GrafanaDashboardsDir())
+	require.NoError(t, err)
+	require.NotEmpty(t, dashboards)
+	return dashboards
+}
+
+// TestGrafanaDashboards tests the structure and datasources of each dashboard
+func TestGrafanaDashboards(t *testing.T) {
+	t.Parallel()
+
+	datasources, err := helpers.LoadProvisionedDatasources(helpers.MonitoringValuesPath())
+	require.NoError(t, err)
+
+	uids := map[string]string{}
+	for _, dashboard := range loadDashboards(t) {
+		dashboard := dashboard
+
+		if first, ok := uids[dashboard.UID]; ok && dashboard.UID != "" {
+			t.Errorf("%s: uid %q is already used by %s", dashboard.Source, dashboard.UID, first)
+		}
+		uids[dashboard.UID] = dashboard.Source
+
+		t.Run(dashboard.Source, func(t *testing.T) {
+			t.Parallel()
+
+			for _, problem := range dashboard.Check() {
+				t.Error(problem)
+			}
+			for _, problem := range dashboard.CheckDatasources(datasources) {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+// TestGrafanaDashboardMetrics tests that every queried metric is recorded or
+// provided by an allowlisted exporter
+func TestGrafanaDashboardMetrics(t *testing.T) {
+	t.Parallel()
+
+	groups, err := helpers.LoadRuleGroups(helpers.RecordingRulesPath())
+	require.NoError(t, err)
+	recorded := map[string]bool{}
+	for _, group := range groups.Groups {
+		for _, rule := range group.Rules {
+			recorded[rule.Record.Value] = true
+		}
+	}
+
+	exporters, err := helpers.LoadExporterMetrics(helpers.ExporterMetricsPath())
+	require.NoError(t, err)
+	used := map[string]bool{}
+
+	for _, dashboard := range loadDashboards(t) {
+		queries := 0
+		for _, query := range dashboard.Queries() {
+			if !query.IsPrometheus(dashboard) {
+				continue
+			}
+			queries++
+
+			metrics, err := query.Metrics()
+			if !assert.NoError(t, err, "%s: %s: %s", dashboard.Source, query.Location, query.Expr) {
+				continue
+			}
+
+			for _, metric := range metrics {
+				if recorded[metric] {
+					continue
+				}
+				if exporters.Exporter(metric) == "" {
+					t.Errorf("%s: %s: %s is neither recorded in %s nor listed in %s",
+						dashboard.Source, query.Location, metric, "prometheus/recording-rules.yaml", "testdata/grafana/exporter-metrics.yaml")
+					continue
+				}
+				used[metric] = true
+			}
+		}
+		t.Logf("%s: %d PromQL queries", dashboard.Source, queries)
+	}
+
+	for exporter, metrics := range exporters.Exporters {
+		for _, metric := range metrics {
+			if !used[metric] {
+				t.Errorf("exporter-metrics.yaml: %s (%s) is not queried by any dashboard; remove it", metric, exporter)
+			}
+		}
+	}
+}
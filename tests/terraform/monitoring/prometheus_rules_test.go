@@ -0,0 +1,92 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - PROMETHEUS RULE TESTS
+// =============================================================================
+//
+// Validates prometheus/alerting-rules.yaml and prometheus/recording-rules.yaml
+// before they reach the cluster (see helpers/prometheus.go):
+//
+//   - both files parse with the Prometheus rule-group parser, so every expr
+//     is valid PromQL and every annotation a valid template
+//   - alerts carry the labels and annotations Alertmanager routing and the
+//     runbooks rely on
+//   - recording rules are named level:metric:operation and defined once
+//
+// Run with: go test -v -run TestPrometheus ./monitoring/
+//
+// =============================================================================
+
+package monitoring
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// TestPrometheusRules tests that both rule files parse and follow the
+// conventions
+func TestPrometheusRules(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		path string
+		// alerts is true for the alerting file, false for the recording file
+		alerts bool
+	}{
+		{"alerting", helpers.AlertingRulesPath(), true},
+		{"recording", helpers.RecordingRulesPath(), false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			groups, err := helpers.LoadRuleGroups(tc.path)
+			require.NoError(t, err)
+			require.NotEmpty(t, groups.Groups)
+
+			file := filepath.Base(tc.path)
+			for _, problem := range helpers.CheckRuleConventions(groups) {
+				t.Errorf("%s:%s", file, problem)
+			}
+
+			for _, group := range groups.Groups {
+				assert.NotEmpty(t, group.Rules, "%s: group %s has no rules", file, group.Name)
+				for _, rule := range group.Rules {
+					if tc.alerts {
+						assert.NotEmpty(t, rule.Alert.Value, "%s:%d: recording rule %s belongs in %s",
+							file, rule.Record.Line, rule.Record.Value, filepath.Base(helpers.RecordingRulesPath()))
+					} else {
+						assert.NotEmpty(t, rule.Record.Value, "%s:%d: alert %s belongs in %s",
+							file, rule.Alert.Line, rule.Alert.Value, filepath.Base(helpers.AlertingRulesPath()))
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestPrometheusRecordingRulesUnique tests that no series is recorded twice
+func TestPrometheusRecordingRulesUnique(t *testing.T) {
+	t.Parallel()
+
+	groups, err := helpers.LoadRuleGroups(helpers.RecordingRulesPath())
+	require.NoError(t, err)
+
+	seen := map[string]int{}
+	for _, group := range groups.Groups {
+		for _, rule := range group.Rules {
+			name := rule.Record.Value
+			if first, ok := seen[name]; ok {
+				t.Errorf("recording-rules.yaml:%d: %s is already recorded on line %d", rule.Record.Line, name, first)
+				continue
+			}
+			seen[name] = rule.Record.Line
+		}
+	}
+}
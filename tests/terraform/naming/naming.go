@@ -0,0 +1,379 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - AZURE NAMING CONVENTION
+// =============================================================================
+//
+// Go implementation of terraform/modules/naming, for tools that generate
+// Azure resource names outside Terraform (RHDH templates, scripts) and must
+// match the module exactly:
+//
+//	names, err := naming.Names(naming.Input{
+//	    ProjectName: "contoso",
+//	    Environment: "prd",
+//	    Location:    "brazilsouth",
+//	})
+//	names["key_vault"] // kv-contoso-prd-brs
+//
+// Names are keyed by the module's output names. The package only depends on
+// the standard library. modules/naming_reference_test.go plans the module
+// for a large input table and fails on any difference, so a change to the
+// module needs the same change here.
+//
+// =============================================================================
+
+package naming
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultInstance is the instance when Input.Instance is empty.
+const DefaultInstance = "001"
+
+// Environments are the environment short names the module accepts.
+var Environments = []string{"dev", "stg", "prd", "sbx", "tst"}
+
+// RegionCodes are the Azure region short codes (the region_codes local).
+// Other regions use the first four letters of their name.
+var RegionCodes = map[string]string{
+	// Americas
+	"brazilsouth":     "brs",
+	"brazilsoutheast": "brse",
+	"eastus":          "eus",
+	"eastus2":         "eus2",
+	"westus":          "wus",
+	"westus2":         "wus2",
+	"westus3":         "wus3",
+	"centralus":       "cus",
+	"northcentralus":  "ncus",
+	"southcentralus":  "scus",
+	"westcentralus":   "wcus",
+	"canadacentral":   "cac",
+	"canadaeast":      "cae",
+
+	// Europe
+	"westeurope":         "weu",
+	"northeurope":        "neu",
+	"uksouth":            "uks",
+	"ukwest":             "ukw",
+	"francecentral":      "frc",
+	"francesouth":        "frs",
+	"germanywestcentral": "gwc",
+	"switzerlandnorth":   "chn",
+
+	// Asia Pacific
+	"eastasia":           "ea",
+	"southeastasia":      "sea",
+	"japaneast":          "jpe",
+	"japanwest":          "jpw",
+	"australiaeast":      "aue",
+	"australiasoutheast": "ause",
+	"centralindia":       "inc",
+	"southindia":         "ins",
+	"koreacentral":       "krc",
+	"koreasouth":         "krs",
+}
+
+// The validation blocks of variables.tf.
+var (
+	projectNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]{1,9}$`)
+	instancePattern    = regexp.MustCompile(`^[0-9]{3}$`)
+	orgCodePattern     = regexp.MustCompile(`^[a-z0-9]{2,4}$`)
+)
+
+// Input holds the variables of the naming module.
+type Input struct {
+	ProjectName string
+	Environment string
+	Location    string
+	// Instance defaults to DefaultInstance.
+	Instance string
+	// OrgCode is optional.
+	OrgCode string
+}
+
+// Validate returns the error message of the first variable validation that
+// in fails, as Terraform reports it.
+func (in Input) Validate() error {
+	if !projectNamePattern.MatchString(in.ProjectName) {
+		return fmt.Errorf("project_name %q: Project name must be 2-10 lowercase alphanumeric characters, starting with a letter.", in.ProjectName)
+	}
+	if !containsString(Environments, in.Environment) {
+		return fmt.Errorf("environment %q: Environment must be: dev, stg, prd, sbx, or tst.", in.Environment)
+	}
+	if !instancePattern.MatchString(in.instance()) {
+		return fmt.Errorf("instance %q: Instance must be 3 digits (e.g., '001').", in.Instance)
+	}
+	if in.OrgCode != "" && !orgCodePattern.MatchString(in.OrgCode) {
+		return fmt.Errorf("org_code %q: Organization code must be empty or 2-4 lowercase alphanumeric characters.", in.OrgCode)
+	}
+	return nil
+}
+
+func (in Input) instance() string {
+	if in.Instance == "" {
+		return DefaultInstance
+	}
+	return in.Instance
+}
+
+// RegionCode returns the short code of an Azure region.
+func RegionCode(location string) string {
+	if code, ok := RegionCodes[location]; ok {
+		return code
+	}
+	return substr(location, 0, 4)
+}
+
+// Prefix returns {org}-{project}-{env}-{region}, without the org when
+// OrgCode is empty.
+func (in Input) Prefix() string {
+	prefix := fmt.Sprintf("%s-%s-%s", in.ProjectName, in.Environment, RegionCode(in.Location))
+	if in.OrgCode != "" {
+		prefix = in.OrgCode + "-" + prefix
+	}
+	return prefix
+}
+
+// PrefixNoDash returns Prefix without hyphens, for resources that do not
+// allow them.
+func (in Input) PrefixNoDash() string {
+	return strings.ReplaceAll(in.Prefix(), "-", "")
+}
+
+// ShortPrefix returns {project}{env}{region}, for length-limited resources.
+func (in Input) ShortPrefix() string {
+	return in.ProjectName + in.Environment + RegionCode(in.Location)
+}
+
+// Name patterns.
+type pattern int
+
+const (
+	// {abbreviation}-{prefix}[-{suffix}]
+	dashed pattern = iota
+	// {abbreviation}{prefix without hyphens}[{instance}]
+	noDash
+	// {abbreviation}-{short prefix}
+	short
+)
+
+// convention is how the name of one module output is built.
+type convention struct {
+	Abbreviation string
+	Pattern      pattern
+	Suffix       string
+	// WithInstance appends the instance (noDash only).
+	WithInstance bool
+	// MaxLength shortens longer names (see limit); 0 is no limit.
+	MaxLength int
+}
+
+// conventions are the module outputs built from an abbreviation and a
+// prefix. The other outputs are built by Names.
+var conventions = map[string]convention{
+	// General / management
+	"resource_group":    {Abbreviation: "rg"},
+	"management_group":  {Abbreviation: "mg"},
+	"policy_definition": {Abbreviation: "policy"},
+	"api_management":    {Abbreviation: "apim"},
+
+	// Networking
+	"virtual_network":            {Abbreviation: "vnet"},
+	"subnet":                     {Abbreviation: "snet"},
+	"subnet_aks":                 {Abbreviation: "snet", Suffix: "aks"},
+	"subnet_db":                  {Abbreviation: "snet", Suffix: "db"},
+	"subnet_pe":                  {Abbreviation: "snet", Suffix: "pe"},
+	"network_security_group":     {Abbreviation: "nsg"},
+	"application_security_group": {Abbreviation: "asg"},
+	"route_table":                {Abbreviation: "rt"},
+	"nat_gateway":                {Abbreviation: "ng"},
+	"public_ip":                  {Abbreviation: "pip"},
+	"public_ip_prefix":           {Abbreviation: "ippre"},
+	"load_balancer_internal":     {Abbreviation: "lbi"},
+	"load_balancer_external":     {Abbreviation: "lbe"},
+	"application_gateway":        {Abbreviation: "agw"},
+	"private_endpoint":           {Abbreviation: "pe"},
+	"firewall":                   {Abbreviation: "afw"},
+	"firewall_policy":            {Abbreviation: "afwp"},
+	"bastion":                    {Abbreviation: "bas"},
+	"front_door":                 {Abbreviation: "fd"},
+	"waf_policy":                 {Abbreviation: "waf"},
+
+	// Compute
+	"virtual_machine":           {Abbreviation: "vm", Pattern: short},
+	"virtual_machine_scale_set": {Abbreviation: "vmss"},
+	"availability_set":          {Abbreviation: "avail"},
+	"disk_managed":              {Abbreviation: "disk"},
+	"disk_os":                   {Abbreviation: "osdisk"},
+
+	// Containers
+	"aks_cluster":               {Abbreviation: "aks"},
+	"container_registry":        {Abbreviation: "cr", Pattern: noDash},
+	"container_instance":        {Abbreviation: "ci"},
+	"container_app":             {Abbreviation: "ca"},
+	"container_app_environment": {Abbreviation: "cae"},
+
+	// Databases
+	"sql_server":          {Abbreviation: "sql"},
+	"sql_database":        {Abbreviation: "sqldb"},
+	"sql_elastic_pool":    {Abbreviation: "sqlep"},
+	"postgresql_server":   {Abbreviation: "psql"},
+	"postgresql_database": {Abbreviation: "psqldb"},
+	"mysql_server":        {Abbreviation: "mysql"},
+	"cosmos_account":      {Abbreviation: "cosmos"},
+	"redis_cache":         {Abbreviation: "redis"},
+
+	// Storage
+	"storage_account":      {Abbreviation: "st", Pattern: noDash, WithInstance: true, MaxLength: 24},
+	"storage_account_diag": {Abbreviation: "stdiag", Pattern: noDash, MaxLength: 24},
+	"storage_container":    {Abbreviation: "blob"},
+	"storage_queue":        {Abbreviation: "queue"},
+	"storage_table":        {Abbreviation: "table", Pattern: noDash},
+	"storage_file_share":   {Abbreviation: "share"},
+	"data_lake_store":      {Abbreviation: "dls", Pattern: noDash},
+
+	// Security
+	"key_vault":                {Abbreviation: "kv", MaxLength: 24},
+	"key_vault_key":            {Abbreviation: "key"},
+	"key_vault_secret":         {Abbreviation: "secret"},
+	"managed_identity":         {Abbreviation: "id"},
+	"managed_identity_aks":     {Abbreviation: "id", Suffix: "aks"},
+	"application_registration": {Abbreviation: "app"},
+	"service_principal":        {Abbreviation: "sp"},
+
+	// Monitoring
+	"log_analytics_workspace": {Abbreviation: "log"},
+	"application_insights":    {Abbreviation: "appi"},
+	"action_group":            {Abbreviation: "ag"},
+	"dashboard":               {Abbreviation: "dash"},
+	"monitor_workspace":       {Abbreviation: "amw"},
+	"grafana":                 {Abbreviation: "amg", MaxLength: 23},
+
+	// AI & machine learning
+	"cognitive_services":         {Abbreviation: "cog"},
+	"openai_service":             {Abbreviation: "oai"},
+	"ai_hub":                     {Abbreviation: "aih"},
+	"ai_project":                 {Abbreviation: "aip"},
+	"machine_learning_workspace": {Abbreviation: "mlw"},
+	"search_service":             {Abbreviation: "srch"},
+
+	// Governance
+	"purview_account": {Abbreviation: "pview"},
+	"defender_plan":   {Abbreviation: "defender"},
+
+	// Integration
+	"service_bus_namespace": {Abbreviation: "sb"},
+	"event_hub_namespace":   {Abbreviation: "evh"},
+	"event_grid_topic":      {Abbreviation: "evgt"},
+	"logic_app":             {Abbreviation: "logic"},
+	"function_app":          {Abbreviation: "func"},
+	"app_service_plan":      {Abbreviation: "asp"},
+	"web_app":               {Abbreviation: "app"},
+
+	// DevOps
+	"automation_account":     {Abbreviation: "aa"},
+	"deployment_environment": {Abbreviation: "ade"},
+	"dev_center":             {Abbreviation: "dc"},
+}
+
+// Names returns every name output of the module for in, keyed by output
+// name. The tags output is returned by Tags.
+func Names(in Input) (map[string]string, error) {
+	if err := in.Validate(); err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{
+		"aks_node_pool":        substr(strings.ReplaceAll(in.ProjectName+in.Environment, "-", ""), 0, 6),
+		"aks_node_pool_system": "system",
+		"aks_node_pool_user":   "user" + in.instance(),
+		"private_dns_zone":     "privatelink.azurecr.io",
+		"name_prefix":          in.Prefix(),
+		"name_prefix_no_dash":  in.PrefixNoDash(),
+		"short_prefix":         in.ShortPrefix(),
+		"region_code":          RegionCode(in.Location),
+	}
+
+	for output, c := range conventions {
+		var name string
+		switch c.Pattern {
+		case dashed:
+			name = c.Abbreviation + "-" + in.Prefix()
+			if c.Suffix != "" {
+				name += "-" + c.Suffix
+			}
+		case noDash:
+			name = c.Abbreviation + in.PrefixNoDash()
+			if c.WithInstance {
+				name += in.instance()
+			}
+		case short:
+			name = c.Abbreviation + "-" + in.ShortPrefix()
+		}
+		if c.MaxLength > 0 {
+			name = limit(name, c.MaxLength)
+		}
+		names[output] = name
+	}
+	return names, nil
+}
+
+// Name returns a single name output of the module.
+func Name(in Input, output string) (string, error) {
+	names, err := Names(in)
+	if err != nil {
+		return "", err
+	}
+	name, ok := names[output]
+	if !ok {
+		return "", fmt.Errorf("naming module has no output %q", output)
+	}
+	return name, nil
+}
+
+// Tags returns the tags output of the module.
+func Tags(in Input) map[string]string {
+	return map[string]string{
+		"Project":     in.ProjectName,
+		"Environment": in.Environment,
+		"Region":      in.Location,
+		"ManagedBy":   "Terraform",
+	}
+}
+
+// limit keeps a name longer than max to its first max-5 characters and
+// appends the first 5 hex characters of the SHA-1 of the full name.
+func limit(name string, max int) string {
+	if len([]rune(name)) <= max {
+		return name
+	}
+	sum := sha1.Sum([]byte(name))
+	return substr(name, 0, max-5) + hex.EncodeToString(sum[:])[:5]
+}
+
+// substr is Terraform's substr for a non-negative offset: a length past the
+// end returns the rest of the string.
+func substr(s string, offset, length int) string {
+	runes := []rune(s)
+	if offset >= len(runes) {
+		return ""
+	}
+	end := offset + length
+	if end > len(runes) {
+		end = len(runes)
+	}
+	return string(runes[offset:end])
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
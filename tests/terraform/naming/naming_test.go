@@ -0,0 +1,174 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - AZURE NAMING CONVENTION TESTS
+// =============================================================================
+//
+// Static checks of the Go naming convention. The comparison with the
+// Terraform module's outputs is modules/naming_reference_test.go.
+//
+// Run with: go test -v ./naming/
+//
+// =============================================================================
+
+package naming
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// TestNames tests each pattern, length limits and region fallbacks
+func TestNames(t *testing.T) {
+	t.Parallel()
+
+	contoso := Input{ProjectName: "contoso", Environment: "prd", Location: "brazilsouth"}
+	longest := Input{ProjectName: "abcdefghij", Environment: "prd", Location: "eastus2", Instance: "001", OrgCode: "abcd"}
+
+	testCases := []struct {
+		name     string
+		input    Input
+		output   string
+		expected string
+	}{
+		{"dashed", contoso, "resource_group", "rg-contoso-prd-brs"},
+		{"suffix", contoso, "subnet_aks", "snet-contoso-prd-brs-aks"},
+		{"no_dash", contoso, "container_registry", "crcontosoprdbrs"},
+		{"instance", contoso, "storage_account", "stcontosoprdbrs001"},
+		{"short_prefix", contoso, "virtual_machine", "vm-contosoprdbrs"},
+		{"node_pool", contoso, "aks_node_pool", "contos"},
+		{"node_pool_user", Input{ProjectName: "contoso", Environment: "dev", Location: "eastus", Instance: "042"}, "aks_node_pool_user", "user042"},
+		{"fixed", contoso, "private_dns_zone", "privatelink.azurecr.io"},
+		{"org_code", Input{ProjectName: "contoso", Environment: "dev", Location: "westeurope", OrgCode: "ms"}, "key_vault", "kv-ms-contoso-dev-weu"},
+		{"unknown_region", Input{ProjectName: "contoso", Environment: "dev", Location: "qatarcentral"}, "name_prefix", "contoso-dev-qata"},
+		{"short_unknown_region", Input{ProjectName: "contoso", Environment: "dev", Location: "abc"}, "region_code", "abc"},
+		{"key_vault_limit", longest, "key_vault", "kv-abcd-abcdefghij-0d336"},
+		{"storage_account_limit", longest, "storage_account", "stabcdabcdefghijprd16f9e"},
+		{"storage_account_diag_limit", longest, "storage_account_diag", "stdiagabcdabcdefghicb15b"},
+		{"grafana_limit", longest, "grafana", "amg-abcd-abcdefghifc994"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			name, err := Name(tc.input, tc.output)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, name)
+		})
+	}
+
+	_, err := Name(contoso, "kubernetes")
+	assert.EqualError(t, err, `naming module has no output "kubernetes"`)
+}
+
+// TestValidate tests the variable validation of the module
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := Input{ProjectName: "contoso", Environment: "dev", Location: "eastus2"}
+
+	testCases := []struct {
+		name     string
+		mutate   func(in *Input)
+		expected string
+	}{
+		{"valid", func(in *Input) {}, ""},
+		{"project_too_long", func(in *Input) { in.ProjectName = "thisprojectnameiswaytoolong" }, "project_name"},
+		{"project_starts_with_digit", func(in *Input) { in.ProjectName = "3horizons" }, "project_name"},
+		{"canonical_environment", func(in *Input) { in.Environment = "staging" }, "environment"},
+		{"instance", func(in *Input) { in.Instance = "1" }, "instance"},
+		{"org_code", func(in *Input) { in.OrgCode = "Contoso" }, "org_code"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			in := valid
+			tc.mutate(&in)
+			err := in.Validate()
+			if tc.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, strings.HasPrefix(err.Error(), tc.expected+" "), err.Error())
+
+			_, err = Names(in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestRegionCodesMatchModule tests that RegionCodes is the region_codes
+// local of the module and covers config/region-availability.yaml
+func TestRegionCodesMatchModule(t *testing.T) {
+	t.Parallel()
+
+	codes, err := helpers.NamingRegionCodes()
+	require.NoError(t, err)
+	assert.Equal(t, codes, RegionCodes)
+
+	matrix, err := helpers.LoadRegionAvailability()
+	require.NoError(t, err)
+	for _, region := range matrix.Names() {
+		assert.Contains(t, RegionCodes, region)
+	}
+}
+
+// TestNamesCoverModuleOutputs tests that Names returns every output of the
+// module except tags
+func TestNamesCoverModuleOutputs(t *testing.T) {
+	t.Parallel()
+
+	outputs, err := helpers.DirOutputs(helpers.ModuleDir(helpers.ModuleNaming))
+	require.NoError(t, err)
+
+	names, err := Names(Input{ProjectName: "contoso", Environment: "dev", Location: "eastus2"})
+	require.NoError(t, err)
+
+	var expected, actual []string
+	for output := range outputs {
+		if output != "tags" {
+			expected = append(expected, output)
+		}
+	}
+	for output := range names {
+		actual = append(actual, output)
+	}
+	sort.Strings(expected)
+	sort.Strings(actual)
+	assert.Equal(t, expected, actual)
+}
+
+// TestNamesFollowAzureNamingRules tests the names of the longest and
+// shortest inputs against helpers.AzureNamingRules
+func TestNamesFollowAzureNamingRules(t *testing.T) {
+	t.Parallel()
+
+	var inputs []Input
+	for _, location := range []string{"eastasia", "australiasoutheast", "qatarcentral"} {
+		for _, environment := range Environments {
+			inputs = append(inputs,
+				Input{ProjectName: "ab", Environment: environment, Location: location},
+				Input{ProjectName: "abcdefghij", Environment: environment, Location: location, Instance: "999", OrgCode: "abcd"},
+			)
+		}
+	}
+
+	for _, in := range inputs {
+		names, err := Names(in)
+		require.NoError(t, err)
+		for output, resourceType := range helpers.NamingOutputResourceTypes {
+			problems := helpers.AzureNamingRules[resourceType].Check(names[output])
+			assert.Empty(t, problems, "%+v: %s %q: %s", in, output, names[output], strings.Join(problems, "; "))
+		}
+	}
+}
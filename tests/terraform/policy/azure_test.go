@@ -0,0 +1,188 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - TERRAFORM POLICY RULE TESTS
+// =============================================================================
+//
+// Unit tests for policies/terraform/azure.rego (package terraform.azure).
+//
+// Every deny/warn rule carries a METADATA annotation with a custom.id, and
+// testdata/policy/<id>/ holds its fixtures:
+//
+//	pass.json      plan that must produce no deny or warn message
+//	fail.json      plan that violates the rule
+//	expected.yaml  the exact deny and warn messages of fail.json
+//
+// A rule without both fixtures, or fixtures without a rule, fails the suite.
+// No Terraform or Azure credentials are needed.
+//
+// Run with: go test -v ./policy/
+//
+// =============================================================================
+
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+	"gopkg.in/yaml.v3"
+)
+
+// fixtureFiles are required in every testdata/policy/<id>/ directory.
+var fixtureFiles = []string{"pass.json", "fail.json", "expected.yaml"}
+
+// policyRule is one annotated deny or warn rule.
+type policyRule struct {
+	ID    string
+	Kind  string
+	Title string
+	Line  int
+}
+
+// expectedMessages is an expected.yaml file.
+type expectedMessages struct {
+	Deny []string `yaml:"deny"`
+	Warn []string `yaml:"warn"`
+}
+
+func fixtureDir() string {
+	return filepath.Join(helpers.TestDataDir(), "policy")
+}
+
+// policyRules parses azure.rego and returns its deny and warn rules, failing
+// on rules without a unique custom.id.
+func policyRules(t *testing.T) []policyRule {
+	t.Helper()
+
+	path := filepath.Join(helpers.TerraformPolicyDir(), "azure.rego")
+	src, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	module, err := ast.ParseModuleWithOpts(path, string(src), ast.ParserOptions{ProcessAnnotation: true})
+	require.NoError(t, err)
+
+	var rules []policyRule
+	seen := map[string]int{}
+
+	for _, rule := range module.Rules {
+		kind := rule.Head.Name.String()
+		if kind != "deny" && kind != "warn" {
+			continue
+		}
+
+		line := rule.Location.Row
+		var id, title string
+		for _, annotation := range rule.Annotations {
+			title = annotation.Title
+			id, _ = annotation.Custom["id"].(string)
+		}
+		if id == "" {
+			t.Errorf("%s:%d: %s rule has no METADATA annotation with custom.id", path, line, kind)
+			continue
+		}
+		if first, ok := seen[id]; ok {
+			t.Errorf("%s:%d: rule id %q is already used on line %d", path, line, id, first)
+			continue
+		}
+		seen[id] = line
+
+		rules = append(rules, policyRule{ID: id, Kind: kind, Title: title, Line: line})
+	}
+
+	return rules
+}
+
+func prepareTerraformPolicy(t *testing.T) rego.PreparedEvalQuery {
+	t.Helper()
+
+	query, err := helpers.PreparePolicy(helpers.TerraformPolicyDir(), helpers.TerraformPolicyPackage)
+	require.NoError(t, err)
+	return query
+}
+
+func evaluateFixture(t *testing.T, query rego.PreparedEvalQuery, path string) helpers.PolicyResult {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var input interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&input), path)
+
+	result, err := helpers.EvaluatePolicy(query, input)
+	require.NoError(t, err, path)
+	return result
+}
+
+// TestTerraformPolicyRules tests each rule against its passing and violating plans
+func TestTerraformPolicyRules(t *testing.T) {
+	t.Parallel()
+
+	query := prepareTerraformPolicy(t)
+
+	for _, rule := range policyRules(t) {
+		rule := rule
+		t.Run(rule.ID, func(t *testing.T) {
+			t.Parallel()
+
+			dir := filepath.Join(fixtureDir(), rule.ID)
+
+			data, err := os.ReadFile(filepath.Join(dir, "expected.yaml"))
+			require.NoError(t, err)
+			var expected expectedMessages
+			decoder := yaml.NewDecoder(bytes.NewReader(data))
+			decoder.KnownFields(true)
+			require.NoError(t, decoder.Decode(&expected))
+
+			pass := evaluateFixture(t, query, filepath.Join(dir, "pass.json"))
+			assert.Empty(t, pass.Deny, "pass.json deny")
+			assert.Empty(t, pass.Warn, "pass.json warn")
+
+			fail := evaluateFixture(t, query, filepath.Join(dir, "fail.json"))
+			assert.ElementsMatch(t, expected.Deny, fail.Deny, "fail.json deny")
+			assert.ElementsMatch(t, expected.Warn, fail.Warn, "fail.json warn")
+
+			// The violation has to come from this rule's kind
+			if rule.Kind == "deny" {
+				assert.NotEmpty(t, expected.Deny, "a deny rule's fail.json must expect a deny message")
+			} else {
+				assert.NotEmpty(t, expected.Warn, "a warn rule's fail.json must expect a warn message")
+			}
+		})
+	}
+}
+
+// TestTerraformPolicyFixtures tests that every rule has fixtures and every
+// fixture has a rule
+func TestTerraformPolicyFixtures(t *testing.T) {
+	t.Parallel()
+
+	rules := policyRules(t)
+	require.NotEmpty(t, rules)
+
+	ids := map[string]bool{}
+	for _, rule := range rules {
+		ids[rule.ID] = true
+		for _, name := range fixtureFiles {
+			assert.FileExists(t, filepath.Join(fixtureDir(), rule.ID, name),
+				"rule %q (%s %q, azure.rego line %d) needs %s", rule.ID, rule.Kind, rule.Title, rule.Line, name)
+		}
+	}
+
+	entries, err := os.ReadDir(fixtureDir())
+	require.NoError(t, err)
+	for _, entry := range entries {
+		if entry.IsDir() && !ids[entry.Name()] {
+			t.Errorf("testdata/policy/%s has no matching rule in azure.rego", entry.Name())
+		}
+	}
+}
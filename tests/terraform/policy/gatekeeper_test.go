@@ -0,0 +1,163 @@
+// =============================================================================
+// THREE HORIZONS ACCELERATOR - GATEKEEPER CONSTRAINT TESTS
+// =============================================================================
+//
+// Evaluates the ConstraintTemplates in policies/kubernetes/constraint-templates
+// with the parameters of policies/kubernetes/constraints/*.yaml, as Gatekeeper
+// would at admission (see helpers/gatekeeper.go):
+//
+//   - testdata/gatekeeper/<constraint>.yaml holds fixtures for each
+//     constraint, annotated with the expected outcome
+//   - the platform manifests in argocd/apps and argocd/secrets must not be
+//     rejected by any constraint
+//
+// Run with: go test -v -run TestGatekeeper ./policy/
+//
+// =============================================================================
+
+package policy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/three-horizons/accelerator/tests/helpers"
+)
+
+// expectAnnotation holds the expected outcome of a fixture: allowed or
+// rejected.
+const expectAnnotation = "test.three-horizons.io/expect"
+
+// gatekeeperVars stand in for the values the deploy scripts substitute.
+var gatekeeperVars = map[string]string{
+	"ACR_NAME": "crthreehorizons",
+}
+
+// platformManifestDirs are the manifests we ship and must be admitted.
+var platformManifestDirs = []string{
+	filepath.Join("argocd", "apps"),
+	filepath.Join("argocd", "secrets"),
+}
+
+func loadGatekeeper(t *testing.T) (map[string]*helpers.ConstraintTemplate, []*helpers.Constraint) {
+	t.Helper()
+
+	templates, err := helpers.LoadConstraintTemplates(helpers.ConstraintTemplatesDir())
+	require.NoError(t, err)
+	require.NotEmpty(t, templates)
+
+	constraints, err := helpers.LoadConstraints(helpers.ConstraintsDir(), gatekeeperVars)
+	require.NoError(t, err)
+	require.NotEmpty(t, constraints)
+
+	return templates, constraints
+}
+
+// TestGatekeeperConstraints tests that every constraint has a template,
+// valid parameters and fixtures
+func TestGatekeeperConstraints(t *testing.T) {
+	t.Parallel()
+
+	templates, constraints := loadGatekeeper(t)
+
+	for _, constraint := range constraints {
+		template, ok := templates[constraint.Kind]
+		if !assert.True(t, ok, "constraint %s: no ConstraintTemplate defines kind %s", constraint.Name, constraint.Kind) {
+			continue
+		}
+
+		assert.Empty(t, template.CheckParameters(constraint), "constraint %s parameters", constraint.Name)
+		assert.FileExists(t, filepath.Join(helpers.TestDataDir(), "gatekeeper", constraint.Name+".yaml"),
+			"constraint %s needs fixtures", constraint.Name)
+	}
+}
+
+// TestGatekeeperFixtures tests each constraint against its allowed and
+// rejected fixture manifests
+func TestGatekeeperFixtures(t *testing.T) {
+	t.Parallel()
+
+	templates, constraints := loadGatekeeper(t)
+
+	for _, constraint := range constraints {
+		constraint := constraint
+		t.Run(constraint.Name, func(t *testing.T) {
+			t.Parallel()
+
+			manifests, err := helpers.LoadManifests(filepath.Join(helpers.TestDataDir(), "gatekeeper", constraint.Name+".yaml"))
+			require.NoError(t, err)
+
+			seen := map[string]bool{}
+			for _, manifest := range manifests {
+				expect := manifest.Annotation(expectAnnotation)
+				require.Contains(t, []string{"allowed", "rejected"}, expect, "%s: %s annotation", manifest, expectAnnotation)
+				seen[expect] = true
+
+				results, err := helpers.Admit(templates, []*helpers.Constraint{constraint}, []helpers.Manifest{manifest})
+				require.NoError(t, err)
+
+				rejected := false
+				for _, result := range results {
+					for _, violation := range result.Violations {
+						t.Logf("%s: %s", manifest, violation.Msg)
+					}
+					rejected = rejected || result.Rejected()
+				}
+
+				assert.Equal(t, expect == "rejected", rejected, "%s: expected %s", manifest, expect)
+			}
+
+			assert.True(t, seen["allowed"] && seen["rejected"], "fixtures need at least one allowed and one rejected manifest")
+		})
+	}
+}
+
+// TestGatekeeperPlatformManifests tests that no constraint rejects the
+// manifests we ship
+func TestGatekeeperPlatformManifests(t *testing.T) {
+	t.Parallel()
+
+	templates, constraints := loadGatekeeper(t)
+
+	var paths []string
+	for _, dir := range platformManifestDirs {
+		files, err := filepath.Glob(filepath.Join(helpers.RepoRoot(), dir, "*.yaml"))
+		require.NoError(t, err)
+		paths = append(paths, files...)
+	}
+	require.NotEmpty(t, paths)
+
+	manifests, err := helpers.LoadManifests(paths...)
+	require.NoError(t, err)
+
+	results, err := helpers.Admit(templates, constraints, manifests)
+	require.NoError(t, err)
+
+	matched := map[string]bool{}
+	for _, result := range results {
+		matched[result.Manifest.Source] = true
+
+		var messages []string
+		for _, violation := range result.Violations {
+			messages = append(messages, violation.Msg)
+		}
+
+		switch {
+		case result.Rejected():
+			t.Errorf("%s would be rejected by %s:\n  %s", result.Manifest, result.Constraint.Name, strings.Join(messages, "\n  "))
+		case len(messages) > 0:
+			t.Logf("%s: %s (%s) reports:\n  %s", result.Manifest, result.Constraint.Name,
+				result.Constraint.EnforcementAction, strings.Join(messages, "\n  "))
+		}
+	}
+
+	t.Logf("Reviewed %d platform manifests; %d matched a constraint", len(manifests), len(matched))
+	for _, manifest := range manifests {
+		if !matched[manifest.Source] {
+			t.Logf("  not matched: %s", manifest)
+		}
+	}
+}
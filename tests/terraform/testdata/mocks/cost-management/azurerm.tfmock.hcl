@@ -0,0 +1,31 @@
+# =============================================================================
+# CANNED AZURERM DATA - cost-management
+# =============================================================================
+#
+# Data-source results used by mock-provider plans (helpers/mock.go). IDs match
+# the fake identifiers and baselines in helpers/modules.go.
+#
+# =============================================================================
+
+mock_data "azurerm_subscription" {
+  defaults = {
+    id              = "/subscriptions/00000000-0000-0000-0000-000000000000"
+    subscription_id = "00000000-0000-0000-0000-000000000000"
+    tenant_id       = "00000000-0000-0000-0000-000000000000"
+    display_name    = "three-horizons-test"
+    state           = "Enabled"
+  }
+}
+
+mock_data "azurerm_resource_group" {
+  defaults = {
+    id       = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test-cost"
+    location = "brazilsouth"
+  }
+}
+
+mock_data "azurerm_advisor_recommendations" {
+  defaults = {
+    recommendations = []
+  }
+}
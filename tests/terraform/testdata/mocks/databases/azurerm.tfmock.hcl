@@ -0,0 +1,17 @@
+# =============================================================================
+# CANNED AZURERM DATA - databases
+# =============================================================================
+#
+# Data-source results used by mock-provider plans (helpers/mock.go). IDs match
+# the fake identifiers and baselines in helpers/modules.go.
+#
+# =============================================================================
+
+mock_data "azurerm_client_config" {
+  defaults = {
+    client_id       = "00000000-0000-0000-0000-000000000002"
+    tenant_id       = "00000000-0000-0000-0000-000000000000"
+    object_id       = "00000000-0000-0000-0000-000000000001"
+    subscription_id = "00000000-0000-0000-0000-000000000000"
+  }
+}
@@ -0,0 +1,25 @@
+# =============================================================================
+# CANNED AZURERM DATA - external-secrets
+# =============================================================================
+#
+# Data-source results used by mock-provider plans (helpers/mock.go). IDs match
+# the fake identifiers and baselines in helpers/modules.go.
+#
+# =============================================================================
+
+mock_data "azurerm_client_config" {
+  defaults = {
+    client_id       = "00000000-0000-0000-0000-000000000002"
+    tenant_id       = "00000000-0000-0000-0000-000000000000"
+    object_id       = "00000000-0000-0000-0000-000000000001"
+    subscription_id = "00000000-0000-0000-0000-000000000000"
+  }
+}
+
+mock_data "azurerm_kubernetes_cluster" {
+  defaults = {
+    id              = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test-eso/providers/Microsoft.ContainerService/managedClusters/aks-test-eso"
+    location        = "brazilsouth"
+    oidc_issuer_url = "https://brazilsouth.oic.prod-aks.azure.com/00000000-0000-0000-0000-000000000000/00000000-0000-0000-0000-000000000003/"
+  }
+}
@@ -0,0 +1,40 @@
+# =============================================================================
+# NAMING MODULE INPUT TABLE
+# =============================================================================
+#
+# Evaluates terraform/modules/naming for many inputs in a single plan, for
+# modules/naming_reference_test.go. The inputs come from
+# terraform.tfvars.json, written by the test.
+#
+# =============================================================================
+
+terraform {
+  required_version = ">= 1.5.0"
+}
+
+variable "inputs" {
+  description = "Naming module variables, keyed by test case"
+  type = map(object({
+    project_name = string
+    environment  = string
+    location     = string
+    instance     = string
+    org_code     = string
+  }))
+}
+
+module "naming" {
+  source   = "../../../../terraform/modules/naming"
+  for_each = var.inputs
+
+  project_name = each.value.project_name
+  environment  = each.value.environment
+  location     = each.value.location
+  instance     = each.value.instance
+  org_code     = each.value.org_code
+}
+
+output "names" {
+  description = "Every output of the naming module, keyed by test case"
+  value       = module.naming
+}